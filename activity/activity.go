@@ -0,0 +1,220 @@
+// activity/activity.go
+//
+// Package activity keeps a rolling view of live traffic for the operator
+// dashboard: generations in flight, request and Gemini model throughput over
+// the last minute, and the most recent errors. It is in-memory and per
+// process; /metrics and tracing remain the durable record.
+package activity
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/requestid"
+)
+
+const (
+	// Window is how far back throughput is counted.
+	Window = time.Minute
+	// maxErrors is how many recent errors the ticker keeps.
+	maxErrors = 20
+)
+
+// Throughput summarizes the calls to one route or model within the window.
+type Throughput struct {
+	Name         string  `json:"name"`
+	Count        int     `json:"count"`
+	Errors       int     `json:"errors"`
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+}
+
+// Error is one failed request in the error ticker.
+type Error struct {
+	Time       time.Time `json:"time"`
+	Route      string    `json:"route"`
+	Status     int       `json:"status"`
+	RequestID  string    `json:"requestId,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Snapshot is the live traffic view at one point in time.
+type Snapshot struct {
+	Time          time.Time      `json:"time"`
+	WindowSeconds int            `json:"windowSeconds"`
+	Active        map[string]int `json:"active"`
+	Routes        []Throughput   `json:"routes"`
+	Models        []Throughput   `json:"models"`
+	Errors        []Error        `json:"errors"`
+}
+
+type sample struct {
+	at      time.Time
+	name    string
+	elapsed time.Duration
+	failed  bool
+}
+
+// Monitor records live traffic. It is safe for concurrent use.
+type Monitor struct {
+	mu     sync.Mutex
+	active map[string]int
+	routes []sample
+	models []sample
+	errors []Error
+}
+
+// NewMonitor creates an empty Monitor.
+func NewMonitor() *Monitor {
+	return &Monitor{active: make(map[string]int)}
+}
+
+// Begin counts a generation of the given pipeline (e.g. "generate" or
+// "swap") as in flight and returns the function that ends it.
+func (m *Monitor) Begin(pipeline string) func() {
+	m.mu.Lock()
+	m.active[pipeline]++
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		m.active[pipeline]--
+		m.mu.Unlock()
+	}
+}
+
+// ObserveModel records one Gemini call.
+func (m *Monitor) ObserveModel(model string, elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.models = append(prune(m.models), sample{at: time.Now(), name: model, elapsed: elapsed, failed: err != nil})
+}
+
+// Middleware records every request by the route pattern the mux matched.
+// Like tracing.Route, it must wrap the mux directly. Responses of 500 and
+// above, and 429s, go to the error ticker.
+func (m *Monitor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		elapsed := time.Since(start)
+		failed := status >= 500 || status == http.StatusTooManyRequests
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.routes = append(prune(m.routes), sample{at: time.Now(), name: route, elapsed: elapsed, failed: failed})
+		if failed {
+			m.errors = append(m.errors, Error{
+				Time:       start,
+				Route:      route,
+				Status:     status,
+				RequestID:  requestid.FromRequest(r),
+				DurationMs: elapsed.Milliseconds(),
+			})
+			if len(m.errors) > maxErrors {
+				m.errors = m.errors[len(m.errors)-maxErrors:]
+			}
+		}
+	})
+}
+
+// Snapshot returns the current view. Errors are newest first.
+func (m *Monitor) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = prune(m.routes)
+	m.models = prune(m.models)
+
+	snap := Snapshot{
+		Time:          time.Now().UTC(),
+		WindowSeconds: int(Window.Seconds()),
+		Active:        make(map[string]int),
+		Routes:        summarize(m.routes),
+		Models:        summarize(m.models),
+		Errors:        make([]Error, 0, len(m.errors)),
+	}
+	for pipeline, n := range m.active {
+		if n > 0 {
+			snap.Active[pipeline] = n
+		}
+	}
+	for i := len(m.errors) - 1; i >= 0; i-- {
+		snap.Errors = append(snap.Errors, m.errors[i])
+	}
+	return snap
+}
+
+// prune drops samples older than the window. Samples are appended as calls
+// finish, in chronological order, so it stops at the first recent one.
+func prune(samples []sample) []sample {
+	cutoff := time.Now().Add(-Window)
+	i := 0
+	for i < len(samples) && samples[i].at.Before(cutoff) {
+		i++
+	}
+	return samples[i:]
+}
+
+// summarize groups samples by name, busiest first.
+func summarize(samples []sample) []Throughput {
+	byName := make(map[string]*Throughput)
+	totals := make(map[string]time.Duration)
+	for _, s := range samples {
+		t, ok := byName[s.name]
+		if !ok {
+			t = &Throughput{Name: s.name}
+			byName[s.name] = t
+		}
+		t.Count++
+		if s.failed {
+			t.Errors++
+		}
+		totals[s.name] += s.elapsed
+	}
+	out := make([]Throughput, 0, len(byName))
+	for name, t := range byName {
+		t.AvgLatencyMs = float64(totals[name].Milliseconds()) / float64(t.Count)
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// statusWriter remembers the final response status.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	// Interim responses such as 102 Processing heartbeats are not the final status.
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
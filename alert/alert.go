@@ -0,0 +1,69 @@
+// alert/alert.go
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Notifier raises operator alerts. Alerts are always logged at error level and,
+// when a webhook URL is configured, posted as a Slack-compatible {"text": ...} payload.
+type Notifier struct {
+	logger     *slog.Logger
+	webhookURL string
+	client     *http.Client
+}
+
+// NewNotifier creates a Notifier. An empty webhookURL only logs alerts.
+func NewNotifier(logger *slog.Logger, webhookURL string) *Notifier {
+	return &Notifier{
+		logger:     logger,
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Alert logs msg and delivers it to the webhook in the background.
+func (n *Notifier) Alert(msg string, args ...any) {
+	n.logger.Error("ALERT: "+msg, args...)
+	if n.webhookURL == "" {
+		return
+	}
+	go func() {
+		if err := n.post(context.Background(), msg); err != nil {
+			n.logger.Error("Failed to deliver alert webhook", "error", err)
+		}
+	}()
+}
+
+func (n *Notifier) post(ctx context.Context, msg string) error {
+	return PostJSON(ctx, n.client, n.webhookURL, map[string]string{"text": msg})
+}
+
+// PostJSON posts payload as JSON to a webhook URL, treating any non-2xx
+// response as a failure.
+func PostJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", res.StatusCode)
+	}
+	return nil
+}
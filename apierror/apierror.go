@@ -0,0 +1,97 @@
+// apierror/apierror.go
+//
+// Package apierror writes every error response in the same JSON shape,
+// models.ErrorResponse, so clients can branch on a stable code instead of the
+// HTTP status or the message text.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/requestid"
+)
+
+// Error codes. Codes are part of the API: add new ones freely, but never
+// rename or reuse one. Messages are for humans and may change.
+const (
+	// Request problems.
+	CodeBadRequest         = "BAD_REQUEST"
+	CodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
+	CodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	CodeFileTooLarge       = "FILE_TOO_LARGE"
+	CodeImageTooLarge      = "IMAGE_TOO_LARGE"
+	CodeImageTooManyPixels = "IMAGE_TOO_MANY_PIXELS"
+	CodeInvalidImage       = "INVALID_IMAGE"
+	CodeImageFetchFailed   = "IMAGE_FETCH_FAILED"
+	CodeNoFaceDetected     = "NO_FACE_DETECTED"
+	CodePhotosMismatch     = "PHOTOS_MISMATCH"
+	CodeCaptchaFailed      = "CAPTCHA_FAILED"
+
+	// Authentication and authorization.
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeInvalidCredentials = "INVALID_CREDENTIALS"
+	CodeInvalidSignature   = "INVALID_SIGNATURE"
+	CodeSignatureExpired   = "SIGNATURE_EXPIRED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeKeySuspended       = "KEY_SUSPENDED"
+	CodeOriginNotAllowed   = "ORIGIN_NOT_ALLOWED"
+
+	// Resources.
+	CodeNotFound        = "NOT_FOUND"
+	CodeSessionRequired = "SESSION_REQUIRED"
+	CodeSessionExpired  = "SESSION_EXPIRED"
+	CodeConflict        = "CONFLICT"
+	CodeLinkExpired     = "LINK_EXPIRED"
+
+	// End-to-end encryption.
+	CodeEncryptionRequired   = "ENCRYPTION_REQUIRED"
+	CodeEncryptionKeyInvalid = "ENCRYPTION_KEY_INVALID"
+	CodeEncryptionKeyExpired = "ENCRYPTION_KEY_EXPIRED"
+	CodeDecryptionFailed     = "DECRYPTION_FAILED"
+
+	// Generation.
+	CodeUpstreamFailed     = "UPSTREAM_FAILED"
+	CodeInvalidModelOutput = "INVALID_MODEL_OUTPUT"
+	CodeContentRejected    = "CONTENT_REJECTED"
+	CodeContentDisallowed  = "CONTENT_DISALLOWED"
+
+	// Throttling; these responses are models.ThrottledResponse.
+	CodeRateLimited      = "RATE_LIMITED"
+	CodeQuotaExceeded    = "QUOTA_EXCEEDED"
+	CodeServiceSaturated = "SERVICE_SATURATED"
+	CodeMaintenance      = "MAINTENANCE"
+
+	// Server problems.
+	CodeNotConfigured = "NOT_CONFIGURED"
+	CodeInternal      = "INTERNAL"
+)
+
+// New builds an error body carrying the request's ID.
+func New(r *http.Request, code, message string) models.ErrorResponse {
+	return models.ErrorResponse{Code: code, Message: message, RequestID: requestid.FromRequest(r)}
+}
+
+// Write writes an error response.
+func Write(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	WriteBody(w, status, New(r, code, message))
+}
+
+// WriteDetails writes an error response with details, such as the limit a
+// request exceeded.
+func WriteDetails(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]any) {
+	body := New(r, code, message)
+	body.Details = details
+	WriteBody(w, status, body)
+}
+
+// WriteBody writes body, which must embed models.ErrorResponse, as an error
+// response.
+func WriteBody(w http.ResponseWriter, status int, body any) {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,623 @@
+// apikeys/apikeys.go
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+const (
+	// keysNamespace holds Key records by ID; hashNamespace maps secret hashes to IDs.
+	keysNamespace   = "apikeys"
+	hashNamespace   = "apikey-hashes"
+	domainNamespace = "apikey-domains"
+	// originNamespace maps a browser origin to the IDs of the keys allowing it.
+	originNamespace = "apikey-origins"
+
+	// secretPrefix makes keys recognizable in logs and secret scanners.
+	secretPrefix = "dsk_"
+	// signingSecretPrefix marks secrets for HMAC-signed requests.
+	signingSecretPrefix = "dss_"
+
+	// domainCacheTTL bounds how long a domain lookup is reused, so changes
+	// made through other instances show up. Changes made through this one
+	// take effect at once.
+	domainCacheTTL = time.Minute
+	// maxDomainCache bounds the cached lookups, which include misses for
+	// whatever Host headers clients send.
+	maxDomainCache = 4096
+)
+
+// Scopes a key may be granted.
+const (
+	// ScopeGenerate allows the model endpoints (/generate, /swap-style, /refine,
+	// /accessories, /styles/more, /styles/regenerate).
+	ScopeGenerate = "generate"
+	// ScopeRead allows read-only endpoints such as /styles and /usage.
+	ScopeRead = "read"
+	// ScopePrompt allows a promptSuffix with /generate, which appends the
+	// caller's own instructions to the image prompt. It is only granted
+	// explicitly, to trusted clients.
+	ScopePrompt = "prompt"
+)
+
+// AllScopes lists the scopes granted to keys created without explicit scopes.
+var AllScopes = []string{ScopeGenerate, ScopeRead}
+
+// grantableScopes lists every scope a key may be given.
+var grantableScopes = []string{ScopeGenerate, ScopeRead, ScopePrompt}
+
+var (
+	ErrNotFound      = errors.New("api key not found")
+	ErrRevoked       = errors.New("api key revoked")
+	ErrInvalidScope  = errors.New("invalid api key scope")
+	ErrInvalidDomain = errors.New("invalid domain")
+	ErrDomainTaken   = errors.New("domain is already assigned to another key")
+	ErrInvalidOrigin = errors.New("invalid origin")
+)
+
+// Key is a client API key. The secret itself is never stored, only its hash.
+type Key struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the first characters of the secret, to help identify keys.
+	Prefix string   `json:"prefix"`
+	Hash   string   `json:"hash"`
+	Scopes []string `json:"scopes"`
+	// Domain is the tenant's custom host for public share and gallery URLs.
+	Domain string `json:"domain,omitempty"`
+	// AllowedOrigins are the browser origins the key may be used from. They
+	// are added to the CORS policy, and requests from other origins are refused.
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
+	// SigningSecret lets partner backends sign requests instead of sending the
+	// key. Unlike the key itself it is stored in plaintext, since verifying an
+	// HMAC needs it.
+	SigningSecret string     `json:"signingSecret,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	RotatedAt     *time.Time `json:"rotatedAt,omitempty"`
+	RevokedAt     *time.Time `json:"revokedAt,omitempty"`
+	// Suspension, unlike revocation, temporarily blocks the key's requests
+	// and can be lifted.
+	Suspension *Suspension `json:"suspension,omitempty"`
+}
+
+// Suspension records why and until when a key is disabled. A nil Until
+// suspends the key until it is resumed.
+type Suspension struct {
+	Reason      string     `json:"reason"`
+	SuspendedAt time.Time  `json:"suspendedAt"`
+	Until       *time.Time `json:"until,omitempty"`
+}
+
+// Suspended returns the key's suspension if it is in effect at now. A
+// suspension lifts itself once its Until has passed.
+func (k *Key) Suspended(now time.Time) (*Suspension, bool) {
+	if k.Suspension == nil || (k.Suspension.Until != nil && !now.Before(*k.Suspension.Until)) {
+		return nil, false
+	}
+	return k.Suspension, true
+}
+
+// AllowsOrigin reports whether the key may be used from a browser origin.
+// Keys without allowed origins are not bound to any.
+func (k *Key) AllowsOrigin(origin string) bool {
+	return len(k.AllowedOrigins) == 0 || slices.Contains(k.AllowedOrigins, strings.ToLower(origin))
+}
+
+// HasScope reports whether the key grants scope.
+func (k *Key) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Manager creates, authenticates and revokes API keys persisted in a store.
+type Manager struct {
+	store store.Store
+	// mu serializes writes so the key and hash records stay consistent.
+	mu sync.Mutex
+
+	// byDomain and domainOf cache tenant lookups, which run on every request
+	// and for every public URL, by domain and by key ID.
+	cacheMu  sync.Mutex
+	byDomain map[string]cachedTenant
+	domainOf map[string]cachedTenant
+}
+
+// cachedTenant is a cached lookup; key is nil if there was no active tenant.
+type cachedTenant struct {
+	key     *Key
+	expires time.Time
+}
+
+// NewManager creates a Manager backed by st.
+func NewManager(st store.Store) *Manager {
+	return &Manager{store: st, byDomain: make(map[string]cachedTenant), domainOf: make(map[string]cachedTenant)}
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSecret() (string, error) {
+	return randomSecret(secretPrefix)
+}
+
+func randomSecret(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func validateScopes(scopes []string) error {
+	for _, s := range scopes {
+		if !slices.Contains(grantableScopes, s) {
+			return fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+	}
+	return nil
+}
+
+// Create issues a new key and returns it along with the plaintext secret,
+// which is only available at creation time.
+func (m *Manager) Create(ctx context.Context, name string, scopes []string) (*Key, string, error) {
+	if len(scopes) == 0 {
+		scopes = AllScopes
+	}
+	if err := validateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &Key{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Prefix:    secret[:len(secretPrefix)+6],
+		Hash:      hashSecret(secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.put(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// put writes the key record and its hash index entry. The caller must hold m.mu.
+func (m *Manager) put(ctx context.Context, key *Key) error {
+	if err := store.PutJSON(ctx, m.store, keysNamespace, key.ID, key); err != nil {
+		return fmt.Errorf("failed to save api key: %w", err)
+	}
+	if err := m.store.Put(ctx, hashNamespace, key.Hash, []byte(key.ID)); err != nil {
+		return fmt.Errorf("failed to index api key: %w", err)
+	}
+	return nil
+}
+
+// Get returns a key by ID.
+func (m *Manager) Get(ctx context.Context, id string) (*Key, error) {
+	var key Key
+	if err := store.GetJSON(ctx, m.store, keysNamespace, id, &key); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// List returns all keys, including revoked ones, newest first.
+func (m *Manager) List(ctx context.Context) ([]*Key, error) {
+	ids, err := m.store.List(ctx, keysNamespace)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(ids))
+	for _, id := range ids {
+		key, err := m.Get(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Authenticate resolves a plaintext secret to its key.
+func (m *Manager) Authenticate(ctx context.Context, secret string) (*Key, error) {
+	id, err := m.store.Get(ctx, hashNamespace, hashSecret(secret))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := m.Get(ctx, string(id))
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	return key, nil
+}
+
+// Revoke permanently disables a key and releases its custom domain, so it can
+// be assigned to another key.
+func (m *Manager) Revoke(ctx context.Context, id string) (*Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer m.forgetTenant(key.ID, key.Domain)
+	if key.Domain != "" {
+		if err := m.store.Delete(ctx, domainNamespace, key.Domain); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, fmt.Errorf("failed to release domain: %w", err)
+		}
+		key.Domain = ""
+	}
+	if key.RevokedAt == nil {
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+	}
+	if err := store.PutJSON(ctx, m.store, keysNamespace, key.ID, key); err != nil {
+		return nil, fmt.Errorf("failed to save api key: %w", err)
+	}
+	return key, nil
+}
+
+// Suspend blocks a key's requests with reason, until the given time if set,
+// replacing any previous suspension.
+func (m *Manager) Suspend(ctx context.Context, id, reason string, until *time.Time) (*Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	key.Suspension = &Suspension{Reason: reason, SuspendedAt: time.Now().UTC(), Until: until}
+	if err := store.PutJSON(ctx, m.store, keysNamespace, key.ID, key); err != nil {
+		return nil, fmt.Errorf("failed to save api key: %w", err)
+	}
+	return key, nil
+}
+
+// Resume lifts a key's suspension.
+func (m *Manager) Resume(ctx context.Context, id string) (*Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Suspension != nil {
+		key.Suspension = nil
+		if err := store.PutJSON(ctx, m.store, keysNamespace, key.ID, key); err != nil {
+			return nil, fmt.Errorf("failed to save api key: %w", err)
+		}
+	}
+	return key, nil
+}
+
+// Rotate replaces a key's secret, keeping its ID, name and scopes so usage and
+// billing history carry over. The old secret stops working immediately.
+func (m *Manager) Rotate(ctx context.Context, id string) (*Key, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if key.RevokedAt != nil {
+		return nil, "", ErrRevoked
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	oldHash := key.Hash
+	now := time.Now().UTC()
+	key.Hash = hashSecret(secret)
+	key.Prefix = secret[:len(secretPrefix)+6]
+	key.RotatedAt = &now
+	if err := m.put(ctx, key); err != nil {
+		return nil, "", err
+	}
+	if err := m.store.Delete(ctx, hashNamespace, oldHash); err != nil {
+		return nil, "", fmt.Errorf("failed to remove old api key hash: %w", err)
+	}
+	return key, secret, nil
+}
+
+// normalizeDomain lowercases a bare hostname and rejects anything that isn't one.
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(domain, "/:@ ") || !strings.Contains(domain, ".") {
+		return "", fmt.Errorf("%w: %q must be a bare hostname like looks.example.com", ErrInvalidDomain, domain)
+	}
+	return domain, nil
+}
+
+// SetDomain assigns a custom domain to a key's tenant, replacing any previous
+// one. An empty domain removes it.
+func (m *Manager) SetDomain(ctx context.Context, id, domain string) (*Key, error) {
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if domain != "" && key.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	if domain != "" {
+		owner, err := m.store.Get(ctx, domainNamespace, domain)
+		if err == nil && string(owner) != id {
+			return nil, ErrDomainTaken
+		}
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	if key.Domain != "" && key.Domain != domain {
+		if err := m.store.Delete(ctx, domainNamespace, key.Domain); err != nil {
+			return nil, fmt.Errorf("failed to release old domain: %w", err)
+		}
+	}
+	defer m.forgetTenant(key.ID, key.Domain, domain)
+	key.Domain = domain
+	if err := store.PutJSON(ctx, m.store, keysNamespace, key.ID, key); err != nil {
+		return nil, fmt.Errorf("failed to save api key: %w", err)
+	}
+	if domain != "" {
+		if err := m.store.Put(ctx, domainNamespace, domain, []byte(key.ID)); err != nil {
+			return nil, fmt.Errorf("failed to index domain: %w", err)
+		}
+	}
+	return key, nil
+}
+
+// ByDomain returns the active key whose tenant owns domain, or ErrNotFound.
+// Lookups are cached for up to a minute.
+func (m *Manager) ByDomain(ctx context.Context, domain string) (*Key, error) {
+	domain = strings.ToLower(domain)
+	m.cacheMu.Lock()
+	c, ok := m.byDomain[domain]
+	m.cacheMu.Unlock()
+	if !ok || time.Now().After(c.expires) {
+		key, err := m.lookupDomain(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+		c = cachedTenant{key: key, expires: time.Now().Add(domainCacheTTL)}
+		m.cache(m.byDomain, domain, c)
+	}
+	if c.key == nil {
+		return nil, ErrNotFound
+	}
+	key := *c.key
+	return &key, nil
+}
+
+// lookupDomain reads the active key owning domain from the store, or nil.
+func (m *Manager) lookupDomain(ctx context.Context, domain string) (*Key, error) {
+	id, err := m.store.Get(ctx, domainNamespace, domain)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := m.Get(ctx, string(id))
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, nil
+	}
+	return key, nil
+}
+
+// Domain returns the custom domain of the active key id, or "" if it has
+// none. Lookups are cached for up to a minute.
+func (m *Manager) Domain(ctx context.Context, id string) (string, error) {
+	m.cacheMu.Lock()
+	c, ok := m.domainOf[id]
+	m.cacheMu.Unlock()
+	if !ok || time.Now().After(c.expires) {
+		key, err := m.Get(ctx, id)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+		if key != nil && key.RevokedAt != nil {
+			key = nil
+		}
+		c = cachedTenant{key: key, expires: time.Now().Add(domainCacheTTL)}
+		m.cache(m.domainOf, id, c)
+	}
+	if c.key == nil {
+		return "", nil
+	}
+	return c.key.Domain, nil
+}
+
+// cache stores a lookup, emptying the cache first if it is full.
+func (m *Manager) cache(cache map[string]cachedTenant, k string, c cachedTenant) {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	if len(cache) >= maxDomainCache {
+		clear(cache)
+	}
+	cache[k] = c
+}
+
+// forgetTenant drops the cached lookups of key id and its domains.
+func (m *Manager) forgetTenant(id string, domains ...string) {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	delete(m.domainOf, id)
+	for _, d := range domains {
+		delete(m.byDomain, d)
+	}
+}
+
+// normalizeOrigin lowercases a browser origin such as https://app.partner.com
+// and rejects anything with a path, query or credentials.
+func normalizeOrigin(origin string) (string, error) {
+	u, err := url.Parse(strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/")))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("%w: %q must be a scheme and host like https://app.example.com", ErrInvalidOrigin, origin)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// SetOrigins replaces the browser origins a key may be used from. An empty
+// list unbinds the key from any origin.
+func (m *Manager) SetOrigins(ctx context.Context, id string, origins []string) (*Key, error) {
+	normalized := make([]string, 0, len(origins))
+	for _, o := range origins {
+		n, err := normalizeOrigin(o)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(normalized, n) {
+			normalized = append(normalized, n)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	for _, o := range key.AllowedOrigins {
+		if !slices.Contains(normalized, o) {
+			if err := m.indexOrigin(ctx, o, id, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, o := range normalized {
+		if err := m.indexOrigin(ctx, o, id, true); err != nil {
+			return nil, err
+		}
+	}
+	key.AllowedOrigins = normalized
+	if err := store.PutJSON(ctx, m.store, keysNamespace, key.ID, key); err != nil {
+		return nil, fmt.Errorf("failed to save api key: %w", err)
+	}
+	return key, nil
+}
+
+// indexOrigin adds or removes id from the keys allowing origin. The caller
+// must hold m.mu.
+func (m *Manager) indexOrigin(ctx context.Context, origin, id string, add bool) error {
+	var ids []string
+	if err := store.GetJSON(ctx, m.store, originNamespace, origin, &ids); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	ids = slices.DeleteFunc(ids, func(v string) bool { return v == id })
+	if add {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return m.store.Delete(ctx, originNamespace, origin)
+	}
+	if err := store.PutJSON(ctx, m.store, originNamespace, origin, ids); err != nil {
+		return fmt.Errorf("failed to index origin: %w", err)
+	}
+	return nil
+}
+
+// OriginAllowed reports whether an active key allows requests from origin.
+func (m *Manager) OriginAllowed(ctx context.Context, origin string) bool {
+	var ids []string
+	if err := store.GetJSON(ctx, m.store, originNamespace, strings.ToLower(origin), &ids); err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if key, err := m.Get(ctx, id); err == nil && key.RevokedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IssueSigningSecret generates a new signing secret for a key, replacing any
+// previous one, and returns it. Requests signed with the old secret stop
+// being accepted immediately.
+func (m *Manager) IssueSigningSecret(ctx context.Context, id string) (*Key, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if key.RevokedAt != nil {
+		return nil, "", ErrRevoked
+	}
+	secret, err := randomSecret(signingSecretPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	key.SigningSecret = secret
+	if err := store.PutJSON(ctx, m.store, keysNamespace, key.ID, key); err != nil {
+		return nil, "", fmt.Errorf("failed to save api key: %w", err)
+	}
+	return key, secret, nil
+}
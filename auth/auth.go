@@ -0,0 +1,165 @@
+// auth/auth.go
+//
+// Package auth authenticates client requests. Each way of proving identity,
+// such as an API key or a signed JWT, is an Authenticator; a Chain tries the
+// ones a route group accepts and records the caller's Identity on the request.
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/apikeys"
+)
+
+// Authentication methods, also the names providers are registered under.
+const (
+	MethodAPIKey = "apikey"
+	MethodHMAC   = "hmac"
+	MethodJWT    = "jwt"
+)
+
+var (
+	// ErrInvalidCredentials wraps every rejection of credentials a provider
+	// recognized as its own. Other errors are internal failures.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrTooLarge is returned when a body too large to verify was signed.
+	ErrTooLarge = errors.New("request body is too large")
+)
+
+// Identity is an authenticated caller.
+type Identity struct {
+	// Subject identifies the caller for usage, quotas and ownership, e.g.
+	// "key:<id>" for API keys or "user:<sub>" for JWTs.
+	Subject string
+	// Method is the authentication method that accepted the request.
+	Method string
+	Scopes []string
+	// Key is the API key the caller authenticated with, if any.
+	Key *apikeys.Key
+}
+
+// HasScope reports whether the identity grants scope.
+func (id *Identity) HasScope(scope string) bool {
+	return slices.Contains(id.Scopes, scope)
+}
+
+// Authenticator is one authentication method.
+type Authenticator interface {
+	// Authenticate returns the caller's identity, or nil without an error if
+	// the request carries no credentials for this method. Rejected
+	// credentials wrap ErrInvalidCredentials.
+	Authenticate(w http.ResponseWriter, r *http.Request) (*Identity, error)
+}
+
+type identityContextKey struct{}
+
+// FromRequest returns the identity a Chain authenticated, or nil for anonymous requests.
+func FromRequest(r *http.Request) *Identity {
+	id, _ := r.Context().Value(identityContextKey{}).(*Identity)
+	return id
+}
+
+// Chain holds the registered authentication methods.
+type Chain struct {
+	logger    *slog.Logger
+	providers map[string]Authenticator
+	// required rejects anonymous requests.
+	required bool
+}
+
+// NewChain creates an empty Chain. When required is set, requests without
+// credentials are rejected instead of being served anonymously.
+func NewChain(logger *slog.Logger, required bool) *Chain {
+	return &Chain{logger: logger, providers: make(map[string]Authenticator), required: required}
+}
+
+// Register adds a provider under a method name, replacing any previous one.
+func (c *Chain) Register(method string, a Authenticator) {
+	c.providers[method] = a
+}
+
+// Enabled reports whether a provider is registered for method.
+func (c *Chain) Enabled(method string) bool {
+	_, ok := c.providers[method]
+	return ok
+}
+
+// Require authenticates requests with the first of methods whose credentials
+// they carry, and only lets them through if the identity grants scope. Methods
+// without a registered provider are skipped.
+func (c *Chain) Require(scope string, methods []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id *Identity
+		for _, method := range methods {
+			provider, ok := c.providers[method]
+			if !ok {
+				continue
+			}
+			var err error
+			id, err = provider.Authenticate(w, r)
+			switch {
+			case errors.Is(err, ErrTooLarge):
+				apierror.Write(w, r, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge, "Request body is too large.")
+				return
+			case errors.Is(err, ErrInvalidCredentials):
+				c.logger.Warn("Rejected credentials", "method", method, "error", err, "path", r.URL.Path)
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Unauthorized: "+err.Error()+".")
+				return
+			case err != nil:
+				c.logger.Error("Authentication failed", "method", method, "error", err)
+				apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to authenticate request.")
+				return
+			}
+			if id != nil {
+				break
+			}
+		}
+
+		if id == nil {
+			if c.required {
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Missing credentials.")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if id.Key != nil {
+			if susp, ok := id.Key.Suspended(time.Now()); ok {
+				writeSuspended(w, r, susp)
+				return
+			}
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && id.Key != nil && !id.Key.AllowsOrigin(origin) {
+			c.logger.Warn("API key used from disallowed origin", "keyID", id.Key.ID, "origin", origin, "path", r.URL.Path)
+			apierror.Write(w, r, http.StatusForbidden, apierror.CodeOriginNotAllowed, "API key is not allowed from this origin.")
+			return
+		}
+		if !id.HasScope(scope) {
+			c.logger.Warn("Caller lacks scope", "subject", id.Subject, "scope", scope, "path", r.URL.Path)
+			apierror.Write(w, r, http.StatusForbidden, apierror.CodeForbidden, "Not allowed to access this endpoint.")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), identityContextKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writeSuspended rejects a request made with a suspended key, giving the
+// reason and, for timed suspensions, when the key is re-enabled.
+func writeSuspended(w http.ResponseWriter, r *http.Request, susp *apikeys.Suspension) {
+	details := map[string]any{"reason": susp.Reason}
+	if susp.Until != nil {
+		details["until"] = susp.Until
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, int(time.Until(*susp.Until).Seconds()))))
+	}
+	apierror.WriteDetails(w, r, http.StatusForbidden, apierror.CodeKeySuspended, "API key is suspended: "+strings.TrimSuffix(susp.Reason, ".")+".", details)
+}
@@ -0,0 +1,303 @@
+// auth/jwt.go
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwtLeeway tolerates clock skew when checking exp and nbf.
+	jwtLeeway = time.Minute
+	// jwksTTL is how long a fetched key set is used before it is refreshed.
+	jwksTTL = time.Hour
+	// jwksMinRefetch is the least time between fetches of the key set, so
+	// tokens with made-up key IDs can't make the server fetch it on every request.
+	jwksMinRefetch = time.Minute
+)
+
+// JWTConfig configures the JWT provider. Tokens are verified either with a
+// shared HS256 secret or with the RS256/ES256 keys published at JWKSURL, as
+// OAuth 2.0 and OpenID Connect providers such as Firebase Auth do.
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	JWKSURL  string
+	Secret   string
+	// Scopes are granted to every caller with a valid token.
+	Scopes []string
+}
+
+// JWT authenticates bearer tokens in the Authorization header.
+type JWT struct {
+	cfg    JWTConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// attemptedAt is when the key set was last fetched or tried, and
+	// fetchErr why that failed. fetching is closed when a fetch in progress ends.
+	attemptedAt time.Time
+	fetchErr    error
+	fetching    chan struct{}
+}
+
+// NewJWT creates the JWT provider.
+func NewJWT(cfg JWTConfig) *JWT {
+	return &JWT{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+// audiences decodes aud, which may be a single string or a list.
+func (c jwtClaims) audiences() []string {
+	var one string
+	if json.Unmarshal(c.Audience, &one) == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(c.Audience, &many)
+	return many
+}
+
+func (j *JWT) Authenticate(w http.ResponseWriter, r *http.Request) (*Identity, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, nil
+	}
+	claims, err := j.verify(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Subject: "user:" + claims.Subject, Method: MethodJWT, Scopes: j.cfg.Scopes}, nil
+}
+
+// invalid wraps a token rejection reason.
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCredentials, fmt.Sprintf(format, args...))
+}
+
+// verify checks the token's signature and standard claims.
+func (j *JWT) verify(ctx context.Context, token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalid("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed token signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := j.verifySignature(ctx, header, signed, signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalid("malformed token claims")
+	}
+	now := time.Now()
+	switch {
+	case claims.ExpiresAt == nil:
+		return nil, invalid("token has no expiry")
+	case now.After(time.Unix(*claims.ExpiresAt, 0).Add(jwtLeeway)):
+		return nil, invalid("token expired")
+	case claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(*claims.NotBefore, 0)):
+		return nil, invalid("token not yet valid")
+	case claims.Subject == "":
+		return nil, invalid("token has no subject")
+	case j.cfg.Issuer != "" && claims.Issuer != j.cfg.Issuer:
+		return nil, invalid("unexpected issuer %q", claims.Issuer)
+	case j.cfg.Audience != "" && !slices.Contains(claims.audiences(), j.cfg.Audience):
+		return nil, invalid("unexpected audience")
+	}
+	return &claims, nil
+}
+
+func (j *JWT) verifySignature(ctx context.Context, header jwtHeader, signed, signature []byte) error {
+	if header.Alg == "HS256" {
+		if j.cfg.Secret == "" {
+			return invalid("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, []byte(j.cfg.Secret))
+		mac.Write(signed)
+		if subtle.ConstantTimeCompare(mac.Sum(nil), signature) != 1 {
+			return invalid("bad signature")
+		}
+		return nil
+	}
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return invalid("unsupported algorithm %q", header.Alg)
+	}
+	if j.cfg.JWKSURL == "" {
+		return invalid("%s tokens are not accepted", header.Alg)
+	}
+	key, err := j.key(ctx, header.Kid)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(signed)
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg == "ES256" && len(signature) == 64 {
+			rs, ss := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(key, digest[:], rs, ss) {
+				return nil
+			}
+		}
+	}
+	return invalid("bad signature")
+}
+
+// key returns the public key with ID kid, refetching the key set when it is
+// stale or does not contain kid, as happens after the issuer rotates keys.
+// Refetches happen at most once per jwksMinRefetch and outside j.mu; a stale
+// key is used while one runs, and an unknown kid waits for it. In between,
+// kids missing from the cached set are rejected.
+func (j *JWT) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	if ok && time.Since(j.fetchedAt) < jwksTTL {
+		j.mu.Unlock()
+		return key, nil
+	}
+	done := j.fetching
+	if done == nil && time.Since(j.attemptedAt) >= jwksMinRefetch {
+		done = make(chan struct{})
+		j.fetching, j.attemptedAt = done, time.Now()
+		go j.refresh(done)
+	}
+	j.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+	if j.fetchErr != nil {
+		return nil, j.fetchErr
+	}
+	return nil, invalid("unknown signing key %q", kid)
+}
+
+// refresh fetches the key set and closes done. On failure the previous keys
+// stay in use.
+func (j *JWT) refresh(done chan struct{}) {
+	keys, err := j.fetchKeys(context.Background())
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err == nil {
+		j.keys, j.fetchedAt = keys, time.Now()
+	}
+	j.fetchErr, j.fetching = err, nil
+	close(done)
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j *JWT) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable keys")
+	}
+	return keys, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
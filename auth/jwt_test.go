@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// hs256 signs a token with the shared secret.
+func hs256(t *testing.T, secret string, header, claims map[string]any) string {
+	t.Helper()
+	signed := segment(t, header) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// rs256 signs a token with an RSA key.
+func rs256(t *testing.T, key *rsa.PrivateKey, header, claims map[string]any) string {
+	t.Helper()
+	signed := segment(t, header) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss": "https://issuer.example",
+		"aud": "dreswap",
+		"sub": "user-1",
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+}
+
+func with(claims map[string]any, key string, value any) map[string]any {
+	out := make(map[string]any, len(claims))
+	for k, v := range claims {
+		out[k] = v
+	}
+	if value == nil {
+		delete(out, key)
+	} else {
+		out[key] = value
+	}
+	return out
+}
+
+func TestVerifyHS256(t *testing.T) {
+	j := NewJWT(JWTConfig{Issuer: "https://issuer.example", Audience: "dreswap", Secret: testSecret})
+	header := map[string]any{"alg": "HS256", "typ": "JWT"}
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", hs256(t, testSecret, header, validClaims()), true},
+		{"audience list", hs256(t, testSecret, header, with(validClaims(), "aud", []string{"other", "dreswap"})), true},
+		{"within leeway", hs256(t, testSecret, header, with(validClaims(), "exp", now.Add(-30*time.Second).Unix())), true},
+		{"expired", hs256(t, testSecret, header, with(validClaims(), "exp", now.Add(-2*time.Minute).Unix())), false},
+		{"no expiry", hs256(t, testSecret, header, with(validClaims(), "exp", nil)), false},
+		{"not yet valid", hs256(t, testSecret, header, with(validClaims(), "nbf", now.Add(2*time.Minute).Unix())), false},
+		{"no subject", hs256(t, testSecret, header, with(validClaims(), "sub", nil)), false},
+		{"wrong issuer", hs256(t, testSecret, header, with(validClaims(), "iss", "https://evil.example")), false},
+		{"wrong audience", hs256(t, testSecret, header, with(validClaims(), "aud", "other")), false},
+		{"wrong secret", hs256(t, "other-secret", header, validClaims()), false},
+		{"alg none", segment(t, map[string]any{"alg": "none"}) + "." + segment(t, validClaims()) + ".", false},
+		{"RS256 without JWKS", hs256(t, testSecret, map[string]any{"alg": "RS256"}, validClaims()), false},
+		{"malformed", "not-a-token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := j.verify(context.Background(), tt.token)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("verify: %v", err)
+				}
+				if claims.Subject != "user-1" {
+					t.Errorf("subject = %q, want user-1", claims.Subject)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("verify error = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestVerifyJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+			"kid": "k1",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+
+	// Only JWKS is configured, so HS256 tokens must be refused, even when
+	// "signed" with the public key an attacker can download.
+	j := NewJWT(JWTConfig{Issuer: "https://issuer.example", Audience: "dreswap", JWKSURL: srv.URL})
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", rs256(t, key, map[string]any{"alg": "RS256", "kid": "k1"}, validClaims()), true},
+		{"expired", rs256(t, key, map[string]any{"alg": "RS256", "kid": "k1"}, with(validClaims(), "exp", time.Now().Add(-time.Hour).Unix())), false},
+		{"wrong audience", rs256(t, key, map[string]any{"alg": "RS256", "kid": "k1"}, with(validClaims(), "aud", "other")), false},
+		{"other key", rs256(t, other, map[string]any{"alg": "RS256", "kid": "k1"}, validClaims()), false},
+		{"ES256 header on RSA key", rs256(t, key, map[string]any{"alg": "ES256", "kid": "k1"}, validClaims()), false},
+		{"HS256 with public key", hs256(t, string(publicDER), map[string]any{"alg": "HS256", "kid": "k1"}, validClaims()), false},
+		{"HS256 with modulus", hs256(t, string(key.N.Bytes()), map[string]any{"alg": "HS256", "kid": "k1"}, validClaims()), false},
+		{"HS256 with empty secret", hs256(t, "", map[string]any{"alg": "HS256"}, validClaims()), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := j.verify(context.Background(), tt.token)
+			if tt.ok && err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("verify error = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// auth/keys.go
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apikeys"
+	"github.com/sanjayshr/event-outfitter-backend/signing"
+)
+
+// keyIdentity is the identity of a caller using an API key.
+func keyIdentity(method string, key *apikeys.Key) *Identity {
+	return &Identity{Subject: "key:" + key.ID, Method: method, Scopes: key.Scopes, Key: key}
+}
+
+// APIKey authenticates the X-API-Key header.
+type APIKey struct {
+	keys *apikeys.Manager
+}
+
+// NewAPIKey creates the API key provider.
+func NewAPIKey(keys *apikeys.Manager) *APIKey {
+	return &APIKey{keys: keys}
+}
+
+func (a *APIKey) Authenticate(w http.ResponseWriter, r *http.Request) (*Identity, error) {
+	secret := r.Header.Get("X-API-Key")
+	if secret == "" {
+		return nil, nil
+	}
+	key, err := a.keys.Authenticate(r.Context(), secret)
+	if errors.Is(err, apikeys.ErrNotFound) || errors.Is(err, apikeys.ErrRevoked) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return keyIdentity(MethodAPIKey, key), nil
+}
+
+// HMAC authenticates requests signed with an API key's signing secret and
+// naming the key in X-Key-ID.
+type HMAC struct {
+	keys     *apikeys.Manager
+	verifier *signing.Verifier
+}
+
+// NewHMAC creates the signed-request provider.
+func NewHMAC(keys *apikeys.Manager, verifier *signing.Verifier) *HMAC {
+	return &HMAC{keys: keys, verifier: verifier}
+}
+
+func (h *HMAC) Authenticate(w http.ResponseWriter, r *http.Request) (*Identity, error) {
+	keyID := r.Header.Get(signing.KeyIDHeader)
+	if keyID == "" {
+		return nil, nil
+	}
+	key, err := h.keys.Get(r.Context(), keyID)
+	if errors.Is(err, apikeys.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, apikeys.ErrRevoked)
+	}
+	if key.SigningSecret == "" {
+		return nil, fmt.Errorf("%w: api key has no signing secret", ErrInvalidCredentials)
+	}
+	if err := h.verifier.VerifyKeyed(w, r, key.ID, []byte(key.SigningSecret)); err != nil {
+		if errors.Is(err, signing.ErrBodyTooLarge) {
+			return nil, ErrTooLarge
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	return keyIdentity(MethodHMAC, key), nil
+}
@@ -0,0 +1,293 @@
+// billing/stripe.go
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+const (
+	stripeAPIBase = "https://api.stripe.com/v1"
+	// namespace is the store namespace mapping client keys to Stripe accounts.
+	namespace = "billing"
+	// reportAttempts is how many times a usage record is sent before giving
+	// up. Retries reuse the idempotency key, so Stripe counts it once.
+	reportAttempts = 3
+	// checkoutLifetime is how long a Checkout session can be completed, after
+	// which Active stops looking for it.
+	checkoutLifetime = 24 * time.Hour
+)
+
+// ErrDisabled is returned when billing is not configured.
+var ErrDisabled = errors.New("billing is not enabled")
+
+// Account links a client to its Stripe customer and metered subscription item.
+type Account struct {
+	ClientKey          string    `json:"clientKey"`
+	CustomerID         string    `json:"customerId,omitempty"`
+	SubscriptionItemID string    `json:"subscriptionItemId,omitempty"`
+	CheckoutSessionID  string    `json:"checkoutSessionId,omitempty"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// Active reports whether the account has a metered subscription to report usage against.
+func (a Account) Active() bool {
+	return a.SubscriptionItemID != ""
+}
+
+// CheckoutStatus is the state of a Stripe Checkout session.
+type CheckoutStatus struct {
+	Status        string `json:"status"`
+	PaymentStatus string `json:"paymentStatus"`
+	Active        bool   `json:"active"`
+}
+
+// Stripe reports metered usage to Stripe and manages Checkout sessions for
+// clients who want to generate beyond the free tier.
+type Stripe struct {
+	logger     *slog.Logger
+	store      store.Store
+	secretKey  string
+	priceID    string
+	successURL string
+	cancelURL  string
+	client     *http.Client
+}
+
+// NewStripe creates a Stripe billing client. An empty secretKey disables billing.
+func NewStripe(logger *slog.Logger, st store.Store, secretKey, priceID, successURL, cancelURL string) *Stripe {
+	return &Stripe{
+		logger:     logger,
+		store:      st,
+		secretKey:  secretKey,
+		priceID:    priceID,
+		successURL: successURL,
+		cancelURL:  cancelURL,
+		client:     &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Enabled reports whether Stripe credentials are configured.
+func (s *Stripe) Enabled() bool {
+	return s != nil && s.secretKey != "" && s.priceID != ""
+}
+
+// Account returns the billing account for a client, or a zero Account if none exists.
+func (s *Stripe) Account(ctx context.Context, clientKey string) (Account, error) {
+	var acct Account
+	err := store.GetJSON(ctx, s.store, namespace, clientKey, &acct)
+	if errors.Is(err, store.ErrNotFound) {
+		return Account{ClientKey: clientKey}, nil
+	}
+	return acct, err
+}
+
+// Active reports whether the client has a paid metered subscription. If the
+// client started a checkout recently but never polled its status, the session
+// is checked, so a completed checkout takes effect either way.
+func (s *Stripe) Active(ctx context.Context, clientKey string) bool {
+	if !s.Enabled() {
+		return false
+	}
+	acct, err := s.Account(ctx, clientKey)
+	if err != nil {
+		s.logger.Error("Failed to load billing account", "client", clientKey, "error", err)
+		return false
+	}
+	if acct.Active() {
+		return true
+	}
+	if acct.CheckoutSessionID == "" || time.Since(acct.UpdatedAt) > checkoutLifetime {
+		return false
+	}
+	status, err := s.CheckoutStatus(ctx, clientKey, acct.CheckoutSessionID)
+	if err != nil {
+		s.logger.Error("Failed to check pending checkout", "client", clientKey, "sessionID", acct.CheckoutSessionID, "error", err)
+		return false
+	}
+	return status.Active
+}
+
+// apiError is an error response from the Stripe API.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("stripe returned status %d: %s", e.status, e.message)
+}
+
+// retryable reports whether a failed request may succeed if sent again.
+func retryable(err error) bool {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return apiErr.status == http.StatusTooManyRequests || apiErr.status >= 500
+}
+
+// do sends a form-encoded request to the Stripe API and decodes the JSON response into out.
+func (s *Stripe) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out any) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, stripeAPIBase+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.secretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&apiErr)
+		return &apiError{status: res.StatusCode, message: apiErr.Error.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// CreateCheckout starts a Stripe Checkout session subscribing the client to
+// the metered price, and returns the hosted checkout URL and session ID.
+func (s *Stripe) CreateCheckout(ctx context.Context, clientKey string) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrDisabled
+	}
+
+	form := url.Values{
+		"mode":                 {"subscription"},
+		"line_items[0][price]": {s.priceID},
+		"success_url":          {s.successURL},
+		"cancel_url":           {s.cancelURL},
+		"client_reference_id":  {clientKey},
+		"metadata[client_key]": {clientKey},
+	}
+	var session struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/checkout/sessions", form, "", &session); err != nil {
+		return "", "", err
+	}
+
+	acct, err := s.Account(ctx, clientKey)
+	if err != nil {
+		return "", "", err
+	}
+	acct.CheckoutSessionID = session.ID
+	acct.UpdatedAt = time.Now().UTC()
+	if err := store.PutJSON(ctx, s.store, namespace, clientKey, acct); err != nil {
+		return "", "", fmt.Errorf("failed to save billing account: %w", err)
+	}
+	return session.URL, session.ID, nil
+}
+
+// CheckoutStatus fetches a Checkout session from Stripe. Once the session is
+// complete, the client's account is linked to the new subscription item.
+func (s *Stripe) CheckoutStatus(ctx context.Context, clientKey, sessionID string) (CheckoutStatus, error) {
+	if !s.Enabled() {
+		return CheckoutStatus{}, ErrDisabled
+	}
+
+	var session struct {
+		Status            string `json:"status"`
+		PaymentStatus     string `json:"payment_status"`
+		ClientReferenceID string `json:"client_reference_id"`
+		Customer          string `json:"customer"`
+		Subscription      struct {
+			Items struct {
+				Data []struct {
+					ID string `json:"id"`
+				} `json:"data"`
+			} `json:"items"`
+		} `json:"subscription"`
+	}
+	path := "/checkout/sessions/" + url.PathEscape(sessionID) + "?expand[]=subscription"
+	if err := s.do(ctx, http.MethodGet, path, nil, "", &session); err != nil {
+		return CheckoutStatus{}, err
+	}
+	if session.ClientReferenceID != clientKey {
+		return CheckoutStatus{}, fmt.Errorf("checkout session %s does not belong to this client", sessionID)
+	}
+
+	out := CheckoutStatus{Status: session.Status, PaymentStatus: session.PaymentStatus}
+	if session.Status == "complete" && len(session.Subscription.Items.Data) > 0 {
+		acct, err := s.Account(ctx, clientKey)
+		if err != nil {
+			return out, err
+		}
+		acct.CustomerID = session.Customer
+		acct.SubscriptionItemID = session.Subscription.Items.Data[0].ID
+		acct.UpdatedAt = time.Now().UTC()
+		if err := store.PutJSON(ctx, s.store, namespace, clientKey, acct); err != nil {
+			return out, fmt.Errorf("failed to save billing account: %w", err)
+		}
+		out.Active = true
+	}
+	return out, nil
+}
+
+// ReportUsage records quantity billable generations for the client against its
+// metered subscription item. idempotencyKey must identify the generation, so
+// sending the same usage again never bills twice. Failed requests are retried.
+func (s *Stripe) ReportUsage(ctx context.Context, clientKey string, quantity int, idempotencyKey string) error {
+	if !s.Enabled() {
+		return ErrDisabled
+	}
+	acct, err := s.Account(ctx, clientKey)
+	if err != nil {
+		return err
+	}
+	if !acct.Active() {
+		return fmt.Errorf("client %s has no active subscription", clientKey)
+	}
+
+	form := url.Values{
+		"quantity":  {strconv.Itoa(quantity)},
+		"timestamp": {strconv.FormatInt(time.Now().Unix(), 10)},
+		"action":    {"increment"},
+	}
+	path := "/subscription_items/" + url.PathEscape(acct.SubscriptionItemID) + "/usage_records"
+	for attempt := 1; ; attempt++ {
+		err = s.do(ctx, http.MethodPost, path, form, idempotencyKey, nil)
+		if err == nil || attempt == reportAttempts || !retryable(err) {
+			return err
+		}
+		s.logger.Warn("Retrying usage report", "client", clientKey, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
@@ -0,0 +1,765 @@
+// client/client.go
+//
+// Package client is a thin Go SDK for the DreSwap API. It wraps the HTTP
+// endpoints with typed requests and responses from the models package,
+// tracks the session ID across calls, and retries throttled requests using
+// the server's retryAfterSeconds guidance.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/signing"
+)
+
+// Client calls a DreSwap server. Set the optional fields before first use.
+type Client struct {
+	BaseURL string
+	APIKey  string
+	// SigningSecret signs /generate and /swap-style when the server requires it.
+	SigningSecret string
+	// KeyID and KeySigningSecret sign every request with the API key's own
+	// signing secret, for backends that should not send APIKey itself.
+	KeyID            string
+	KeySigningSecret string
+	// ClientToken identifies an anonymous caller's sessions and looks, and is
+	// ignored for callers with an API key. New generates one; keep it to get
+	// back to them later.
+	ClientToken string
+	HTTPClient  *http.Client
+	// MaxRetries bounds retries of throttled or failed requests.
+	MaxRetries int
+	// MaxRetryWait caps how long a single retry waits; longer waits, such as
+	// an exhausted daily quota, are returned as errors instead.
+	MaxRetryWait time.Duration
+}
+
+// New creates a Client for the server at baseURL, e.g. https://api.dreswap.app.
+func New(baseURL, apiKey string) *Client {
+	token := make([]byte, 32)
+	rand.Read(token)
+	return &Client{
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		APIKey:       apiKey,
+		ClientToken:  base64.RawURLEncoding.EncodeToString(token),
+		HTTPClient:   &http.Client{Timeout: 3 * time.Minute},
+		MaxRetries:   3,
+		MaxRetryWait: time.Minute,
+	}
+}
+
+// Error is a non-2xx response. Code is the machine-readable error code, such
+// as SESSION_EXPIRED, and is empty if the body was not a JSON error (e.g. from
+// a proxy). Throttled is set for rate limit, quota, saturation and maintenance
+// rejections.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	Details    map[string]any
+	Throttled  *models.ThrottledResponse
+}
+
+func (e *Error) Error() string {
+	if e.Throttled != nil {
+		return fmt.Sprintf("dreswap: %d %s: %s (retry after %ds)", e.StatusCode, e.Code, e.Message, e.Throttled.RetryAfterSeconds)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("dreswap: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("dreswap: %d: %s", e.StatusCode, e.Message)
+}
+
+// request describes one API call. body is re-sent on each attempt.
+type request struct {
+	method      string
+	path        string
+	contentType string
+	body        []byte
+	sessionID   string
+	// accept, if set, is sent as the Accept header.
+	accept string
+	signed bool
+}
+
+// response is a successful API response.
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+// do sends req, retrying throttled requests, which the server rejected before
+// doing any work, and failed GETs with backoff.
+func (c *Client) do(ctx context.Context, req request) (*response, error) {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		resp, err := c.once(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		wait, retry := c.retryAfter(err, backoff, req.method == http.MethodGet)
+		if !retry || attempt >= c.MaxRetries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// retryAfter decides whether err is worth retrying and how long to wait first.
+// Network and server errors are only retried for idempotent requests, since a
+// generation may already have been charged.
+func (c *Client) retryAfter(err error, backoff time.Duration, idempotent bool) (time.Duration, bool) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return backoff, idempotent
+	}
+	if t := apiErr.Throttled; t != nil {
+		wait := time.Duration(t.RetryAfterSeconds) * time.Second
+		return wait, wait <= c.MaxRetryWait
+	}
+	return backoff, idempotent && apiErr.StatusCode >= 500
+}
+
+// once performs a single attempt.
+func (c *Client) once(ctx context.Context, req request) (*response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.BaseURL+req.path, bytes.NewReader(req.body))
+	if err != nil {
+		return nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if c.APIKey != "" {
+		httpReq.Header.Set("X-API-Key", c.APIKey)
+	}
+	if c.ClientToken != "" {
+		httpReq.Header.Set("X-Client-Token", c.ClientToken)
+	}
+	if req.sessionID != "" {
+		httpReq.Header.Set("X-Session-ID", req.sessionID)
+	}
+	if req.accept != "" {
+		httpReq.Header.Set("Accept", req.accept)
+	}
+	var secret string
+	if c.KeyID != "" && c.KeySigningSecret != "" {
+		secret = c.KeySigningSecret
+		httpReq.Header.Set(signing.KeyIDHeader, c.KeyID)
+	} else if req.signed && c.SigningSecret != "" {
+		secret = c.SigningSecret
+	}
+	if secret != "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		ts, nonce := strconv.FormatInt(time.Now().Unix(), 10), hex.EncodeToString(b)
+		httpReq.Header.Set(signing.TimestampHeader, ts)
+		httpReq.Header.Set(signing.NonceHeader, nonce)
+		httpReq.Header.Set(signing.SignatureHeader, signing.SignRequest([]byte(secret), req.method, httpReq.URL.RequestURI(), ts, nonce, req.body))
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// A whitespace heartbeat sends 200 early; the real status and headers
+	// follow as trailers.
+	status := resp.StatusCode
+	if s, err := strconv.Atoi(resp.Trailer.Get("X-Response-Status")); err == nil {
+		status = s
+		maps.Copy(resp.Header, resp.Trailer)
+	}
+	if status >= 300 {
+		apiErr := &Error{StatusCode: status, Message: strings.TrimSpace(string(body))}
+		var t models.ThrottledResponse
+		if json.Unmarshal(body, &t) == nil && t.Code != "" {
+			apiErr.Code, apiErr.Message, apiErr.RequestID, apiErr.Details = t.Code, t.Message, t.RequestID, t.Details
+			if t.Reason != "" {
+				apiErr.Throttled = &t
+			}
+		}
+		return nil, apiErr
+	}
+	return &response{header: resp.Header, body: body}, nil
+}
+
+// getJSON performs a GET and decodes the JSON response into v.
+func (c *Client) getJSON(ctx context.Context, path, sessionID string, v any) error {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path, sessionID: sessionID})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.body, v)
+}
+
+// Image is a generated look.
+type Image struct {
+	Data     []byte
+	MimeType string
+	LookID   string
+	// Styles are the session's style suggestions, returned with the first
+	// look by Generate so they need no separate Styles call.
+	Styles []models.Style
+	// Partial is set instead of Data when the server's text-only fallback
+	// answered a failed image call.
+	Partial *models.PartialResultResponse
+}
+
+// Session is a generation session: the uploaded photo and its suggested
+// styles, held by the server for follow-up swaps.
+type Session struct {
+	ID     string
+	client *Client
+}
+
+// Generate uploads a photo and returns the first generated look along with
+// the session for further calls.
+func (c *Client) Generate(ctx context.Context, photo []byte, filename string, req models.GenerateRequest) (*Session, *Image, error) {
+	return c.GenerateWithReferences(ctx, photo, filename, nil, req)
+}
+
+// GenerateWithReferences is like Generate, but also uploads up to two more
+// photos of the same person, e.g. from the side or smiling, to improve
+// likeness.
+func (c *Client) GenerateWithReferences(ctx context.Context, photo []byte, filename string, references [][]byte, req models.GenerateRequest) (*Session, *Image, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("data", string(data)); err != nil {
+		return nil, nil, err
+	}
+	part, err := mw.CreateFormFile("image", filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := part.Write(photo); err != nil {
+		return nil, nil, err
+	}
+	for i, ref := range references {
+		part, err := mw.CreateFormFile("reference", fmt.Sprintf("reference-%d", i+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := part.Write(ref); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return c.generate(ctx, mw.FormDataContentType(), buf.Bytes())
+}
+
+// GenerateFromURL is like Generate, but the server fetches the photo from
+// req.ImageURL instead of an upload.
+func (c *Client) GenerateFromURL(ctx context.Context, req models.GenerateRequest) (*Session, *Image, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.generate(ctx, "application/json", data)
+}
+
+func (c *Client) generate(ctx context.Context, contentType string, body []byte) (*Session, *Image, error) {
+	resp, err := c.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/v1/generate",
+		contentType: contentType,
+		body:        body,
+		// The look and the session's styles in one response.
+		accept: "multipart/mixed",
+		signed: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	session := &Session{ID: resp.header.Get("X-Session-ID"), client: c}
+	img, err := imageFrom(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, img, nil
+}
+
+// Resume returns a handle to an existing session by ID.
+func (c *Client) Resume(sessionID string) *Session {
+	return &Session{ID: sessionID, client: c}
+}
+
+func imageFrom(resp *response) (*Image, error) {
+	if resp.header.Get("X-Partial-Result") != "" {
+		var partial models.PartialResultResponse
+		if json.Unmarshal(resp.body, &partial) == nil {
+			return &Image{Partial: &partial}, nil
+		}
+	}
+	mediaType, params, _ := mime.ParseMediaType(resp.header.Get("Content-Type"))
+	if mediaType == "multipart/mixed" {
+		return multipartImage(resp.body, params["boundary"])
+	}
+	return &Image{Data: resp.body, MimeType: resp.header.Get("Content-Type"), LookID: resp.header.Get("X-Look-ID")}, nil
+}
+
+// multipartImage reads a multipart/mixed look: a models.ImageResponse part
+// followed by the image.
+func multipartImage(body []byte, boundary string) (*Image, error) {
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, fmt.Errorf("dreswap: reading look metadata: %w", err)
+	}
+	var meta models.ImageResponse
+	if err := json.NewDecoder(part).Decode(&meta); err != nil {
+		return nil, fmt.Errorf("dreswap: decoding look metadata: %w", err)
+	}
+	if part, err = mr.NextPart(); err != nil {
+		return nil, fmt.Errorf("dreswap: reading look image: %w", err)
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		return nil, fmt.Errorf("dreswap: reading look image: %w", err)
+	}
+	return &Image{Data: data, MimeType: meta.MimeType, LookID: meta.LookID, Styles: meta.Styles}, nil
+}
+
+// Styles returns the style suggestions for the session.
+func (s *Session) Styles(ctx context.Context) ([]models.Style, error) {
+	return s.StylesMatching(ctx, nil, nil)
+}
+
+// StylesMatching returns the session's styles that have all of tags, e.g.
+// "formal", and a palette color matching each of colors, a color name or a
+// family such as "neutral".
+func (s *Session) StylesMatching(ctx context.Context, tags, colors []string) ([]models.Style, error) {
+	path := "/api/v1/styles"
+	if query := (url.Values{"tag": tags, "color": colors}).Encode(); query != "" {
+		path += "?" + query
+	}
+	var styles []models.Style
+	err := s.client.getJSON(ctx, path, s.ID, &styles)
+	return styles, err
+}
+
+// MoreStyles asks for more style suggestions, different from the session's
+// current ones, and returns those added to the session with their indexes.
+func (s *Session) MoreStyles(ctx context.Context) ([]models.IndexedStyle, error) {
+	resp, err := s.client.do(ctx, request{method: http.MethodPost, path: "/api/v1/styles/more", sessionID: s.ID, signed: true})
+	if err != nil {
+		return nil, err
+	}
+	var out models.MoreStylesResponse
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return out.Styles, nil
+}
+
+// RegenerateStyles replaces the styles the user disliked with new
+// suggestions, which respect the reasons given, and returns the
+// replacements with the indexes of the styles they replaced.
+func (s *Session) RegenerateStyles(ctx context.Context, disliked []models.DislikedStyle) ([]models.IndexedStyle, error) {
+	body, err := json.Marshal(models.RegenerateStylesRequest{Disliked: disliked})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/v1/styles/regenerate",
+		contentType: "application/json",
+		body:        body,
+		sessionID:   s.ID,
+		signed:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out models.RegenerateStylesResponse
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return out.Styles, nil
+}
+
+// Refresh replaces the session's style suggestions with its preset's current
+// ones after an admin updated them, as flagged by PresetUpdatedAt in
+// Client.Sessions. Swap to one of the returned styles to regenerate.
+func (s *Session) Refresh(ctx context.Context) ([]models.Style, error) {
+	resp, err := s.client.do(ctx, request{method: http.MethodPost, path: "/api/v1/sessions/" + s.ID + "/refresh"})
+	if err != nil {
+		return nil, err
+	}
+	var styles []models.Style
+	if err := json.Unmarshal(resp.body, &styles); err != nil {
+		return nil, err
+	}
+	return styles, nil
+}
+
+// Favorites lists the styles and looks shortlisted in the session, most
+// recently added first.
+func (s *Session) Favorites(ctx context.Context) ([]models.Favorite, error) {
+	var out models.FavoritesResponse
+	if err := s.client.getJSON(ctx, "/api/v1/sessions/"+s.ID+"/favorites", "", &out); err != nil {
+		return nil, err
+	}
+	return out.Favorites, nil
+}
+
+// FavoriteStyle shortlists the session's style with the given ID.
+func (s *Session) FavoriteStyle(ctx context.Context, id string) ([]models.Favorite, error) {
+	return s.favorite(ctx, http.MethodPut, "styles", id)
+}
+
+// UnfavoriteStyle removes a style from the session's favorites.
+func (s *Session) UnfavoriteStyle(ctx context.Context, id string) ([]models.Favorite, error) {
+	return s.favorite(ctx, http.MethodDelete, "styles", id)
+}
+
+// FavoriteLook shortlists an image generated in the session.
+func (s *Session) FavoriteLook(ctx context.Context, lookID string) ([]models.Favorite, error) {
+	return s.favorite(ctx, http.MethodPut, "looks", lookID)
+}
+
+// UnfavoriteLook removes a look from the session's favorites.
+func (s *Session) UnfavoriteLook(ctx context.Context, lookID string) ([]models.Favorite, error) {
+	return s.favorite(ctx, http.MethodDelete, "looks", lookID)
+}
+
+func (s *Session) favorite(ctx context.Context, method, kind, id string) ([]models.Favorite, error) {
+	resp, err := s.client.do(ctx, request{method: method, path: "/api/v1/sessions/" + s.ID + "/favorites/" + kind + "/" + url.PathEscape(id)})
+	if err != nil {
+		return nil, err
+	}
+	var out models.FavoritesResponse
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return out.Favorites, nil
+}
+
+// Swap renders the session's photo in the style at index.
+func (s *Session) Swap(ctx context.Context, index int) (*Image, error) {
+	return s.swap(ctx, models.SwapStyleRequest{StyleIndex: index})
+}
+
+// SwapID renders the session's photo in the style with the given ID.
+func (s *Session) SwapID(ctx context.Context, id string) (*Image, error) {
+	return s.swap(ctx, models.SwapStyleRequest{StyleID: id})
+}
+
+// SwapText renders the session's photo in an outfit the user described. The
+// description is added to the session's styles, so Swap can pick it again.
+func (s *Session) SwapText(ctx context.Context, text string) (*Image, error) {
+	return s.swap(ctx, models.SwapStyleRequest{StyleText: text})
+}
+
+func (s *Session) swap(ctx context.Context, req models.SwapStyleRequest) (*Image, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/v1/swap-style",
+		contentType: "application/json",
+		body:        body,
+		sessionID:   s.ID,
+		signed:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imageFrom(resp)
+}
+
+// Refine edits a look of the session as instruction says, e.g. "make it more
+// formal", starting from the look rather than the original photo. An empty
+// lookID refines the session's most recent look.
+func (s *Session) Refine(ctx context.Context, lookID, instruction string) (*Image, error) {
+	return s.refine(ctx, models.RefineRequest{LookID: lookID, Instruction: instruction})
+}
+
+// RefineComponent replaces one component of a look, e.g. "shoes", with
+// change, e.g. "white sneakers", keeping the rest of the outfit. An empty
+// lookID uses the session's most recent look.
+func (s *Session) RefineComponent(ctx context.Context, lookID, component, change string) (*Image, error) {
+	return s.refine(ctx, models.RefineRequest{LookID: lookID, Component: component, Change: change})
+}
+
+func (s *Session) refine(ctx context.Context, req models.RefineRequest) (*Image, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/v1/refine",
+		contentType: "application/json",
+		body:        body,
+		sessionID:   s.ID,
+		signed:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imageFrom(resp)
+}
+
+// AddAccessories layers accessories onto a look of the session without
+// regenerating the outfit: types from Client.Accessories, plus text
+// describing more in the user's words. An empty lookID uses the session's
+// most recent look.
+func (s *Session) AddAccessories(ctx context.Context, lookID string, types []string, text string) (*Image, error) {
+	body, err := json.Marshal(models.AccessoriesRequest{LookID: lookID, Types: types, Text: text})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/v1/accessories",
+		contentType: "application/json",
+		body:        body,
+		sessionID:   s.ID,
+		signed:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imageFrom(resp)
+}
+
+// Result fetches the session's most recent look again, e.g. after a page
+// refresh or on another device, without regenerating it.
+func (s *Session) Result(ctx context.Context) (*Image, error) {
+	resp, err := s.client.do(ctx, request{method: http.MethodGet, path: "/api/v1/result", sessionID: s.ID})
+	if err != nil {
+		return nil, err
+	}
+	return imageFrom(resp)
+}
+
+// History lists the images generated in the session, oldest first.
+func (s *Session) History(ctx context.Context) ([]models.HistoryEntry, error) {
+	var out models.HistoryResponse
+	if err := s.client.getJSON(ctx, "/api/v1/history", s.ID, &out); err != nil {
+		return nil, err
+	}
+	return out.Images, nil
+}
+
+// Previews renders a low-resolution preview of every style in the session, so
+// the user can pick one before rendering it at full quality with Swap.
+func (s *Session) Previews(ctx context.Context) (*models.PreviewsResponse, error) {
+	resp, err := s.client.do(ctx, request{
+		method:    http.MethodPost,
+		path:      "/api/v1/previews",
+		sessionID: s.ID,
+		signed:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out models.PreviewsResponse
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Sessions lists the caller's sessions, newest first.
+func (c *Client) Sessions(ctx context.Context) ([]models.SessionResponse, error) {
+	var out []models.SessionResponse
+	err := c.getJSON(ctx, "/api/v1/sessions", "", &out)
+	return out, err
+}
+
+// Update renames the session or replaces its notes.
+func (s *Session) Update(ctx context.Context, req models.UpdateSessionRequest) (*models.SessionResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.do(ctx, request{method: http.MethodPut, path: "/api/v1/sessions/" + s.ID, contentType: "application/json", body: body})
+	if err != nil {
+		return nil, err
+	}
+	var out models.SessionResponse
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetTags replaces a look's tags.
+func (c *Client) SetTags(ctx context.Context, lookID string, tags []string) (*models.LookResponse, error) {
+	body, err := json.Marshal(models.TagsRequest{Tags: tags})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, request{method: http.MethodPut, path: "/api/v1/looks/" + url.PathEscape(lookID) + "/tags", contentType: "application/json", body: body})
+	if err != nil {
+		return nil, err
+	}
+	var out models.LookResponse
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Accessories lists the accessory types Session.AddAccessories accepts.
+func (c *Client) Accessories(ctx context.Context) ([]models.Accessory, error) {
+	var out []models.Accessory
+	err := c.getJSON(ctx, "/api/v1/accessories", "", &out)
+	return out, err
+}
+
+// Tags lists the tags on the caller's looks, most used first.
+func (c *Client) Tags(ctx context.Context) ([]models.TagResponse, error) {
+	var out []models.TagResponse
+	err := c.getJSON(ctx, "/api/v1/tags", "", &out)
+	return out, err
+}
+
+// History lists the caller's looks, newest first, narrowed to looks with all
+// of tags if any are given.
+func (c *Client) History(ctx context.Context, tags ...string) ([]models.LookResponse, error) {
+	query := url.Values{"tag": tags}
+	path := "/api/v1/looks"
+	if len(tags) > 0 {
+		path += "?" + query.Encode()
+	}
+	var out []models.LookResponse
+	err := c.getJSON(ctx, path, "", &out)
+	return out, err
+}
+
+// Usage returns the caller's usage and daily quota.
+func (c *Client) Usage(ctx context.Context) (*models.UsageResponse, error) {
+	var usage models.UsageResponse
+	if err := c.getJSON(ctx, "/api/v1/usage", "", &usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// Gallery returns a page of the public gallery.
+func (c *Client) Gallery(ctx context.Context, page, pageSize int) (*models.GalleryPage, error) {
+	var out models.GalleryPage
+	path := fmt.Sprintf("/api/v1/gallery?page=%d&pageSize=%d", page, pageSize)
+	if err := c.getJSON(ctx, path, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Favorites lists the favorites of all the caller's sessions, most recently
+// added first.
+func (c *Client) Favorites(ctx context.Context) ([]models.Favorite, error) {
+	var out models.FavoritesResponse
+	if err := c.getJSON(ctx, "/api/v1/favorites", "", &out); err != nil {
+		return nil, err
+	}
+	return out.Favorites, nil
+}
+
+// PublishLook submits a look to the public gallery. It is listed once a
+// moderator approves it.
+func (c *Client) PublishLook(ctx context.Context, req models.PublishLookRequest) (*models.GalleryItem, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/gallery", contentType: "application/json", body: body})
+	if err != nil {
+		return nil, err
+	}
+	var out models.GalleryItem
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ShareLook mints a public link to a look's image that expires after ttl,
+// or after seven days if ttl is zero.
+func (c *Client) ShareLook(ctx context.Context, lookID string, ttl time.Duration) (*models.ShareResponse, error) {
+	body, err := json.Marshal(models.CreateShareRequest{TTLSeconds: int64(ttl.Seconds())})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/looks/" + url.PathEscape(lookID) + "/share", contentType: "application/json", body: body})
+	if err != nil {
+		return nil, err
+	}
+	var out models.ShareResponse
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ShareQR returns a QR code PNG of a share's link, about size pixels wide,
+// or 512 if size is zero.
+func (c *Client) ShareQR(ctx context.Context, token string, size int) ([]byte, error) {
+	path := "/api/v1/share/" + url.PathEscape(token) + "/qr"
+	if size > 0 {
+		path += "?size=" + strconv.Itoa(size)
+	}
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path})
+	if err != nil {
+		return nil, err
+	}
+	return resp.body, nil
+}
+
+// CreateShortLink shortens a share, poll or referral URL.
+func (c *Client) CreateShortLink(ctx context.Context, req models.CreateShortLinkRequest) (*models.ShortLinkResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/links", contentType: "application/json", body: body})
+	if err != nil {
+		return nil, err
+	}
+	var out models.ShortLinkResponse
+	if err := json.Unmarshal(resp.body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
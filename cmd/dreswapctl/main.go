@@ -0,0 +1,273 @@
+// cmd/dreswapctl/main.go
+//
+// dreswapctl is the operator CLI for a running server. It talks to the admin
+// API, so the server must have ADMIN_TOKEN set.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: dreswapctl [flags] <command> [args]
+
+Commands:
+  sessions list              List active sessions
+  sessions evict <id>        Drop a session from memory
+  cache stats                Show session cache counters
+  cache flush                Drop cached preset suggestions
+  jobs list [status]         List bulk jobs (running, done or failed)
+  jobs show <id>             Show a bulk job's progress
+  retention run              Delete expired short links and old incidents now
+  presets update <eventType|venue|theme> [style...]
+                             Replace a preset's suggestions (refetch if none
+                             given) and flag recent sessions to regenerate
+  config reload              Apply runtime settings without a restart
+  config versions            List recorded runtime configurations
+  config show <version>      Show a version's settings
+  config diff <version> [against]
+                             Show what changes from version against (default:
+                             the current settings) to version
+  config rollback <version>  Re-apply a version's settings
+  maintenance status         Show whether maintenance mode is on
+  maintenance on [message]   Reject generation requests with a 503
+  maintenance off            Resume generation
+  log level [level]          Show or set the log level (debug, info, warn, error)
+  keys list                  List API keys
+  keys create <name> [scope...]
+                             Create an API key (all scopes if none given)
+  keys rotate <id>           Issue a new secret for a key
+  keys origins <id> [origin...]
+                             Bind a key to browser origins (none to unbind)
+  keys signing-secret <id>   Issue a secret for HMAC-signed requests
+  keys suspend <id> <reason> [duration]
+                             Block a key's requests, until resumed or for
+                             duration (e.g. 72h)
+  keys resume <id>           Lift a key's suspension
+  keys revoke <id>           Revoke a key
+
+Flags:
+`
+
+// client calls the admin API of one server.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// do sends a request and pretty-prints the JSON response to stdout.
+func (c *client) do(method, path string, body any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.baseURL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if len(data) == 0 {
+		fmt.Println("OK")
+		return nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		os.Stdout.Write(data)
+		return nil
+	}
+	fmt.Println(out.String())
+	return nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func main() {
+	flags := flag.NewFlagSet("dreswapctl", flag.ExitOnError)
+	baseURL := flags.String("url", envOr("DRESWAP_URL", "http://localhost:8081"), "server base URL ($DRESWAP_URL)")
+	token := flags.String("token", os.Getenv("ADMIN_TOKEN"), "admin bearer token ($ADMIN_TOKEN)")
+	timeout := flags.Duration("timeout", 30*time.Second, "request timeout")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	args := flags.Args()
+	if len(args) < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "dreswapctl: an admin token is required (-token or $ADMIN_TOKEN)")
+		os.Exit(2)
+	}
+
+	c := &client{baseURL: *baseURL, token: *token, http: &http.Client{Timeout: *timeout}}
+	if err := run(c, args[0], args[1], args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "dreswapctl:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches a command to the matching admin endpoint.
+func run(c *client, group, cmd string, args []string) error {
+	need := func(n int) error {
+		if len(args) < n {
+			return fmt.Errorf("%s %s: missing argument", group, cmd)
+		}
+		return nil
+	}
+
+	switch group + " " + cmd {
+	case "sessions list":
+		return c.do(http.MethodGet, "/admin/sessions", nil)
+	case "sessions evict":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodDelete, "/admin/sessions/"+args[0], nil)
+	case "cache stats":
+		return c.do(http.MethodGet, "/admin/cache/stats", nil)
+	case "cache flush":
+		return c.do(http.MethodPost, "/admin/cache/flush", nil)
+	case "jobs list":
+		path := "/admin/jobs"
+		if len(args) > 0 {
+			path += "?status=" + url.QueryEscape(args[0])
+		}
+		return c.do(http.MethodGet, path, nil)
+	case "jobs show":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodGet, "/admin/jobs/"+args[0], nil)
+	case "retention run":
+		return c.do(http.MethodPost, "/admin/retention/run", nil)
+	case "presets update":
+		if err := need(1); err != nil {
+			return err
+		}
+		parts := strings.Split(args[0], "|")
+		if len(parts) != 3 {
+			return fmt.Errorf("presets update: preset must be \"eventType|venue|theme\"")
+		}
+		return c.do(http.MethodPut, "/admin/presets", map[string]any{
+			"eventType": parts[0], "venue": parts[1], "theme": parts[2], "styles": append([]string{}, args[1:]...),
+		})
+	case "config reload":
+		return c.do(http.MethodPost, "/admin/config/reload", nil)
+	case "config versions":
+		return c.do(http.MethodGet, "/admin/config/versions", nil)
+	case "config show":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodGet, "/admin/config/versions/"+args[0], nil)
+	case "config diff":
+		if err := need(1); err != nil {
+			return err
+		}
+		path := "/admin/config/versions/" + args[0] + "/diff"
+		if len(args) > 1 {
+			path += "?against=" + args[1]
+		}
+		return c.do(http.MethodGet, path, nil)
+	case "config rollback":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodPost, "/admin/config/versions/"+args[0]+"/rollback", nil)
+	case "maintenance status":
+		return c.do(http.MethodGet, "/admin/maintenance", nil)
+	case "maintenance on":
+		return c.do(http.MethodPut, "/admin/maintenance", map[string]any{"enabled": true, "message": strings.Join(args, " ")})
+	case "maintenance off":
+		return c.do(http.MethodPut, "/admin/maintenance", map[string]any{"enabled": false})
+	case "log level":
+		if len(args) == 0 {
+			return c.do(http.MethodGet, "/admin/log-level", nil)
+		}
+		return c.do(http.MethodPut, "/admin/log-level", map[string]any{"level": args[0]})
+	case "keys list":
+		return c.do(http.MethodGet, "/admin/api-keys", nil)
+	case "keys create":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodPost, "/admin/api-keys", map[string]any{"name": args[0], "scopes": args[1:]})
+	case "keys rotate":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodPost, "/admin/api-keys/"+args[0]+"/rotate", nil)
+	case "keys origins":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodPut, "/admin/api-keys/"+args[0]+"/origins", map[string]any{"origins": append([]string{}, args[1:]...)})
+	case "keys signing-secret":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodPost, "/admin/api-keys/"+args[0]+"/signing-secret", nil)
+	case "keys suspend":
+		if err := need(2); err != nil {
+			return err
+		}
+		body := map[string]any{"reason": args[1]}
+		if len(args) > 2 {
+			d, err := time.ParseDuration(args[2])
+			if err != nil || d <= 0 {
+				return fmt.Errorf("keys suspend: invalid duration %q", args[2])
+			}
+			body["until"] = time.Now().Add(d).UTC()
+		}
+		return c.do(http.MethodPut, "/admin/api-keys/"+args[0]+"/suspension", body)
+	case "keys resume":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodDelete, "/admin/api-keys/"+args[0]+"/suspension", nil)
+	case "keys revoke":
+		if err := need(1); err != nil {
+			return err
+		}
+		return c.do(http.MethodDelete, "/admin/api-keys/"+args[0], nil)
+	default:
+		return fmt.Errorf("unknown command %q; run dreswapctl -h for usage", group+" "+cmd)
+	}
+}
@@ -0,0 +1,152 @@
+// cmd/tsgen/main.go
+//
+// tsgen generates TypeScript interfaces from the API models so the TypeScript
+// SDK stays in sync with the server. Run it with go generate ./models.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	in := flag.String("in", "models.go", "Go source file with the API models")
+	out := flag.String("out", "models.ts", "TypeScript file to write")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, *in, nil, parser.ParseComments)
+	if err != nil {
+		log.Fatalf("tsgen: %v", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by tsgen from %s. DO NOT EDIT.\n", *in)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || !ts.Name.IsExported() {
+				continue
+			}
+			writeInterface(&buf, ts.Name.Name, gen.Doc, st)
+		}
+	}
+
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		log.Fatalf("tsgen: %v", err)
+	}
+}
+
+// writeInterface emits one struct as a TypeScript interface. Embedded structs
+// become extended interfaces, matching how encoding/json flattens them.
+func writeInterface(buf *bytes.Buffer, name string, doc *ast.CommentGroup, st *ast.StructType) {
+	var extends []string
+	var fields bytes.Buffer
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			extends = append(extends, tsType(f.Type))
+			continue
+		}
+		jsonName, omitempty := jsonTag(f)
+		if jsonName == "-" || !f.Names[0].IsExported() {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = f.Names[0].Name
+		}
+		_, isPointer := f.Type.(*ast.StarExpr)
+		writeDoc(&fields, f.Doc, "  ")
+		optional := ""
+		if omitempty || isPointer {
+			optional = "?"
+		}
+		fmt.Fprintf(&fields, "  %s%s: %s;\n", jsonName, optional, tsType(f.Type))
+	}
+
+	buf.WriteString("\n")
+	writeDoc(buf, doc, "")
+	fmt.Fprintf(buf, "export interface %s", name)
+	if len(extends) > 0 {
+		fmt.Fprintf(buf, " extends %s", strings.Join(extends, ", "))
+	}
+	fmt.Fprintf(buf, " {\n%s}\n", fields.String())
+}
+
+func writeDoc(buf *bytes.Buffer, doc *ast.CommentGroup, indent string) {
+	if doc == nil {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(doc.Text()), "\n")
+	if len(lines) == 1 {
+		fmt.Fprintf(buf, "%s/** %s */\n", indent, lines[0])
+		return
+	}
+	fmt.Fprintf(buf, "%s/**\n", indent)
+	for _, line := range lines {
+		fmt.Fprintf(buf, "%s * %s\n", indent, line)
+	}
+	fmt.Fprintf(buf, "%s */\n", indent)
+}
+
+func jsonTag(f *ast.Field) (name string, omitempty bool) {
+	if f.Tag == nil {
+		return "", false
+	}
+	raw, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(reflect.StructTag(raw).Get("json"), ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty
+}
+
+// tsType maps a Go type expression to TypeScript.
+func tsType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return "string"
+		case "bool":
+			return "boolean"
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+			return "number"
+		case "any":
+			return "unknown"
+		}
+		return t.Name
+	case *ast.StarExpr:
+		return tsType(t.X)
+	case *ast.ArrayType:
+		return tsType(t.Elt) + "[]"
+	case *ast.MapType:
+		return fmt.Sprintf("Record<%s, %s>", tsType(t.Key), tsType(t.Value))
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" && t.Sel.Name == "Time" {
+			return "string"
+		}
+		return "unknown"
+	case *ast.InterfaceType:
+		return "unknown"
+	}
+	return "unknown"
+}
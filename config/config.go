@@ -0,0 +1,916 @@
+// config/config.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete application configuration. It is loaded once at
+// startup from an optional YAML file (CONFIG_FILE) with environment variables
+// taking precedence, then validated.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	CORS     CORSConfig     `yaml:"cors"`
+	Headers  HeadersConfig  `yaml:"headers"`
+	Gemini   GeminiConfig   `yaml:"gemini"`
+	Store    StoreConfig    `yaml:"store"`
+	Usage    UsageConfig    `yaml:"usage"`
+	Security SecurityConfig `yaml:"security"`
+	Billing  BillingConfig  `yaml:"billing"`
+	Alerts   AlertsConfig   `yaml:"alerts"`
+	Presets  PresetsConfig  `yaml:"presets"`
+	// Suggestions configures the style suggestions of new sessions.
+	Suggestions SuggestionsConfig `yaml:"suggestions"`
+	TLS         TLSConfig         `yaml:"tls"`
+	Hooks       HooksConfig       `yaml:"hooks"`
+	// Maintenance is the startup state; admins can toggle it at runtime.
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	LowQuality  LowQualityConfig  `yaml:"lowQuality"`
+	Previews    PreviewsConfig    `yaml:"previews"`
+	Preprocess  PreprocessConfig  `yaml:"preprocess"`
+	AVIF        AVIFConfig        `yaml:"avif"`
+	Output      OutputConfig      `yaml:"output"`
+	// ImageMetadata controls the generation parameters written into images.
+	ImageMetadata ImageMetadataConfig `yaml:"imageMetadata"`
+	C2PA          C2PAConfig          `yaml:"c2pa"`
+	ImageURL      ImageURLConfig      `yaml:"imageUrl"`
+	FaceCheck     FaceCheckConfig     `yaml:"faceCheck"`
+	Moderation    ModerationConfig    `yaml:"moderation"`
+	Weather       WeatherConfig       `yaml:"weather"`
+	Shopping      ShoppingConfig      `yaml:"shopping"`
+	// PaletteAnalysis tailors suggestions to the colors of the uploaded photo.
+	PaletteAnalysis PaletteAnalysisConfig `yaml:"paletteAnalysis"`
+	// Log is the startup log configuration; admins can change the level at runtime.
+	Log LogConfig `yaml:"log"`
+	// Prompts replaces built-in prompt templates, by name (image, refine,
+	// suggestions, grade, faces, samePerson, moderation). They are YAML only,
+	// and are checked like the built-in ones.
+	Prompts map[string]string `yaml:"prompts"`
+}
+
+// LogConfig configures the structured logger.
+type LogConfig struct {
+	// Level is debug, info, warn or error. Full Gemini prompts and responses
+	// are only logged at debug.
+	Level string `yaml:"level"`
+}
+
+// SlogLevel returns Level as a slog.Level, or info if it is not valid.
+func (c LogConfig) SlogLevel() slog.Level {
+	level, ok := ParseLogLevel(c.Level)
+	if !ok {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// ParseLogLevel parses debug, info, warn or error, ignoring case.
+func ParseLogLevel(s string) (slog.Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info":
+		return slog.LevelInfo, true
+	case "warn":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	}
+	return 0, false
+}
+
+// LowQualityConfig sizes the reduced renders sent to clients on constrained
+// networks. The full-quality image is still stored.
+type LowQualityConfig struct {
+	// MaxDimension caps the longer side of the image, in pixels.
+	MaxDimension int64 `yaml:"maxDimension"`
+	// JPEGQuality is the JPEG encoder quality, 1-100.
+	JPEGQuality int64 `yaml:"jpegQuality"`
+}
+
+// PreprocessConfig controls how uploaded photos are shrunk before they are
+// cached and sent to Gemini.
+type PreprocessConfig struct {
+	// MaxDimension caps the longer side of a photo, in pixels; larger photos
+	// are downscaled and re-encoded as JPEG. 0 disables preprocessing.
+	MaxDimension int64 `yaml:"maxDimension"`
+	// JPEGQuality is the JPEG encoder quality, 1-100.
+	JPEGQuality int64 `yaml:"jpegQuality"`
+	// HEICConverter is a command that reads a HEIC photo on stdin and writes
+	// a JPEG to stdout, e.g. "magick heic:- jpeg:-". HEIC uploads are
+	// rejected when it is empty.
+	HEICConverter string `yaml:"heicConverter"`
+	// HEICTimeout bounds each conversion.
+	HEICTimeout time.Duration `yaml:"heicTimeout"`
+	// MaxConversions caps how many HEIC, AVIF and WebP conversions run at
+	// once, across all converters.
+	MaxConversions int64 `yaml:"maxConversions"`
+}
+
+// AVIFConfig configures the external programs that read AVIF uploads and
+// encode results as AVIF for clients that accept it.
+type AVIFConfig struct {
+	// Converter reads an AVIF photo on stdin and writes a JPEG to stdout, e.g.
+	// "magick avif:- jpeg:-". AVIF uploads are rejected when it is empty.
+	Converter string `yaml:"converter"`
+	// Encoder reads a PNG or JPEG on stdin and writes an AVIF to stdout, e.g.
+	// "magick - -quality 50 avif:-". Results are not sent as AVIF when it is empty.
+	Encoder string `yaml:"encoder"`
+	// Timeout bounds each conversion.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// OutputConfig configures the formats a client can request for generated
+// images with ?format=.
+type OutputConfig struct {
+	// JPEGQuality is the JPEG encoder quality of format=jpeg and of framed
+	// JPEG results (see a generate request's aspectRatio), 1-100.
+	JPEGQuality int64 `yaml:"jpegQuality"`
+	// WebPEncoder reads a PNG or JPEG on stdin and writes a WebP to stdout,
+	// e.g. "magick - -quality 80 webp:-". WebP output is refused when it is empty.
+	WebPEncoder string `yaml:"webpEncoder"`
+	// WebPTimeout bounds each WebP encoding.
+	WebPTimeout time.Duration `yaml:"webpTimeout"`
+}
+
+// ImageMetadataConfig controls the XMP metadata written into generated PNG
+// and JPEG images: the event, style, model and a reference to the session and
+// request, so shared images can be traced.
+type ImageMetadataConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// C2PAConfig configures the C2PA content credentials attached to generated
+// images by an external tool.
+type C2PAConfig struct {
+	// Command signs {input} with the manifest definition {manifest} into
+	// {output}, e.g. "c2patool {input} -m {manifest} -o {output} -f".
+	// Images are not signed when it is empty.
+	Command string `yaml:"command"`
+	// SignCert and PrivateKey are the paths of the PEM certificate chain and
+	// key. Without them, the tool's test certificate is used.
+	SignCert   string `yaml:"signCert"`
+	PrivateKey string `yaml:"privateKey"`
+	// Alg is the signing algorithm of the key: es256, es384, es512, ps256,
+	// ps384, ps512 or ed25519.
+	Alg string `yaml:"alg"`
+	// TSAURL is an RFC 3161 time-stamp authority, so signatures stay valid
+	// after the certificate expires.
+	TSAURL string `yaml:"tsaUrl"`
+	// Timeout bounds each signing.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// FaceCheckConfig controls the face-presence check on uploaded photos, which
+// costs one text model call per upload.
+type FaceCheckConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MinFacePercent is the face height, as a percentage of the photo height,
+	// below which the response warns that the face is too small.
+	MinFacePercent int64 `yaml:"minFacePercent"`
+}
+
+// ModerationConfig controls the content moderation check on uploaded photos,
+// which costs one text model call per upload.
+type ModerationConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// FailOpen accepts photos when the check itself fails; by default they
+	// are refused so an outage cannot be used to slip content past it.
+	FailOpen bool `yaml:"failOpen"`
+}
+
+// WeatherConfig controls looking up the weather at the venue on a generate
+// request's eventDate, for the style suggestions.
+type WeatherConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Timeout bounds each geocoding and weather API call.
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long the conditions at a venue on a date are reused.
+	CacheTTL time.Duration `yaml:"cacheTtl"`
+}
+
+// SuggestionsConfig configures the style suggestions of new sessions.
+type SuggestionsConfig struct {
+	// StyleCount is how many styles are suggested when a generate request
+	// doesn't set styleCount. The preset cache only keeps suggestions of
+	// this many styles.
+	StyleCount int64 `yaml:"styleCount"`
+}
+
+// PaletteAnalysisConfig controls analyzing uploaded photos for the person's
+// skin tone and dominant colors, which the style suggestions then favor.
+// Suggestions for such sessions skip the preset cache.
+type PaletteAnalysisConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// ShoppingConfig configures the product search that makes suggested styles
+// shoppable. It is disabled unless SearchURL is set.
+type ShoppingConfig struct {
+	// SearchURL is the product search API or retailer feed, with a {query}
+	// placeholder, e.g. https://feed.example.com/search?q={query}.
+	SearchURL string `yaml:"searchUrl"`
+	// APIKey, if set, is sent to it as a bearer token.
+	APIKey  string        `yaml:"apiKey"`
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long the results of a search are reused.
+	CacheTTL time.Duration `yaml:"cacheTtl"`
+	// ProductsPerItem caps the products returned per item of a style.
+	ProductsPerItem int64 `yaml:"productsPerItem"`
+}
+
+// ImageURLConfig controls fetching photos from a generate request's imageUrl.
+// Fetches are limited to public addresses and MAX_UPLOAD_BYTES.
+type ImageURLConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"`
+	// AllowedHosts, if set, limits fetches to these hosts and their subdomains.
+	AllowedHosts []string `yaml:"allowedHosts"`
+	// DeniedHosts are never fetched from, nor their subdomains.
+	DeniedHosts []string `yaml:"deniedHosts"`
+}
+
+// PreviewsConfig sizes the low-resolution style previews rendered by /previews.
+type PreviewsConfig struct {
+	// InputDimension caps the longer side of the photo sent to the model, in pixels.
+	InputDimension int64 `yaml:"inputDimension"`
+	// MaxDimension caps the longer side of each preview, in pixels.
+	MaxDimension int64 `yaml:"maxDimension"`
+	// JPEGQuality is the JPEG encoder quality, 1-100.
+	JPEGQuality int64 `yaml:"jpegQuality"`
+}
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/HTTP traces URL. When empty the standard
+	// OTEL_EXPORTER_OTLP_ENDPOINT variables apply.
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"serviceName"`
+}
+
+// MaintenanceConfig puts the generation endpoints into maintenance mode,
+// e.g. while the Gemini quota is exhausted.
+type MaintenanceConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Message is shown to users while maintenance mode is on.
+	Message string `yaml:"message"`
+	// RetryAfter is the retry hint given to clients.
+	RetryAfter time.Duration `yaml:"retryAfter"`
+}
+
+// ServerConfig controls the HTTP listener and request limits.
+type ServerConfig struct {
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	IdleTimeout  time.Duration `yaml:"idleTimeout"`
+	// GenerateTimeout replaces WriteTimeout on the image endpoints, whose
+	// Gemini calls routinely outlast it.
+	GenerateTimeout time.Duration `yaml:"generateTimeout"`
+	// HeartbeatInterval is how often the image endpoints send a keep-alive
+	// while waiting on Gemini, for proxies that drop idle connections. 0 disables it.
+	HeartbeatInterval time.Duration `yaml:"heartbeatInterval"`
+	// HeartbeatMode is processing, for 102 Processing interim responses, or
+	// whitespace, for spaces ahead of the JSON body.
+	HeartbeatMode  string `yaml:"heartbeatMode"`
+	MaxUploadBytes int64  `yaml:"maxUploadBytes"`
+	// MaxImageDimension caps the longer side of an uploaded photo, and
+	// MaxImageMegapixels its pixel count, before it is decoded. 0 disables either.
+	MaxImageDimension  int64 `yaml:"maxImageDimension"`
+	MaxImageMegapixels int64 `yaml:"maxImageMegapixels"`
+	// UploadTTL is how long a resumable upload is kept after its last chunk.
+	UploadTTL time.Duration `yaml:"uploadTtl"`
+	// UploadMemoryBytes caps the combined declared length of all pending
+	// resumable uploads, which are held in memory.
+	UploadMemoryBytes int64 `yaml:"uploadMemoryBytes"`
+	// ReadyTimeout bounds each dependency check of /readyz.
+	ReadyTimeout time.Duration `yaml:"readyTimeout"`
+	// ReadyGeminiInterval is how long a Gemini check result is reused by
+	// /readyz, so frequent probes don't call the API each time.
+	ReadyGeminiInterval time.Duration `yaml:"readyGeminiInterval"`
+	// TrustedProxies lists CIDRs whose forwarding headers are honored.
+	TrustedProxies []string `yaml:"trustedProxies"`
+	// PublicBaseURL is the default origin for public links, e.g. https://api.dreswap.app.
+	// Tenants with a custom domain get links on their own host instead.
+	PublicBaseURL string `yaml:"publicBaseUrl"`
+}
+
+// TLSConfig configures native HTTPS, either from certificate files or with
+// certificates from Let's Encrypt.
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	// Autocert obtains certificates for Hosts and tenant custom domains automatically.
+	Autocert bool     `yaml:"autocert"`
+	Hosts    []string `yaml:"hosts"`
+	CacheDir string   `yaml:"cacheDir"`
+	Email    string   `yaml:"email"`
+	// HTTPAddr serves ACME HTTP-01 challenges and redirects to HTTPS.
+	HTTPAddr string `yaml:"httpAddr"`
+}
+
+// HooksConfig registers image pipeline hooks run around each generation.
+type HooksConfig struct {
+	// Pre and Post are command lines of programs run before and after generation.
+	Pre  []string `yaml:"pre"`
+	Post []string `yaml:"post"`
+	// Plugins are paths to Go plugins exporting a Hook variable.
+	Plugins []string      `yaml:"plugins"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// CORSConfig is the cross-origin policy for browser clients.
+type CORSConfig struct {
+	// AllowedOrigins may contain a wildcard subdomain, e.g. https://*.vercel.app
+	// for preview deployments.
+	AllowedOrigins   []string      `yaml:"allowedOrigins"`
+	AllowedMethods   []string      `yaml:"allowedMethods"`
+	AllowedHeaders   []string      `yaml:"allowedHeaders"`
+	ExposedHeaders   []string      `yaml:"exposedHeaders"`
+	AllowCredentials bool          `yaml:"allowCredentials"`
+	MaxAge           time.Duration `yaml:"maxAge"`
+}
+
+// AllowsOrigin reports whether origin matches one of the allowed origins.
+// A "*." prefix on the host matches any subdomain, but not the bare domain.
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == origin {
+			return true
+		}
+		scheme, host, ok := strings.Cut(allowed, "://*.")
+		if !ok {
+			continue
+		}
+		rest, ok := strings.CutPrefix(origin, scheme+"://")
+		if ok && strings.HasSuffix(rest, "."+host) && !strings.Contains(strings.TrimSuffix(rest, "."+host), "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// HeadersConfig sets the security headers added to every response. An empty
+// value omits that header, e.g. when a fronting proxy already sets it.
+type HeadersConfig struct {
+	// HSTS is the Strict-Transport-Security value, sent on HTTPS requests only.
+	HSTS                  string `yaml:"hsts"`
+	ContentTypeOptions    string `yaml:"contentTypeOptions"`
+	ReferrerPolicy        string `yaml:"referrerPolicy"`
+	ContentSecurityPolicy string `yaml:"contentSecurityPolicy"`
+	FrameOptions          string `yaml:"frameOptions"`
+}
+
+// GeminiConfig holds the Gemini API credentials and model names.
+type GeminiConfig struct {
+	APIKey string `yaml:"apiKey"`
+	// SkipStartupCheck disables the boot-time API call that verifies the key
+	// and models, e.g. for offline development.
+	SkipStartupCheck bool `yaml:"skipStartupCheck"`
+	// TextFallback answers failed image calls with the style suggestions and
+	// outfit description instead of an error.
+	TextFallback   bool   `yaml:"textFallback"`
+	ImageModel     string `yaml:"imageModel"`
+	TextModel      string `yaml:"textModel"`
+	EmbeddingModel string `yaml:"embeddingModel"`
+}
+
+// StoreConfig configures persistent storage.
+type StoreConfig struct {
+	Dir string `yaml:"dir"`
+	// Objects moves generated images to an S3-compatible bucket.
+	Objects ObjectStorageConfig `yaml:"objects"`
+}
+
+// ObjectStorageConfig configures the bucket for generated images. It is
+// enabled by setting Bucket.
+type ObjectStorageConfig struct {
+	// Endpoint is the S3 API base URL, e.g. https://s3.eu-west-1.amazonaws.com
+	// or https://storage.googleapis.com.
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"accessKeyId"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	// URLTTL is how long signed download URLs stay valid.
+	URLTTL time.Duration `yaml:"urlTtl"`
+}
+
+// Enabled reports whether generated images go to a bucket.
+func (c ObjectStorageConfig) Enabled() bool {
+	return c.Bucket != ""
+}
+
+// UsageConfig configures metering and the free tier.
+type UsageConfig struct {
+	// FreeDailyLimit caps generations plus swaps per client per day; 0 disables it.
+	FreeDailyLimit int64 `yaml:"freeDailyLimit"`
+}
+
+// SecurityConfig holds request authentication settings.
+type SecurityConfig struct {
+	SigningSecret string `yaml:"signingSecret"`
+	// SignatureMaxSkew is how far a signature timestamp may drift from the
+	// server clock; nonces of key-signed requests are remembered for twice as long.
+	SignatureMaxSkew time.Duration `yaml:"signatureMaxSkew"`
+	AdminToken       string        `yaml:"adminToken"`
+	// RequireAPIKey rejects anonymous requests; any accepted authentication
+	// method satisfies it, not only API keys.
+	RequireAPIKey   bool   `yaml:"requireApiKey"`
+	CaptchaProvider string `yaml:"captchaProvider"`
+	CaptchaSecret   string `yaml:"captchaSecret"`
+	// E2EEMode is off, optional or required: whether clients may (or must)
+	// encrypt uploaded photos with a per-session key.
+	E2EEMode   string        `yaml:"e2eeMode"`
+	E2EEKeyTTL time.Duration `yaml:"e2eeKeyTtl"`
+	// JWT accepts bearer tokens from an OAuth or OpenID Connect provider.
+	JWT JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig configures bearer token authentication. It is disabled unless a
+// JWKS URL or an HS256 secret is set.
+type JWTConfig struct {
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	JWKSURL  string `yaml:"jwksUrl"`
+	Secret   string `yaml:"secret"`
+	// Scopes are granted to every caller with a valid token.
+	Scopes []string `yaml:"scopes"`
+}
+
+// Enabled reports whether bearer tokens are accepted.
+func (c JWTConfig) Enabled() bool {
+	return c.JWKSURL != "" || c.Secret != ""
+}
+
+// BillingConfig configures Stripe metered billing.
+type BillingConfig struct {
+	StripeSecretKey string `yaml:"stripeSecretKey"`
+	StripePriceID   string `yaml:"stripePriceId"`
+	SuccessURL      string `yaml:"successUrl"`
+	CancelURL       string `yaml:"cancelUrl"`
+}
+
+// AlertsConfig configures operator alerting.
+type AlertsConfig struct {
+	WebhookURL string `yaml:"webhookUrl"`
+}
+
+// PresetsConfig configures the preset suggestion cache.
+type PresetsConfig struct {
+	// Warm lists presets to always keep warm, as "eventType|venue|theme".
+	Warm            []string      `yaml:"warm"`
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+	// NotifyWindow is how far back sessions are flagged when an admin updates
+	// a preset's suggestions. Zero flags none.
+	NotifyWindow time.Duration `yaml:"notifyWindow"`
+	// WebhookURL receives the flagged sessions so they can be notified. Empty disables it.
+	WebhookURL string `yaml:"webhookUrl"`
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8081",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     time.Minute,
+			GenerateTimeout: 2 * time.Minute,
+			HeartbeatMode:   "processing",
+			MaxUploadBytes:  10 * 1024 * 1024, // 10 MB
+
+			MaxImageDimension:  8192,
+			MaxImageMegapixels: 40,
+			UploadTTL:          time.Hour,
+			UploadMemoryBytes:  512 * 1024 * 1024, // 512 MB
+
+			ReadyTimeout:        5 * time.Second,
+			ReadyGeminiInterval: 30 * time.Second,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"https://dreswap-ui.vercel.app", "http://localhost:3000"},
+			AllowedMethods: []string{"POST", "GET", "HEAD", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "X-Session-ID", "X-API-Key", "X-Signature", "X-Signature-Timestamp", "X-Signature-Nonce", "X-Captcha-Token", "Authorization", "X-E2EE-Key-ID", "X-E2EE-Public-Key", "traceparent", "tracestate", "X-Request-ID", "X-Client-Token", "Tus-Resumable", "Upload-Length", "Upload-Offset"},
+			ExposedHeaders: []string{"X-Session-ID", "X-Look-ID", "Retry-After", "X-Degraded-Mode", "X-Request-ID", "Server-Timing", "X-Image-Quality", "X-Partial-Result", "X-Photo-Warning", "X-Heartbeat", "X-Client-Token", "Location", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Expires", "Content-Disposition"},
+			MaxAge:         10 * time.Minute,
+		},
+		Headers: HeadersConfig{
+			HSTS:               "max-age=63072000; includeSubDomains",
+			ContentTypeOptions: "nosniff",
+			ReferrerPolicy:     "no-referrer",
+			// The API serves JSON and images only, so nothing may be loaded or framed.
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			FrameOptions:          "DENY",
+		},
+		Gemini: GeminiConfig{
+			ImageModel:     "gemini-2.5-flash-image-preview",
+			TextModel:      "gemini-2.5-flash",
+			EmbeddingModel: "text-embedding-004",
+		},
+		Store:    StoreConfig{Dir: "data", Objects: ObjectStorageConfig{Region: "us-east-1", URLTTL: 15 * time.Minute}},
+		Usage:    UsageConfig{FreeDailyLimit: 5},
+		Security: SecurityConfig{SignatureMaxSkew: 5 * time.Minute, E2EEMode: "off", E2EEKeyTTL: time.Hour, JWT: JWTConfig{Scopes: []string{"generate", "read"}}},
+		Presets:  PresetsConfig{RefreshInterval: 6 * time.Hour, NotifyWindow: 72 * time.Hour},
+		TLS:      TLSConfig{CacheDir: "data/certs", HTTPAddr: ":80"},
+		Hooks:    HooksConfig{Timeout: 30 * time.Second},
+		Tracing:  TracingConfig{Exporter: "none", ServiceName: "dreswap-backend"},
+		LowQuality: LowQualityConfig{
+			MaxDimension: 768,
+			JPEGQuality:  60,
+		},
+		Previews: PreviewsConfig{
+			InputDimension: 512,
+			MaxDimension:   320,
+			JPEGQuality:    50,
+		},
+		Preprocess: PreprocessConfig{
+			MaxDimension:   1536,
+			JPEGQuality:    90,
+			HEICTimeout:    30 * time.Second,
+			MaxConversions: 4,
+		},
+		AVIF:          AVIFConfig{Timeout: 30 * time.Second},
+		Output:        OutputConfig{JPEGQuality: 90, WebPTimeout: 30 * time.Second},
+		ImageMetadata: ImageMetadataConfig{Enabled: true},
+		C2PA:          C2PAConfig{Alg: "es256", Timeout: 10 * time.Second},
+		ImageURL:      ImageURLConfig{Timeout: 15 * time.Second},
+		FaceCheck:     FaceCheckConfig{MinFacePercent: 10},
+		Suggestions:   SuggestionsConfig{StyleCount: 5},
+		Weather:       WeatherConfig{Timeout: 5 * time.Second, CacheTTL: 3 * time.Hour},
+		Shopping:      ShoppingConfig{Timeout: 5 * time.Second, CacheTTL: time.Hour, ProductsPerItem: 2},
+		Log:           LogConfig{Level: "info"},
+		Maintenance: MaintenanceConfig{
+			Message:    "DreSwap is down for maintenance. Please try again soon.",
+			RetryAfter: 15 * time.Minute,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the YAML file named by
+// CONFIG_FILE (if set), and environment variables, and validates the result.
+func Load() (*Config, error) {
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides fields from environment variables.
+func (c *Config) applyEnv() error {
+	var errs []error
+	str := func(dst *string, name string) {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = v
+		}
+	}
+	list := func(dst *[]string, name, sep string) {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = splitList(v, sep)
+		}
+	}
+	duration := func(dst *time.Duration, name string) {
+		if v, ok := os.LookupEnv(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid duration %q", name, v))
+				return
+			}
+			*dst = d
+		}
+	}
+	integer := func(dst *int64, name string) {
+		if v, ok := os.LookupEnv(name); ok {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid integer %q", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	boolean := func(dst *bool, name string) {
+		if v, ok := os.LookupEnv(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid boolean %q", name, v))
+				return
+			}
+			*dst = b
+		}
+	}
+
+	// PORT is set by platforms such as Cloud Run; an explicit LISTEN_ADDR wins.
+	if port, ok := os.LookupEnv("PORT"); ok && port != "" {
+		c.Server.Addr = ":" + port
+	}
+	str(&c.Server.Addr, "LISTEN_ADDR")
+	duration(&c.Server.ReadTimeout, "READ_TIMEOUT")
+	duration(&c.Server.WriteTimeout, "WRITE_TIMEOUT")
+	duration(&c.Server.IdleTimeout, "IDLE_TIMEOUT")
+	duration(&c.Server.GenerateTimeout, "GENERATE_TIMEOUT")
+	duration(&c.Server.HeartbeatInterval, "HEARTBEAT_INTERVAL")
+	str(&c.Server.HeartbeatMode, "HEARTBEAT_MODE")
+	duration(&c.Server.ReadyTimeout, "READY_TIMEOUT")
+	duration(&c.Server.ReadyGeminiInterval, "READY_GEMINI_INTERVAL")
+	integer(&c.Server.MaxUploadBytes, "MAX_UPLOAD_BYTES")
+	integer(&c.Server.MaxImageDimension, "MAX_IMAGE_DIMENSION")
+	integer(&c.Server.MaxImageMegapixels, "MAX_IMAGE_MEGAPIXELS")
+	duration(&c.Server.UploadTTL, "UPLOAD_TTL")
+	integer(&c.Server.UploadMemoryBytes, "UPLOAD_MEMORY_BYTES")
+	list(&c.Server.TrustedProxies, "TRUSTED_PROXIES", ",")
+	str(&c.Server.PublicBaseURL, "PUBLIC_BASE_URL")
+
+	list(&c.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS", ",")
+	list(&c.CORS.AllowedMethods, "CORS_ALLOWED_METHODS", ",")
+	list(&c.CORS.AllowedHeaders, "CORS_ALLOWED_HEADERS", ",")
+	list(&c.CORS.ExposedHeaders, "CORS_EXPOSED_HEADERS", ",")
+	boolean(&c.CORS.AllowCredentials, "CORS_ALLOW_CREDENTIALS")
+	duration(&c.CORS.MaxAge, "CORS_MAX_AGE")
+
+	str(&c.Headers.HSTS, "HEADER_HSTS")
+	str(&c.Headers.ContentTypeOptions, "HEADER_CONTENT_TYPE_OPTIONS")
+	str(&c.Headers.ReferrerPolicy, "HEADER_REFERRER_POLICY")
+	str(&c.Headers.ContentSecurityPolicy, "HEADER_CSP")
+	str(&c.Headers.FrameOptions, "HEADER_FRAME_OPTIONS")
+
+	// GOOGLE_API_KEY wins over GEMINI_API_KEY, matching the genai SDK.
+	str(&c.Gemini.APIKey, "GEMINI_API_KEY")
+	str(&c.Gemini.APIKey, "GOOGLE_API_KEY")
+	boolean(&c.Gemini.SkipStartupCheck, "GEMINI_SKIP_STARTUP_CHECK")
+	boolean(&c.Gemini.TextFallback, "GEMINI_TEXT_FALLBACK")
+	str(&c.Gemini.ImageModel, "GEMINI_IMAGE_MODEL")
+	str(&c.Gemini.TextModel, "GEMINI_TEXT_MODEL")
+	str(&c.Gemini.EmbeddingModel, "GEMINI_EMBEDDING_MODEL")
+
+	str(&c.Store.Dir, "STORE_DIR")
+	str(&c.Store.Objects.Endpoint, "OBJECT_STORAGE_ENDPOINT")
+	str(&c.Store.Objects.Region, "OBJECT_STORAGE_REGION")
+	str(&c.Store.Objects.Bucket, "OBJECT_STORAGE_BUCKET")
+	str(&c.Store.Objects.AccessKeyID, "OBJECT_STORAGE_ACCESS_KEY_ID")
+	str(&c.Store.Objects.SecretAccessKey, "OBJECT_STORAGE_SECRET_ACCESS_KEY")
+	duration(&c.Store.Objects.URLTTL, "OBJECT_STORAGE_URL_TTL")
+	integer(&c.Usage.FreeDailyLimit, "FREE_DAILY_LIMIT")
+
+	str(&c.Security.SigningSecret, "REQUEST_SIGNING_SECRET")
+	duration(&c.Security.SignatureMaxSkew, "SIGNATURE_MAX_SKEW")
+	str(&c.Security.AdminToken, "ADMIN_TOKEN")
+	boolean(&c.Security.RequireAPIKey, "REQUIRE_API_KEY")
+	str(&c.Security.JWT.Issuer, "JWT_ISSUER")
+	str(&c.Security.JWT.Audience, "JWT_AUDIENCE")
+	str(&c.Security.JWT.JWKSURL, "JWT_JWKS_URL")
+	str(&c.Security.JWT.Secret, "JWT_SECRET")
+	list(&c.Security.JWT.Scopes, "JWT_SCOPES", ",")
+	str(&c.Security.CaptchaProvider, "CAPTCHA_PROVIDER")
+	str(&c.Security.CaptchaSecret, "CAPTCHA_SECRET_KEY")
+	str(&c.Security.E2EEMode, "E2EE_MODE")
+	duration(&c.Security.E2EEKeyTTL, "E2EE_KEY_TTL")
+
+	str(&c.Billing.StripeSecretKey, "STRIPE_SECRET_KEY")
+	str(&c.Billing.StripePriceID, "STRIPE_PRICE_ID")
+	str(&c.Billing.SuccessURL, "STRIPE_SUCCESS_URL")
+	str(&c.Billing.CancelURL, "STRIPE_CANCEL_URL")
+
+	str(&c.Alerts.WebhookURL, "ALERT_WEBHOOK_URL")
+
+	list(&c.Presets.Warm, "WARM_PRESETS", ";")
+	duration(&c.Presets.RefreshInterval, "PRESET_REFRESH_INTERVAL")
+	duration(&c.Presets.NotifyWindow, "PRESET_NOTIFY_WINDOW")
+	str(&c.Presets.WebhookURL, "PRESET_WEBHOOK_URL")
+
+	str(&c.TLS.CertFile, "TLS_CERT_FILE")
+	str(&c.TLS.KeyFile, "TLS_KEY_FILE")
+	boolean(&c.TLS.Autocert, "TLS_AUTOCERT")
+	list(&c.TLS.Hosts, "TLS_HOSTS", ",")
+	str(&c.TLS.CacheDir, "TLS_CACHE_DIR")
+	str(&c.TLS.Email, "TLS_EMAIL")
+	str(&c.TLS.HTTPAddr, "TLS_HTTP_ADDR")
+
+	list(&c.Hooks.Pre, "HOOKS_PRE", ";")
+	list(&c.Hooks.Post, "HOOKS_POST", ";")
+	list(&c.Hooks.Plugins, "HOOK_PLUGINS", ",")
+	duration(&c.Hooks.Timeout, "HOOK_TIMEOUT")
+
+	boolean(&c.Maintenance.Enabled, "MAINTENANCE_MODE")
+	str(&c.Maintenance.Message, "MAINTENANCE_MESSAGE")
+	duration(&c.Maintenance.RetryAfter, "MAINTENANCE_RETRY_AFTER")
+
+	str(&c.Tracing.Exporter, "TRACING_EXPORTER")
+	str(&c.Tracing.Endpoint, "TRACING_ENDPOINT")
+	str(&c.Tracing.ServiceName, "TRACING_SERVICE_NAME")
+	integer(&c.LowQuality.MaxDimension, "LOW_QUALITY_MAX_DIMENSION")
+	integer(&c.LowQuality.JPEGQuality, "LOW_QUALITY_JPEG_QUALITY")
+	integer(&c.Previews.InputDimension, "PREVIEW_INPUT_DIMENSION")
+	integer(&c.Previews.MaxDimension, "PREVIEW_MAX_DIMENSION")
+	integer(&c.Previews.JPEGQuality, "PREVIEW_JPEG_QUALITY")
+	integer(&c.Preprocess.MaxDimension, "PREPROCESS_MAX_DIMENSION")
+	integer(&c.Preprocess.JPEGQuality, "PREPROCESS_JPEG_QUALITY")
+	str(&c.Preprocess.HEICConverter, "HEIC_CONVERTER")
+	duration(&c.Preprocess.HEICTimeout, "HEIC_TIMEOUT")
+	integer(&c.Preprocess.MaxConversions, "MAX_CONVERSIONS")
+	str(&c.AVIF.Converter, "AVIF_CONVERTER")
+	str(&c.AVIF.Encoder, "AVIF_ENCODER")
+	duration(&c.AVIF.Timeout, "AVIF_TIMEOUT")
+	integer(&c.Output.JPEGQuality, "OUTPUT_JPEG_QUALITY")
+	str(&c.Output.WebPEncoder, "WEBP_ENCODER")
+	duration(&c.Output.WebPTimeout, "WEBP_TIMEOUT")
+	boolean(&c.ImageMetadata.Enabled, "IMAGE_METADATA_ENABLED")
+	str(&c.C2PA.Command, "C2PA_COMMAND")
+	str(&c.C2PA.SignCert, "C2PA_SIGN_CERT")
+	str(&c.C2PA.PrivateKey, "C2PA_PRIVATE_KEY")
+	str(&c.C2PA.Alg, "C2PA_ALG")
+	str(&c.C2PA.TSAURL, "C2PA_TSA_URL")
+	duration(&c.C2PA.Timeout, "C2PA_TIMEOUT")
+	boolean(&c.ImageURL.Enabled, "IMAGE_URL_ENABLED")
+	duration(&c.ImageURL.Timeout, "IMAGE_URL_TIMEOUT")
+	list(&c.ImageURL.AllowedHosts, "IMAGE_URL_ALLOWED_HOSTS", ",")
+	list(&c.ImageURL.DeniedHosts, "IMAGE_URL_DENIED_HOSTS", ",")
+	boolean(&c.FaceCheck.Enabled, "FACE_CHECK_ENABLED")
+	integer(&c.FaceCheck.MinFacePercent, "FACE_CHECK_MIN_PERCENT")
+
+	boolean(&c.Moderation.Enabled, "MODERATION_ENABLED")
+	boolean(&c.Moderation.FailOpen, "MODERATION_FAIL_OPEN")
+
+	integer(&c.Suggestions.StyleCount, "STYLE_COUNT")
+
+	boolean(&c.Weather.Enabled, "WEATHER_ENABLED")
+	duration(&c.Weather.Timeout, "WEATHER_TIMEOUT")
+	duration(&c.Weather.CacheTTL, "WEATHER_CACHE_TTL")
+
+	boolean(&c.PaletteAnalysis.Enabled, "PALETTE_ANALYSIS_ENABLED")
+
+	str(&c.Shopping.SearchURL, "SHOPPING_SEARCH_URL")
+	str(&c.Shopping.APIKey, "SHOPPING_API_KEY")
+	duration(&c.Shopping.Timeout, "SHOPPING_TIMEOUT")
+	duration(&c.Shopping.CacheTTL, "SHOPPING_CACHE_TTL")
+	integer(&c.Shopping.ProductsPerItem, "SHOPPING_PRODUCTS_PER_ITEM")
+
+	str(&c.Log.Level, "LOG_LEVEL")
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the configuration and reports every problem at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr (LISTEN_ADDR) must not be empty")
+	check(c.Server.ReadTimeout > 0, "server.readTimeout (READ_TIMEOUT) must be positive")
+	check(c.Server.WriteTimeout > 0, "server.writeTimeout (WRITE_TIMEOUT) must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idleTimeout (IDLE_TIMEOUT) must be positive")
+	check(c.Server.GenerateTimeout > 0, "server.generateTimeout (GENERATE_TIMEOUT) must be positive")
+	check(c.Server.HeartbeatInterval >= 0, "server.heartbeatInterval (HEARTBEAT_INTERVAL) must not be negative")
+	switch c.Server.HeartbeatMode {
+	case "processing", "whitespace":
+	default:
+		check(false, "server.heartbeatMode (HEARTBEAT_MODE) must be processing or whitespace, got %q", c.Server.HeartbeatMode)
+	}
+	check(c.Server.ReadyTimeout > 0, "server.readyTimeout (READY_TIMEOUT) must be positive")
+	check(c.Server.ReadyGeminiInterval >= 0, "server.readyGeminiInterval (READY_GEMINI_INTERVAL) must not be negative")
+	check(c.Server.MaxUploadBytes > 0, "server.maxUploadBytes (MAX_UPLOAD_BYTES) must be positive")
+	check(c.Server.MaxImageDimension >= 0, "server.maxImageDimension (MAX_IMAGE_DIMENSION) must not be negative")
+	check(c.Server.MaxImageMegapixels >= 0, "server.maxImageMegapixels (MAX_IMAGE_MEGAPIXELS) must not be negative")
+	check(c.Server.UploadTTL > 0, "server.uploadTtl (UPLOAD_TTL) must be positive")
+	check(c.Server.UploadMemoryBytes >= c.Server.MaxUploadBytes, "server.uploadMemoryBytes (UPLOAD_MEMORY_BYTES) must be at least server.maxUploadBytes")
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+			"cors.allowedOrigins (CORS_ALLOWED_ORIGINS): %q must start with http:// or https://", origin)
+		_, host, _ := strings.Cut(origin, "://")
+		check(!strings.Contains(strings.TrimPrefix(host, "*."), "*"),
+			"cors.allowedOrigins (CORS_ALLOWED_ORIGINS): %q may only use a leading *. wildcard", origin)
+	}
+	check(len(c.CORS.AllowedMethods) > 0, "cors.allowedMethods (CORS_ALLOWED_METHODS) must not be empty")
+	check(c.CORS.MaxAge >= 0, "cors.maxAge (CORS_MAX_AGE) must not be negative")
+	check(c.Gemini.APIKey != "", "gemini.apiKey (GEMINI_API_KEY or GOOGLE_API_KEY) is required")
+	check(c.Gemini.ImageModel != "", "gemini.imageModel (GEMINI_IMAGE_MODEL) must not be empty")
+	check(c.Gemini.TextModel != "", "gemini.textModel (GEMINI_TEXT_MODEL) must not be empty")
+	check(c.Gemini.EmbeddingModel != "", "gemini.embeddingModel (GEMINI_EMBEDDING_MODEL) must not be empty")
+	check(c.Store.Dir != "", "store.dir (STORE_DIR) must not be empty")
+	if c.Store.Objects.Enabled() {
+		objects := c.Store.Objects
+		check(objects.Endpoint != "", "store.objects.endpoint (OBJECT_STORAGE_ENDPOINT) is required with a bucket")
+		check(objects.Region != "", "store.objects.region (OBJECT_STORAGE_REGION) must not be empty")
+		check(objects.AccessKeyID != "" && objects.SecretAccessKey != "", "store.objects.accessKeyId and secretAccessKey (OBJECT_STORAGE_ACCESS_KEY_ID, OBJECT_STORAGE_SECRET_ACCESS_KEY) are required with a bucket")
+		check(objects.URLTTL >= time.Second && objects.URLTTL <= 7*24*time.Hour, "store.objects.urlTtl (OBJECT_STORAGE_URL_TTL) must be between 1s and 168h")
+	}
+	check(c.Usage.FreeDailyLimit >= 0, "usage.freeDailyLimit (FREE_DAILY_LIMIT) must not be negative")
+	switch c.Security.CaptchaProvider {
+	case "", "turnstile", "recaptcha":
+	default:
+		check(false, "security.captchaProvider (CAPTCHA_PROVIDER) must be turnstile or recaptcha, got %q", c.Security.CaptchaProvider)
+	}
+	switch c.Security.E2EEMode {
+	case "off", "optional", "required":
+	default:
+		check(false, "security.e2eeMode (E2EE_MODE) must be off, optional or required, got %q", c.Security.E2EEMode)
+	}
+	check(c.Security.SignatureMaxSkew > 0, "security.signatureMaxSkew (SIGNATURE_MAX_SKEW) must be positive")
+	check(c.Security.E2EEKeyTTL > 0, "security.e2eeKeyTtl (E2EE_KEY_TTL) must be positive")
+	if u := c.Security.JWT.JWKSURL; u != "" {
+		check(strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://"), "security.jwt.jwksUrl (JWT_JWKS_URL) must be an http(s) URL, got %q", u)
+	}
+	if c.Billing.StripeSecretKey != "" {
+		check(c.Billing.StripePriceID != "", "billing.stripePriceId (STRIPE_PRICE_ID) is required when Stripe is enabled")
+		check(c.Billing.SuccessURL != "", "billing.successUrl (STRIPE_SUCCESS_URL) is required when Stripe is enabled")
+		check(c.Billing.CancelURL != "", "billing.cancelUrl (STRIPE_CANCEL_URL) is required when Stripe is enabled")
+	}
+	if c.Server.PublicBaseURL != "" {
+		check(strings.HasPrefix(c.Server.PublicBaseURL, "https://") || strings.HasPrefix(c.Server.PublicBaseURL, "http://"),
+			"server.publicBaseUrl (PUBLIC_BASE_URL) must start with http:// or https://")
+	}
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""),
+		"tls.certFile (TLS_CERT_FILE) and tls.keyFile (TLS_KEY_FILE) must be set together")
+	check(!(c.TLS.Autocert && c.TLS.CertFile != ""),
+		"tls.autocert (TLS_AUTOCERT) cannot be combined with tls.certFile (TLS_CERT_FILE)")
+	if c.TLS.Autocert {
+		check(c.TLS.CacheDir != "", "tls.cacheDir (TLS_CACHE_DIR) is required when autocert is enabled")
+		check(c.TLS.HTTPAddr != "", "tls.httpAddr (TLS_HTTP_ADDR) is required when autocert is enabled")
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		check(false, "tracing.exporter (TRACING_EXPORTER) must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	_, ok := ParseLogLevel(c.Log.Level)
+	check(ok, "log.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Maintenance.RetryAfter > 0, "maintenance.retryAfter (MAINTENANCE_RETRY_AFTER) must be positive")
+	check(c.Hooks.Timeout > 0, "hooks.timeout (HOOK_TIMEOUT) must be positive")
+	check(c.Presets.RefreshInterval > 0, "presets.refreshInterval (PRESET_REFRESH_INTERVAL) must be positive")
+	check(c.Presets.NotifyWindow >= 0, "presets.notifyWindow (PRESET_NOTIFY_WINDOW) must not be negative")
+	check(c.LowQuality.MaxDimension > 0, "lowQuality.maxDimension (LOW_QUALITY_MAX_DIMENSION) must be positive")
+	check(c.LowQuality.JPEGQuality >= 1 && c.LowQuality.JPEGQuality <= 100, "lowQuality.jpegQuality (LOW_QUALITY_JPEG_QUALITY) must be between 1 and 100")
+	check(c.Previews.InputDimension > 0, "previews.inputDimension (PREVIEW_INPUT_DIMENSION) must be positive")
+	check(c.Previews.MaxDimension > 0, "previews.maxDimension (PREVIEW_MAX_DIMENSION) must be positive")
+	check(c.Previews.JPEGQuality >= 1 && c.Previews.JPEGQuality <= 100, "previews.jpegQuality (PREVIEW_JPEG_QUALITY) must be between 1 and 100")
+	check(c.Preprocess.MaxDimension >= 0, "preprocess.maxDimension (PREPROCESS_MAX_DIMENSION) must not be negative")
+	check(c.Preprocess.JPEGQuality >= 1 && c.Preprocess.JPEGQuality <= 100, "preprocess.jpegQuality (PREPROCESS_JPEG_QUALITY) must be between 1 and 100")
+	check(c.Preprocess.HEICTimeout > 0, "preprocess.heicTimeout (HEIC_TIMEOUT) must be positive")
+	check(c.Preprocess.MaxConversions > 0, "preprocess.maxConversions (MAX_CONVERSIONS) must be positive")
+	check(c.AVIF.Timeout > 0, "avif.timeout (AVIF_TIMEOUT) must be positive")
+	check(c.Output.JPEGQuality >= 1 && c.Output.JPEGQuality <= 100, "output.jpegQuality (OUTPUT_JPEG_QUALITY) must be between 1 and 100")
+	check(c.Output.WebPTimeout > 0, "output.webpTimeout (WEBP_TIMEOUT) must be positive")
+	check((c.C2PA.SignCert == "") == (c.C2PA.PrivateKey == ""), "c2pa.signCert (C2PA_SIGN_CERT) and c2pa.privateKey (C2PA_PRIVATE_KEY) must be set together")
+	check(slices.Contains([]string{"es256", "es384", "es512", "ps256", "ps384", "ps512", "ed25519"}, c.C2PA.Alg), "c2pa.alg (C2PA_ALG) must be es256, es384, es512, ps256, ps384, ps512 or ed25519")
+	check(c.C2PA.Timeout > 0, "c2pa.timeout (C2PA_TIMEOUT) must be positive")
+	check(c.ImageURL.Timeout > 0, "imageUrl.timeout (IMAGE_URL_TIMEOUT) must be positive")
+	check(c.Suggestions.StyleCount >= 3 && c.Suggestions.StyleCount <= 10, "suggestions.styleCount (STYLE_COUNT) must be between 3 and 10")
+	check(c.Weather.Timeout > 0, "weather.timeout (WEATHER_TIMEOUT) must be positive")
+	check(c.Weather.CacheTTL > 0, "weather.cacheTtl (WEATHER_CACHE_TTL) must be positive")
+	check(c.Shopping.Timeout > 0, "shopping.timeout (SHOPPING_TIMEOUT) must be positive")
+	check(c.Shopping.CacheTTL > 0, "shopping.cacheTtl (SHOPPING_CACHE_TTL) must be positive")
+	check(c.Shopping.ProductsPerItem >= 1 && c.Shopping.ProductsPerItem <= 10, "shopping.productsPerItem (SHOPPING_PRODUCTS_PER_ITEM) must be between 1 and 10")
+	check(c.FaceCheck.MinFacePercent >= 0 && c.FaceCheck.MinFacePercent <= 100, "faceCheck.minFacePercent (FACE_CHECK_MIN_PERCENT) must be between 0 and 100")
+	if _, err := prompt.Load(c.Prompts); err != nil {
+		check(false, "prompts: %v", err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// splitList splits a separated list, trimming whitespace and dropping empty entries.
+func splitList(s, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,295 @@
+// configversions/configversions.go
+//
+// Package configversions records every configuration applied at runtime as a
+// numbered snapshot, so a bad push can be compared against earlier versions
+// and rolled back. Only the settings that can change without a restart are
+// captured: limits, Gemini models, CORS, maintenance, the log level, prompt
+// templates and the preset style suggestions pushed by admins.
+package configversions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/config"
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+// namespace is the store namespace holding versions by number.
+const namespace = "config-versions"
+
+// maxVersions is how many versions are kept; older ones are pruned.
+const maxVersions = 100
+
+// Sources of a version.
+const (
+	SourceStartup  = "startup"
+	SourceReload   = "reload"
+	SourcePreset   = "preset"
+	SourceRollback = "rollback"
+)
+
+var ErrNotFound = errors.New("config version not found")
+
+// CORS is the CORS policy of a snapshot.
+type CORS struct {
+	AllowedOrigins   []string `json:"allowedOrigins"`
+	AllowedMethods   []string `json:"allowedMethods"`
+	AllowedHeaders   []string `json:"allowedHeaders"`
+	ExposedHeaders   []string `json:"exposedHeaders"`
+	AllowCredentials bool     `json:"allowCredentials"`
+	MaxAgeSeconds    int      `json:"maxAgeSeconds"`
+}
+
+// Maintenance is the maintenance mode of a snapshot.
+type Maintenance struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds"`
+}
+
+// Settings is the runtime configuration captured by a version.
+type Settings struct {
+	FreeDailyLimit int64       `json:"freeDailyLimit"`
+	ImageModel     string      `json:"imageModel"`
+	TextModel      string      `json:"textModel"`
+	EmbeddingModel string      `json:"embeddingModel"`
+	CORS           CORS        `json:"cors"`
+	Maintenance    Maintenance `json:"maintenance"`
+	LogLevel       string      `json:"logLevel"`
+	// Prompts are the prompt templates that replace built-in ones, by name.
+	Prompts map[string]string `json:"prompts"`
+	// Presets maps "eventType|venue|theme" to the style suggestions an admin
+	// pushed for it.
+	Presets map[string][]string `json:"presets"`
+}
+
+// FromConfig captures the runtime settings of cfg. Presets are left empty.
+func FromConfig(cfg config.Config) Settings {
+	settings := Settings{
+		FreeDailyLimit: cfg.Usage.FreeDailyLimit,
+		ImageModel:     cfg.Gemini.ImageModel,
+		TextModel:      cfg.Gemini.TextModel,
+		EmbeddingModel: cfg.Gemini.EmbeddingModel,
+		CORS: CORS{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAgeSeconds:    int(cfg.CORS.MaxAge.Seconds()),
+		},
+		Maintenance: Maintenance{
+			Enabled:           cfg.Maintenance.Enabled,
+			Message:           cfg.Maintenance.Message,
+			RetryAfterSeconds: int(cfg.Maintenance.RetryAfter.Seconds()),
+		},
+		LogLevel: cfg.Log.Level,
+		Prompts:  maps.Clone(cfg.Prompts),
+		Presets:  map[string][]string{},
+	}
+	if settings.Prompts == nil {
+		settings.Prompts = map[string]string{}
+	}
+	return settings
+}
+
+// ApplyTo overwrites the runtime settings of cfg with s.
+func (s Settings) ApplyTo(cfg *config.Config) {
+	cfg.Usage.FreeDailyLimit = s.FreeDailyLimit
+	cfg.Gemini.ImageModel = s.ImageModel
+	cfg.Gemini.TextModel = s.TextModel
+	cfg.Gemini.EmbeddingModel = s.EmbeddingModel
+	cfg.CORS = config.CORSConfig{
+		AllowedOrigins:   s.CORS.AllowedOrigins,
+		AllowedMethods:   s.CORS.AllowedMethods,
+		AllowedHeaders:   s.CORS.AllowedHeaders,
+		ExposedHeaders:   s.CORS.ExposedHeaders,
+		AllowCredentials: s.CORS.AllowCredentials,
+		MaxAge:           time.Duration(s.CORS.MaxAgeSeconds) * time.Second,
+	}
+	cfg.Maintenance = config.MaintenanceConfig{
+		Enabled:    s.Maintenance.Enabled,
+		Message:    s.Maintenance.Message,
+		RetryAfter: time.Duration(s.Maintenance.RetryAfterSeconds) * time.Second,
+	}
+	cfg.Log.Level = s.LogLevel
+	cfg.Prompts = maps.Clone(s.Prompts)
+}
+
+// Version is one applied configuration.
+type Version struct {
+	Number    int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Source is what applied it: startup, reload, preset or rollback.
+	Source string `json:"source"`
+	// RolledBackFrom is the version a rollback restored.
+	RolledBackFrom int      `json:"rolledBackFrom,omitempty"`
+	Settings       Settings `json:"settings"`
+}
+
+// Change is one setting that differs between two versions. Path is the
+// setting's JSON path, e.g. "cors.allowedOrigins" or "presets.wedding|goa|boho".
+type Change struct {
+	Path string `json:"path"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
+
+// Diff lists the settings that differ from a to b, sorted by path. Lists are
+// compared as a whole.
+func Diff(a, b Settings) []Change {
+	from, to := flatten(a), flatten(b)
+	var changes []Change
+	for path, v := range from {
+		if w, ok := to[path]; !ok || !reflect.DeepEqual(v, w) {
+			changes = append(changes, Change{Path: path, From: v, To: to[path]})
+		}
+	}
+	for path, w := range to {
+		if _, ok := from[path]; !ok {
+			changes = append(changes, Change{Path: path, To: w})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// flatten maps the JSON paths of s to their values.
+func flatten(s Settings) map[string]any {
+	data, _ := json.Marshal(s)
+	var tree map[string]any
+	json.Unmarshal(data, &tree)
+	out := make(map[string]any)
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			out[prefix] = v
+			return
+		}
+		for k, child := range obj {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			walk(k, child)
+		}
+	}
+	walk("", tree)
+	return out
+}
+
+// History stores versions.
+type History struct {
+	store store.Store
+	// mu serializes numbering new versions.
+	mu     sync.Mutex
+	latest int
+}
+
+// NewHistory creates a History backed by st.
+func NewHistory(st store.Store) *History {
+	return &History{store: st}
+}
+
+func key(n int) string {
+	// Zero-padded so keys sort by number.
+	return fmt.Sprintf("%010d", n)
+}
+
+// numbers returns the stored version numbers, oldest first.
+func (h *History) numbers(ctx context.Context) ([]int, error) {
+	keys, err := h.store.List(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list config versions: %w", err)
+	}
+	var nums []int
+	for _, k := range keys {
+		if n, err := strconv.Atoi(k); err == nil {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	return nums, nil
+}
+
+// Record stores settings as a new version, unless they are identical to the
+// latest one, which is then returned with created false.
+func (h *History) Record(ctx context.Context, source string, rolledBackFrom int, settings Settings) (v *Version, created bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	nums, err := h.numbers(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(nums) > 0 {
+		h.latest = max(h.latest, nums[len(nums)-1])
+		latest, err := h.Get(ctx, nums[len(nums)-1])
+		if err != nil {
+			return nil, false, err
+		}
+		if len(Diff(latest.Settings, settings)) == 0 && source != SourceRollback {
+			return latest, false, nil
+		}
+	}
+
+	h.latest++
+	v = &Version{
+		Number:         h.latest,
+		CreatedAt:      time.Now().UTC(),
+		Source:         source,
+		RolledBackFrom: rolledBackFrom,
+		Settings:       settings,
+	}
+	if err := store.PutJSON(ctx, h.store, namespace, key(v.Number), v); err != nil {
+		return nil, false, fmt.Errorf("failed to save config version: %w", err)
+	}
+	for len(nums) >= maxVersions {
+		if err := h.store.Delete(ctx, namespace, key(nums[0])); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, false, fmt.Errorf("failed to prune config versions: %w", err)
+		}
+		nums = nums[1:]
+	}
+	return v, true, nil
+}
+
+// Get loads a version by number.
+func (h *History) Get(ctx context.Context, n int) (*Version, error) {
+	var v Version
+	if err := store.GetJSON(ctx, h.store, namespace, key(n), &v); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// List returns all kept versions, newest first.
+func (h *History) List(ctx context.Context) ([]*Version, error) {
+	nums, err := h.numbers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Version, 0, len(nums))
+	for i := len(nums) - 1; i >= 0; i-- {
+		v, err := h.Get(ctx, nums[i])
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
@@ -0,0 +1,227 @@
+// e2ee/e2ee.go
+package e2ee
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Algorithm names the scheme: an X25519 key exchange, HKDF-SHA256 key
+// derivation and AES-256-GCM with a 12-byte nonce prepended to the ciphertext.
+const Algorithm = "X25519-HKDF-SHA256-AES256GCM"
+
+// Encryption modes.
+const (
+	ModeOff      = "off"
+	ModeOptional = "optional"
+	ModeRequired = "required"
+)
+
+// Limits on exchanges that have been started but not yet bound to a client
+// key. Each holds a private key in memory, so anonymous callers must not be
+// able to pile them up.
+const (
+	// PendingTTL is how long an exchange waits for the client's key.
+	PendingTTL = 10 * time.Minute
+	// maxPendingPerClient and maxPending cap unbound exchanges per client
+	// and in total.
+	maxPendingPerClient = 5
+	maxPending          = 10000
+	// pruneInterval is how often bound keys are swept for expiry.
+	pruneInterval = time.Minute
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown or expired encryption key")
+	ErrTooMany    = errors.New("too many pending key exchanges")
+	ErrBadKey     = errors.New("invalid client public key")
+	ErrDecrypt    = errors.New("failed to decrypt payload")
+)
+
+// Exchange is the server half of a key exchange, returned to the client.
+type Exchange struct {
+	KeyID     string
+	PublicKey []byte
+	ExpiresAt time.Time
+}
+
+type entry struct {
+	private *ecdh.PrivateKey
+	// aead is set once the client's public key is bound to the exchange.
+	aead      cipher.AEAD
+	expiresAt time.Time
+	// client started the exchange; it is counted against them until bound.
+	client string
+}
+
+// Manager holds server key pairs and the derived per-session keys. Keys live
+// only in memory, so encrypted sessions do not survive a restart.
+type Manager struct {
+	// ttl is how long a key stays usable after its last use.
+	ttl time.Duration
+
+	mu   sync.Mutex
+	keys map[string]*entry
+	// pending lists unbound exchanges in the order they were started, which
+	// is also the order they expire in, and pendingBy counts them per client.
+	pending    []string
+	pendingBy  map[string]int
+	lastPruned time.Time
+}
+
+// NewManager creates a Manager whose keys expire after ttl of inactivity.
+func NewManager(ttl time.Duration) *Manager {
+	return &Manager{ttl: ttl, keys: make(map[string]*entry), pendingBy: make(map[string]int)}
+}
+
+// NewExchange generates a server key pair for a new session started by
+// client. The exchange must be bound within PendingTTL. It returns ErrTooMany
+// if the client, or everyone together, has too many exchanges waiting.
+func (m *Manager) NewExchange(client string) (*Exchange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	if m.pendingBy[client] >= maxPendingPerClient || len(m.pending) >= maxPending {
+		return nil, ErrTooMany
+	}
+
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	e := &entry{private: private, expiresAt: time.Now().Add(min(PendingTTL, m.ttl)), client: client}
+	id := uuid.New().String()
+	m.keys[id] = e
+	m.pending = append(m.pending, id)
+	m.pendingBy[client]++
+	return &Exchange{KeyID: id, PublicKey: private.PublicKey().Bytes(), ExpiresAt: e.expiresAt}, nil
+}
+
+// prune drops expired unbound exchanges, and every minute or so expired bound
+// keys too. The caller must hold m.mu.
+func (m *Manager) prune() {
+	now := time.Now()
+	for len(m.pending) > 0 {
+		id := m.pending[0]
+		if e, ok := m.keys[id]; ok && e.aead == nil {
+			if !now.After(e.expiresAt) {
+				break
+			}
+			m.remove(id)
+		}
+		m.pending = m.pending[1:]
+	}
+	if now.Sub(m.lastPruned) < pruneInterval {
+		return
+	}
+	m.lastPruned = now
+	for id, e := range m.keys {
+		if e.aead != nil && now.After(e.expiresAt) {
+			delete(m.keys, id)
+		}
+	}
+}
+
+// remove deletes a key, releasing its client's pending slot if it was never
+// bound. The caller must hold m.mu.
+func (m *Manager) remove(id string) {
+	e, ok := m.keys[id]
+	if !ok {
+		return
+	}
+	delete(m.keys, id)
+	if e.aead == nil {
+		m.release(e.client)
+	}
+}
+
+// release frees one of client's pending slots. The caller must hold m.mu.
+func (m *Manager) release(client string) {
+	if m.pendingBy[client] <= 1 {
+		delete(m.pendingBy, client)
+		return
+	}
+	m.pendingBy[client]--
+}
+
+// Bind completes the exchange with the client's X25519 public key and derives
+// the session key. A key can only be bound once.
+func (m *Manager) Bind(keyID string, clientPublic []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.keys[keyID]
+	if !ok || time.Now().After(e.expiresAt) {
+		return ErrUnknownKey
+	}
+	if e.aead != nil {
+		return fmt.Errorf("%w: key %s is already bound", ErrBadKey, keyID)
+	}
+	peer, err := ecdh.X25519().NewPublicKey(clientPublic)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadKey, err)
+	}
+	shared, err := e.private.ECDH(peer)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadKey, err)
+	}
+	key, err := hkdf.Key(sha256.New, shared, nil, Algorithm+":"+keyID, 32)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	e.aead = aead
+	e.expiresAt = time.Now().Add(m.ttl)
+	m.release(e.client)
+	return nil
+}
+
+// Decrypt opens nonce||ciphertext with the session key and extends the key's lifetime.
+func (m *Manager) Decrypt(keyID string, payload []byte) ([]byte, error) {
+	m.mu.Lock()
+	e, ok := m.keys[keyID]
+	if ok && time.Now().After(e.expiresAt) {
+		m.remove(keyID)
+		ok = false
+	}
+	if !ok || e.aead == nil {
+		m.mu.Unlock()
+		return nil, ErrUnknownKey
+	}
+	e.expiresAt = time.Now().Add(m.ttl)
+	aead := e.aead
+	m.mu.Unlock()
+
+	if len(payload) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, payload[:aead.NonceSize()], payload[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// Forget discards a key, e.g. when its session is evicted.
+func (m *Manager) Forget(keyID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(keyID)
+}
@@ -0,0 +1,62 @@
+// gemini/errors.go
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/genai"
+)
+
+// Errors returned by the client, wrapped with details. Match them with
+// errors.Is; API failures also still match *genai.APIError with errors.As.
+var (
+	// ErrQuota means Gemini is rate limiting us or the project's quota is
+	// exhausted. It clears by itself, so the request can be retried later.
+	ErrQuota = errors.New("gemini quota exceeded")
+	// ErrAuth means Gemini rejected the API key. Retrying does not help.
+	ErrAuth = errors.New("gemini rejected the API key")
+	// ErrSafetyBlocked means the prompt, photo or result was blocked by the
+	// model's safety filters. Retrying the same input does not help.
+	ErrSafetyBlocked = errors.New("blocked by gemini safety filters")
+	// ErrNoImage means an image call answered without an image.
+	ErrNoImage = errors.New("model returned no image")
+	// ErrBadResponse means a text call answered with something that could not
+	// be parsed.
+	ErrBadResponse = errors.New("model returned an unusable response")
+)
+
+// classify wraps an API error with the matching sentinel.
+func classify(err error) error {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.Code {
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %w", ErrQuota, err)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %w", ErrAuth, err)
+	}
+	return err
+}
+
+// blocked returns ErrSafetyBlocked, with the reason, if the response was
+// withheld by the safety filters, and nil otherwise.
+func blocked(res *genai.GenerateContentResponse) error {
+	if res == nil {
+		return nil
+	}
+	if res.PromptFeedback != nil && res.PromptFeedback.BlockReason != "" {
+		return fmt.Errorf("%w: prompt blocked (%s)", ErrSafetyBlocked, res.PromptFeedback.BlockReason)
+	}
+	if len(res.Candidates) > 0 {
+		switch reason := res.Candidates[0].FinishReason; reason {
+		case genai.FinishReasonSafety, genai.FinishReasonImageSafety, genai.FinishReasonProhibitedContent,
+			genai.FinishReasonBlocklist, genai.FinishReasonSPII:
+			return fmt.Errorf("%w: response blocked (%s)", ErrSafetyBlocked, reason)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// gemini/faces.go
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+	"google.golang.org/genai"
+)
+
+// Face is a detected face. Its edges are fractions (0-1) of the image's
+// height and width.
+type Face struct {
+	Top, Left, Bottom, Right float64
+}
+
+// Height is the face's height as a fraction of the image height.
+func (f Face) Height() float64 {
+	return f.Bottom - f.Top
+}
+
+// DetectFaces locates the faces in a photo with the text model, which is far
+// cheaper than an image generation wasted on a photo without anyone in it.
+func (c *Client) DetectFaces(ctx context.Context, img []byte, mimeType string) ([]Face, error) {
+	promptText, err := prompt.Faces()
+	if err != nil {
+		return nil, err
+	}
+	parts := []*genai.Part{
+		{Text: promptText},
+		{InlineData: &genai.Blob{Data: img, MIMEType: mimeType}},
+	}
+	contentConfig := &genai.GenerateContentConfig{ResponseMIMEType: "application/json"}
+
+	res, err := c.generateContent(ctx, "DetectFaces", c.config().TextModel, []*genai.Content{{Parts: parts}}, contentConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect faces: %w", err)
+	}
+	text := res.Text()
+	if text == "" {
+		if err := blocked(res); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: no text content found", ErrBadResponse)
+	}
+	var boxes []struct {
+		Box []float64 `json:"box_2d"`
+	}
+	if err := json.Unmarshal([]byte(text), &boxes); err != nil {
+		return nil, fmt.Errorf("%w: face boxes JSON: %w; raw response: %s", ErrBadResponse, err, text)
+	}
+
+	faces := make([]Face, 0, len(boxes))
+	for _, b := range boxes {
+		if len(b.Box) != 4 {
+			continue
+		}
+		edge := func(v float64) float64 { return min(max(v/1000, 0), 1) }
+		f := Face{Top: edge(b.Box[0]), Left: edge(b.Box[1]), Bottom: edge(b.Box[2]), Right: edge(b.Box[3])}
+		if f.Bottom > f.Top && f.Right > f.Left {
+			faces = append(faces, f)
+		}
+	}
+	c.logger.Debug("Detected faces", "count", len(faces))
+	return faces, nil
+}
+
+// Reference is an extra photo of the person, e.g. from another angle.
+type Reference struct {
+	Data     []byte
+	MimeType string
+}
+
+// SamePerson asks the text model whether the photos all show the same
+// person, returning its one-sentence reason.
+func (c *Client) SamePerson(ctx context.Context, photos []Reference) (bool, string, error) {
+	promptText, err := prompt.SamePerson()
+	if err != nil {
+		return false, "", err
+	}
+	parts := []*genai.Part{{Text: promptText}}
+	for _, p := range photos {
+		parts = append(parts, &genai.Part{InlineData: &genai.Blob{Data: p.Data, MIMEType: p.MimeType}})
+	}
+	contentConfig := &genai.GenerateContentConfig{ResponseMIMEType: "application/json"}
+
+	res, err := c.generateContent(ctx, "SamePerson", c.config().TextModel, []*genai.Content{{Parts: parts}}, contentConfig)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to compare photos: %w", err)
+	}
+	text := res.Text()
+	if text == "" {
+		if err := blocked(res); err != nil {
+			return false, "", err
+		}
+		return false, "", fmt.Errorf("%w: no text content found", ErrBadResponse)
+	}
+	var verdict struct {
+		SamePerson *bool  `json:"samePerson"`
+		Reason     string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(text), &verdict); err != nil || verdict.SamePerson == nil {
+		return false, "", fmt.Errorf("%w: same person JSON: %v; raw response: %s", ErrBadResponse, err, text)
+	}
+	c.logger.Debug("Compared photos", "photos", len(photos), "samePerson", *verdict.SamePerson, "reason", verdict.Reason)
+	return *verdict.SamePerson, verdict.Reason, nil
+}
@@ -2,55 +2,190 @@
 package gemini
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/sanjayshr/event-outfitter-backend/config"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+	"github.com/sanjayshr/event-outfitter-backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
 	"google.golang.org/genai"
 )
 
-// systemPromptTemplate is a detailed, professional prompt based on the prompt guide.
-// It instructs the model to perform an image-to-image task, preserving the subject
-// while transforming the context (outfit and background).
-const systemPromptTemplate = `
-A photorealistic close-up portrait of the people from the provided image.
-Place them in a new context for a '{eventType}' at '{venue}' with the theme '{theme}'.
+// Client wraps a genai client with the configured model names. A single Client
+// is shared by all requests.
+type Client struct {
+	logger *slog.Logger
+	genai  *genai.Client
 
-**CRITICAL INSTRUCTION:** Dress the people in a very specific, stylish, high-fashion outfit that perfectly matches this detailed description: %s.
+	// observe, if set, is told about every model call.
+	observe func(model string, elapsed time.Duration, err error)
 
-Ensure the background, lighting, and mood are photorealistic and match the event.
-Preserve the people's faces and features from the original photo. Style and pose can be changed to fit the outfit.
-The final image should be captured with an 85mm portrait lens with a soft, blurred background.
-`
+	// mu guards cfg, whose model names can change on config reload.
+	mu  sync.RWMutex
+	cfg config.GeminiConfig
+}
 
-// GenerateImage uses the Gemini API to generate a new image based on a user's photo and text inputs.
-func GenerateImage(ctx context.Context, logger *slog.Logger, imgData []byte, mimeType string, eventType, venue, theme, styleDescription string) ([]byte, string, error) {
-	logger.Info("Starting generare image")
-	apiKey := os.Getenv("GOOGLE_API_KEY")
-	if apiKey == "" {
-		apiKey = os.Getenv("GEMINI_API_KEY")
+// NewClient creates a Client.
+func NewClient(ctx context.Context, logger *slog.Logger, cfg config.GeminiConfig) (*Client, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY or GOOGLE_API_KEY environment variable not set")
 	}
-	if apiKey == "" {
-		return nil, "", fmt.Errorf("GEMINI_API_KEY or GOOGLE_API_KEY environment variable not set")
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: cfg.APIKey, Backend: genai.BackendGeminiAPI})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
 	}
+	return &Client{logger: logger, cfg: cfg, genai: client}, nil
+}
 
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey})
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create genai client: %w", err)
+// OnCall registers fn to be told the model, duration and outcome of every
+// model call, e.g. for the live activity view. It must be called before the
+// client is used.
+func (c *Client) OnCall(fn func(model string, elapsed time.Duration, err error)) {
+	c.observe = fn
+}
+
+// observed reports a finished model call to the OnCall function.
+func (c *Client) observed(model string, start time.Time, err error) {
+	if c.observe != nil {
+		c.observe(model, time.Since(start), err)
 	}
+}
+
+// config returns the current configuration.
+func (c *Client) config() config.GeminiConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cfg
+}
+
+// ImageModel is the name of the current image model.
+func (c *Client) ImageModel() string {
+	return c.config().ImageModel
+}
+
+// SetModels switches to the model names in cfg. The API key is fixed for the
+// lifetime of the client.
+func (c *Client) SetModels(cfg config.GeminiConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg.ImageModel = cfg.ImageModel
+	c.cfg.TextModel = cfg.TextModel
+	c.cfg.EmbeddingModel = cfg.EmbeddingModel
+}
 
+// Ping verifies the API key and model names by fetching the metadata of every
+// configured model. It costs no tokens, so it is safe to run at startup.
+func (c *Client) Ping(ctx context.Context) error {
+	cfg := c.config()
+	for _, model := range []string{cfg.TextModel, cfg.ImageModel, cfg.EmbeddingModel} {
+		if _, err := c.genai.Models.Get(ctx, model, nil); err != nil {
+			return fmt.Errorf("failed to look up model %s: %w", model, classify(err))
+		}
+	}
+	return nil
+}
+
+// generateContent calls a model inside a span named after the call, so traces
+// show how much of a request each Gemini call took.
+func (c *Client) generateContent(ctx context.Context, call, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	ctx, span := tracing.Start(ctx, "gemini."+call, attribute.String("gemini.model", model))
+	start := time.Now()
+	res, err := c.genai.Models.GenerateContent(ctx, model, contents, cfg)
+	c.observed(model, start, err)
+	tracing.End(span, err)
+	return res, classify(err)
+}
+
+// ImageOptions tune the image prompt beyond the event and style.
+type ImageOptions struct {
+	// AspectRatio, as width:height, asks the model to compose for that frame;
+	// empty leaves it free.
+	AspectRatio string
+	// PromptSuffix, if set, is appended to the prompt as extra instructions.
+	PromptSuffix string
+	// KeepBackground only changes the clothing, keeping the photo's
+	// background, lighting and framing.
+	KeepBackground bool
+	// Wearer tailors the outfit to what the user said about the person.
+	Wearer prompt.Wearer
+}
+
+// GenerateImage uses the Gemini API to generate a new image based on a user's photo and text inputs.
+// References are more photos of the same person from other angles, sent
+// after the photo to help the model keep the face faithful.
+func (c *Client) GenerateImage(ctx context.Context, imgData []byte, mimeType string, eventType, venue, theme, styleDescription string, opts ImageOptions, references ...Reference) ([]byte, string, error) {
+	c.logger.Info("Starting generare image")
+	return c.generateImage(ctx, "GenerateImage", imgData, mimeType, eventType, venue, theme, styleDescription, opts, references, "", imageAttempts)
+}
+
+// GeneratePreview is a cheaper GenerateImage for quick previews of a style:
+// the photo is read at low media resolution and a damaged image is not
+// retried. Callers should pass a downscaled photo and shrink the result;
+// opts.AspectRatio is ignored.
+func (c *Client) GeneratePreview(ctx context.Context, imgData []byte, mimeType string, eventType, venue, theme, styleDescription string, opts ImageOptions) ([]byte, string, error) {
+	opts.AspectRatio = ""
+	return c.generateImage(ctx, "GeneratePreview", imgData, mimeType, eventType, venue, theme, styleDescription, opts, nil, genai.MediaResolutionLow, 1)
+}
+
+// generateImage makes the image call that restyles the photo.
+func (c *Client) generateImage(ctx context.Context, call string, imgData []byte, mimeType string, eventType, venue, theme, styleDescription string, opts ImageOptions, references []Reference, resolution genai.MediaResolution, attempts int) ([]byte, string, error) {
 	// Construct the detailed prompt using our template
-	prompt := fmt.Sprintf(systemPromptTemplate, eventType, venue, theme, styleDescription)
-	logger.Info("Generated Gemini Prompt", "prompt", prompt)
+	promptText, err := prompt.Image(prompt.ImageInput{
+		Event:          prompt.Event{EventType: eventType, Venue: venue, Theme: theme},
+		Wearer:         opts.Wearer,
+		Style:          styleDescription,
+		References:     len(references),
+		AspectRatio:    opts.AspectRatio,
+		Suffix:         opts.PromptSuffix,
+		KeepBackground: opts.KeepBackground,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	c.logger.Debug("Generated Gemini Prompt", "prompt", promptText)
+	return c.renderImage(ctx, call, promptText, imgData, mimeType, references, resolution, attempts)
+}
+
+// RefineImage edits a previously generated look as the instruction says,
+// starting from the look rather than the original photo. References, if
+// any, keep the face faithful.
+func (c *Client) RefineImage(ctx context.Context, lookData []byte, mimeType string, eventType, venue, theme, styleDescription, instruction, promptSuffix string, references ...Reference) ([]byte, string, error) {
+	promptText, err := prompt.Refine(prompt.RefineInput{
+		Event:       prompt.Event{EventType: eventType, Venue: venue, Theme: theme},
+		Style:       styleDescription,
+		Instruction: instruction,
+		References:  len(references),
+		Suffix:      promptSuffix,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	c.logger.Debug("Generated Gemini Prompt", "prompt", promptText)
+	return c.renderImage(ctx, "RefineImage", promptText, lookData, mimeType, references, "", imageAttempts)
+}
 
+// renderImage sends promptText with the image and references to the image
+// model, retrying up to attempts times when the model returns image data
+// that does not decode.
+func (c *Client) renderImage(ctx context.Context, call, promptText string, imgData []byte, mimeType string, references []Reference, resolution genai.MediaResolution, attempts int) ([]byte, string, error) {
 	// Prepare the multi-modal content (image + text)
 	parts := []*genai.Part{
-		{Text: prompt},
+		{Text: promptText},
 		{InlineData: &genai.Blob{Data: imgData, MIMEType: mimeType}},
 	}
+	for _, ref := range references {
+		parts = append(parts, &genai.Part{InlineData: &genai.Blob{Data: ref.Data, MIMEType: ref.MimeType}})
+	}
 
 	// Define safety settings to block only high-probability harmful content.
 	safetySettings := []*genai.SafetySetting{
@@ -73,56 +208,109 @@ func GenerateImage(ctx context.Context, logger *slog.Logger, imgData []byte, mim
 	}
 
 	// Use the correct GenerateContentConfig struct to pass the settings.
-	config := &genai.GenerateContentConfig{
-		SafetySettings: safetySettings,
+	contentConfig := &genai.GenerateContentConfig{
+		SafetySettings:  safetySettings,
+		MediaResolution: resolution,
 	}
 
-	res, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash-image-preview", []*genai.Content{{Parts: parts}}, config)
-	if err != nil {
-		logger.Error("Gemini text content generation failed", "error", err, "response", res)
-		return nil, "", fmt.Errorf("failed to generate prmots(text): %w", err)
-	}
-	logger.Info("Gemini content generation successful")
+	// The model occasionally returns corrupt or zero-byte image data, so
+	// validate it and try again rather than passing it on to the client.
+	var invalid error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		res, err := c.generateContent(ctx, call, c.config().ImageModel, []*genai.Content{{Parts: parts}}, contentConfig)
+		if err != nil {
+			c.logger.Error("Gemini text content generation failed", "error", err, "response", res)
+			return nil, "", fmt.Errorf("failed to generate image: %w", err)
+		}
+		c.logger.Info("Gemini content generation successful")
 
-	// Extract the generated image data from the response
-	if len(res.Candidates) > 0 && res.Candidates[0].Content != nil {
-		for _, part := range res.Candidates[0].Content.Parts {
-			if part.InlineData != nil {
-				logger.Info("Successfully generated image", "mimeType", part.InlineData.MIMEType, "size_bytes", len(part.InlineData.Data))
-				return part.InlineData.Data, part.InlineData.MIMEType, nil
+		// Extract the generated image data from the response
+		var blob *genai.Blob
+		if len(res.Candidates) > 0 && res.Candidates[0].Content != nil {
+			for _, part := range res.Candidates[0].Content.Parts {
+				if part.InlineData != nil {
+					blob = part.InlineData
+					break
+				}
 			}
 		}
+		if blob == nil {
+			// No image data at all. Log the full response for debugging.
+			c.logger.Error("No image data found in Gemini response", "full_response", res)
+			if err := blocked(res); err != nil {
+				return nil, "", err
+			}
+			return nil, "", ErrNoImage
+		}
+
+		decodedMimeType, err := validateImage(blob.Data)
+		if err != nil {
+			invalid = err
+			c.logger.Warn("Gemini returned an invalid image", "attempt", attempt, "mimeType", blob.MIMEType, "size_bytes", len(blob.Data), "error", err)
+			continue
+		}
+		c.logger.Info("Successfully generated image", "mimeType", decodedMimeType, "size_bytes", len(blob.Data))
+		return blob.Data, decodedMimeType, nil
 	}
+	return nil, "", fmt.Errorf("%w after %d attempts: %v", ErrInvalidImage, attempts, invalid)
+}
 
-	// If we reach here, no image data was found. Log the full response for debugging.
-	logger.Error("No image data found in Gemini response", "full_response", res)
-	return nil, "", fmt.Errorf("no image data found in Gemini response")
+// SuggestionOptions narrow the style suggestions of GetStyleSuggestions.
+type SuggestionOptions struct {
+	// Count is how many suggestions to ask for; zero asks for
+	// prompt.DefaultStyleCount.
+	Count int
+	// Tags are style tags every suggestion must fit, e.g. "formal".
+	Tags []string
+	// Exclude are suggestions the user already has, which the new ones must
+	// differ from.
+	Exclude []string
+	// Avoid are the user's reasons for disliking earlier suggestions, e.g.
+	// "no floral prints".
+	Avoid []string
+	// Language is the BCP 47 tag of a language to translate the suggestions
+	// into, or empty for English only.
+	Language string
+	// Wearer tailors the suggestions to what the user said about the person.
+	Wearer prompt.Wearer
+	// Weather is the expected weather at the venue on the day, e.g.
+	// "24-32°C, humid", or empty if unknown.
+	Weather string
+	// Coloring is what the user's photo shows of their coloring.
+	Coloring prompt.Coloring
 }
 
+// maxStyleItems caps the product search terms kept per suggested style.
+const maxStyleItems = 4
+
 // GetStyleSuggestions uses the Gemini API to generate a list of style suggestions based on event details.
-func GetStyleSuggestions(ctx context.Context, logger *slog.Logger, eventType, venue, theme string) ([]string, error) {
-	apiKey := os.Getenv("GOOGLE_API_KEY")
-	if apiKey == "" {
-		apiKey = os.Getenv("GEMINI_API_KEY")
+func (c *Client) GetStyleSuggestions(ctx context.Context, eventType, venue, theme string, opts SuggestionOptions) ([]models.Style, error) {
+	// Construct the prompt for style suggestions
+	in := prompt.SuggestionsInput{
+		Event:    prompt.Event{EventType: eventType, Venue: venue, Theme: theme},
+		Count:    cmp.Or(opts.Count, prompt.DefaultStyleCount),
+		Wearer:   opts.Wearer,
+		Coloring: opts.Coloring,
+		Tags:     opts.Tags,
+		Weather:  opts.Weather,
+		Avoid:    opts.Avoid,
+		Exclude:  opts.Exclude,
 	}
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY or GOOGLE_API_KEY environment variable not set")
+	if opts.Language != "" {
+		in.Language = display.English.Tags().Name(language.Make(opts.Language))
 	}
-
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey})
+	promptText, err := prompt.StyleSuggestions(in)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create genai client: %w", err)
+		return nil, err
 	}
-	prompt := fmt.Sprintf(`Based on the person in the user's photo, identify their likely gender. Then, for an event '%s' at location '%s' with the theme '%s', generate a JSON array of 5 distinct and creative fashion apparel descriptions for them.Be specific and evocative.Example for a man: ["a crisp white linen shirt with tailored khaki shorts and leather sandals", "a lightweight navy blazer over a crew-neck t-shirt and chinos"].Example for a woman: ["a vibrant tropical print maxi dress with woven sandals", "bohemian chic with a crochet top and a flowy tiered skirt"].`, eventType, venue, theme)
-	// Construct the prompt for style suggestions
-	logger.Info("Generated Style Suggestion Prompt", "prompt", prompt)
+	c.logger.Debug("Generated Style Suggestion Prompt", "prompt", promptText)
 
-	res, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash", genai.Text(prompt), nil)
+	res, err := c.generateContent(ctx, "GetStyleSuggestions", c.config().TextModel, genai.Text(promptText), nil)
 	if err != nil {
-		logger.Error("Gemini style suggestion generation failed", "error", err, "response", res)
+		c.logger.Error("Gemini style suggestion generation failed", "error", err, "response", res)
 		return nil, fmt.Errorf("failed to generate style suggestions: %w", err)
 	}
-	logger.Info("Gemini style suggestion generation successful", "response", res)
+	c.logger.Debug("Gemini style suggestion generation successful", "response", res)
 
 	if len(res.Candidates) > 0 && res.Candidates[0].Content != nil {
 		var fullResponseText string
@@ -133,28 +321,85 @@ func GetStyleSuggestions(ctx context.Context, logger *slog.Logger, eventType, ve
 		}
 
 		if fullResponseText == "" {
-			return nil, fmt.Errorf("no text content found in Gemini response")
+			if err := blocked(res); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: no text content found", ErrBadResponse)
 		}
 
 		// Now, proceed with your existing JSON parsing logic on the fullResponseText
-		logger.Info("Received text response for style suggestions", "text", fullResponseText)
+		c.logger.Debug("Received text response for style suggestions", "text", fullResponseText)
 
 		startIndex := strings.Index(fullResponseText, "[")
 		endIndex := strings.LastIndex(fullResponseText, "]")
 
 		if startIndex == -1 || endIndex == -1 || endIndex < startIndex {
-			return nil, fmt.Errorf("could not find a valid JSON array in the AI response: %s", fullResponseText)
+			return nil, fmt.Errorf("%w: no JSON array in %q", ErrBadResponse, fullResponseText)
 		}
 
 		jsonString := fullResponseText[startIndex : endIndex+1]
 
-		var styles []string
-		if err := json.Unmarshal([]byte(jsonString), &styles); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal style suggestions JSON: %w; raw response: %s", err, jsonString)
+		var suggested []models.Style
+		if err := json.Unmarshal([]byte(jsonString), &suggested); err != nil {
+			return nil, fmt.Errorf("%w: style suggestions JSON: %w; raw response: %s", ErrBadResponse, err, jsonString)
 		}
 
+		styles := make([]models.Style, 0, len(suggested))
+		for _, style := range suggested {
+			style.Description = strings.TrimSpace(style.Description)
+			if style.Description == "" {
+				continue
+			}
+			style.ID = models.StyleID(style.Description)
+			style.Name = strings.TrimSpace(style.Name)
+			for i, tag := range style.Tags {
+				style.Tags[i] = strings.ToLower(strings.TrimSpace(tag))
+			}
+			items := style.Items[:0]
+			for _, item := range style.Items {
+				if item = strings.TrimSpace(item); item != "" && len(items) < maxStyleItems {
+					items = append(items, item)
+				}
+			}
+			style.Items = items
+			style.Products = nil
+			if t := style.Translation; t != nil {
+				if opts.Language == "" || strings.TrimSpace(t.Description) == "" {
+					style.Translation = nil
+				} else {
+					t.Language = opts.Language
+				}
+			}
+			styles = append(styles, style)
+			if len(styles) == in.Count {
+				// The model sometimes suggests more than it was asked for
+				break
+			}
+		}
 		return styles, nil
 	}
 
-	return nil, fmt.Errorf("no style suggestions found in Gemini response")
+	if err := blocked(res); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: no style suggestions found", ErrBadResponse)
+}
+
+// EmbedText uses the Gemini embedding model to compute a vector for a piece of text,
+// such as a style description, for similarity search.
+func (c *Client) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	model := c.config().EmbeddingModel
+	ctx, span := tracing.Start(ctx, "gemini.EmbedText", attribute.String("gemini.model", model))
+	start := time.Now()
+	res, err := c.genai.Models.EmbedContent(ctx, model, genai.Text(text), nil)
+	c.observed(model, start, err)
+	tracing.End(span, err)
+	if err != nil {
+		c.logger.Error("Gemini embedding failed", "error", err)
+		return nil, fmt.Errorf("failed to embed text: %w", classify(err))
+	}
+	if len(res.Embeddings) == 0 || len(res.Embeddings[0].Values) == 0 {
+		return nil, fmt.Errorf("%w: no embedding found", ErrBadResponse)
+	}
+	return res.Embeddings[0].Values, nil
 }
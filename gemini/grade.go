@@ -0,0 +1,54 @@
+// gemini/grade.go
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+	"google.golang.org/genai"
+)
+
+// Grade is the model's comparison of a generated look with the real event photo.
+type Grade struct {
+	Score       int      `json:"score"`
+	Summary     string   `json:"summary"`
+	Matches     []string `json:"matches"`
+	Differences []string `json:"differences"`
+}
+
+// GradeRealism compares a generated look with the photo the user took at the
+// event and scores how close they got.
+func (c *Client) GradeRealism(ctx context.Context, lookImg []byte, lookMime string, eventImg []byte, eventMime string, eventType, venue, theme, style string) (*Grade, error) {
+	promptText, err := prompt.Grade(prompt.GradeInput{Event: prompt.Event{EventType: eventType, Venue: venue, Theme: theme}, Style: style})
+	if err != nil {
+		return nil, err
+	}
+	parts := []*genai.Part{
+		{Text: promptText},
+		{InlineData: &genai.Blob{Data: lookImg, MIMEType: lookMime}},
+		{InlineData: &genai.Blob{Data: eventImg, MIMEType: eventMime}},
+	}
+	contentConfig := &genai.GenerateContentConfig{ResponseMIMEType: "application/json"}
+
+	res, err := c.generateContent(ctx, "GradeRealism", c.config().TextModel, []*genai.Content{{Parts: parts}}, contentConfig)
+	if err != nil {
+		c.logger.Error("Gemini realism grading failed", "error", err)
+		return nil, fmt.Errorf("failed to grade event photo: %w", err)
+	}
+
+	text := res.Text()
+	if text == "" {
+		if err := blocked(res); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: no text content found", ErrBadResponse)
+	}
+	var grade Grade
+	if err := json.Unmarshal([]byte(text), &grade); err != nil {
+		return nil, fmt.Errorf("%w: grade JSON: %w; raw response: %s", ErrBadResponse, err, text)
+	}
+	grade.Score = min(max(grade.Score, 0), 100)
+	return &grade, nil
+}
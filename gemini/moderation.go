@@ -0,0 +1,72 @@
+// gemini/moderation.go
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+	"google.golang.org/genai"
+)
+
+// Moderation categories reported by ModerateImage.
+const (
+	ModerationSexual          = "sexual"
+	ModerationMinorSexualized = "minor_sexualized"
+	ModerationViolence        = "violence"
+	ModerationHate            = "hate"
+	ModerationSelfHarm        = "self_harm"
+)
+
+var moderationCategories = map[string]bool{
+	ModerationSexual:          true,
+	ModerationMinorSexualized: true,
+	ModerationViolence:        true,
+	ModerationHate:            true,
+	ModerationSelfHarm:        true,
+}
+
+// ModerateImage classifies an upload with the text model and returns the
+// disallowed categories it falls into; none means the photo is acceptable.
+// If Gemini's own safety filters refuse to look at the photo, the error is
+// ErrSafetyBlocked, which callers should treat as disallowed too.
+func (c *Client) ModerateImage(ctx context.Context, img []byte, mimeType string) ([]string, error) {
+	promptText, err := prompt.Moderation()
+	if err != nil {
+		return nil, err
+	}
+	parts := []*genai.Part{
+		{Text: promptText},
+		{InlineData: &genai.Blob{Data: img, MIMEType: mimeType}},
+	}
+	contentConfig := &genai.GenerateContentConfig{ResponseMIMEType: "application/json"}
+
+	res, err := c.generateContent(ctx, "ModerateImage", c.config().TextModel, []*genai.Content{{Parts: parts}}, contentConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to moderate image: %w", err)
+	}
+	text := res.Text()
+	if text == "" {
+		if err := blocked(res); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: no text content found", ErrBadResponse)
+	}
+	var verdict struct {
+		Categories []string `json:"categories"`
+	}
+	if err := json.Unmarshal([]byte(text), &verdict); err != nil {
+		return nil, fmt.Errorf("%w: moderation JSON: %w; raw response: %s", ErrBadResponse, err, text)
+	}
+
+	var flagged []string
+	for _, category := range verdict.Categories {
+		// Ignore anything the model made up beyond the categories asked for.
+		if moderationCategories[category] {
+			flagged = append(flagged, category)
+		}
+	}
+	c.logger.Debug("Moderated image", "categories", flagged)
+	return flagged, nil
+}
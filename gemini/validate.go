@@ -0,0 +1,39 @@
+// gemini/validate.go
+package gemini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// imageAttempts is how many times an image call is made when the model
+// returns image data that does not decode.
+const imageAttempts = 3
+
+// ErrInvalidImage is returned when every attempt produced empty, truncated or
+// otherwise undecodable image data.
+var ErrInvalidImage = errors.New("model returned an invalid image")
+
+// validateImage fully decodes data, so truncated output is caught as well as
+// garbage headers, and returns the MIME type of the format it decoded as,
+// which is more trustworthy than the one the model declared.
+func validateImage(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("image data is empty")
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("image data does not decode: %w", err)
+	}
+	if b := img.Bounds(); b.Empty() {
+		return "", fmt.Errorf("image has no pixels (%dx%d)", b.Dx(), b.Dy())
+	}
+	return "image/" + format, nil
+}
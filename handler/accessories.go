@@ -0,0 +1,113 @@
+// handler/accessories.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// maxAccessoryTextLength limits the free-text accessories of a request.
+const maxAccessoryTextLength = 200
+
+// accessoryType is an entry of the accessory taxonomy. Phrase is how it
+// reads in the edit instruction.
+type accessoryType struct {
+	ID, Name, Phrase string
+}
+
+// accessoryTypes is the taxonomy of accessories a client can pick from, in
+// the order they are listed.
+var accessoryTypes = []accessoryType{
+	{"jewelry", "Jewelry", "jewelry"},
+	{"watch", "Watch", "a wristwatch"},
+	{"hat", "Hat", "a hat"},
+	{"bag", "Bag", "a bag or clutch"},
+	{"sunglasses", "Sunglasses", "sunglasses"},
+	{"scarf", "Scarf", "a scarf"},
+	{"belt", "Belt", "a belt"},
+	{"tie", "Tie", "a tie or bow tie"},
+}
+
+// AccessoriesHandler handles GET /api/v1/accessories, listing the accessory
+// types POST /api/v1/accessories accepts.
+func AccessoriesHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out := make([]models.Accessory, len(accessoryTypes))
+		for i, t := range accessoryTypes {
+			out[i] = models.Accessory{ID: t.ID, Name: t.Name}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// AddAccessoriesHandler handles POST /api/v1/accessories, layering
+// accessories onto a look of the session. It is a refinement whose
+// instruction is built from the chosen types and text, so the outfit itself
+// is kept and the result is stored as a new look.
+func AddAccessoriesHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		refineLook(s, w, r, "accessories", func() (string, string, bool) {
+			var req models.AccessoriesRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.Logger.Error("Failed to decode accessories request", "error", err)
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+				return "", "", false
+			}
+			instruction, err := accessoryInstruction(req.Types, req.Text)
+			if err != nil {
+				apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error(),
+					map[string]any{"types": accessoryIDs()})
+				return "", "", false
+			}
+			return req.LookID, instruction, true
+		})
+	}
+}
+
+// accessoryInstruction builds the edit instruction that adds the given
+// accessory types and free-text accessories to a look.
+func accessoryInstruction(types []string, text string) (string, error) {
+	var items []string
+	for _, id := range types {
+		i := slices.IndexFunc(accessoryTypes, func(t accessoryType) bool { return t.ID == id })
+		if i < 0 {
+			return "", fmt.Errorf("unknown accessory type %q", id)
+		}
+		if phrase := accessoryTypes[i].Phrase; !slices.Contains(items, phrase) {
+			items = append(items, phrase)
+		}
+	}
+	if text = cleanPromptText(text); text != "" {
+		if utf8.RuneCountInString(text) > maxAccessoryTextLength {
+			return "", fmt.Errorf("text must be at most %d characters", maxAccessoryTextLength)
+		}
+		items = append(items, text)
+	}
+	if len(items) == 0 {
+		return "", errors.New("at least one accessory type or text is required")
+	}
+	list := items[len(items)-1]
+	if len(items) > 1 {
+		list = strings.Join(items[:len(items)-1], ", ") + " and " + list
+	}
+	return "add " + list + ", chosen to complement the outfit and suit the event, keeping every garment exactly as it is", nil
+}
+
+// accessoryIDs returns the IDs of the accessory taxonomy.
+func accessoryIDs() []string {
+	ids := make([]string, len(accessoryTypes))
+	for i, t := range accessoryTypes {
+		ids[i] = t.ID
+	}
+	return ids
+}
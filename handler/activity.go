@@ -0,0 +1,75 @@
+// handler/activity.go
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// defaultActivityInterval is how often the activity stream sends a snapshot.
+const defaultActivityInterval = 2 * time.Second
+
+// ActivityHandler handles GET /admin/activity, returning the current live
+// traffic snapshot: generations in flight, per-route and per-model
+// throughput over the last minute, and the latest errors.
+func ActivityHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Activity.Snapshot())
+	}
+}
+
+// ActivityStreamHandler handles GET /admin/activity/stream, sending a
+// snapshot as a server-sent "snapshot" event every ?interval= seconds
+// (default 2, 1-60) until the client disconnects.
+func ActivityStreamHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		interval := defaultActivityInterval
+		if v := r.URL.Query().Get("interval"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 60 {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "interval must be between 1 and 60 seconds.")
+				return
+			}
+			interval = time.Duration(n) * time.Second
+		}
+
+		// The stream outlives WRITE_TIMEOUT by design.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			s.Logger.Warn("Failed to clear write deadline for activity stream", "error", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			data, err := json.Marshal(s.Activity.Snapshot())
+			if err != nil {
+				s.Logger.Error("Failed to encode activity snapshot", "error", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				s.Logger.Warn("Activity stream cannot be flushed", "error", err)
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// handler/admin.go
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/alert"
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/config"
+	"github.com/sanjayshr/event-outfitter-backend/gemini"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/presets"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/sessions"
+)
+
+// presetWebhookClient delivers preset update notifications.
+var presetWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// ListSessionsHandler handles GET /admin/sessions, listing the sessions held in memory.
+func ListSessionsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.CacheMutex.Lock()
+		out := make([]models.SessionSummary, 0, len(s.SessionCache))
+		for id, sess := range s.SessionCache {
+			out = append(out, models.SessionSummary{
+				ID:         id,
+				EventType:  sess.RequestData.EventType,
+				Venue:      sess.RequestData.Venue,
+				Theme:      sess.RequestData.Theme,
+				Styles:     len(sess.Styles),
+				ImageBytes: len(sess.ImageData),
+			})
+		}
+		s.CacheMutex.Unlock()
+		sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// EvictSessionHandler handles DELETE /admin/sessions/{id}, dropping a session
+// and its uploaded image from memory.
+func EvictSessionHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		sess, ok := s.EvictSession(id)
+		if !ok {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Session not found.")
+			return
+		}
+		if sess.E2EEKeyID != "" {
+			s.E2EE.Forget(sess.E2EEKeyID)
+		}
+		s.Logger.Info("Evicted session", "sessionID", id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CacheStatsHandler handles GET /admin/cache/stats, reporting the session
+// cache counters.
+func CacheStatsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := s.CacheStats()
+		resp := models.CacheStatsResponse{
+			Entries:   stats.Entries,
+			Creations: stats.Creations,
+			Hits:      stats.Hits,
+			Misses:    stats.Misses,
+			Evictions: stats.Evictions,
+			Bytes:     stats.Bytes,
+		}
+		if lookups := stats.Hits + stats.Misses; lookups > 0 {
+			resp.HitRatio = float64(stats.Hits) / float64(lookups)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// FlushCacheHandler handles POST /admin/cache/flush, dropping cached preset
+// suggestions so they are fetched fresh from Gemini.
+func FlushCacheHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := models.FlushCacheResponse{Presets: s.Presets.Flush()}
+		s.Logger.Info("Flushed caches", "presets", resp.Presets)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// UpdatePresetHandler handles PUT /admin/presets, replacing the cached style
+// suggestions for a preset with the given ones, or with fresh ones from Gemini
+// if none are given. Sessions created for the preset within
+// PRESET_NOTIFY_WINDOW are flagged so clients can offer to regenerate, and
+// are posted to PRESET_WEBHOOK_URL for notification.
+func UpdatePresetHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.UpdatePresetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		preset := presets.Preset{EventType: req.EventType, Venue: req.Venue, Theme: req.Theme}
+		if strings.TrimSpace(preset.EventType) == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "eventType is required.")
+			return
+		}
+
+		styles := models.NewStyles(req.Styles)
+		if len(styles) == 0 {
+			var err error
+			styles, err = s.Gemini.GetStyleSuggestions(r.Context(), preset.EventType, preset.Venue, preset.Theme, gemini.SuggestionOptions{Count: int(s.Config().Suggestions.StyleCount)})
+			if err != nil || len(styles) == 0 {
+				s.Logger.Error("Failed to fetch preset suggestions", "preset", preset, "error", err)
+				apierror.Write(w, r, http.StatusBadGateway, apierror.CodeUpstreamFailed, "Failed to get style suggestions.")
+				return
+			}
+		}
+		s.PushPreset(r.Context(), preset, styles)
+
+		var flagged []*sessions.Record
+		if window := s.Config().Presets.NotifyWindow; window > 0 {
+			var err error
+			flagged, err = s.Sessions.MarkPresetUpdated(r.Context(), time.Now().Add(-window), func(rec *sessions.Record) bool {
+				return preset.Equal(presets.Preset{EventType: rec.EventType, Venue: rec.Venue, Theme: rec.Theme})
+			})
+			if err != nil {
+				s.Logger.Error("Failed to flag sessions for preset update", "preset", preset, "error", err)
+			}
+		}
+		if url := s.Config().Presets.WebhookURL; url != "" && len(flagged) > 0 {
+			go notifyPresetUpdate(s, url, preset, models.Descriptions(styles), flagged)
+		}
+		s.Logger.Info("Updated preset suggestions", "preset", preset, "styles", len(styles), "sessions", len(flagged))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.UpdatePresetResponse{Styles: styles, Sessions: len(flagged)})
+	}
+}
+
+// notifyPresetUpdate posts the sessions affected by a preset update to the
+// preset webhook, which is expected to notify their owners.
+func notifyPresetUpdate(s *server.Server, url string, preset presets.Preset, styles []string, flagged []*sessions.Record) {
+	type session struct {
+		ID    string `json:"id"`
+		Owner string `json:"owner"`
+		Name  string `json:"name,omitempty"`
+	}
+	payload := struct {
+		Event     string    `json:"event"`
+		EventType string    `json:"eventType"`
+		Venue     string    `json:"venue"`
+		Theme     string    `json:"theme"`
+		Styles    []string  `json:"styles"`
+		Sessions  []session `json:"sessions"`
+	}{Event: "preset.updated", EventType: preset.EventType, Venue: preset.Venue, Theme: preset.Theme, Styles: styles}
+	for _, rec := range flagged {
+		payload.Sessions = append(payload.Sessions, session{ID: rec.ID, Owner: rec.Owner, Name: rec.Name})
+	}
+	if err := alert.PostJSON(context.Background(), presetWebhookClient, url, payload); err != nil {
+		s.Logger.Error("Failed to deliver preset update webhook", "preset", preset, "error", err)
+	}
+}
+
+// ListJobsHandler handles GET /admin/jobs, listing every client's running and
+// recently finished bulk jobs, newest first. ?status= keeps only jobs in that
+// state.
+func ListJobsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		out := []models.AdminBulkJob{}
+		for _, job := range s.Looks.BulkJobs() {
+			if status == "" || job.Status == status {
+				out = append(out, models.AdminBulkJob{BulkJobResponse: bulkJobResponse(job), Owner: job.Owner})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// GetJobHandler handles GET /admin/jobs/{id}, reporting any client's bulk job.
+func GetJobHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, job := range s.Looks.BulkJobs() {
+			if job.ID == r.PathValue("id") {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(models.AdminBulkJob{BulkJobResponse: bulkJobResponse(job), Owner: job.Owner})
+				return
+			}
+		}
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Job not found.")
+	}
+}
+
+// RunRetentionHandler handles POST /admin/retention/run, deleting expired
+// short links and old incidents now instead of at the next hourly run.
+func RunRetentionHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resp models.RetentionRunResponse
+		var err error
+		if resp.ShortLinks, err = s.Links.Cleanup(r.Context()); err == nil {
+			resp.Incidents, err = s.Status.Prune(r.Context())
+		}
+		if err != nil {
+			s.Logger.Error("Retention run failed", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Retention run failed.")
+			return
+		}
+		s.Logger.Info("Ran retention", "shortLinks", resp.ShortLinks, "incidents", resp.Incidents)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// ReloadConfigHandler handles POST /admin/config/reload, applying runtime
+// settings from the config file and environment without a restart.
+func ReloadConfigHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		applied, restartRequired, err := s.ReloadConfig(r.Context())
+		if err != nil {
+			s.Logger.Error("Config reload failed", "error", err)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		s.Logger.Info("Reloaded config", "applied", applied, "restartRequired", restartRequired)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.ReloadConfigResponse{
+			Applied:         append([]string{}, applied...),
+			RestartRequired: append([]string{}, restartRequired...),
+		})
+	}
+}
+
+func maintenanceResponse(m server.Maintenance) models.MaintenanceResponse {
+	return models.MaintenanceResponse{
+		Enabled:           m.Enabled,
+		Message:           m.Message,
+		RetryAfterSeconds: int(m.RetryAfter.Seconds()),
+		Since:             m.Since,
+	}
+}
+
+// GetMaintenanceHandler handles GET /admin/maintenance.
+func GetMaintenanceHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(maintenanceResponse(s.Maintenance()))
+	}
+}
+
+// SetMaintenanceHandler handles PUT /admin/maintenance, switching maintenance
+// mode on or off for the generation endpoints.
+func SetMaintenanceHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.MaintenanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RetryAfterSeconds < 0 {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		m := s.SetMaintenance(req.Enabled, req.Message, time.Duration(req.RetryAfterSeconds)*time.Second)
+		s.Logger.Warn("Maintenance mode changed", "enabled", m.Enabled, "message", m.Message)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(maintenanceResponse(m))
+	}
+}
+
+func logLevelResponse(level slog.Level) models.LogLevelResponse {
+	return models.LogLevelResponse{Level: strings.ToLower(level.String())}
+}
+
+// GetLogLevelHandler handles GET /admin/log-level.
+func GetLogLevelHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(logLevelResponse(s.LogLevel()))
+	}
+}
+
+// SetLogLevelHandler handles PUT /admin/log-level, changing the minimum log
+// level until the next restart or config reload that changes LOG_LEVEL.
+func SetLogLevelHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.LogLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		level, ok := config.ParseLogLevel(req.Level)
+		if !ok {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "level must be debug, info, warn or error.")
+			return
+		}
+		prev := s.LogLevel()
+		s.SetLogLevel(level)
+		// Logged at warn so the change is recorded whatever the new level.
+		s.Logger.Warn("Log level changed", "from", strings.ToLower(prev.String()), "to", strings.ToLower(level.String()))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(logLevelResponse(level))
+	}
+}
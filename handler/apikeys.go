@@ -0,0 +1,230 @@
+// handler/apikeys.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/apikeys"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// apiKeyResponse converts a key to its admin API representation. secret is only
+// set when a key is created or rotated.
+func apiKeyResponse(k *apikeys.Key, secret string) models.APIKeyResponse {
+	resp := models.APIKeyResponse{
+		ID:               k.ID,
+		Name:             k.Name,
+		Prefix:           k.Prefix,
+		Scopes:           k.Scopes,
+		Domain:           k.Domain,
+		AllowedOrigins:   k.AllowedOrigins,
+		HasSigningSecret: k.SigningSecret != "",
+		CreatedAt:        k.CreatedAt,
+		RotatedAt:        k.RotatedAt,
+		RevokedAt:        k.RevokedAt,
+		Key:              secret,
+	}
+	if susp, ok := k.Suspended(time.Now()); ok {
+		resp.Suspension = &models.APIKeySuspension{Reason: susp.Reason, SuspendedAt: susp.SuspendedAt, Until: susp.Until}
+	}
+	return resp
+}
+
+// writeAPIKeyError maps key manager errors to HTTP responses.
+func writeAPIKeyError(s *server.Server, w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, apikeys.ErrNotFound):
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "API key not found.")
+	case errors.Is(err, apikeys.ErrRevoked):
+		apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "API key has been revoked.")
+	case errors.Is(err, apikeys.ErrInvalidScope), errors.Is(err, apikeys.ErrInvalidDomain), errors.Is(err, apikeys.ErrInvalidOrigin):
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+	case errors.Is(err, apikeys.ErrDomainTaken):
+		apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, err.Error())
+	default:
+		s.Logger.Error("API key operation failed", "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "API key operation failed.")
+	}
+}
+
+// CreateAPIKeyHandler handles POST /admin/api-keys.
+func CreateAPIKeyHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "A JSON body with a name is required.")
+			return
+		}
+
+		key, secret, err := s.APIKeys.Create(r.Context(), req.Name, req.Scopes)
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		s.Logger.Info("Created API key", "keyID", key.ID, "name", key.Name, "scopes", key.Scopes)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(apiKeyResponse(key, secret))
+	}
+}
+
+// ListAPIKeysHandler handles GET /admin/api-keys.
+func ListAPIKeysHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := s.APIKeys.List(r.Context())
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		out := make([]models.APIKeyResponse, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, apiKeyResponse(k, ""))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// RevokeAPIKeyHandler handles DELETE /admin/api-keys/{id}.
+func RevokeAPIKeyHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.APIKeys.Revoke(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		s.Logger.Info("Revoked API key", "keyID", key.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(apiKeyResponse(key, ""))
+	}
+}
+
+// SuspendAPIKeyHandler handles PUT /admin/api-keys/{id}/suspension, blocking
+// the tenant's requests with a 403 that carries the reason, e.g. for abuse or
+// non-payment. A timed suspension re-enables the key on its own.
+func SuspendAPIKeyHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.SuspendAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "A JSON body with a reason is required.")
+			return
+		}
+		if req.Until != nil && !req.Until.After(time.Now()) {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "until must be in the future.")
+			return
+		}
+
+		key, err := s.APIKeys.Suspend(r.Context(), r.PathValue("id"), strings.TrimSpace(req.Reason), req.Until)
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		s.Logger.Warn("Suspended API key", "keyID", key.ID, "reason", key.Suspension.Reason, "until", key.Suspension.Until)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(apiKeyResponse(key, ""))
+	}
+}
+
+// ResumeAPIKeyHandler handles DELETE /admin/api-keys/{id}/suspension,
+// re-enabling a suspended key.
+func ResumeAPIKeyHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.APIKeys.Resume(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		s.Logger.Info("Resumed API key", "keyID", key.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(apiKeyResponse(key, ""))
+	}
+}
+
+// RotateAPIKeyHandler handles POST /admin/api-keys/{id}/rotate.
+func RotateAPIKeyHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, secret, err := s.APIKeys.Rotate(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		s.Logger.Info("Rotated API key", "keyID", key.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(apiKeyResponse(key, secret))
+	}
+}
+
+// SetAPIKeyDomainHandler handles PUT /admin/api-keys/{id}/domain, assigning the
+// custom domain used for the tenant's public links. An empty domain removes it.
+func SetAPIKeyDomainHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.SetDomainRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+
+		key, err := s.APIKeys.SetDomain(r.Context(), r.PathValue("id"), req.Domain)
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		s.Logger.Info("Set API key domain", "keyID", key.ID, "domain", key.Domain)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(apiKeyResponse(key, ""))
+	}
+}
+
+// SetAPIKeyOriginsHandler handles PUT /admin/api-keys/{id}/origins, binding a
+// key to the browser origins of a partner frontend. The origins are allowed
+// by CORS from then on; an empty list unbinds the key.
+func SetAPIKeyOriginsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.SetOriginsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+
+		key, err := s.APIKeys.SetOrigins(r.Context(), r.PathValue("id"), req.Origins)
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		s.Logger.Info("Set API key origins", "keyID", key.ID, "origins", key.AllowedOrigins)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(apiKeyResponse(key, ""))
+	}
+}
+
+// IssueSigningSecretHandler handles POST /admin/api-keys/{id}/signing-secret,
+// issuing the secret a partner backend uses to sign requests. It is only
+// returned in this response.
+func IssueSigningSecretHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, secret, err := s.APIKeys.IssueSigningSecret(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeAPIKeyError(s, w, r, err)
+			return
+		}
+		s.Logger.Info("Issued API key signing secret", "keyID", key.ID)
+
+		resp := apiKeyResponse(key, "")
+		resp.SigningSecret = secret
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
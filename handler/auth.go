@@ -0,0 +1,30 @@
+// handler/auth.go
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// RequireAdmin only lets requests through that carry the admin bearer token.
+// The admin API is disabled entirely when no token is configured.
+func RequireAdmin(s *server.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		adminToken := s.Config().Security.AdminToken
+		if adminToken == "" {
+			apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeNotConfigured, "Admin API is not configured.")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			s.Logger.Warn("Rejected admin request", "path", r.URL.Path)
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,70 @@
+// handler/billing.go
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/auth"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// CreateCheckoutHandler handles POST /api/v1/billing/checkout, starting a Stripe
+// Checkout session so the caller can pay per image beyond the free tier. Only
+// authenticated callers may subscribe, since an IP address is shared and
+// changes.
+func CreateCheckoutHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.Billing.Enabled() {
+			apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeNotConfigured, "Billing is not enabled.")
+			return
+		}
+
+		if auth.FromRequest(r) == nil {
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Sign in or use an API key to subscribe.")
+			return
+		}
+
+		checkoutURL, sessionID, err := s.Billing.CreateCheckout(r.Context(), clientKey(r))
+		if err != nil {
+			s.Logger.Error("Failed to create checkout session", "error", err)
+			apierror.Write(w, r, http.StatusBadGateway, apierror.CodeUpstreamFailed, "Failed to start checkout.")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.CheckoutResponse{URL: checkoutURL, SessionID: sessionID})
+	}
+}
+
+// CheckoutStatusHandler handles GET /api/v1/billing/checkout/{id}, reporting
+// whether the checkout completed and metered billing is active.
+func CheckoutStatusHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.Billing.Enabled() {
+			apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeNotConfigured, "Billing is not enabled.")
+			return
+		}
+
+		if auth.FromRequest(r) == nil {
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Sign in or use an API key to subscribe.")
+			return
+		}
+
+		status, err := s.Billing.CheckoutStatus(r.Context(), clientKey(r), r.PathValue("id"))
+		if err != nil {
+			s.Logger.Error("Failed to get checkout status", "sessionID", r.PathValue("id"), "error", err)
+			apierror.Write(w, r, http.StatusBadGateway, apierror.CodeUpstreamFailed, "Failed to get checkout status.")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.CheckoutStatusResponse{
+			Status:        status.Status,
+			PaymentStatus: status.PaymentStatus,
+			Active:        status.Active,
+		})
+	}
+}
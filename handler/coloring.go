@@ -0,0 +1,29 @@
+// handler/coloring.go
+package handler
+
+import (
+	"github.com/sanjayshr/event-outfitter-backend/palette"
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// photoColoring analyzes a photo for the person's skin tone and its dominant
+// colors. A photo that cannot be analyzed gets suggestions without them.
+func photoColoring(s *server.Server, photo []byte) palette.Analysis {
+	analysis, err := palette.Analyze(photo)
+	if err != nil {
+		s.Logger.Warn("Failed to analyze the photo's colors", "error", err)
+		return palette.Analysis{}
+	}
+	s.Logger.Info("Analyzed the photo's colors", "skinTone", analysis.SkinTone, "undertone", analysis.Undertone, "dominant", analysis.Dominant)
+	return analysis
+}
+
+// coloringPrompt turns a palette analysis into the suggestions prompt's input.
+func coloringPrompt(analysis palette.Analysis) prompt.Coloring {
+	coloring := prompt.Coloring{Flattering: analysis.Recommended, PhotoColors: analysis.Dominant}
+	if analysis.SkinTone != "" {
+		coloring.SkinTone = analysis.Undertone + " " + analysis.SkinTone
+	}
+	return coloring
+}
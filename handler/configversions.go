@@ -0,0 +1,133 @@
+// handler/configversions.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/configversions"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+func configVersionResponse(v *configversions.Version, withSettings bool) models.ConfigVersionResponse {
+	res := models.ConfigVersionResponse{
+		Version:        v.Number,
+		CreatedAt:      v.CreatedAt,
+		Source:         v.Source,
+		RolledBackFrom: v.RolledBackFrom,
+	}
+	if withSettings {
+		res.Settings = v.Settings
+	}
+	return res
+}
+
+// configVersion loads the version named by a path or query value, writing an
+// error response if it is malformed or unknown.
+func configVersion(s *server.Server, w http.ResponseWriter, r *http.Request, value string) (*configversions.Version, bool) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Config versions are positive numbers.")
+		return nil, false
+	}
+	v, err := s.Versions.Get(r.Context(), n)
+	if errors.Is(err, configversions.ErrNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Config version not found.")
+		return nil, false
+	}
+	if err != nil {
+		s.Logger.Error("Failed to load config version", "version", n, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load config version.")
+		return nil, false
+	}
+	return v, true
+}
+
+// ListConfigVersionsHandler handles GET /admin/config/versions, listing the
+// recorded runtime configurations, newest first.
+func ListConfigVersionsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		versions, err := s.Versions.List(r.Context())
+		if err != nil {
+			s.Logger.Error("Failed to list config versions", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list config versions.")
+			return
+		}
+		res := make([]models.ConfigVersionResponse, 0, len(versions))
+		for _, v := range versions {
+			res = append(res, configVersionResponse(v, false))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}
+}
+
+// GetConfigVersionHandler handles GET /admin/config/versions/{version},
+// returning the version with its settings.
+func GetConfigVersionHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, ok := configVersion(s, w, r, r.PathValue("version"))
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(configVersionResponse(v, true))
+	}
+}
+
+// DiffConfigVersionHandler handles GET /admin/config/versions/{version}/diff,
+// listing what changes from version ?against= (default: the current
+// settings) to {version}. Without against, that is what a rollback to
+// {version} would change.
+func DiffConfigVersionHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, ok := configVersion(s, w, r, r.PathValue("version"))
+		if !ok {
+			return
+		}
+		res := models.ConfigDiffResponse{To: v.Number, Changes: []models.ConfigChange{}}
+		from := s.Settings()
+		if against := r.URL.Query().Get("against"); against != "" {
+			base, ok := configVersion(s, w, r, against)
+			if !ok {
+				return
+			}
+			res.From, from = base.Number, base.Settings
+		}
+		for _, c := range configversions.Diff(from, v.Settings) {
+			res.Changes = append(res.Changes, models.ConfigChange{Path: c.Path, From: c.From, To: c.To})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}
+}
+
+// RollbackConfigHandler handles POST /admin/config/versions/{version}/rollback,
+// applying that version's settings and recording them as a new version.
+func RollbackConfigHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target, ok := configVersion(s, w, r, r.PathValue("version"))
+		if !ok {
+			return
+		}
+		v, applied, err := s.RollbackConfig(r.Context(), target.Number)
+		if err != nil {
+			// The settings are applied even if the new version was not saved.
+			s.Logger.Error("Failed to record config rollback", "version", target.Number, "applied", applied, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "The rollback was applied but could not be recorded.")
+			return
+		}
+		s.Logger.Warn("Rolled back config", "to", target.Number, "version", v.Number, "applied", applied)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.RollbackConfigResponse{
+			Version:        v.Number,
+			RolledBackFrom: target.Number,
+			Applied:        append([]string{}, applied...),
+		})
+	}
+}
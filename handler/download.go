@@ -0,0 +1,54 @@
+// handler/download.go
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// maxFilenameWords caps how much of the event type and the style go into a
+// download filename; styles are whole sentences.
+const maxFilenameWords = 6
+
+// imageExtensions are the filename extensions of the image types served.
+var imageExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/webp": "webp",
+	"image/avif": "avif",
+}
+
+// downloadDisposition returns the Content-Disposition that makes browsers
+// save an image as e.g. dreswap-wedding-an-ivory-silk-saree-with.png when the
+// request has ?download=1, or "" otherwise.
+func downloadDisposition(r *http.Request, eventType, style, mimeType string) string {
+	switch r.URL.Query().Get("download") {
+	case "1", "true":
+	default:
+		return ""
+	}
+	name := "dreswap"
+	for _, part := range []string{eventType, style} {
+		if slug := filenameSlug(part); slug != "" {
+			name += "-" + slug
+		}
+	}
+	ext, ok := imageExtensions[mimeType]
+	if !ok {
+		ext = "img"
+	}
+	return `attachment; filename="` + name + "." + ext + `"`
+}
+
+// filenameSlug lowercases s and keeps its first words of ASCII letters and
+// digits, joined by hyphens, so the filename needs no quoting or encoding in
+// any browser.
+func filenameSlug(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(c rune) bool {
+		return !('a' <= c && c <= 'z' || '0' <= c && c <= '9')
+	})
+	if len(words) > maxFilenameWords {
+		words = words[:maxFilenameWords]
+	}
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,129 @@
+// handler/e2ee.go
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/e2ee"
+	"github.com/sanjayshr/event-outfitter-backend/hooks"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// Headers carrying the client half of the key exchange on /generate.
+const (
+	e2eeKeyIDHeader     = "X-E2EE-Key-ID"
+	e2eePublicKeyHeader = "X-E2EE-Public-Key"
+)
+
+// CapabilitiesHandler handles GET /api/v1/capabilities.
+func CapabilitiesHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := models.CapabilitiesResponse{E2EE: models.E2EECapability{Mode: s.Config().Security.E2EEMode}}
+		if resp.E2EE.Mode != e2ee.ModeOff {
+			resp.E2EE.Algorithm = e2ee.Algorithm
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// KeyExchangeHandler handles POST /api/v1/e2ee/keys, starting a key exchange
+// for an encrypted session. Each caller may only have a few exchanges waiting
+// for their key at a time.
+func KeyExchangeHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Config().Security.E2EEMode == e2ee.ModeOff {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotConfigured, "End-to-end encryption is not enabled.")
+			return
+		}
+		ex, err := s.E2EE.NewExchange(clientKey(r))
+		if errors.Is(err, e2ee.ErrTooMany) {
+			t := throttled(r, apierror.CodeRateLimited, reasonRateLimit, "Too many key exchanges in progress. Use or let one expire first.", e2ee.PendingTTL)
+			writeThrottled(w, http.StatusTooManyRequests, t, t)
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to create encryption key", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create encryption key.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(models.KeyExchangeResponse{
+			KeyID:     ex.KeyID,
+			PublicKey: base64.StdEncoding.EncodeToString(ex.PublicKey),
+			Algorithm: e2ee.Algorithm,
+			ExpiresAt: ex.ExpiresAt,
+		})
+	}
+}
+
+// acceptEncryptedUpload completes the key exchange for an encrypted upload
+// and returns its key ID, or "" for a plaintext upload. It writes an error
+// response and returns ok false if the upload is not acceptable.
+func acceptEncryptedUpload(s *server.Server, w http.ResponseWriter, r *http.Request) (keyID string, ok bool) {
+	mode := s.Config().Security.E2EEMode
+	keyID = r.Header.Get(e2eeKeyIDHeader)
+	if keyID == "" {
+		if mode == e2ee.ModeRequired {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeEncryptionRequired, "Photos must be end-to-end encrypted. See GET /api/v1/capabilities.")
+			return "", false
+		}
+		return "", true
+	}
+	if mode == e2ee.ModeOff {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeNotConfigured, "End-to-end encryption is not enabled.")
+		return "", false
+	}
+
+	clientKey, err := base64.StdEncoding.DecodeString(r.Header.Get(e2eePublicKeyHeader))
+	if err == nil {
+		err = s.E2EE.Bind(keyID, clientKey)
+	}
+	if err != nil {
+		s.Logger.Warn("Rejected encrypted upload", "error", err)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeEncryptionKeyInvalid, "Invalid or expired encryption key.")
+		return "", false
+	}
+	return keyID, true
+}
+
+// sessionImage returns the session's uploaded photo, decrypting it in memory
+// for encrypted sessions. The plaintext is never stored.
+func sessionImage(ctx context.Context, s *server.Server, sd server.SessionData) (hooks.Image, error) {
+	if sd.E2EEKeyID == "" {
+		return hooks.Image{Data: sd.ImageData, MimeType: sd.MimeType}, nil
+	}
+	data, err := s.E2EE.Decrypt(sd.E2EEKeyID, sd.ImageData)
+	if err != nil {
+		return hooks.Image{}, err
+	}
+	// The upload was checked when the session was created, so this only fails
+	// if the HEIC converter has since failed.
+	data, mimeType, err := readPhoto(ctx, s, data)
+	if err != nil {
+		return hooks.Image{}, err
+	}
+	data, mimeType = preprocessPhoto(s, data, mimeType)
+	return hooks.Image{Data: data, MimeType: mimeType}, nil
+}
+
+// writeE2EEError maps decryption failures to HTTP responses.
+func writeE2EEError(s *server.Server, w http.ResponseWriter, r *http.Request, err error) {
+	if isPhotoError(err) {
+		writePhotoError(s, w, r, err)
+		return
+	}
+	s.Logger.Warn("Failed to decrypt session photo", "error", err)
+	if errors.Is(err, e2ee.ErrUnknownKey) {
+		apierror.Write(w, r, http.StatusGone, apierror.CodeEncryptionKeyExpired, "The session's encryption key has expired. Please upload the photo again.")
+		return
+	}
+	apierror.Write(w, r, http.StatusBadRequest, apierror.CodeDecryptionFailed, "The encrypted photo could not be decrypted.")
+}
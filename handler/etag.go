@@ -0,0 +1,37 @@
+// handler/etag.go
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// lookImageETag is the strong ETag of a look's stored image. A look's image
+// never changes once stored, so the look ID identifies its bytes and the
+// image need not be read to answer a conditional request.
+func lookImageETag(id string) string {
+	return `"look-` + id + `"`
+}
+
+// notModified sets the ETag header and, if the request's If-None-Match
+// already names etag, answers 304 Not Modified and returns true.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header value lists etag or is
+// "*". If-None-Match uses the weak comparison, so a W/ prefix is ignored.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+// handler/export.go
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// imageExt returns the file extension for an image MIME type.
+func imageExt(mimeType string) string {
+	switch ext := strings.TrimPrefix(mimeType, "image/"); ext {
+	case "jpeg":
+		return "jpg"
+	case "", mimeType:
+		return "bin"
+	default:
+		return ext
+	}
+}
+
+// ExportSessionHandler handles GET /api/v1/sessions/{id}/export.zip, streaming
+// a ZIP of every look the caller generated in a session plus a metadata.json.
+func ExportSessionHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exportSession(s, w, r, r.PathValue("id"))
+	}
+}
+
+// ExportHandler handles GET /api/v1/export, the ZIP export of the session of
+// the X-Session-ID header, for clients that only hold the session header.
+func ExportHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get("X-Session-ID")
+		if sessionID == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
+			return
+		}
+		exportSession(s, w, r, sessionID)
+	}
+}
+
+// exportSession streams the ZIP of a session's looks. Each image is named
+// after its style, e.g. looks/01-an-ivory-silk-saree-with.png, and written
+// uncompressed, since images are already compressed, one at a time, so
+// memory stays bounded however large the session is.
+func exportSession(s *server.Server, w http.ResponseWriter, r *http.Request, sessionID string) {
+	sessionLooks, err := s.Looks.BySession(r.Context(), ownerKey(r), sessionID)
+	if err != nil {
+		s.Logger.Error("Failed to load session looks", "sessionID", sessionID, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export session.")
+		return
+	}
+	if len(sessionLooks) == 0 {
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Session not found.")
+		return
+	}
+
+	first := sessionLooks[0]
+	manifest := models.SessionExport{
+		SessionID:  sessionID,
+		EventType:  first.EventType,
+		Venue:      first.Venue,
+		Theme:      first.Theme,
+		ExportedAt: time.Now().UTC(),
+		Looks:      make([]models.SessionExportLook, 0, len(sessionLooks)),
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="dreswap-%s.zip"`, sessionID))
+	zw := zip.NewWriter(w)
+	for i, look := range sessionLooks {
+		entry := models.SessionExportLook{LookResponse: lookResponse(look)}
+		img, mimeType, err := s.Looks.Image(r.Context(), look.ID)
+		switch {
+		case errors.Is(err, looks.ErrNoImage):
+			// End-to-end encrypted sessions have no stored images.
+		case err != nil:
+			s.Logger.Error("Failed to load look image for export", "lookID", look.ID, "error", err)
+		default:
+			name := filenameSlug(look.Style)
+			if name == "" {
+				name = look.ID
+			}
+			entry.File = fmt.Sprintf("looks/%02d-%s.%s", i+1, name, imageExt(mimeType))
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: entry.File, Method: zip.Store, Modified: look.CreatedAt})
+			if err == nil {
+				_, err = f.Write(img)
+			}
+			if err != nil {
+				// The response is already streaming; all we can do is stop.
+				s.Logger.Error("Failed to write session export", "sessionID", sessionID, "error", err)
+				return
+			}
+		}
+		manifest.Looks = append(manifest.Looks, entry)
+	}
+
+	f, err := zw.Create("metadata.json")
+	if err == nil {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(manifest)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		s.Logger.Error("Failed to write session export", "sessionID", sessionID, "error", err)
+		return
+	}
+	s.Logger.Info("Exported session", "sessionID", sessionID, "looks", len(sessionLooks))
+}
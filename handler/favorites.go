@@ -0,0 +1,157 @@
+// handler/favorites.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/sessions"
+)
+
+// favorites lists the favorites of the records, most recently added first.
+func favorites(s *server.Server, r *http.Request, recs ...*sessions.Record) models.FavoritesResponse {
+	resp := models.FavoritesResponse{Favorites: []models.Favorite{}}
+	for _, rec := range recs {
+		for _, f := range rec.Favorites {
+			fav := models.Favorite{
+				Kind:        f.Kind,
+				ID:          f.ID,
+				SessionID:   rec.ID,
+				Name:        f.Name,
+				Description: f.Description,
+				CreatedAt:   f.CreatedAt,
+			}
+			if f.Kind == sessions.FavoriteLook {
+				fav.ImageURL = publicURL(s, r, rec.Owner, "/api/v1/looks/"+f.ID+"/image")
+			}
+			resp.Favorites = append(resp.Favorites, fav)
+		}
+	}
+	slices.SortStableFunc(resp.Favorites, func(a, b models.Favorite) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return resp
+}
+
+// writeFavorites writes the session's favorites after adding or removing one.
+func writeFavorites(s *server.Server, w http.ResponseWriter, r *http.Request, rec *sessions.Record, err error) {
+	if errors.Is(err, sessions.ErrTooManyFavorites) {
+		apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, fmt.Sprintf("A session can have at most %d favorites.", sessions.MaxFavorites))
+		return
+	}
+	if err != nil {
+		s.Logger.Error("Failed to update favorites", "sessionID", r.PathValue("id"), "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update favorites.")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(favorites(s, r, rec))
+}
+
+// FavoritesHandler handles GET /api/v1/favorites, listing the favorites of
+// all the caller's sessions.
+func FavoritesHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recs, err := s.Sessions.List(r.Context(), ownerKey(r))
+		if err != nil {
+			s.Logger.Error("Failed to list sessions", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list favorites.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(favorites(s, r, recs...))
+	}
+}
+
+// SessionFavoritesHandler handles GET /api/v1/sessions/{id}/favorites.
+func SessionFavoritesHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := ownedSession(s, w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(favorites(s, r, rec))
+	}
+}
+
+// FavoriteStyleHandler handles PUT /api/v1/sessions/{id}/favorites/styles/{styleId},
+// shortlisting one of an active session's styles.
+func FavoriteStyleHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := ownedSession(s, w, r)
+		if !ok {
+			return
+		}
+		sessionData, found := s.CachedSession(rec.ID)
+		if !found {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+		i := slices.IndexFunc(sessionData.Styles, func(style models.Style) bool { return style.ID == r.PathValue("styleId") })
+		if i < 0 {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Style not found.")
+			return
+		}
+		style := sessionData.Styles[i]
+		rec, err := s.Sessions.AddFavorite(r.Context(), rec.ID, sessions.Favorite{
+			Kind:        sessions.FavoriteStyle,
+			ID:          style.ID,
+			Name:        style.Name,
+			Description: style.Description,
+		})
+		writeFavorites(s, w, r, rec, err)
+	}
+}
+
+// FavoriteLookHandler handles PUT /api/v1/sessions/{id}/favorites/looks/{lookId},
+// shortlisting an image generated in the session.
+func FavoriteLookHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := ownedSession(s, w, r)
+		if !ok {
+			return
+		}
+		look, ok := ownedLook(s, w, r, r.PathValue("lookId"))
+		if !ok {
+			return
+		}
+		if look.SessionID != rec.ID {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Look not found.")
+			return
+		}
+		rec, err := s.Sessions.AddFavorite(r.Context(), rec.ID, sessions.Favorite{
+			Kind:        sessions.FavoriteLook,
+			ID:          look.ID,
+			Description: look.Style,
+		})
+		writeFavorites(s, w, r, rec, err)
+	}
+}
+
+// UnfavoriteStyleHandler handles DELETE /api/v1/sessions/{id}/favorites/styles/{styleId}.
+// Styles are unmarked by ID, so this works after the session expired.
+func UnfavoriteStyleHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ownedSession(s, w, r); !ok {
+			return
+		}
+		rec, err := s.Sessions.RemoveFavorite(r.Context(), r.PathValue("id"), sessions.FavoriteStyle, r.PathValue("styleId"))
+		writeFavorites(s, w, r, rec, err)
+	}
+}
+
+// UnfavoriteLookHandler handles DELETE /api/v1/sessions/{id}/favorites/looks/{lookId}.
+func UnfavoriteLookHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ownedSession(s, w, r); !ok {
+			return
+		}
+		rec, err := s.Sessions.RemoveFavorite(r.Context(), r.PathValue("id"), sessions.FavoriteLook, r.PathValue("lookId"))
+		writeFavorites(s, w, r, rec, err)
+	}
+}
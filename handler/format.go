@@ -0,0 +1,83 @@
+// handler/format.go
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/imageconv"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"golang.org/x/image/draw"
+)
+
+// outputFormats maps the ?format= values clients may request to their media
+// types: PNG for clients that handle transparency, JPEG and WebP for smaller
+// downloads.
+var outputFormats = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"webp": "image/webp",
+}
+
+// checkFormat validates ?format= before the image is generated, so a typo or
+// an unavailable format doesn't cost a generation.
+func checkFormat(s *server.Server, w http.ResponseWriter, r *http.Request) bool {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		return true
+	}
+	if _, ok := outputFormats[format]; !ok {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "format must be png, jpeg or webp.")
+		return false
+	}
+	if format == "webp" && s.WebPEncoder == nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeNotConfigured, "WebP output is not enabled.")
+		return false
+	}
+	return true
+}
+
+// transcode converts img to format, one of outputFormats. An image already in
+// that format is returned as is.
+func transcode(ctx context.Context, s *server.Server, img []byte, mimeType, format string) ([]byte, string, error) {
+	target := outputFormats[format]
+	if mimeType == target {
+		return img, mimeType, nil
+	}
+	if format == "webp" {
+		encoded, err := s.WebPEncoder.Convert(ctx, img)
+		if err != nil {
+			return nil, "", err
+		}
+		if !imageconv.IsWebP(encoded) {
+			return nil, "", errors.New("WebP encoder did not produce a WebP image")
+		}
+		return encoded, target, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, "", err
+	}
+	var buf bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&buf, src)
+	} else {
+		// JPEG has no alpha channel, so transparent areas are laid over white
+		// rather than left to turn black.
+		flat := image.NewRGBA(src.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), src, src.Bounds().Min, draw.Over)
+		err = jpeg.Encode(&buf, flat, &jpeg.Options{Quality: int(s.Config().Output.JPEGQuality)})
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), target, nil
+}
@@ -0,0 +1,136 @@
+// handler/framing.go
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/sanjayshr/event-outfitter-backend/gemini"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"golang.org/x/image/draw"
+)
+
+// aspectRatio is a frame's width:height.
+type aspectRatio struct {
+	width, height int
+}
+
+func (a aspectRatio) String() string {
+	return fmt.Sprintf("%d:%d", a.width, a.height)
+}
+
+// aspectRatios are the frames a generate request may ask for: a feed
+// portrait, a square post and a full-screen story.
+var aspectRatios = map[string]aspectRatio{
+	"portrait": {4, 5},
+	"square":   {1, 1},
+	"story":    {9, 16},
+}
+
+// Bounds of a generate request's maxDimension.
+const (
+	minMaxDimension = 64
+	maxMaxDimension = 4096
+)
+
+// validateFraming checks the aspectRatio and maxDimension of a generate request.
+func validateFraming(req models.GenerateRequest) error {
+	if _, ok := aspectRatios[req.AspectRatio]; req.AspectRatio != "" && !ok {
+		return errors.New("aspectRatio must be portrait, square or story")
+	}
+	if req.MaxDimension != 0 && (req.MaxDimension < minMaxDimension || req.MaxDimension > maxMaxDimension) {
+		return fmt.Errorf("maxDimension must be between %d and %d", minMaxDimension, maxMaxDimension)
+	}
+	return nil
+}
+
+// aspectRatioHint is the width:height the image prompt asks for, or empty.
+func aspectRatioHint(req models.GenerateRequest) string {
+	if ratio, ok := aspectRatios[req.AspectRatio]; ok {
+		return ratio.String()
+	}
+	return ""
+}
+
+// imageOptions are the settings of req that apply to every image prompt of
+// the session.
+func imageOptions(req models.GenerateRequest) gemini.ImageOptions {
+	return gemini.ImageOptions{
+		AspectRatio:    aspectRatioHint(req),
+		PromptSuffix:   req.PromptSuffix,
+		KeepBackground: req.KeepBackground,
+		Wearer:         wearer(req),
+	}
+}
+
+// frameGenerated crops a generated image to the session's aspect ratio and
+// scales it down to its maxDimension, since the model treats the ratio in
+// the prompt as a suggestion. If the image cannot be framed, it is returned
+// unchanged.
+func frameGenerated(s *server.Server, req models.GenerateRequest, img []byte, mimeType string) ([]byte, string) {
+	if req.AspectRatio == "" && req.MaxDimension == 0 {
+		return img, mimeType
+	}
+	framed, framedType, err := frameImage(img, mimeType, aspectRatios[req.AspectRatio], req.MaxDimension, int(s.Config().Output.JPEGQuality))
+	if err != nil {
+		s.Logger.Warn("Failed to frame generated image", "aspectRatio", req.AspectRatio, "maxDimension", req.MaxDimension, "error", err)
+		return img, mimeType
+	}
+	return framed, framedType
+}
+
+// frameImage crops img to ratio, if set, and scales it so its longer side is
+// at most maxDimension, if set. PNGs stay PNG, keeping any transparency;
+// anything else is encoded as JPEG at quality.
+func frameImage(img []byte, mimeType string, ratio aspectRatio, maxDimension, quality int) ([]byte, string, error) {
+	src, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, "", err
+	}
+	bounds := src.Bounds()
+	crop := bounds
+	if ratio.width > 0 {
+		crop = cropToRatio(bounds, ratio)
+	}
+	width, height := crop.Dx(), crop.Dy()
+	if longer := max(width, height); maxDimension > 0 && longer > maxDimension {
+		width, height = max(width*maxDimension/longer, 1), max(height*maxDimension/longer, 1)
+	}
+	if crop == bounds && width == bounds.Dx() && height == bounds.Dy() {
+		return img, mimeType, nil
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	var buf bytes.Buffer
+	if mimeType == "image/png" {
+		err = png.Encode(&buf, dst)
+	} else {
+		mimeType = "image/jpeg"
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mimeType, nil
+}
+
+// cropToRatio returns the largest rectangle of bounds with the given ratio.
+// Width is cropped evenly from both sides; height mostly from the bottom,
+// since faces sit in the upper part of a portrait.
+func cropToRatio(bounds image.Rectangle, ratio aspectRatio) image.Rectangle {
+	width, height := bounds.Dx(), bounds.Dy()
+	if width*ratio.height > height*ratio.width {
+		cropped := height * ratio.width / ratio.height
+		x := bounds.Min.X + (width-cropped)/2
+		return image.Rect(x, bounds.Min.Y, x+cropped, bounds.Max.Y)
+	}
+	cropped := width * ratio.height / ratio.width
+	y := bounds.Min.Y + (height-cropped)/3
+	return image.Rect(bounds.Min.X, y, bounds.Max.X, y+cropped)
+}
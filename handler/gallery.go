@@ -0,0 +1,291 @@
+// handler/gallery.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+const (
+	defaultGalleryPageSize = 20
+	maxGalleryPageSize     = 100
+)
+
+// galleryItem converts a look to its gallery representation, honoring the
+// owner's attribution choice.
+func galleryItem(s *server.Server, r *http.Request, l *looks.Look) models.GalleryItem {
+	item := models.GalleryItem{
+		ID:        l.ID,
+		EventType: l.EventType,
+		Venue:     l.Venue,
+		Theme:     l.Theme,
+		Style:     l.Style,
+		Tags:      l.Tags,
+		ImageURL:  publicURL(s, r, l.Owner, "/api/v1/gallery/"+l.ID+"/image"),
+	}
+	if g := l.Gallery; g != nil {
+		item.Status = g.Status
+		item.Featured = g.Featured
+		item.SubmittedAt = g.SubmittedAt
+		if g.ShowAttribution {
+			item.Attribution = g.DisplayName
+		}
+	}
+	return item
+}
+
+// parseGalleryQuery reads pagination and search parameters from the query string.
+func parseGalleryQuery(r *http.Request, status string) looks.GalleryQuery {
+	q := looks.GalleryQuery{
+		Status:    status,
+		Text:      r.URL.Query().Get("q"),
+		EventType: r.URL.Query().Get("eventType"),
+		Tag:       r.URL.Query().Get("tag"),
+		Page:      1,
+		PageSize:  defaultGalleryPageSize,
+	}
+	if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && page > 0 {
+		q.Page = page
+	}
+	if size, err := strconv.Atoi(r.URL.Query().Get("pageSize")); err == nil && size > 0 {
+		q.PageSize = min(size, maxGalleryPageSize)
+	}
+	return q
+}
+
+// writeGalleryPage runs a gallery query and writes the JSON page.
+func writeGalleryPage(s *server.Server, w http.ResponseWriter, r *http.Request, q looks.GalleryQuery) {
+	items, total, err := s.Looks.Gallery(r.Context(), q)
+	if err != nil {
+		s.Logger.Error("Failed to list gallery", "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list gallery.")
+		return
+	}
+	page := models.GalleryPage{Items: make([]models.GalleryItem, 0, len(items)), Page: q.Page, PageSize: q.PageSize, Total: total}
+	for _, l := range items {
+		page.Items = append(page.Items, galleryItem(s, r, l))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// ownedLook loads a look and checks that the caller owns it, writing an error response if not.
+func ownedLook(s *server.Server, w http.ResponseWriter, r *http.Request, id string) (*looks.Look, bool) {
+	look, err := s.Looks.Get(r.Context(), id)
+	if errors.Is(err, looks.ErrNotFound) || (err == nil && !owns(r, look.Owner)) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Look not found.")
+		return nil, false
+	}
+	if err != nil {
+		s.Logger.Error("Failed to load look", "lookID", id, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load look.")
+		return nil, false
+	}
+	return look, true
+}
+
+// LookImageHandler handles GET /api/v1/looks/{id}/image, letting the owner
+// download a look's full-quality image, e.g. after receiving a low-quality
+// render on a slow connection.
+func LookImageHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if _, ok := ownedLook(s, w, r, id); !ok {
+			return
+		}
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+		writeLookImage(s, w, r, id)
+	}
+}
+
+// PublishLookHandler handles POST /api/v1/gallery, letting a user opt a look
+// into the public gallery. The look is queued for moderation.
+func PublishLookHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.PublishLookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.LookID == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "A JSON body with a lookId is required.")
+			return
+		}
+		if _, ok := ownedLook(s, w, r, req.LookID); !ok {
+			return
+		}
+
+		look, err := s.Looks.Submit(r.Context(), req.LookID, req.DisplayName, req.ShowAttribution)
+		if err != nil {
+			s.Logger.Error("Failed to submit look to gallery", "lookID", req.LookID, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to publish look.")
+			return
+		}
+		s.Logger.Info("Look submitted to gallery", "lookID", look.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(galleryItem(s, r, look))
+	}
+}
+
+// UnpublishLookHandler handles DELETE /api/v1/gallery/{id}, withdrawing a look from the gallery.
+func UnpublishLookHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if _, ok := ownedLook(s, w, r, id); !ok {
+			return
+		}
+		if _, err := s.Looks.Withdraw(r.Context(), id); err != nil {
+			s.Logger.Error("Failed to withdraw look from gallery", "lookID", id, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to unpublish look.")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GalleryHandler handles GET /api/v1/gallery, listing approved looks with
+// optional ?q= text search, ?eventType= filter and ?page=/&pageSize= pagination.
+// On a tenant's custom domain only that tenant's looks are listed.
+func GalleryHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := parseGalleryQuery(r, looks.GalleryApproved)
+		if t := tenantFromRequest(r); t != nil {
+			q.Owner = "key:" + t.ID
+		}
+		writeGalleryPage(s, w, r, q)
+	}
+}
+
+// writeLookImage writes a look's stored image, as an attachment with ?download=1.
+// Conditional requests for an image the client already has get a 304; a
+// redirect to object storage carries no ETag, since revalidating it would keep
+// an expired signed URL in use.
+func writeLookImage(s *server.Server, w http.ResponseWriter, r *http.Request, id string) {
+	look, err := s.Looks.Get(r.Context(), id)
+	if errors.Is(err, looks.ErrNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Image not found.")
+		return
+	}
+	if err != nil {
+		s.Logger.Error("Failed to load look", "lookID", id, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load image.")
+		return
+	}
+	disposition := downloadDisposition(r, look.EventType, look.Style, look.MimeType)
+	if redirectToObject(s, w, r, look, disposition) {
+		return
+	}
+	if notModified(w, r, lookImageETag(id)) {
+		return
+	}
+	img, mimeType, err := s.Looks.Image(r.Context(), id)
+	if errors.Is(err, looks.ErrNotFound) || errors.Is(err, looks.ErrNoImage) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Image not found.")
+		return
+	}
+	if err != nil {
+		s.Logger.Error("Failed to load look image", "lookID", id, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load image.")
+		return
+	}
+	w.Header().Set("Content-Type", mimeType)
+	if disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+	w.Write(img)
+}
+
+// redirectToObject redirects to a signed URL of a look's image in object
+// storage, so the bytes don't pass through this server. It returns false if
+// the image is not in the bucket, e.g. because it was stored before object
+// storage was set up.
+func redirectToObject(s *server.Server, w http.ResponseWriter, r *http.Request, look *looks.Look, disposition string) bool {
+	if s.Objects == nil {
+		return false
+	}
+	ok, err := s.Objects.Exists(r.Context(), looks.ImageNamespace, look.ID)
+	if err != nil {
+		s.Logger.Warn("Failed to check look image in object storage", "lookID", look.ID, "error", err)
+	}
+	if !ok {
+		return false
+	}
+	ttl := s.Config().Store.Objects.URLTTL
+	// The redirect must not be reused after the URL expires.
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(ttl.Seconds())/2))
+	http.Redirect(w, r, s.Objects.SignedURL(looks.ImageNamespace, look.ID, look.MimeType, disposition, ttl), http.StatusFound)
+	return true
+}
+
+// GalleryImageHandler handles GET /api/v1/gallery/{id}/image for approved looks.
+func GalleryImageHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		look, err := s.Looks.Get(r.Context(), id)
+		if err != nil || !look.Public {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Image not found.")
+			return
+		}
+		if t := tenantFromRequest(r); t != nil && look.Owner != "key:"+t.ID {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Image not found.")
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		writeLookImage(s, w, r, id)
+	}
+}
+
+// AdminGalleryHandler handles GET /admin/gallery, listing looks in a moderation
+// state (default pending) for curation.
+func AdminGalleryHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = looks.GalleryPending
+		}
+		writeGalleryPage(s, w, r, parseGalleryQuery(r, status))
+	}
+}
+
+// AdminGalleryImageHandler handles GET /admin/gallery/{id}/image so moderators
+// can review looks that are not yet public.
+func AdminGalleryImageHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeLookImage(s, w, r, r.PathValue("id"))
+	}
+}
+
+// ModerateLookHandler handles POST /admin/gallery/{id}/moderate.
+func ModerateLookHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.ModerateLookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+
+		id := r.PathValue("id")
+		look, err := s.Looks.Moderate(r.Context(), id, req.Action, req.Note)
+		switch {
+		case errors.Is(err, looks.ErrNotFound):
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Look not found.")
+			return
+		case errors.Is(err, looks.ErrInvalidTransition):
+			apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, err.Error())
+			return
+		case err != nil:
+			s.Logger.Error("Failed to moderate look", "lookID", id, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to moderate look.")
+			return
+		}
+		s.Logger.Info("Moderated gallery look", "lookID", id, "action", req.Action)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(galleryItem(s, r, look))
+	}
+}
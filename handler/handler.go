@@ -2,145 +2,449 @@
 package handler
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"os"
-	"path/filepath"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
 	"github.com/sanjayshr/event-outfitter-backend/gemini"
+	"github.com/sanjayshr/event-outfitter-backend/hooks"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/metrics"
 	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/palette"
+	"github.com/sanjayshr/event-outfitter-backend/presets"
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+	"github.com/sanjayshr/event-outfitter-backend/realip"
 	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/sessions"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/tracing"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+	"golang.org/x/sync/errgroup"
 )
 
-// maxUploadSize defines the maximum allowed file upload size (10 MB).
-const maxUploadSize = 10 * 1024 * 1024 // 10 MB
-
 // GenerateHandler handles the /api/v1/generate endpoint.
 func GenerateHandler(s *server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+		if !checkFormat(s, w, r) {
 			return
 		}
 
-		// Enforce a maximum request body size
-		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
-		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-			s.Logger.Error("Failed to parse multipart form", "error", err)
-			http.Error(w, "The uploaded file is too big. Please choose an image that is less than 10MB in size.", http.StatusBadRequest)
+		quota, billable, ok := checkQuota(s, w, r)
+		if !ok {
 			return
 		}
+		defer quota.Release()
+
+		defer s.Activity.Begin("generate")()
+		timing := metrics.NewTiming()
+		endPreprocess := timing.Start(metrics.StagePreprocess)
 
-		// 1. Parse the JSON data part
-		jsonData := r.FormValue("data")
+		// Enforce a maximum request body size. Photos referenced by imageUrl
+		// come as a plain JSON body instead of a multipart upload.
+		maxUploadSize := s.Config().Server.MaxUploadBytes
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+		jsonBody := isJSONRequest(r)
+		if !jsonBody {
+			_, span := tracing.Start(r.Context(), "parse upload")
+			err := r.ParseMultipartForm(maxUploadSize)
+			tracing.End(span, err)
+			if err != nil {
+				s.Logger.Error("Failed to parse multipart form", "error", err)
+				writeFileTooLarge(w, r, maxUploadSize)
+				return
+			}
+		}
+
+		// Reject scripted traffic before spending anything on Gemini
+		if s.Captcha.Enabled() {
+			if err := s.Captcha.Verify(r.Context(), s.Captcha.TokenFromRequest(r), realip.FromRequest(r)); err != nil {
+				s.Logger.Warn("Captcha verification failed", "error", err, "clientIP", realip.FromRequest(r))
+				apierror.Write(w, r, http.StatusForbidden, apierror.CodeCaptchaFailed, "Bot verification failed. Please refresh the page and try again.")
+				return
+			}
+		}
+
+		// 1. Parse the JSON data part, or the whole body of a JSON request
 		var reqData models.GenerateRequest
-		if err := json.Unmarshal([]byte(jsonData), &reqData); err != nil {
+		var err error
+		if jsonBody {
+			err = json.NewDecoder(r.Body).Decode(&reqData)
+		} else {
+			err = json.Unmarshal([]byte(r.FormValue("data")), &reqData)
+		}
+		if err != nil {
 			s.Logger.Error("Failed to unmarshal JSON data", "error", err)
-			http.Error(w, "Invalid JSON data provided.", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid JSON data provided.")
 			return
 		}
-		s.Logger.Info("Received generation request", "data", reqData)
-
-		// 2. Parse the image file part
-		file, handler, err := r.FormFile("image")
-		if err != nil {
-			s.Logger.Error("Failed to get image from form", "error", err)
-			http.Error(w, "Invalid image file provided.", http.StatusBadRequest)
+		s.Logger.Info("Received generation request", "data", reqData, "clientIP", realip.FromRequest(r))
+		if err := sessions.Validate(reqData.Name, reqData.Notes); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
 			return
 		}
-		defer file.Close()
-
-		imgData, err := io.ReadAll(file)
-		if err != nil {
-			s.Logger.Error("Failed to read image data", "error", err)
-			http.Error(w, "Could not read image data.", http.StatusInternalServerError)
+		if err := validateFraming(reqData); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if err := validateCount(reqData.Count); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if err := validateStyleCount(reqData); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if reqData.StyleTags, err = normalizeStyleTags(reqData.StyleTags); err != nil {
+			apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error(),
+				map[string]any{"tags": styleTags})
+			return
+		}
+		if err := validateEventDate(reqData); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if err := validateWearer(reqData); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if reqData.Language, err = suggestionLanguage(r, reqData.Language); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if !checkVariationQuota(s, w, r, quota, billable, reqData.Count) {
+			return
+		}
+		if !checkPromptSuffix(s, w, r, &reqData) {
 			return
 		}
 
-		// --- Replace with this robust logic ---
-		var mimeType string
-
-		// First, try to get the MIME type from the file extension.
-		// This is often the most reliable method.
-		mimeType = mime.TypeByExtension(filepath.Ext(handler.Filename))
-
-		// If the extension is unknown, fall back to content detection.
-		if mimeType == "" {
-			mimeType = http.DetectContentType(imgData)
+		// Style suggestions only depend on the event, so they are fetched from the
+		// preset cache or Gemini while the photo is read, checked and preprocessed.
+		// With palette analysis, they wait for the photo's colors, which are
+		// analyzed as soon as it is read. Returning early, e.g. because the photo
+		// is rejected, cancels the call.
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		pipeline, ctx := errgroup.WithContext(ctx)
+		var styles []models.Style
+		var coloring chan palette.Analysis
+		if s.Config().PaletteAnalysis.Enabled {
+			coloring = make(chan palette.Analysis, 1)
 		}
+		pipeline.Go(func() error {
+			defer timing.Start(metrics.StageSuggestions)()
+			var analysis palette.Analysis
+			if coloring != nil {
+				select {
+				case analysis = <-coloring:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			var err error
+			styles, err = suggestStyles(ctx, s, reqData, analysis)
+			if err != nil {
+				return err
+			}
+			styles = shopStyles(ctx, s, styles)
+			return nil
+		})
 
-		// FINAL CHECK: If the type is still generic, make an educated guess based on the extension.
-		// This handles cases where system mime types are not configured for .jpg, etc.
-		if mimeType == "application/octet-stream" {
-			ext := strings.ToLower(filepath.Ext(handler.Filename))
-			switch ext {
-			case ".jpg", ".jpeg":
-				mimeType = "image/jpeg"
-			case ".png":
-				mimeType = "image/png"
-			case ".webp":
-				mimeType = "image/webp"
-				// Add other supported image types as needed
+		// 2. Parse the image file part, or take the photo from a resumable upload or imageUrl
+		if reqData.ImageURL != "" && reqData.UploadID != "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Pass either imageUrl or uploadId, not both.")
+			return
+		}
+		var imgData []byte
+		filename := reqData.ImageURL
+		if reqData.UploadID != "" {
+			filename = "upload:" + reqData.UploadID
+			if imgData, ok = uploadedPhoto(s, w, r, reqData.UploadID); !ok {
+				return
+			}
+		} else if reqData.ImageURL != "" {
+			if imgData, ok = fetchPhoto(s, w, r, reqData.ImageURL); !ok {
+				return
+			}
+		} else {
+			file, handler, err := r.FormFile("image")
+			if err != nil {
+				s.Logger.Error("Failed to get image from form", "error", err)
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidImage, "Invalid image file provided. Upload it as the image part or pass an uploadId or imageUrl.")
+				return
+			}
+			defer file.Close()
+			filename = handler.Filename
+
+			imgData, err = io.ReadAll(file)
+			if err != nil {
+				s.Logger.Error("Failed to read image data", "error", err)
+				apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Could not read image data.")
+				return
 			}
 		}
 
-		s.Logger.Info("Image received", "filename", handler.Filename, "size", handler.Size, "mimeType", mimeType)
-		// --- End of replacement ---
+		// Encrypted photos stay encrypted in the session and are only decrypted for model calls
+		e2eeKeyID, ok := acceptEncryptedUpload(s, w, r)
+		if !ok {
+			return
+		}
+		if e2eeKeyID != "" && reqData.ImageURL != "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Photos fetched from imageUrl cannot be end-to-end encrypted; upload the encrypted photo instead.")
+			return
+		}
 
-		// 3. Get style suggestions from Gemini (text-only call)
-		styles, err := gemini.GetStyleSuggestions(r.Context(), s.Logger, reqData.EventType, reqData.Venue, reqData.Theme)
+		// Identify the photo by its content rather than the filename, which is
+		// client-controlled. Encrypted photos are checked in memory once decrypted.
+		plain := imgData
+		if e2eeKeyID != "" {
+			if plain, err = s.E2EE.Decrypt(e2eeKeyID, imgData); err != nil {
+				writeE2EEError(s, w, r, err)
+				return
+			}
+		}
+		plain, mimeType, err := readPhoto(r.Context(), s, plain)
 		if err != nil {
-			s.Logger.Error("Failed to get style suggestions", "error", err)
-			http.Error(w, "Failed to get style suggestions.", http.StatusInternalServerError)
+			writePhotoError(s, w, r, err)
 			return
 		}
-		if len(styles) == 0 {
-			s.Logger.Error("No style suggestions returned")
-			http.Error(w, "No style suggestions could be generated.", http.StatusInternalServerError)
+		var analysis palette.Analysis
+		if coloring != nil {
+			analysis = photoColoring(s, plain)
+			coloring <- analysis
+		}
+		if !checkModeration(s, w, r, plain, mimeType) || !checkFaces(s, w, r, plain, mimeType) {
 			return
 		}
+		s.Logger.Info("Image received", "filename", filename, "size", len(imgData), "mimeType", mimeType)
+		if e2eeKeyID == "" {
+			// Large or HEIC photos are converted once here, so the session holds the
+			// smaller copy. Encrypted photos are converted each time they are decrypted.
+			imgData, mimeType = preprocessPhoto(s, plain, mimeType)
+		}
+		if e2eeKeyID != "" && r.MultipartForm != nil && len(r.MultipartForm.File["reference"]) > 0 {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Reference photos cannot be end-to-end encrypted; upload a single encrypted photo instead.")
+			return
+		}
+		references, ok := readReferences(s, w, r, gemini.Reference{Data: imgData, MimeType: mimeType})
+		if !ok {
+			return
+		}
+		endPreprocess()
 
-		// 4. Generate a session ID and store image data and styles in cache
-		sessionID := uuid.New().String()
-		// Save session ID to a file for easy access
-		f, err := os.OpenFile("session.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			s.Logger.Error("Failed to open session log file", "error", err)
-			// Do not fail the request, just log the error
-		} else {
-			if _, err := f.WriteString(sessionID + "\n"); err != nil {
-				s.Logger.Error("Failed to write session ID to log file", "error", err)
+		// 3. Wait for the style suggestions started in step 1
+		if err := pipeline.Wait(); err != nil {
+			if errors.Is(err, errNoStyles) {
+				apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeUpstreamFailed, "No style suggestions could be generated.")
+				return
 			}
-			f.Close()
+			writeGeminiError(s, w, r, err, "Failed to get style suggestions.")
+			return
 		}
+
+		// 4. Generate a session ID and store image data and styles in cache.
+		// The session record is persisted while the first image renders; the
+		// response waits for it, and so does every early return.
+		sessionID := uuid.New().String()
+		owner := issueOwner(w, r)
+		var persist errgroup.Group
+		defer persist.Wait()
+		persist.Go(func() error {
+			saveSessionRecord(s, r, sessionID, owner, reqData)
+			return nil
+		})
 		sessionData := server.SessionData{
 			Styles:      styles,
 			ImageData:   imgData,
 			MimeType:    mimeType,
 			RequestData: reqData,
+			References:  references,
+			E2EEKeyID:   e2eeKeyID,
+			Coloring:    analysis,
+			Owner:       owner,
 		}
 
-		s.CacheMutex.Lock()
-		s.SessionCache[sessionID] = sessionData
-		s.CacheMutex.Unlock()
+		s.CacheSession(sessionID, sessionData)
+		// The session holds the photo now, so a resumable upload is done with
+		if reqData.UploadID != "" {
+			s.Uploads.Delete(reqData.UploadID, owner)
+		}
 
-		// 5. Generate the first image using the first style
-		generatedImg, generatedMimeType, err := gemini.GenerateImage(r.Context(), s.Logger, sessionData.ImageData, sessionData.MimeType, sessionData.RequestData.EventType, sessionData.RequestData.Venue, sessionData.RequestData.Theme, sessionData.Styles[0])
+		// 5. Generate the first image using the first style, running any image hooks around it
+		endPreprocess = timing.Start(metrics.StagePreprocess)
+		photo, err := sessionImage(r.Context(), s, sessionData)
+		if err != nil {
+			writeE2EEError(s, w, r, err)
+			return
+		}
+		hookReq := hookRequest(r, sessionID, sessionData, sessionData.Styles[0].Description)
+		input, err := s.Hooks.Pre(r.Context(), hookReq, photo)
+		if err != nil {
+			writeHookError(s, w, r, err)
+			return
+		}
+		endPreprocess()
+		if reqData.Count > 1 {
+			w.Header().Set("X-Session-ID", sessionID)
+			writeVariations(s, w, r, timing, variationJob{
+				pipeline:    "generate",
+				kind:        usage.KindGeneration,
+				quota:       quota,
+				billable:    billable,
+				sessionID:   sessionID,
+				sessionData: sessionData,
+				count:       reqData.Count,
+				hookReq:     hookReq,
+				input:       input,
+				failure:     "Failed to generate initial image.",
+			})
+			return
+		}
+		endImage := timing.Start(metrics.StageImage)
+		generatedImg, generatedMimeType, err := s.Gemini.GenerateImage(r.Context(), input.Data, input.MimeType, sessionData.RequestData.EventType, sessionData.RequestData.Venue, sessionData.RequestData.Theme, sessionData.Styles[0].Description, imageOptions(sessionData.RequestData), sessionData.References...)
+		s.Status.Observe(r.Context(), status.ComponentGemini, err)
 		if err != nil {
 			s.Logger.Error("Failed to generate initial image via Gemini", "error", err)
-			http.Error(w, "Failed to generate initial image.", http.StatusInternalServerError)
+			writeImageError(s, w, r, err, sessionID, sessionData.Styles, 0, "Failed to generate initial image.")
 			return
 		}
+		endImage()
+		endPostprocess := timing.Start(metrics.StagePostprocess)
+		generatedImg, generatedMimeType = frameGenerated(s, sessionData.RequestData, generatedImg, generatedMimeType)
+		output, err := s.Hooks.Post(r.Context(), hookReq, hooks.Image{Data: generatedImg, MimeType: generatedMimeType})
+		if err != nil {
+			writeHookError(s, w, r, err)
+			return
+		}
+		generatedImg, generatedMimeType = output.Data, output.MimeType
+		meta := generationMetadata(s, r, sessionID, sessionData.RequestData, hookReq.Style)
+		generatedImg = tagImage(s, r, generatedImg, generatedMimeType, meta)
+		endPostprocess()
+
+		record := quota.Record(r.Context(), usage.KindGeneration)
+		if billable {
+			reportBillableUsage(s, clientKey(r), record)
+		}
+
+		// The look is stored while a constrained client's smaller render is
+		// made; the stored look keeps the original
+		var lookID string
+		persist.Go(func() error {
+			defer timing.Start(metrics.StageStorage)()
+			lookID = recordLook(s, r, sessionID, sessionData, sessionData.Styles[0].Description, generatedImg, generatedMimeType)
+			return nil
+		})
+		endPostprocess = timing.Start(metrics.StagePostprocess)
+		responseImg, responseMimeType := adaptImage(s, w, r, generatedImg, generatedMimeType)
+		if len(responseImg) != len(generatedImg) {
+			// A smaller or converted render is a new file without the metadata
+			responseImg = tagImage(s, r, responseImg, responseMimeType, meta)
+		}
+		endPostprocess()
+		persist.Wait()
+		finishTiming(s, w, "generate", timing)
 
 		// 6. Write the successful response with the first image and session ID
-		w.Header().Set("Content-Type", generatedMimeType)
 		w.Header().Set("X-Session-ID", sessionID) // Return session ID in header
-		w.WriteHeader(http.StatusOK)
-		w.Write(generatedImg)
+		resp := models.ImageResponse{
+			SessionID: sessionID,
+			LookID:    lookID,
+			Styles:    sessionData.Styles,
+		}
+		// The stored look is only the image sent if no lower quality or AVIF
+		// render replaced it, which always comes out smaller.
+		if len(responseImg) == len(generatedImg) && sessionData.E2EEKeyID == "" {
+			signLookURL(s, &resp, generatedMimeType, downloadDisposition(r, sessionData.RequestData.EventType, resp.Styles[resp.StyleIndex].Description, generatedMimeType))
+		}
+		writeImage(s, w, r, responseImg, responseMimeType, downloadDisposition(r, sessionData.RequestData.EventType, resp.Styles[resp.StyleIndex].Description, responseMimeType), resp)
+	}
+}
+
+// errNoStyles is returned by suggestStyles when Gemini suggests no styles.
+var errNoStyles = errors.New("no style suggestions returned")
+
+// suggestStyles returns the style suggestions for the event of req, from the
+// preset cache if warm, otherwise from Gemini (text-only call). Suggestions
+// narrowed to styleTags, tailored to the wearer, the photo's coloring or the
+// weather, or translated are not cached, since presets hold each event's
+// plain English suggestions.
+func suggestStyles(ctx context.Context, s *server.Server, req models.GenerateRequest, coloring palette.Analysis) ([]models.Style, error) {
+	preset := presets.Preset{EventType: req.EventType, Venue: req.Venue, Theme: req.Theme}
+	weather := eventWeather(ctx, s, req)
+	count := styleCount(s, req)
+	// The preset cache only keeps suggestions of the default count
+	plain := len(req.StyleTags) == 0 && req.Language == "" && wearer(req) == prompt.Wearer{} && weather == "" &&
+		coloring.SkinTone == "" && len(coloring.Dominant) == 0 && count == int(s.Config().Suggestions.StyleCount)
+	if styles, cached := s.Presets.Get(preset); cached && len(styles) > 0 && plain {
+		s.Logger.Info("Using cached style suggestions", "preset", preset)
+		return styles, nil
+	}
+	styles, err := s.Gemini.GetStyleSuggestions(ctx, req.EventType, req.Venue, req.Theme, gemini.SuggestionOptions{
+		Count:    count,
+		Tags:     req.StyleTags,
+		Language: req.Language,
+		Wearer:   wearer(req),
+		Coloring: coloringPrompt(coloring),
+		Weather:  weather,
+	})
+	if ctx.Err() != nil {
+		// The request was abandoned, which says nothing about Gemini's health
+		return nil, ctx.Err()
+	}
+	s.Status.Observe(ctx, status.ComponentGemini, err)
+	if err != nil {
+		s.Logger.Error("Failed to get style suggestions", "error", err)
+		return nil, err
+	}
+	if len(styles) == 0 {
+		s.Logger.Error("No style suggestions returned")
+		return nil, errNoStyles
+	}
+	if plain {
+		s.Presets.Put(preset, styles)
+	}
+	return styles, nil
+}
+
+// saveSessionRecord persists a new session's record, which keeps its name and
+// notes after the session expires, and appends its ID to session.log for easy
+// access. Failures are logged but do not fail the request.
+func saveSessionRecord(s *server.Server, r *http.Request, sessionID, owner string, req models.GenerateRequest) {
+	f, err := os.OpenFile("session.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		s.Logger.Error("Failed to open session log file", "error", err)
+	} else {
+		if _, err := f.WriteString(sessionID + "\n"); err != nil {
+			s.Logger.Error("Failed to write session ID to log file", "error", err)
+		}
+		f.Close()
+	}
+	if err := s.Sessions.Create(r.Context(), &sessions.Record{
+		ID:        sessionID,
+		Owner:     owner,
+		Name:      req.Name,
+		Notes:     req.Notes,
+		EventType: req.EventType,
+		Venue:     req.Venue,
+		Theme:     req.Theme,
+	}); err != nil {
+		s.Logger.Error("Failed to save session record", "sessionID", sessionID, "error", err)
 	}
 }
 
@@ -148,63 +452,172 @@ func GenerateHandler(s *server.Server) http.HandlerFunc {
 func SwapStyleHandler(s *server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed")
 			return
 		}
 
+		timing := metrics.NewTiming()
 		sessionID := r.Header.Get("X-Session-ID")
 		if sessionID == "" {
 			s.Logger.Error("Missing X-Session-ID header")
-			http.Error(w, "Missing X-Session-ID header.", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
+			return
+		}
+		if !checkFormat(s, w, r) {
 			return
 		}
 
+		quota, billable, ok := checkQuota(s, w, r)
+		if !ok {
+			return
+		}
+		defer quota.Release()
+		defer s.Activity.Begin("swap")()
+
 		var swapReq models.SwapStyleRequest
 		if err := json.NewDecoder(r.Body).Decode(&swapReq); err != nil {
 			s.Logger.Error("Failed to decode swap style request", "error", err)
-			http.Error(w, "Invalid request body.", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		if err := validateCount(swapReq.Count); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if !checkVariationQuota(s, w, r, quota, billable, swapReq.Count) {
 			return
 		}
 
-		s.CacheMutex.Lock()
-		sessionData, found := s.SessionCache[sessionID]
-		s.CacheMutex.Unlock()
+		sessionData, found := s.CachedSession(sessionID)
 
 		if !found {
 			s.Logger.Error("Session data not found", "sessionID", sessionID)
-			http.Error(w, "Session expired or invalid.", http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
 			return
 		}
 
 		s.Logger.Info("Found session data", "sessionID", sessionID, "styles", sessionData.Styles, "stylesCount", len(sessionData.Styles), "mimeType", sessionData.MimeType, "requestData", sessionData.RequestData)
 
-		if swapReq.StyleIndex < 0 || swapReq.StyleIndex >= len(sessionData.Styles) {
+		if swapReq.StyleText != "" {
+			style, err := sanitizeStyleText(swapReq.StyleText)
+			if err != nil {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+				return
+			}
+			swapReq.StyleIndex, sessionData.Styles, err = addSessionStyle(s, sessionID, style)
+			switch {
+			case errors.Is(err, errSessionExpired):
+				apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+				return
+			case err != nil:
+				apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Too many custom styles for this session.",
+					map[string]any{"maxStyles": maxSessionStyles})
+				return
+			}
+			s.Logger.Info("Using custom style", "sessionID", sessionID, "styleIndex", swapReq.StyleIndex)
+		} else if swapReq.StyleID != "" {
+			swapReq.StyleIndex = slices.IndexFunc(sessionData.Styles, func(style models.Style) bool { return style.ID == swapReq.StyleID })
+			if swapReq.StyleIndex < 0 {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Unknown style ID for this session.")
+				return
+			}
+		} else if swapReq.StyleIndex < 0 || swapReq.StyleIndex >= len(sessionData.Styles) {
 			s.Logger.Error("Invalid style index", "sessionID", sessionID, "styleIndex", swapReq.StyleIndex, "numStyles", len(sessionData.Styles))
-			http.Error(w, "Invalid style index.", http.StatusBadRequest)
+			apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid style index.",
+				map[string]any{"styleCount": len(sessionData.Styles)})
 			return
 		}
 
-		// Generate the new image using the selected style
-		generatedImg, generatedMimeType, err := gemini.GenerateImage(
+		// Generate the new image using the selected style, running any image hooks around it
+		endPreprocess := timing.Start(metrics.StagePreprocess)
+		photo, err := sessionImage(r.Context(), s, sessionData)
+		if err != nil {
+			writeE2EEError(s, w, r, err)
+			return
+		}
+		hookReq := hookRequest(r, sessionID, sessionData, sessionData.Styles[swapReq.StyleIndex].Description)
+		input, err := s.Hooks.Pre(r.Context(), hookReq, photo)
+		if err != nil {
+			writeHookError(s, w, r, err)
+			return
+		}
+		endPreprocess()
+		if swapReq.Count > 1 {
+			writeVariations(s, w, r, timing, variationJob{
+				pipeline:    "swap",
+				kind:        usage.KindSwap,
+				quota:       quota,
+				billable:    billable,
+				sessionID:   sessionID,
+				sessionData: sessionData,
+				styleIndex:  swapReq.StyleIndex,
+				count:       swapReq.Count,
+				hookReq:     hookReq,
+				input:       input,
+				failure:     "Failed to generate swapped image.",
+			})
+			return
+		}
+		endImage := timing.Start(metrics.StageImage)
+		generatedImg, generatedMimeType, err := s.Gemini.GenerateImage(
 			r.Context(),
-			s.Logger,
-			sessionData.ImageData,
-			sessionData.MimeType,
+			input.Data,
+			input.MimeType,
 			sessionData.RequestData.EventType,
 			sessionData.RequestData.Venue,
 			sessionData.RequestData.Theme,
-			sessionData.Styles[swapReq.StyleIndex],
+			sessionData.Styles[swapReq.StyleIndex].Description,
+			imageOptions(sessionData.RequestData),
+			sessionData.References...,
 		)
+		s.Status.Observe(r.Context(), status.ComponentGemini, err)
 		if err != nil {
 			s.Logger.Error("Failed to generate swapped image via Gemini", "error", err)
-			http.Error(w, "Failed to generate swapped image.", http.StatusInternalServerError)
+			writeImageError(s, w, r, err, sessionID, sessionData.Styles, swapReq.StyleIndex, "Failed to generate swapped image.")
+			return
+		}
+		endImage()
+		endPostprocess := timing.Start(metrics.StagePostprocess)
+		generatedImg, generatedMimeType = frameGenerated(s, sessionData.RequestData, generatedImg, generatedMimeType)
+		output, err := s.Hooks.Post(r.Context(), hookReq, hooks.Image{Data: generatedImg, MimeType: generatedMimeType})
+		if err != nil {
+			writeHookError(s, w, r, err)
 			return
 		}
+		generatedImg, generatedMimeType = output.Data, output.MimeType
+		meta := generationMetadata(s, r, sessionID, sessionData.RequestData, hookReq.Style)
+		generatedImg = tagImage(s, r, generatedImg, generatedMimeType, meta)
+		endPostprocess()
+
+		record := quota.Record(r.Context(), usage.KindSwap)
+		if billable {
+			reportBillableUsage(s, clientKey(r), record)
+		}
+
+		endStorage := timing.Start(metrics.StageStorage)
+		lookID := recordLook(s, r, sessionID, sessionData, sessionData.Styles[swapReq.StyleIndex].Description, generatedImg, generatedMimeType)
+		endStorage()
+		// Constrained clients get a smaller render; the stored look keeps the original
+		endPostprocess = timing.Start(metrics.StagePostprocess)
+		responseImg, responseMimeType := adaptImage(s, w, r, generatedImg, generatedMimeType)
+		if len(responseImg) != len(generatedImg) {
+			// A smaller or converted render is a new file without the metadata
+			responseImg = tagImage(s, r, responseImg, responseMimeType, meta)
+		}
+		endPostprocess()
+		finishTiming(s, w, "swap", timing)
 
 		// Write the successful response
-		w.Header().Set("Content-Type", generatedMimeType)
-		w.WriteHeader(http.StatusOK)
-		w.Write(generatedImg)
+		resp := models.ImageResponse{
+			SessionID:  sessionID,
+			LookID:     lookID,
+			Styles:     sessionData.Styles,
+			StyleIndex: swapReq.StyleIndex,
+		}
+		if len(responseImg) == len(generatedImg) && sessionData.E2EEKeyID == "" {
+			signLookURL(s, &resp, generatedMimeType, downloadDisposition(r, sessionData.RequestData.EventType, resp.Styles[resp.StyleIndex].Description, generatedMimeType))
+		}
+		writeImage(s, w, r, responseImg, responseMimeType, downloadDisposition(r, sessionData.RequestData.EventType, resp.Styles[resp.StyleIndex].Description, responseMimeType), resp)
 	}
 }
 
@@ -212,28 +625,200 @@ func SwapStyleHandler(s *server.Server) http.HandlerFunc {
 func GetStylesHandler(s *server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		sessionID := r.Header.Get("X-Session-ID")
 		if sessionID == "" {
 			s.Logger.Error("Missing X-Session-ID header")
-			http.Error(w, "Missing X-Session-ID header.", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
 			return
 		}
 
-		s.CacheMutex.Lock()
-		sessionData, found := s.SessionCache[sessionID]
-		s.CacheMutex.Unlock()
+		sessionData, found := s.CachedSession(sessionID)
 
 		if !found {
 			s.Logger.Error("Session data not found for styles request", "sessionID", sessionID)
-			http.Error(w, "Session expired or invalid.", http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+
+		// ?tag= and ?color= narrow the list; repeated values must all match
+		query := r.URL.Query()
+		tags, err := normalizeStyleTags(query["tag"])
+		if err != nil {
+			apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error(),
+				map[string]any{"tags": styleTags})
 			return
 		}
+		styles := sessionData.Styles
+		if len(tags) > 0 || len(query["color"]) > 0 {
+			styles = filterStyles(styles, tags, query["color"])
+		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(sessionData.Styles)
+		json.NewEncoder(w).Encode(styles)
 	}
-}
\ No newline at end of file
+}
+
+// finishTiming reports a completed generation's stage timings to the client in
+// the Server-Timing header and adds them to the stage histograms.
+func finishTiming(s *server.Server, w http.ResponseWriter, pipeline string, t *metrics.Timing) {
+	timing := t.ServerTiming()
+	w.Header().Set("Server-Timing", timing)
+	s.Stages.Record(pipeline, t)
+	s.Logger.Info("Generation timing", "pipeline", pipeline, "timing", timing)
+}
+
+// MetricsHandler handles GET /metrics, serving the stage histograms in the
+// Prometheus text format.
+func MetricsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := s.Stages.WritePrometheus(w); err != nil {
+			s.Logger.Error("Failed to write metrics", "error", err)
+			return
+		}
+		writeCacheMetrics(w, s.CacheStats())
+	}
+}
+
+// writeCacheMetrics writes the session cache counters in the Prometheus text
+// exposition format.
+func writeCacheMetrics(w io.Writer, stats server.CacheStats) {
+	for _, m := range []struct {
+		name, kind, help string
+		value            int64
+	}{
+		{"dreswap_session_cache_entries", "gauge", "Sessions held in memory.", stats.Entries},
+		{"dreswap_session_cache_bytes", "gauge", "Size of the uploaded images held by cached sessions.", stats.Bytes},
+		{"dreswap_session_cache_creations_total", "counter", "Sessions cached.", stats.Creations},
+		{"dreswap_session_cache_hits_total", "counter", "Session lookups that found the session.", stats.Hits},
+		{"dreswap_session_cache_misses_total", "counter", "Session lookups for an expired or unknown session.", stats.Misses},
+		{"dreswap_session_cache_evictions_total", "counter", "Sessions evicted from memory.", stats.Evictions},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+}
+
+// geminiError maps a failed Gemini call to the response status, code and
+// message. Errors without a more specific meaning get a 500 with message.
+func geminiError(s *server.Server, err error, message string) (int, string, string) {
+	switch {
+	case errors.Is(err, gemini.ErrSafetyBlocked):
+		return http.StatusUnprocessableEntity, apierror.CodeContentRejected,
+			"The image service declined this photo or outfit. Please try a different photo or style."
+	case errors.Is(err, gemini.ErrInvalidImage):
+		return http.StatusBadGateway, apierror.CodeInvalidModelOutput, "The image service returned a damaged image. Please try again."
+	case errors.Is(err, gemini.ErrNoImage):
+		return http.StatusBadGateway, apierror.CodeUpstreamFailed, "The image service returned no image. Please try again."
+	case errors.Is(err, gemini.ErrBadResponse):
+		return http.StatusBadGateway, apierror.CodeInvalidModelOutput, message
+	case errors.Is(err, gemini.ErrAuth):
+		// An operator problem; the client can only wait for it to be fixed.
+		s.Logger.Error("Gemini rejected the API key; check GEMINI_API_KEY", "error", err)
+		return http.StatusServiceUnavailable, apierror.CodeUpstreamFailed, "The image service is unavailable right now. Please try again later."
+	}
+	return http.StatusInternalServerError, apierror.CodeUpstreamFailed, message
+}
+
+// writeGeminiError responds to a failed Gemini call: saturation as a
+// retryable 503, otherwise as mapped by geminiError.
+func writeGeminiError(s *server.Server, w http.ResponseWriter, r *http.Request, err error, message string) {
+	if writeSaturated(w, r, err) {
+		return
+	}
+	status, code, message := geminiError(s, err, message)
+	apierror.Write(w, r, status, code, message)
+}
+
+// writeImage writes a generated image as JSON, multipart or raw, depending on
+// what the client accepts. disposition is the Content-Disposition of a raw
+// image, if any.
+func writeImage(s *server.Server, w http.ResponseWriter, r *http.Request, img []byte, mimeType, disposition string, resp models.ImageResponse) {
+	w.Header().Set("X-Look-ID", resp.LookID)
+	resp.MimeType = mimeType
+	switch {
+	case wantsJSON(r):
+		if resp.ImageURL == "" {
+			resp.Image = base64.StdEncoding.EncodeToString(img)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+		return
+	case accepts(r, "multipart/mixed"):
+		if err := writeMultipartImage(w, img, resp); err != nil {
+			s.Logger.Error("Failed to write multipart image response", "error", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", mimeType)
+	if disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(img)
+}
+
+// signLookURL sets resp's ImageURL to a signed URL of the look's stored image,
+// if images are kept in object storage. The URL serves the image with
+// disposition as its Content-Disposition, if set.
+func signLookURL(s *server.Server, resp *models.ImageResponse, mimeType, disposition string) {
+	if s.Objects == nil || resp.LookID == "" {
+		return
+	}
+	ttl := s.Config().Store.Objects.URLTTL
+	expires := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	resp.ImageURL = s.Objects.SignedURL(looks.ImageNamespace, resp.LookID, mimeType, disposition, ttl)
+	resp.ImageURLExpiresAt = &expires
+}
+
+// writeMultipartImage writes resp as a JSON part followed by the image part.
+func writeMultipartImage(w http.ResponseWriter, img []byte, resp models.ImageResponse) error {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusOK)
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(part).Encode(resp); err != nil {
+		return err
+	}
+	part, err = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {resp.MimeType}})
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(img); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// writeImageError responds to a failed image call. Saturation is reported as
+// a retryable 503. Otherwise, with the text-only fallback enabled, the client
+// gets the session's styles and the requested outfit as a partial result;
+// without it, the error as mapped by geminiError.
+func writeImageError(s *server.Server, w http.ResponseWriter, r *http.Request, err error, sessionID string, styles []models.Style, index int, message string) {
+	if writeSaturated(w, r, err) {
+		return
+	}
+	status, code, message := geminiError(s, err, message)
+	if !s.Config().Gemini.TextFallback {
+		apierror.Write(w, r, status, code, message)
+		return
+	}
+
+	s.Logger.Info("Returning text-only result", "sessionID", sessionID, "styleIndex", index, "code", code)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Session-ID", sessionID)
+	w.Header().Set("X-Partial-Result", "text-only")
+	json.NewEncoder(w).Encode(models.PartialResultResponse{
+		SessionID:  sessionID,
+		Styles:     styles,
+		StyleIndex: index,
+		Outfit:     styles[index].Description,
+		Failure:    apierror.New(r, code, message),
+	})
+}
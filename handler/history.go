@@ -0,0 +1,166 @@
+// handler/history.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// HistoryHandler handles GET /api/v1/looks, listing the caller's looks newest
+// first. ?archived=true lists the archived looks instead, and each ?tag=
+// restricts the list to looks with that tag.
+func HistoryHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archived := r.URL.Query().Get("archived") == "true"
+		tags, err := looks.NormalizeTags(r.URL.Query()["tag"])
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		history, err := s.Looks.History(r.Context(), ownerKey(r), archived, tags)
+		if err != nil {
+			s.Logger.Error("Failed to load history", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load history.")
+			return
+		}
+
+		out := make([]models.LookResponse, 0, len(history))
+		for _, l := range history {
+			out = append(out, lookResponse(l))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// GenerationHistoryHandler handles GET /api/v1/history, listing every
+// image generated in the session of the X-Session-ID header, oldest first,
+// with the style it shows and where to download it, for a strip of earlier
+// results in the UI.
+func GenerationHistoryHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get("X-Session-ID")
+		if sessionID == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
+			return
+		}
+		sessionData, found := s.CachedSession(sessionID)
+		if !found {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+		sessionLooks, err := s.Looks.BySession(r.Context(), ownerKey(r), sessionID)
+		if err != nil {
+			s.Logger.Error("Failed to list session looks", "sessionID", sessionID, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load history.")
+			return
+		}
+
+		descriptions := models.Descriptions(sessionData.Styles)
+		resp := models.HistoryResponse{SessionID: sessionID, Images: make([]models.HistoryEntry, 0, len(sessionLooks))}
+		for _, l := range sessionLooks {
+			entry := models.HistoryEntry{
+				LookID:      l.ID,
+				Style:       l.Style,
+				StyleIndex:  slices.Index(descriptions, l.Style),
+				MimeType:    l.MimeType,
+				RefinedFrom: l.RefinedFrom,
+				Instruction: l.Instruction,
+				CreatedAt:   l.CreatedAt,
+			}
+			if sessionData.E2EEKeyID == "" {
+				entry.ImageURL = publicURL(s, r, l.Owner, "/api/v1/looks/"+l.ID+"/image")
+			}
+			resp.Images = append(resp.Images, entry)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// bulkJobRetryAfter is how long a client with too many running bulk jobs is
+// asked to wait.
+const bulkJobRetryAfter = 5 * time.Second
+
+func bulkJobResponse(j *looks.BulkJob) models.BulkJobResponse {
+	return models.BulkJobResponse{
+		ID:         j.ID,
+		Action:     j.Action,
+		Status:     j.Status,
+		Total:      j.Total,
+		Processed:  j.Processed,
+		Failed:     j.Failed,
+		CreatedAt:  j.CreatedAt,
+		FinishedAt: j.FinishedAt,
+		Error:      j.Error,
+	}
+}
+
+// BulkLooksHandler handles POST /api/v1/looks/bulk, starting an asynchronous
+// archive, unarchive or delete of the caller's looks. It responds 202 with the
+// job, whose progress is polled at the Location URL.
+func BulkLooksHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.BulkLooksRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		sel := looks.BulkSelection{IDs: req.LookIDs}
+		if req.From != nil {
+			sel.From = *req.From
+		}
+		if req.To != nil {
+			sel.To = *req.To
+		}
+
+		job, err := s.Looks.StartBulk(ownerKey(r), req.Action, sel)
+		if errors.Is(err, looks.ErrInvalidBulkAction) || errors.Is(err, looks.ErrInvalidBulkSelection) {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, looks.ErrTooManyBulkJobs) {
+			t := throttled(r, apierror.CodeRateLimited, reasonRateLimit,
+				"You already have bulk jobs running. Wait for one to finish first.",
+				bulkJobRetryAfter)
+			writeThrottled(w, http.StatusTooManyRequests, t, t)
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to start bulk job", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to start bulk job.")
+			return
+		}
+		s.Logger.Info("Started bulk job", "jobID", job.ID, "action", job.Action)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v1/looks/bulk/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(bulkJobResponse(job))
+	}
+}
+
+// BulkJobHandler handles GET /api/v1/looks/bulk/{id}, reporting a bulk job's
+// progress. Finished jobs are kept for an hour.
+func BulkJobHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := s.Looks.BulkJob(ownerKey(r), r.PathValue("id"))
+		if err != nil {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Bulk job not found.")
+			return
+		}
+		if job.Status == looks.BulkRunning {
+			w.Header().Set("Retry-After", "1")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bulkJobResponse(job))
+	}
+}
@@ -0,0 +1,34 @@
+// handler/hooks.go
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/hooks"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// hookRequest describes a generation to the image hooks.
+func hookRequest(r *http.Request, sessionID string, sd server.SessionData, style string) *hooks.Request {
+	return &hooks.Request{
+		Owner:     sd.Owner,
+		SessionID: sessionID,
+		EventType: sd.RequestData.EventType,
+		Venue:     sd.RequestData.Venue,
+		Theme:     sd.RequestData.Theme,
+		Style:     style,
+	}
+}
+
+// writeHookError maps image hook failures to HTTP responses.
+func writeHookError(s *server.Server, w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, hooks.ErrRejected) {
+		s.Logger.Warn("Image rejected by hook", "error", err)
+		apierror.Write(w, r, http.StatusUnprocessableEntity, apierror.CodeContentRejected, "The image was rejected by content policy.")
+		return
+	}
+	s.Logger.Error("Image hook failed", "error", err)
+	apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to process image.")
+}
@@ -0,0 +1,41 @@
+// handler/language.go
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+var errLanguage = errors.New(`language must be a BCP 47 tag, such as "es" or "pt-BR"`)
+
+// suggestionLanguage returns the BCP 47 tag of the language to write a
+// generate request's style suggestions in: the requested one, or else the
+// client's preferred one from Accept-Language. English is returned as "",
+// since suggestions are written in English anyway.
+func suggestionLanguage(r *http.Request, requested string) (string, error) {
+	if requested != "" {
+		tag, err := language.Parse(requested)
+		if err != nil {
+			return "", errLanguage
+		}
+		return nonEnglish(tag), nil
+	}
+	// A malformed header is ignored rather than failing the request
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return "", nil
+	}
+	return nonEnglish(tags[0]), nil
+}
+
+// nonEnglish returns tag as a string, or "" if it is English, undetermined
+// or a wildcard.
+func nonEnglish(tag language.Tag) string {
+	switch base, _ := tag.Base(); base.String() {
+	case "en", "und", "mul":
+		return ""
+	}
+	return tag.String()
+}
@@ -0,0 +1,326 @@
+// handler/looks.go
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+)
+
+const (
+	defaultSimilarLimit = 10
+	maxSimilarLimit     = 50
+)
+
+// recordLook stores a successfully generated look and its image, and computes its
+// style embedding in the background. Images from end-to-end encrypted sessions
+// are not stored. It returns the new look's ID, or "" if it could not be stored.
+func recordLook(s *server.Server, r *http.Request, sessionID string, sessionData server.SessionData, style string, img []byte, mimeType string) string {
+	return saveLook(s, r, newLook(r, sessionID, sessionData, style, mimeType), sessionData, img)
+}
+
+// newLook describes a look generated in a session, ready for saveLook.
+func newLook(r *http.Request, sessionID string, sessionData server.SessionData, style, mimeType string) *looks.Look {
+	return &looks.Look{
+		Owner:     sessionData.Owner,
+		SessionID: sessionID,
+		EventType: sessionData.RequestData.EventType,
+		Venue:     sessionData.RequestData.Venue,
+		Theme:     sessionData.RequestData.Theme,
+		Style:     style,
+		MimeType:  mimeType,
+	}
+}
+
+// saveLook is recordLook for a look built by newLook.
+func saveLook(s *server.Server, r *http.Request, look *looks.Look, sessionData server.SessionData, img []byte) string {
+	if err := s.Looks.Create(r.Context(), look); err != nil {
+		s.Logger.Error("Failed to record look", "sessionID", look.SessionID, "error", err)
+		return ""
+	}
+	if sessionData.E2EEKeyID == "" {
+		if err := s.Looks.SaveImage(r.Context(), look.ID, img); err != nil {
+			s.Logger.Error("Failed to store look image", "lookID", look.ID, "error", err)
+		}
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		embedding, err := s.Gemini.EmbedText(ctx, look.Style)
+		if err != nil {
+			s.Logger.Error("Failed to embed look style", "lookID", look.ID, "error", err)
+			return
+		}
+		if err := s.Looks.SetEmbedding(ctx, look.ID, embedding); err != nil {
+			s.Logger.Error("Failed to save look embedding", "lookID", look.ID, "error", err)
+		}
+	}()
+	return look.ID
+}
+
+// SimilarLooksHandler handles POST /api/v1/looks/similar, finding previous looks
+// whose style is close to a given description or look.
+func SimilarLooksHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.SimilarLooksRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.Logger.Error("Failed to decode similar looks request", "error", err)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		if req.Limit <= 0 {
+			req.Limit = defaultSimilarLimit
+		}
+		req.Limit = min(req.Limit, maxSimilarLimit)
+
+		owner := ownerKey(r)
+		var query []float32
+		switch {
+		case req.LookID != "":
+			look, err := s.Looks.Get(r.Context(), req.LookID)
+			if errors.Is(err, looks.ErrNotFound) || (err == nil && !look.Public && look.Owner != owner) {
+				apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Look not found.")
+				return
+			}
+			if err != nil {
+				s.Logger.Error("Failed to load look", "lookID", req.LookID, "error", err)
+				apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load look.")
+				return
+			}
+			if len(look.Embedding) == 0 {
+				apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "This look is still being indexed. Please try again shortly.")
+				return
+			}
+			query = look.Embedding
+		case req.StyleText != "":
+			// Only a text query calls the model, so only it is metered
+			quota, billable, ok := checkQuota(s, w, r)
+			if !ok {
+				return
+			}
+			defer quota.Release()
+			var err error
+			query, err = s.Gemini.EmbedText(r.Context(), req.StyleText)
+			if err != nil {
+				s.Logger.Error("Failed to embed query style", "error", err)
+				writeGeminiError(s, w, r, err, "Failed to search looks.")
+				return
+			}
+			record := quota.Record(r.Context(), usage.KindSearch)
+			if billable {
+				reportBillableUsage(s, clientKey(r), record)
+			}
+		default:
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Either styleText or lookId is required.")
+			return
+		}
+
+		var tags []string
+		if req.Tag != "" {
+			tag, err := looks.NormalizeTag(req.Tag)
+			if err != nil {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+				return
+			}
+			tags = []string{tag}
+		}
+		filter := func(l *looks.Look) bool {
+			if l.ID == req.LookID || !l.HasTags(tags) {
+				return false
+			}
+			switch req.Scope {
+			case "mine":
+				return l.Owner == owner
+			case "public":
+				return l.Public
+			default:
+				return l.Owner == owner || l.Public
+			}
+		}
+		matches, err := s.Looks.Similar(r.Context(), query, req.Limit, filter)
+		if err != nil {
+			s.Logger.Error("Failed to search similar looks", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to search looks.")
+			return
+		}
+
+		out := make([]models.LookResponse, 0, len(matches))
+		for _, m := range matches {
+			resp := lookResponse(m.Look)
+			resp.Score = m.Score
+			out = append(out, resp)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// lookResponse converts a stored look to its public API representation.
+func lookResponse(l *looks.Look) models.LookResponse {
+	resp := models.LookResponse{
+		ID:          l.ID,
+		EventType:   l.EventType,
+		Venue:       l.Venue,
+		Theme:       l.Theme,
+		Style:       l.Style,
+		Public:      l.Public,
+		Rating:      l.Rating,
+		Tags:        l.Tags,
+		CreatedAt:   l.CreatedAt,
+		ArchivedAt:  l.ArchivedAt,
+		RefinedFrom: l.RefinedFrom,
+		Instruction: l.Instruction,
+	}
+	if g := l.Grade; g != nil {
+		resp.Grade = &models.RealismGrade{
+			Score:       g.Score,
+			Summary:     g.Summary,
+			Matches:     g.Matches,
+			Differences: g.Differences,
+			GradedAt:    g.GradedAt,
+		}
+	}
+	return resp
+}
+
+// RateLookHandler handles POST /api/v1/looks/{id}/rating, recording the owner's
+// 1-5 star rating. Ratings feed the best-rated trends.
+func RateLookHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.RateLookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Rating < 1 || req.Rating > 5 {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "A rating between 1 and 5 is required.")
+			return
+		}
+		id := r.PathValue("id")
+		if _, ok := ownedLook(s, w, r, id); !ok {
+			return
+		}
+
+		look, err := s.Looks.Rate(r.Context(), id, req.Rating)
+		if err != nil {
+			s.Logger.Error("Failed to rate look", "lookID", id, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to rate look.")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lookResponse(look))
+	}
+}
+
+// GradeEventPhotoHandler handles POST /api/v1/looks/{id}/event-photo. The owner
+// uploads a photo from the actual event as multipart "image", and Gemini grades
+// how close the real outfit came to the generated look.
+func GradeEventPhotoHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		look, ok := ownedLook(s, w, r, id)
+		if !ok {
+			return
+		}
+		quota, billable, ok := checkQuota(s, w, r)
+		if !ok {
+			return
+		}
+		defer quota.Release()
+		lookImg, lookMime, err := s.Looks.Image(r.Context(), id)
+		if errors.Is(err, looks.ErrNoImage) {
+			apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "This look has no stored image to compare with.")
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to load look image", "lookID", id, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load look.")
+			return
+		}
+
+		maxUploadSize := s.Config().Server.MaxUploadBytes
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidImage,
+				fmt.Sprintf("An image of at most %dMB is required.", maxUploadSize>>20),
+				map[string]any{"maxBytes": maxUploadSize})
+			return
+		}
+		defer file.Close()
+		photo, err := io.ReadAll(file)
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidImage, "Could not read image data.")
+			return
+		}
+		photo, photoMime, err := readPhoto(r.Context(), s, photo)
+		if err != nil {
+			writePhotoError(s, w, r, err)
+			return
+		}
+		photo, photoMime = preprocessPhoto(s, photo, photoMime)
+
+		grade, err := s.Gemini.GradeRealism(r.Context(), lookImg, lookMime, photo, photoMime, look.EventType, look.Venue, look.Theme, look.Style)
+		s.Status.Observe(r.Context(), status.ComponentGemini, err)
+		if err != nil {
+			s.Logger.Error("Failed to grade event photo", "lookID", id, "error", err)
+			writeGeminiError(s, w, r, err, "Failed to grade event photo.")
+			return
+		}
+		record := quota.Record(r.Context(), usage.KindGrade)
+		if billable {
+			reportBillableUsage(s, clientKey(r), record)
+		}
+
+		look, err = s.Looks.SetGrade(r.Context(), id, photo, &looks.RealismGrade{
+			Score:       grade.Score,
+			Summary:     grade.Summary,
+			Matches:     grade.Matches,
+			Differences: grade.Differences,
+			MimeType:    photoMime,
+			GradedAt:    time.Now().UTC(),
+		})
+		if err != nil {
+			s.Logger.Error("Failed to save event photo grade", "lookID", id, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save grade.")
+			return
+		}
+		s.Logger.Info("Graded event photo", "lookID", id, "score", grade.Score)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lookResponse(look))
+	}
+}
+
+// EventPhotoHandler handles GET /api/v1/looks/{id}/event-photo, returning the
+// photo from the actual event that the owner uploaded for grading.
+func EventPhotoHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if _, ok := ownedLook(s, w, r, id); !ok {
+			return
+		}
+		data, mimeType, err := s.Looks.EventPhoto(r.Context(), id)
+		if errors.Is(err, looks.ErrNotFound) || errors.Is(err, looks.ErrNoImage) {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "This look has no event photo.")
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to load event photo", "lookID", id, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load event photo.")
+			return
+		}
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+		w.Write(data)
+	}
+}
@@ -0,0 +1,65 @@
+// handler/metadata.go
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/imagemeta"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/provenance"
+	"github.com/sanjayshr/event-outfitter-backend/requestid"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// generationMetadata describes a generation for the image's metadata, and
+// logs the session and request it refers to so operators can resolve them.
+func generationMetadata(s *server.Server, r *http.Request, sessionID string, req models.GenerateRequest, style string) imagemeta.Fields {
+	meta := imagemeta.Fields{
+		EventType:  req.EventType,
+		Venue:      req.Venue,
+		Theme:      req.Theme,
+		Style:      style,
+		Model:      s.Gemini.ImageModel(),
+		SessionRef: sessionRef(sessionID),
+		RequestID:  requestid.FromRequest(r),
+		CreatedAt:  time.Now(),
+	}
+	if s.Config().ImageMetadata.Enabled {
+		s.Logger.Info("Tagging generated image", "sessionID", sessionID, "sessionRef", meta.SessionRef, "requestID", meta.RequestID, "model", meta.Model)
+	}
+	return meta
+}
+
+// sessionRef identifies a session in image metadata. The session ID itself
+// lets anyone who holds it swap styles, and images get shared, so only the
+// first 16 hex digits of its SHA-256 are written.
+func sessionRef(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// tagImage writes meta into img, if image metadata is enabled, then attaches
+// C2PA content credentials, if configured. Signing comes last, since the
+// credentials cover the exact bytes. A step that fails is skipped.
+func tagImage(s *server.Server, r *http.Request, img []byte, mimeType string, meta imagemeta.Fields) []byte {
+	if s.Config().ImageMetadata.Enabled {
+		tagged, err := imagemeta.Embed(img, mimeType, meta)
+		if err != nil {
+			s.Logger.Warn("Failed to write image metadata", "requestID", meta.RequestID, "error", err)
+		} else {
+			img = tagged
+		}
+	}
+	if s.Provenance != nil {
+		signed, err := s.Provenance.Sign(r.Context(), img, mimeType, provenance.Generation{Model: meta.Model, Title: "DreSwap look"})
+		if err != nil {
+			s.Logger.Warn("Failed to attach content credentials", "requestID", meta.RequestID, "error", err)
+		} else {
+			img = signed
+		}
+	}
+	return img
+}
@@ -0,0 +1,100 @@
+// handler/morestyles.go
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/gemini"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+)
+
+// MoreStylesHandler handles POST /api/v1/styles/more, asking the model for
+// more suggestions for the session's event, as many as its first ones, different from the styles
+// it already has, and appending them to the session. Existing styles keep
+// their indexes, so the new ones can be paged in after them.
+func MoreStylesHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get("X-Session-ID")
+		if sessionID == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
+			return
+		}
+		sessionData, found := s.CachedSession(sessionID)
+		if !found {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+		if len(sessionData.Styles) >= maxSessionStyles {
+			apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Too many styles for this session.",
+				map[string]any{"maxStyles": maxSessionStyles})
+			return
+		}
+		quota, billable, ok := checkQuota(s, w, r)
+		if !ok {
+			return
+		}
+		defer quota.Release()
+		event := sessionData.RequestData
+		styles, err := s.Gemini.GetStyleSuggestions(r.Context(), event.EventType, event.Venue, event.Theme, sessionSuggestionOptions(r.Context(), s, sessionData))
+		s.Status.Observe(r.Context(), status.ComponentGemini, err)
+		if err != nil {
+			s.Logger.Error("Failed to get more style suggestions", "sessionID", sessionID, "error", err)
+			writeGeminiError(s, w, r, err, "Failed to suggest more styles.")
+			return
+		}
+		record := quota.Record(r.Context(), usage.KindSuggestions)
+		if billable {
+			reportBillableUsage(s, clientKey(r), record)
+		}
+		styles = shopStyles(r.Context(), s, cleanSuggestions(styles))
+		added, err := appendSessionStyles(s, sessionID, styles)
+		if err != nil {
+			if errors.Is(err, errTooManyStyles) {
+				apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Too many styles for this session.",
+					map[string]any{"maxStyles": maxSessionStyles})
+				return
+			}
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+		s.Logger.Info("Added style suggestions", "sessionID", sessionID, "suggested", len(styles), "added", len(added))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.MoreStylesResponse{Styles: added})
+	}
+}
+
+// sessionSuggestionOptions are the options of further suggestions for a
+// session: the same as its first ones, different from all its styles and
+// respecting what the user disliked.
+func sessionSuggestionOptions(ctx context.Context, s *server.Server, sessionData server.SessionData) gemini.SuggestionOptions {
+	event := sessionData.RequestData
+	return gemini.SuggestionOptions{
+		Count:    styleCount(s, event),
+		Tags:     event.StyleTags,
+		Exclude:  models.Descriptions(sessionData.Styles),
+		Avoid:    sessionData.Avoid,
+		Language: event.Language,
+		Wearer:   wearer(event),
+		Coloring: coloringPrompt(sessionData.Coloring),
+		Weather:  eventWeather(ctx, s, event),
+	}
+}
+
+// cleanSuggestions cleans the descriptions of further suggestions like the
+// user's own styles, since they are sent to image prompts as they are, and
+// recomputes their IDs.
+func cleanSuggestions(styles []models.Style) []models.Style {
+	for i := range styles {
+		styles[i].Description = cleanPromptText(styles[i].Description)
+		styles[i].ID = models.StyleID(styles[i].Description)
+	}
+	return styles
+}
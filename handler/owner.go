@@ -0,0 +1,79 @@
+// handler/owner.go
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/auth"
+)
+
+// clientTokenHeader carries the secret that ties an anonymous caller to their
+// sessions, looks, shares, short links and uploads. Clients generate one and
+// send it on every request; a caller without one is issued a token by the
+// first request that creates something.
+const clientTokenHeader = "X-Client-Token"
+
+// Accepted client token lengths; 32 base64url characters hold 192 random bits.
+const (
+	minClientToken = 32
+	maxClientToken = 128
+)
+
+// ownerKey identifies who owns what the caller creates: the authenticated
+// identity, or for anonymous callers a hash of their client token. Unlike
+// clientKey, it never falls back to the client IP, which everyone behind the
+// same NAT shares. It is "" for anonymous callers without a valid token, which
+// owns nothing.
+func ownerKey(r *http.Request) string {
+	if id := auth.FromRequest(r); id != nil {
+		return id.Subject
+	}
+	token := r.Header.Get(clientTokenHeader)
+	if !validClientToken(token) {
+		return ""
+	}
+	return tokenOwner(token)
+}
+
+// issueOwner is ownerKey for requests that create something the caller will
+// own. An anonymous caller without a token is given a new one in the
+// X-Client-Token response header, which they must send from then on.
+func issueOwner(w http.ResponseWriter, r *http.Request) string {
+	if owner := ownerKey(r); owner != "" {
+		return owner
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	w.Header().Set(clientTokenHeader, token)
+	return tokenOwner(token)
+}
+
+// tokenOwner is the owner for a client token. Only the hash is stored, so
+// records don't leak the token.
+func tokenOwner(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "client:" + hex.EncodeToString(sum[:16])
+}
+
+// owns reports whether the caller owns something owned by owner.
+func owns(r *http.Request, owner string) bool {
+	key := ownerKey(r)
+	return key != "" && key == owner
+}
+
+func validClientToken(token string) bool {
+	if len(token) < minClientToken || len(token) > maxClientToken {
+		return false
+	}
+	for _, c := range token {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
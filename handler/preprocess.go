@@ -0,0 +1,146 @@
+// handler/preprocess.go
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// preprocessPhoto downscales a photo whose longer side exceeds the configured
+// maximum and re-encodes it as JPEG before it is sent to Gemini, cutting
+// upload time, input tokens and session memory. Photos within the limit are
+// returned unchanged, as is any photo that cannot be processed.
+func preprocessPhoto(s *server.Server, data []byte, mimeType string) ([]byte, string) {
+	cfg := s.Config().Preprocess
+	if cfg.MaxDimension <= 0 {
+		return data, mimeType
+	}
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || max(header.Width, header.Height) <= int(cfg.MaxDimension) {
+		return data, mimeType
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		s.Logger.Warn("Failed to decode photo for preprocessing", "error", err)
+		return data, mimeType
+	}
+
+	// Re-encoding drops the EXIF data, so apply its orientation to the pixels
+	// or phone photos would reach the model sideways.
+	orientation := 1
+	if mimeType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	dst := orient(scaleToFit(src, int(cfg.MaxDimension)), orientation)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: int(cfg.JPEGQuality)}); err != nil {
+		s.Logger.Warn("Failed to re-encode photo", "error", err)
+		return data, mimeType
+	}
+	s.Logger.Info("Downscaled photo", "from", image.Pt(header.Width, header.Height), "to", dst.Bounds().Size(),
+		"originalBytes", len(data), "bytes", buf.Len())
+	return buf.Bytes(), "image/jpeg"
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 if it has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || size < 2 || i+2+size > len(data) {
+			// Start of scan: the metadata segments are over.
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of a TIFF
+// structure, as embedded in a JPEG's Exif segment.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := range entries {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient transforms src so that it displays upright for the given EXIF
+// orientation: 2-4 mirror or rotate by 180 degrees, 5-8 also swap width
+// and height.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, src.RGBAAt(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,126 @@
+// handler/previews.go
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/metrics"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+)
+
+// PreviewsHandler handles POST /api/v1/previews, the first tier of the
+// two-tier pipeline: it renders a low-resolution preview of every style in
+// the session in parallel, using cheaper model settings, so the user can pick
+// one visually before rendering it at full quality with /swap-style.
+// Previews are not stored as looks and post-generation hooks are skipped.
+func PreviewsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timing := metrics.NewTiming()
+		sessionID := r.Header.Get("X-Session-ID")
+		if sessionID == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
+			return
+		}
+
+		quota, billable, ok := checkQuota(s, w, r)
+		if !ok {
+			return
+		}
+		defer quota.Release()
+		defer s.Activity.Begin("previews")()
+
+		sessionData, found := s.CachedSession(sessionID)
+		if !found {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+
+		endPreprocess := timing.Start(metrics.StagePreprocess)
+		photo, err := sessionImage(r.Context(), s, sessionData)
+		if err != nil {
+			writeE2EEError(s, w, r, err)
+			return
+		}
+		endPreprocess()
+
+		cfg := s.Config().Previews
+		previews := make([]models.StylePreview, len(sessionData.Styles))
+		errs := make([]error, len(sessionData.Styles))
+		// The previews render in parallel, so their wall time is reported as the
+		// image stage rather than summing the renders.
+		endImage := timing.Start(metrics.StageImage)
+		var wg sync.WaitGroup
+		for i, style := range models.Descriptions(sessionData.Styles) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				previews[i] = models.StylePreview{StyleIndex: i, StyleID: sessionData.Styles[i].ID, Style: style}
+
+				input, err := s.Hooks.Pre(r.Context(), hookRequest(r, sessionID, sessionData, style), photo)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				// A smaller photo means fewer input tokens; fall back to the original if it cannot be reduced
+				if small, err := reduceImage(input.Data, int(cfg.InputDimension), int(cfg.JPEGQuality)); err == nil && len(small) < len(input.Data) {
+					input.Data, input.MimeType = small, "image/jpeg"
+				}
+
+				img, _, err := s.Gemini.GeneratePreview(r.Context(), input.Data, input.MimeType,
+					sessionData.RequestData.EventType, sessionData.RequestData.Venue, sessionData.RequestData.Theme, style, imageOptions(sessionData.RequestData))
+				s.Status.Observe(r.Context(), status.ComponentGemini, err)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+
+				thumb, err := reduceImage(img, int(cfg.MaxDimension), int(cfg.JPEGQuality))
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				previews[i].MimeType = "image/jpeg"
+				previews[i].Image = base64.StdEncoding.EncodeToString(thumb)
+			}()
+		}
+		wg.Wait()
+		endImage()
+
+		rendered := 0
+		for i, err := range errs {
+			if err == nil {
+				rendered++
+				continue
+			}
+			s.Logger.Warn("Failed to render style preview", "sessionID", sessionID, "styleIndex", i, "error", err)
+			_, code, message := geminiError(s, err, "The preview could not be rendered.")
+			failure := apierror.New(r, code, message)
+			previews[i].Error = &failure
+		}
+		if rendered == 0 {
+			if writeSaturated(w, r, errors.Join(errs...)) {
+				return
+			}
+			apierror.Write(w, r, http.StatusBadGateway, apierror.CodeUpstreamFailed, "No previews could be rendered. Please try again.")
+			return
+		}
+
+		// A batch of previews counts as one operation towards the daily quota
+		record := quota.Record(r.Context(), usage.KindPreviews)
+		if billable {
+			reportBillableUsage(s, clientKey(r), record)
+		}
+		finishTiming(s, w, "previews", timing)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.PreviewsResponse{SessionID: sessionID, Previews: previews})
+	}
+}
@@ -0,0 +1,37 @@
+// handler/promptsuffix.go
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/apikeys"
+	"github.com/sanjayshr/event-outfitter-backend/auth"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// maxPromptSuffixLength caps a generate request's promptSuffix.
+const maxPromptSuffixLength = 1000
+
+// checkPromptSuffix cleans the promptSuffix of req in place and makes sure the
+// caller has the prompt scope to send one, writing an error if not.
+func checkPromptSuffix(s *server.Server, w http.ResponseWriter, r *http.Request, req *models.GenerateRequest) bool {
+	if req.PromptSuffix == "" {
+		return true
+	}
+	if id := auth.FromRequest(r); id == nil || !id.HasScope(apikeys.ScopePrompt) {
+		s.Logger.Warn("Prompt suffix without the prompt scope", "client", clientKey(r))
+		apierror.Write(w, r, http.StatusForbidden, apierror.CodeForbidden, "promptSuffix requires the prompt scope.")
+		return false
+	}
+	req.PromptSuffix = cleanPromptText(req.PromptSuffix)
+	if n := utf8.RuneCountInString(req.PromptSuffix); n > maxPromptSuffixLength {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, fmt.Sprintf("promptSuffix must be at most %d characters", maxPromptSuffixLength))
+		return false
+	}
+	s.Logger.Info("Using prompt suffix", "client", clientKey(r), "promptSuffix", req.PromptSuffix)
+	return true
+}
@@ -0,0 +1,163 @@
+// handler/quality.go
+package handler
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sanjayshr/event-outfitter-backend/imageconv"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Image quality levels returned to clients, reported in X-Image-Quality.
+const (
+	qualityFull = "full"
+	qualityLow  = "low"
+)
+
+// requestedQuality picks the quality of the image returned to the client. An
+// explicit ?quality=low or ?quality=full wins; otherwise clients that send
+// Save-Data: on or a 2G/3G effective connection type (ECT) get a low-quality
+// render.
+func requestedQuality(r *http.Request) string {
+	switch q := r.URL.Query().Get("quality"); q {
+	case qualityLow, qualityFull:
+		return q
+	}
+	if strings.EqualFold(strings.TrimSpace(r.Header.Get("Save-Data")), "on") {
+		return qualityLow
+	}
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get("ECT"))) {
+	case "slow-2g", "2g", "3g":
+		return qualityLow
+	}
+	return qualityFull
+}
+
+// adaptImage returns the image to send to the client, downscaled and
+// re-encoded as JPEG if a low-quality render was requested, then converted to
+// the ?format= the client asked for, or else encoded as AVIF or WebP if the
+// client accepts it and that is smaller. The stored look keeps the original.
+// If the image cannot be reduced or converted, the original is sent.
+func adaptImage(s *server.Server, w http.ResponseWriter, r *http.Request, img []byte, mimeType string) ([]byte, string) {
+	w.Header().Add("Vary", "Save-Data, ECT, Accept")
+	img, mimeType = reduceForClient(s, w, r, img, mimeType)
+	if format := r.URL.Query().Get("format"); format != "" {
+		converted, convertedType, err := transcode(r.Context(), s, img, mimeType, format)
+		if err != nil {
+			s.Logger.Warn("Failed to convert image", "format", format, "error", err)
+			return img, mimeType
+		}
+		return converted, convertedType
+	}
+	if s.AVIFEncoder != nil && acceptsAVIF(r) {
+		if encoded, ok := encodeIfSmaller(s, r, s.AVIFEncoder, img, imageconv.IsAVIF, "AVIF"); ok {
+			return encoded, "image/avif"
+		}
+	}
+	if s.WebPEncoder != nil && accepts(r, "image/webp") {
+		if encoded, ok := encodeIfSmaller(s, r, s.WebPEncoder, img, imageconv.IsWebP, "WebP"); ok {
+			return encoded, "image/webp"
+		}
+	}
+	return img, mimeType
+}
+
+// encodeIfSmaller encodes img with enc and returns the result if it is a
+// valid image and smaller than img.
+func encodeIfSmaller(s *server.Server, r *http.Request, enc *imageconv.Converter, img []byte, valid func([]byte) bool, format string) ([]byte, bool) {
+	encoded, err := enc.Convert(r.Context(), img)
+	if err != nil || !valid(encoded) || len(encoded) >= len(img) {
+		if err != nil {
+			s.Logger.Warn("Failed to encode image", "format", format, "error", err)
+		}
+		return nil, false
+	}
+	s.Logger.Info("Sending encoded image", "format", format, "originalBytes", len(img), "encodedBytes", len(encoded))
+	return encoded, true
+}
+
+// acceptsAVIF reports whether the Accept header lists image/avif. Wildcards
+// don't count, since clients that can decode AVIF name it explicitly.
+func acceptsAVIF(r *http.Request) bool {
+	return accepts(r, "image/avif")
+}
+
+// wantsJSON reports whether the client asked for the image wrapped in JSON,
+// for frameworks and proxies that mangle binary bodies.
+func wantsJSON(r *http.Request) bool {
+	return accepts(r, "application/json")
+}
+
+// accepts reports whether the Accept header names mediaType with a non-zero
+// quality.
+func accepts(r *http.Request, mediaType string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), mediaType) {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok && k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// reduceForClient downscales img to a low-quality JPEG if one was requested.
+func reduceForClient(s *server.Server, w http.ResponseWriter, r *http.Request, img []byte, mimeType string) ([]byte, string) {
+	if requestedQuality(r) != qualityLow {
+		w.Header().Set("X-Image-Quality", qualityFull)
+		return img, mimeType
+	}
+	cfg := s.Config().LowQuality
+	reduced, err := reduceImage(img, int(cfg.MaxDimension), int(cfg.JPEGQuality))
+	if err != nil || len(reduced) >= len(img) {
+		if err != nil {
+			s.Logger.Warn("Failed to reduce image quality", "error", err)
+		}
+		w.Header().Set("X-Image-Quality", qualityFull)
+		return img, mimeType
+	}
+	s.Logger.Info("Sending low-quality image", "originalBytes", len(img), "reducedBytes", len(reduced))
+	w.Header().Set("X-Image-Quality", qualityLow)
+	return reduced, "image/jpeg"
+}
+
+// reduceImage scales img so its longer side is at most maxDimension and
+// encodes it as JPEG at the given quality.
+func reduceImage(img []byte, maxDimension, quality int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleToFit(src, maxDimension), &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleToFit returns src scaled so its longer side is at most maxDimension.
+func scaleToFit(src image.Image, maxDimension int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if longer := max(width, height); longer > maxDimension {
+		width, height = max(width*maxDimension/longer, 1), max(height*maxDimension/longer, 1)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,89 @@
+// handler/references.go
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/gemini"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/tracing"
+)
+
+// maxReferences is how many extra photos of the person /generate accepts
+// besides the main one.
+const maxReferences = 2
+
+// readReferences reads the optional "reference" parts of a multipart
+// /generate upload: more photos of the person in the main photo, e.g. from
+// the side or smiling, which are sent along with it to improve likeness.
+// Each is checked like the main photo, and the text model must agree that
+// all of them show the same person. It writes an error response and returns
+// false if they are unusable.
+func readReferences(s *server.Server, w http.ResponseWriter, r *http.Request, main gemini.Reference) ([]gemini.Reference, bool) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File["reference"]) == 0 {
+		return nil, true
+	}
+	files := r.MultipartForm.File["reference"]
+	if len(files) > maxReferences {
+		apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest,
+			"Too many reference photos.", map[string]any{"maxReferences": maxReferences})
+		return nil, false
+	}
+
+	refs := make([]gemini.Reference, 0, len(files))
+	for _, fh := range files {
+		file, err := fh.Open()
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidImage, "Invalid reference photo provided.")
+			return nil, false
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			s.Logger.Error("Failed to read reference photo", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Could not read image data.")
+			return nil, false
+		}
+		data, mimeType, err := readPhoto(r.Context(), s, data)
+		if err != nil {
+			writePhotoError(s, w, r, err)
+			return nil, false
+		}
+		if !checkModeration(s, w, r, data, mimeType) {
+			return nil, false
+		}
+		data, mimeType = preprocessPhoto(s, data, mimeType)
+		refs = append(refs, gemini.Reference{Data: data, MimeType: mimeType})
+	}
+	return refs, checkSamePerson(s, w, r, append([]gemini.Reference{main}, refs...))
+}
+
+// checkSamePerson rejects reference photos of someone other than the person
+// in the main photo, which would blend two faces. If the comparison itself
+// fails, the photos are let through.
+func checkSamePerson(s *server.Server, w http.ResponseWriter, r *http.Request, photos []gemini.Reference) bool {
+	small := make([]gemini.Reference, len(photos))
+	for i, p := range photos {
+		small[i] = p
+		if reduced, err := reduceImage(p.Data, checkDimension, 85); err == nil && len(reduced) < len(p.Data) {
+			small[i] = gemini.Reference{Data: reduced, MimeType: "image/jpeg"}
+		}
+	}
+	ctx, span := tracing.Start(r.Context(), "compare photos")
+	same, reason, err := s.Gemini.SamePerson(ctx, small)
+	tracing.End(span, err)
+	if err != nil {
+		s.Logger.Warn("Same-person check failed; accepting the photos", "error", err)
+		return true
+	}
+	if !same {
+		s.Logger.Info("Rejected reference photos of a different person", "reason", reason)
+		apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodePhotosMismatch,
+			"The reference photos don't seem to show the same person as the main photo. Please upload photos of one person only.",
+			map[string]any{"reason": reason})
+		return false
+	}
+	return true
+}
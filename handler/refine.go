@@ -0,0 +1,250 @@
+// handler/refine.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/hooks"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/metrics"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+)
+
+// Limits of a refine request's instruction.
+const (
+	minInstructionLength = 3
+	maxInstructionLength = 300
+)
+
+// outfitComponents are the garments a structured refine request can change,
+// mapped to how they read in the edit instruction.
+var outfitComponents = map[string]string{
+	"top":       "top or shirt",
+	"bottoms":   "trousers, skirt or shorts",
+	"dress":     "dress",
+	"outerwear": "jacket, coat or blazer",
+	"shoes":     "shoes",
+	"headwear":  "hat or headpiece",
+	"bag":       "bag",
+	"jewelry":   "jewelry",
+}
+
+// componentInstruction builds the edit instruction of a structured refine
+// request, which replaces one garment of the look and nothing else.
+func componentInstruction(component, change string) (string, error) {
+	phrase, ok := outfitComponents[component]
+	if !ok {
+		return "", fmt.Errorf("unknown component %q", component)
+	}
+	change = cleanPromptText(change)
+	if n := utf8.RuneCountInString(change); n < minInstructionLength || n > maxInstructionLength {
+		return "", fmt.Errorf("change must be between %d and %d characters", minInstructionLength, maxInstructionLength)
+	}
+	return "replace only the " + phrase + " with " + change + ", keeping every other garment and accessory exactly as it is", nil
+}
+
+// sanitizeInstruction cleans a refine instruction with cleanPromptText and
+// checks its length.
+func sanitizeInstruction(text string) (string, error) {
+	text = cleanPromptText(text)
+	if n := utf8.RuneCountInString(text); n < minInstructionLength || n > maxInstructionLength {
+		return "", fmt.Errorf("instruction must be between %d and %d characters", minInstructionLength, maxInstructionLength)
+	}
+	return text, nil
+}
+
+// RefineHandler handles POST /api/v1/refine, editing a look of the session as
+// the user instructs, e.g. "change the dress to emerald green", or swapping
+// one component of the outfit for another, e.g. the shoes for white
+// sneakers. The edit starts from the look rather than the original photo, so
+// everything the instruction doesn't mention is kept. The result is stored as
+// a new look and returned like a swap.
+func RefineHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		refineLook(s, w, r, "refine", func() (string, string, bool) {
+			var req models.RefineRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.Logger.Error("Failed to decode refine request", "error", err)
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+				return "", "", false
+			}
+			if req.Component != "" || req.Change != "" {
+				if req.Instruction != "" {
+					apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Send either an instruction or a component and change, not both.")
+					return "", "", false
+				}
+				instruction, err := componentInstruction(req.Component, req.Change)
+				if err != nil {
+					apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error(),
+						map[string]any{"components": slices.Sorted(maps.Keys(outfitComponents))})
+					return "", "", false
+				}
+				return req.LookID, instruction, true
+			}
+			instruction, err := sanitizeInstruction(req.Instruction)
+			if err != nil {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+				return "", "", false
+			}
+			return req.LookID, instruction, true
+		})
+	}
+}
+
+// refineLook edits a look of the session with the model and writes the
+// result as a new look. parse reads the request body into the ID of the look,
+// empty for the session's most recent one, and the edit instruction, writing
+// an error if the body is invalid. pipeline names the call in the activity
+// view and stage metrics.
+func refineLook(s *server.Server, w http.ResponseWriter, r *http.Request, pipeline string, parse func() (lookID, instruction string, ok bool)) {
+	timing := metrics.NewTiming()
+	sessionID := r.Header.Get("X-Session-ID")
+	if sessionID == "" {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
+		return
+	}
+	if !checkFormat(s, w, r) {
+		return
+	}
+
+	quota, billable, ok := checkQuota(s, w, r)
+	if !ok {
+		return
+	}
+	defer quota.Release()
+	defer s.Activity.Begin(pipeline)()
+
+	lookID, instruction, ok := parse()
+	if !ok {
+		return
+	}
+
+	sessionData, found := s.CachedSession(sessionID)
+	if !found {
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+		return
+	}
+	if sessionData.E2EEKeyID != "" {
+		apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "Looks of end-to-end encrypted sessions are not stored, so they cannot be refined.")
+		return
+	}
+	look, ok := refinableLook(s, w, r, sessionID, lookID)
+	if !ok {
+		return
+	}
+	img, mimeType, err := s.Looks.Image(r.Context(), look.ID)
+	if err != nil {
+		if errors.Is(err, looks.ErrNoImage) {
+			apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "The look's image is not stored, so it cannot be refined.")
+			return
+		}
+		s.Logger.Error("Failed to load look image", "lookID", look.ID, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load the look.")
+		return
+	}
+	s.Logger.Info("Refining look", "sessionID", sessionID, "lookID", look.ID, "instruction", instruction)
+
+	// Edit the look, running any image hooks around it
+	endPreprocess := timing.Start(metrics.StagePreprocess)
+	hookReq := hookRequest(r, sessionID, sessionData, look.Style)
+	input, err := s.Hooks.Pre(r.Context(), hookReq, hooks.Image{Data: img, MimeType: mimeType})
+	if err != nil {
+		writeHookError(s, w, r, err)
+		return
+	}
+	endPreprocess()
+	endImage := timing.Start(metrics.StageImage)
+	event := sessionData.RequestData
+	refinedImg, refinedMimeType, err := s.Gemini.RefineImage(r.Context(), input.Data, input.MimeType, event.EventType, event.Venue, event.Theme, look.Style, instruction, event.PromptSuffix, sessionData.References...)
+	s.Status.Observe(r.Context(), status.ComponentGemini, err)
+	if err != nil {
+		s.Logger.Error("Failed to refine image via Gemini", "lookID", look.ID, "error", err)
+		writeGeminiError(s, w, r, err, "Failed to refine image.")
+		return
+	}
+	endImage()
+	endPostprocess := timing.Start(metrics.StagePostprocess)
+	refinedImg, refinedMimeType = frameGenerated(s, event, refinedImg, refinedMimeType)
+	output, err := s.Hooks.Post(r.Context(), hookReq, hooks.Image{Data: refinedImg, MimeType: refinedMimeType})
+	if err != nil {
+		writeHookError(s, w, r, err)
+		return
+	}
+	refinedImg, refinedMimeType = output.Data, output.MimeType
+	meta := generationMetadata(s, r, sessionID, event, look.Style)
+	refinedImg = tagImage(s, r, refinedImg, refinedMimeType, meta)
+	endPostprocess()
+
+	// A refinement is one image call, like a swap
+	record := quota.Record(r.Context(), usage.KindSwap)
+	if billable {
+		reportBillableUsage(s, clientKey(r), record)
+	}
+
+	endStorage := timing.Start(metrics.StageStorage)
+	refined := newLook(r, sessionID, sessionData, look.Style, refinedMimeType)
+	refined.RefinedFrom = look.ID
+	refined.Instruction = instruction
+	refinedID := saveLook(s, r, refined, sessionData, refinedImg)
+	endStorage()
+	endPostprocess = timing.Start(metrics.StagePostprocess)
+	responseImg, responseMimeType := adaptImage(s, w, r, refinedImg, refinedMimeType)
+	if len(responseImg) != len(refinedImg) {
+		// A smaller or converted render is a new file without the metadata
+		responseImg = tagImage(s, r, responseImg, responseMimeType, meta)
+	}
+	endPostprocess()
+	finishTiming(s, w, pipeline, timing)
+
+	resp := models.ImageResponse{
+		SessionID:  sessionID,
+		LookID:     refinedID,
+		Styles:     sessionData.Styles,
+		StyleIndex: slices.Index(models.Descriptions(sessionData.Styles), look.Style),
+	}
+	if len(responseImg) == len(refinedImg) {
+		signLookURL(s, &resp, refinedMimeType, downloadDisposition(r, event.EventType, look.Style, refinedMimeType))
+	}
+	writeImage(s, w, r, responseImg, responseMimeType, downloadDisposition(r, event.EventType, look.Style, responseMimeType), resp)
+}
+
+// refinableLook returns the caller's look with the given ID from the
+// session, or the session's most recent look if id is empty, writing an
+// error if there is none.
+func refinableLook(s *server.Server, w http.ResponseWriter, r *http.Request, sessionID, id string) (*looks.Look, bool) {
+	if id == "" {
+		sessionLooks, err := s.Looks.BySession(r.Context(), ownerKey(r), sessionID)
+		if err != nil {
+			s.Logger.Error("Failed to list session looks", "sessionID", sessionID, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load the look.")
+			return nil, false
+		}
+		if len(sessionLooks) == 0 {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "The session has no look to refine yet.")
+			return nil, false
+		}
+		return sessionLooks[len(sessionLooks)-1], true
+	}
+	look, err := s.Looks.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, looks.ErrNotFound) {
+		s.Logger.Error("Failed to load look", "lookID", id, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load the look.")
+		return nil, false
+	}
+	// Looks of other sessions or clients are reported as missing
+	if err != nil || look.SessionID != sessionID || !owns(r, look.Owner) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Look not found in this session.")
+		return nil, false
+	}
+	return look, true
+}
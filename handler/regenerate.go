@@ -0,0 +1,131 @@
+// handler/regenerate.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+)
+
+const (
+	// maxRegenerate is the most styles one request can replace.
+	maxRegenerate = 5
+	// maxDislikeReasonLength caps the reason given for disliking a style.
+	maxDislikeReasonLength = 200
+)
+
+// RegenerateStylesHandler handles POST /api/v1/styles/regenerate, replacing
+// the styles of the session the user disliked with new suggestions. The
+// reasons for the dislikes, e.g. "no floral prints", go into the prompt and
+// are kept with the session, so later suggestions respect them too. Each
+// replacement takes the index of the style it replaces.
+func RegenerateStylesHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get("X-Session-ID")
+		if sessionID == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
+			return
+		}
+		var req models.RegenerateStylesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.Logger.Error("Failed to decode regenerate styles request", "error", err)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		sessionData, found := s.CachedSession(sessionID)
+		if !found {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+		disliked, reasons, err := dislikedStyles(sessionData.Styles, req.Disliked)
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		quota, billable, ok := checkQuota(s, w, r)
+		if !ok {
+			return
+		}
+		defer quota.Release()
+
+		event := sessionData.RequestData
+		opts := sessionSuggestionOptions(r.Context(), s, sessionData)
+		// Sessions with fewer suggestions still get a replacement for each
+		opts.Count = max(opts.Count, len(disliked))
+		for _, reason := range reasons {
+			if !slices.Contains(opts.Avoid, reason) {
+				opts.Avoid = append(slices.Clip(opts.Avoid), reason)
+			}
+		}
+		styles, err := s.Gemini.GetStyleSuggestions(r.Context(), event.EventType, event.Venue, event.Theme, opts)
+		s.Status.Observe(r.Context(), status.ComponentGemini, err)
+		if err != nil {
+			s.Logger.Error("Failed to get replacement style suggestions", "sessionID", sessionID, "error", err)
+			writeGeminiError(s, w, r, err, "Failed to regenerate styles.")
+			return
+		}
+		record := quota.Record(r.Context(), usage.KindSuggestions)
+		if billable {
+			reportBillableUsage(s, clientKey(r), record)
+		}
+		styles = shopStyles(r.Context(), s, cleanSuggestions(styles))
+
+		replaced, err := replaceSessionStyles(s, sessionID, disliked, styles, reasons)
+		if err != nil {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+		s.Logger.Info("Regenerated styles", "sessionID", sessionID, "disliked", len(disliked), "replaced", len(replaced), "reasons", reasons)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.RegenerateStylesResponse{Styles: replaced})
+	}
+}
+
+// dislikedStyles resolves the disliked styles of a request against the
+// session's styles, returning them with their indexes along with the
+// cleaned reasons given.
+func dislikedStyles(styles []models.Style, disliked []models.DislikedStyle) ([]models.IndexedStyle, []string, error) {
+	if len(disliked) == 0 || len(disliked) > maxRegenerate {
+		return nil, nil, fmt.Errorf("disliked must name between 1 and %d styles", maxRegenerate)
+	}
+	var out []models.IndexedStyle
+	var reasons []string
+	for _, d := range disliked {
+		i := -1
+		switch {
+		case d.StyleID != "":
+			if i = slices.IndexFunc(styles, func(style models.Style) bool { return style.ID == d.StyleID }); i < 0 {
+				return nil, nil, fmt.Errorf("unknown style ID %q", d.StyleID)
+			}
+		case d.StyleIndex != nil:
+			if i = *d.StyleIndex; i < 0 || i >= len(styles) {
+				return nil, nil, fmt.Errorf("styleIndex %d is out of range", i)
+			}
+		default:
+			return nil, nil, errors.New("each disliked style needs a styleIndex or styleId")
+		}
+		if slices.ContainsFunc(out, func(have models.IndexedStyle) bool { return have.Index == i }) {
+			return nil, nil, fmt.Errorf("style %d is disliked more than once", i)
+		}
+		out = append(out, models.IndexedStyle{Index: i, Style: styles[i]})
+
+		reason := cleanPromptText(d.Reason)
+		if utf8.RuneCountInString(reason) > maxDislikeReasonLength {
+			return nil, nil, fmt.Errorf("reason must be at most %d characters", maxDislikeReasonLength)
+		}
+		if reason != "" && !slices.Contains(reasons, reason) {
+			reasons = append(reasons, reason)
+		}
+	}
+	return out, reasons, nil
+}
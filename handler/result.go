@@ -0,0 +1,81 @@
+// handler/result.go
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// ResultHandler handles GET /api/v1/result, returning the session's most
+// recent look the way the call that generated it did, so a page refresh or
+// a second device can show the result again without regenerating it. Looks
+// of end-to-end encrypted sessions are not stored, so they cannot be
+// fetched again.
+func ResultHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get("X-Session-ID")
+		if sessionID == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeSessionRequired, "Missing X-Session-ID header.")
+			return
+		}
+		if !checkFormat(s, w, r) {
+			return
+		}
+		sessionData, found := s.CachedSession(sessionID)
+		if !found {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+		if sessionData.E2EEKeyID != "" {
+			apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "Looks of end-to-end encrypted sessions are not stored, so they cannot be fetched again.")
+			return
+		}
+
+		sessionLooks, err := s.Looks.BySession(r.Context(), ownerKey(r), sessionID)
+		if err != nil {
+			s.Logger.Error("Failed to list session looks", "sessionID", sessionID, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load the result.")
+			return
+		}
+		if len(sessionLooks) == 0 {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "The session has no generated image yet.")
+			return
+		}
+		look := sessionLooks[len(sessionLooks)-1]
+		img, mimeType, err := s.Looks.Image(r.Context(), look.ID)
+		if errors.Is(err, looks.ErrNotFound) || errors.Is(err, looks.ErrNoImage) {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "The result's image is not stored.")
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to load look image", "lookID", look.ID, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load the result.")
+			return
+		}
+
+		// A later swap or refinement changes the result, so it is always revalidated
+		w.Header().Set("Cache-Control", "private, no-cache")
+		event := sessionData.RequestData
+		responseImg, responseMimeType := adaptImage(s, w, r, img, mimeType)
+		if len(responseImg) != len(img) {
+			// A smaller or converted render is a new file without the metadata
+			responseImg = tagImage(s, r, responseImg, responseMimeType, generationMetadata(s, r, sessionID, event, look.Style))
+		}
+		resp := models.ImageResponse{
+			SessionID:  sessionID,
+			LookID:     look.ID,
+			Styles:     sessionData.Styles,
+			StyleIndex: slices.Index(models.Descriptions(sessionData.Styles), look.Style),
+		}
+		if len(responseImg) == len(img) {
+			signLookURL(s, &resp, mimeType, downloadDisposition(r, event.EventType, look.Style, mimeType))
+		}
+		writeImage(s, w, r, responseImg, responseMimeType, downloadDisposition(r, event.EventType, look.Style, responseMimeType), resp)
+	}
+}
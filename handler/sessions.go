@@ -0,0 +1,145 @@
+// handler/sessions.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/presets"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/sessions"
+)
+
+func sessionResponse(s *server.Server, rec *sessions.Record) models.SessionResponse {
+	s.CacheMutex.Lock()
+	_, active := s.SessionCache[rec.ID]
+	s.CacheMutex.Unlock()
+	return models.SessionResponse{
+		ID:        rec.ID,
+		Name:      rec.Name,
+		Notes:     rec.Notes,
+		EventType: rec.EventType,
+		Venue:     rec.Venue,
+		Theme:     rec.Theme,
+		Active:    active,
+		CreatedAt: rec.CreatedAt,
+		UpdatedAt: rec.UpdatedAt,
+
+		PresetUpdatedAt: rec.PresetUpdatedAt,
+	}
+}
+
+// SessionHistoryHandler handles GET /api/v1/sessions, listing the caller's
+// sessions with their names and notes, newest first.
+func SessionHistoryHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recs, err := s.Sessions.List(r.Context(), ownerKey(r))
+		if err != nil {
+			s.Logger.Error("Failed to list sessions", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list sessions.")
+			return
+		}
+		out := make([]models.SessionResponse, 0, len(recs))
+		for _, rec := range recs {
+			out = append(out, sessionResponse(s, rec))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// ownedSession loads the caller's session record, writing a 404 for unknown
+// sessions and those of other callers.
+func ownedSession(s *server.Server, w http.ResponseWriter, r *http.Request) (*sessions.Record, bool) {
+	rec, err := s.Sessions.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sessions.ErrNotFound) || (err == nil && !owns(r, rec.Owner)) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Session not found.")
+		return nil, false
+	}
+	if err != nil {
+		s.Logger.Error("Failed to load session", "sessionID", r.PathValue("id"), "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load session.")
+		return nil, false
+	}
+	return rec, true
+}
+
+// GetSessionHandler handles GET /api/v1/sessions/{id}.
+func GetSessionHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := ownedSession(s, w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessionResponse(s, rec))
+	}
+}
+
+// UpdateSessionHandler handles PUT /api/v1/sessions/{id}, renaming a session
+// or replacing its notes.
+func UpdateSessionHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.UpdateSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		if _, ok := ownedSession(s, w, r); !ok {
+			return
+		}
+		rec, err := s.Sessions.Update(r.Context(), r.PathValue("id"), req.Name, req.Notes)
+		if errors.Is(err, sessions.ErrTooLong) {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to update session", "sessionID", r.PathValue("id"), "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update session.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessionResponse(s, rec))
+	}
+}
+
+// RefreshSessionHandler handles POST /api/v1/sessions/{id}/refresh, replacing
+// an active session's style suggestions with its preset's current ones after
+// an admin updated them. The response is the new style list; the client
+// regenerates with /swap-style as usual.
+func RefreshSessionHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := ownedSession(s, w, r)
+		if !ok {
+			return
+		}
+		styles, ok := s.Presets.Peek(presets.Preset{EventType: rec.EventType, Venue: rec.Venue, Theme: rec.Theme})
+		if !ok || len(styles) == 0 {
+			apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "No updated suggestions are available for this session's preset.")
+			return
+		}
+
+		s.CacheMutex.Lock()
+		sessionData, found := s.SessionCache[rec.ID]
+		if found {
+			sessionData.Styles = styles
+			s.SessionCache[rec.ID] = sessionData
+		}
+		s.CacheMutex.Unlock()
+		if !found {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeSessionExpired, "Session expired or invalid.")
+			return
+		}
+
+		if _, err := s.Sessions.ClearPresetUpdated(r.Context(), rec.ID); err != nil {
+			s.Logger.Error("Failed to clear session preset update", "sessionID", rec.ID, "error", err)
+		}
+		s.Logger.Info("Refreshed session styles", "sessionID", rec.ID, "styles", len(styles))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(styles)
+	}
+}
@@ -0,0 +1,229 @@
+// handler/shares.go
+package handler
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/qrcode"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/shares"
+	"github.com/sanjayshr/event-outfitter-backend/shortlinks"
+)
+
+// Lifetimes of a share.
+const (
+	defaultShareTTL = 7 * 24 * time.Hour
+	maxShareTTL     = 30 * 24 * time.Hour
+)
+
+// Sizes of a share's QR code, in pixels.
+const (
+	defaultQRSize = 512
+	minQRSize     = 128
+	maxQRSize     = 2048
+)
+
+func shareResponse(s *server.Server, r *http.Request, sh *shares.Share) models.ShareResponse {
+	resp := models.ShareResponse{
+		Token:     sh.Token,
+		URL:       publicURL(s, r, sh.Owner, sh.Path()),
+		LookID:    sh.LookID,
+		ExpiresAt: sh.ExpiresAt,
+		CreatedAt: sh.CreatedAt,
+		Views:     sh.Views,
+	}
+	if sh.ShortCode != "" {
+		resp.ShortURL = publicURL(s, r, sh.Owner, "/s/"+sh.ShortCode)
+	}
+	return resp
+}
+
+// CreateShareHandler handles POST /api/v1/looks/{id}/share, minting an
+// expiring token that serves the look's image to anyone at /share/{token},
+// so users can send a result to friends without the session header.
+func CreateShareHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.CreateShareRequest
+		// The body is optional
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body.")
+			return
+		}
+		ttl := time.Duration(req.TTLSeconds) * time.Second
+		if ttl == 0 {
+			ttl = defaultShareTTL
+		}
+		if ttl < 0 || ttl > maxShareTTL {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "ttlSeconds must be between 0 and 30 days.")
+			return
+		}
+		look, ok := ownedLook(s, w, r, r.PathValue("id"))
+		if !ok {
+			return
+		}
+
+		share, err := s.Shares.Create(r.Context(), look.ID, look.Owner, ttl)
+		if err != nil {
+			s.Logger.Error("Failed to create share", "lookID", look.ID, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to share look.")
+			return
+		}
+		s.Logger.Info("Shared look", "lookID", look.ID, "expiresAt", share.ExpiresAt)
+		share = shortenShare(s, r, share, ttl)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(shareResponse(s, r, share))
+	}
+}
+
+// shortenShare gives a share a short link that expires with it. The share is
+// still usable without one, so it is returned unchanged if that fails.
+func shortenShare(s *server.Server, r *http.Request, share *shares.Share, ttl time.Duration) *shares.Share {
+	link, err := s.Links.Create(r.Context(), shortlinks.KindShare, share.Path(), share.Owner, ttl)
+	if err != nil {
+		s.Logger.Warn("Share made without a short link", "lookID", share.LookID, "error", err)
+		return share
+	}
+	shortened, err := s.Shares.SetShortCode(r.Context(), share.Token, link.Code)
+	if err != nil {
+		s.Logger.Warn("Share made without a short link", "lookID", share.LookID, "error", err)
+		if err := s.Links.Delete(r.Context(), link.Code); err != nil {
+			s.Logger.Warn("Failed to delete unused share short link", "code", link.Code, "error", err)
+		}
+		return share
+	}
+	return shortened
+}
+
+// DeleteShareHandler handles DELETE /api/v1/shares/{token}, revoking a share
+// the caller created before it expires.
+func DeleteShareHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.PathValue("token")
+		share, err := s.Shares.Get(r.Context(), token)
+		if errors.Is(err, shares.ErrNotFound) || (err == nil && !owns(r, share.Owner)) {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Share not found.")
+			return
+		}
+		if err == nil {
+			err = s.Shares.Delete(r.Context(), token)
+		}
+		if err != nil {
+			s.Logger.Error("Failed to revoke share", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to revoke share.")
+			return
+		}
+		// The short link only redirects to the revoked share, so a leftover one is harmless
+		if share.ShortCode != "" {
+			if err := s.Links.Delete(r.Context(), share.ShortCode); err != nil {
+				s.Logger.Warn("Failed to delete share short link", "code", share.ShortCode, "error", err)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SharedImageHandler handles GET /share/{token}, serving the shared look's
+// image to anyone with the token and counting the view. Expired shares get
+// 410 Gone.
+func SharedImageHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.PathValue("token")
+		share, err := s.Shares.Resolve(r.Context(), token)
+		switch {
+		case errors.Is(err, shares.ErrNotFound):
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Image not found.")
+			return
+		case errors.Is(err, shares.ErrExpired):
+			apierror.Write(w, r, http.StatusGone, apierror.CodeLinkExpired, "This link has expired.")
+			return
+		case err != nil:
+			s.Logger.Error("Failed to resolve share", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load image.")
+			return
+		}
+		setShareCacheControl(w, share)
+		writeLookImage(s, w, r, share.LookID)
+	}
+}
+
+// ShareQRHandler handles GET /api/v1/share/{token}/qr, rendering the share's
+// link as a QR code PNG, e.g. for a kiosk at an event to show so guests can
+// take their look home on their phone. Like the link, it needs only the
+// token. ?size= sets the width in pixels, from 128 to 2048. Rendering it does
+// not count as a view.
+func ShareQRHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		size := defaultQRSize
+		if v := r.URL.Query().Get("size"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < minQRSize || n > maxQRSize {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, fmt.Sprintf("size must be between %d and %d", minQRSize, maxQRSize))
+				return
+			}
+			size = n
+		}
+		share, err := s.Shares.Get(r.Context(), r.PathValue("token"))
+		switch {
+		case errors.Is(err, shares.ErrNotFound):
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Share not found.")
+			return
+		case err != nil:
+			s.Logger.Error("Failed to load share", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load share.")
+			return
+		case share.Expired():
+			apierror.Write(w, r, http.StatusGone, apierror.CodeLinkExpired, "This link has expired.")
+			return
+		}
+		// The short link makes a smaller code that is quicker to scan
+		resp := shareResponse(s, r, share)
+		link := cmp.Or(resp.ShortURL, resp.URL)
+		if !strings.HasPrefix(link, "http") {
+			// A phone can't open a relative link
+			apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeNotConfigured, "QR codes need PUBLIC_BASE_URL to be set.")
+			return
+		}
+		code, err := qrcode.Encode(link)
+		if errors.Is(err, qrcode.ErrTooLong) {
+			// Only a very long PUBLIC_BASE_URL makes links this long
+			s.Logger.Warn("Share link too long for a QR code", "length", len(link))
+			apierror.Write(w, r, http.StatusUnprocessableEntity, apierror.CodeBadRequest, fmt.Sprintf("The share link is too long for a QR code, which holds at most %d characters.", qrcode.MaxLength))
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to encode share QR code", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to render QR code.")
+			return
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, code.Image(max(1, size/code.Modules()))); err != nil {
+			s.Logger.Error("Failed to encode share QR code", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to render QR code.")
+			return
+		}
+		setShareCacheControl(w, share)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(buf.Bytes())
+	}
+}
+
+// setShareCacheControl lets caches keep a share's response for an hour, but
+// never past the share's expiry.
+func setShareCacheControl(w http.ResponseWriter, share *shares.Share) {
+	maxAge := min(time.Until(share.ExpiresAt), time.Hour)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+}
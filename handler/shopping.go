@@ -0,0 +1,52 @@
+// handler/shopping.go
+package handler
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"golang.org/x/sync/errgroup"
+)
+
+// shopStyles returns a copy of styles with products for each style's items,
+// searched in parallel, if shopping is configured. Searches that fail are
+// logged and leave their item without products, since the styles are useful
+// without them.
+func shopStyles(ctx context.Context, s *server.Server, styles []models.Style) []models.Style {
+	if s.Shopping == nil {
+		return styles
+	}
+	// Copy, since the styles may be shared with the preset cache
+	shopped := slices.Clone(styles)
+	found := make([][][]models.Product, len(shopped))
+	var searches errgroup.Group
+	for i, style := range shopped {
+		found[i] = make([][]models.Product, len(style.Items))
+		for j, item := range style.Items {
+			searches.Go(func() error {
+				products, err := s.Shopping.Search(ctx, item)
+				if err != nil {
+					s.Logger.Warn("Failed to search products", "item", item, "error", err)
+					return nil
+				}
+				found[i][j] = products
+				return nil
+			})
+		}
+	}
+	searches.Wait()
+	for i := range shopped {
+		var products []models.Product
+		for _, itemProducts := range found[i] {
+			for _, p := range itemProducts {
+				if !slices.ContainsFunc(products, func(have models.Product) bool { return have.URL == p.URL }) {
+					products = append(products, p)
+				}
+			}
+		}
+		shopped[i].Products = products
+	}
+	return shopped
+}
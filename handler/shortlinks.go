@@ -0,0 +1,143 @@
+// handler/shortlinks.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/shortlinks"
+)
+
+// maxShortLinkTTL caps how long a short link may live.
+const maxShortLinkTTL = 365 * 24 * time.Hour
+
+func shortLinkResponse(s *server.Server, r *http.Request, l *shortlinks.Link) models.ShortLinkResponse {
+	return models.ShortLinkResponse{
+		Code:      l.Code,
+		ShortURL:  publicURL(s, r, l.Owner, "/s/"+l.Code),
+		Kind:      l.Kind,
+		Target:    l.Target,
+		ExpiresAt: l.ExpiresAt,
+		CreatedAt: l.CreatedAt,
+		Hits:      l.Hits,
+		LastHitAt: l.LastHitAt,
+	}
+}
+
+// allowedLinkTarget reports whether target may be shortened. Only paths on this
+// server and URLs on our own frontends, public base URL or tenant domains are
+// allowed, so short links can't be used as an open redirector.
+func allowedLinkTarget(s *server.Server, r *http.Request, target string) bool {
+	if strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") {
+		return true
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return false
+	}
+	origin := u.Scheme + "://" + u.Host
+	if s.CORS().AllowsOrigin(origin) || origin == strings.TrimSuffix(s.Config().Server.PublicBaseURL, "/") {
+		return true
+	}
+	_, err = s.APIKeys.ByDomain(r.Context(), u.Hostname())
+	return err == nil
+}
+
+// CreateShortLinkHandler handles POST /api/v1/links, shortening a share, poll
+// or referral URL for use in messaging channels.
+func CreateShortLinkHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.CreateShortLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "A JSON body with a target is required.")
+			return
+		}
+		if !allowedLinkTarget(s, r, req.Target) {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Target must be a path on this server or a URL on an allowed origin.")
+			return
+		}
+		if req.Kind == "" {
+			req.Kind = shortlinks.KindShare
+		}
+		ttl := time.Duration(req.TTLSeconds) * time.Second
+		if ttl < 0 || ttl > maxShortLinkTTL {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "ttlSeconds must be between 0 and one year.")
+			return
+		}
+
+		link, err := s.Links.Create(r.Context(), req.Kind, req.Target, issueOwner(w, r), ttl)
+		if errors.Is(err, shortlinks.ErrInvalidKind) {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to create short link", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create short link.")
+			return
+		}
+		s.Logger.Info("Created short link", "code", link.Code, "kind", link.Kind)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(shortLinkResponse(s, r, link))
+	}
+}
+
+// ShortLinkStatsHandler handles GET /api/v1/links/{code}, returning the
+// redirect statistics of a link the caller created.
+func ShortLinkStatsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, err := s.Links.Get(r.Context(), r.PathValue("code"))
+		if errors.Is(err, shortlinks.ErrNotFound) || (err == nil && !owns(r, link.Owner)) {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Short link not found.")
+			return
+		}
+		if err != nil {
+			s.Logger.Error("Failed to load short link", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load short link.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(shortLinkResponse(s, r, link))
+	}
+}
+
+// RedirectShortLinkHandler handles GET /s/{code}, redirecting to the link's
+// target and counting the hit. Clients that keep asking for codes that don't
+// exist are throttled, so codes can't be enumerated.
+func RedirectShortLinkHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := clientKey(r)
+		if wait := s.Links.MissRetryAfter(client); wait > 0 {
+			t := throttled(r, apierror.CodeRateLimited, reasonRateLimit, "Too many unknown links. Please try again later.", wait)
+			writeThrottled(w, http.StatusTooManyRequests, t, t)
+			return
+		}
+		code := r.PathValue("code")
+		link, err := s.Links.Resolve(r.Context(), code)
+		if errors.Is(err, shortlinks.ErrNotFound) || errors.Is(err, shortlinks.ErrExpired) {
+			s.Links.RecordMiss(client)
+		}
+		switch {
+		case errors.Is(err, shortlinks.ErrNotFound):
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Link not found.")
+			return
+		case errors.Is(err, shortlinks.ErrExpired):
+			apierror.Write(w, r, http.StatusGone, apierror.CodeLinkExpired, "This link has expired.")
+			return
+		case err != nil:
+			s.Logger.Error("Failed to resolve short link", "code", code, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to resolve link.")
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, link.Target, http.StatusFound)
+	}
+}
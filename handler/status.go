@@ -0,0 +1,60 @@
+// handler/status.go
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// ReadyHandler handles GET /readyz, the readiness probe. Unlike /health, it
+// fails with 503 while Gemini or the store is unreachable, so load balancers
+// route around this instance.
+func ReadyHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, results := s.Ready.Check(r.Context())
+		resp := models.ReadinessResponse{Ready: ok, Checks: make([]models.ReadinessCheck, 0, len(results))}
+		for _, res := range results {
+			check := models.ReadinessCheck{
+				Name:       res.Name,
+				OK:         res.Err == nil,
+				CheckedAt:  res.CheckedAt,
+				DurationMs: res.Duration.Milliseconds(),
+			}
+			if res.Err != nil {
+				s.Logger.Warn("Readiness check failed", "check", res.Name, "error", res.Err)
+			}
+			resp.Checks = append(resp.Checks, check)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// StatusHandler handles the /api/v1/status endpoint, summarizing dependency
+// health and recent incidents for the public status page.
+func StatusHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apierror.Write(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		summary, err := s.Status.Summary(r.Context())
+		if err != nil {
+			// Still return current component states; only incident history is missing.
+			s.Logger.Error("Failed to load incident history", "error", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summary)
+	}
+}
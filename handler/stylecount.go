@@ -0,0 +1,32 @@
+// handler/stylecount.go
+package handler
+
+import (
+	"fmt"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// Limits of a generate request's styleCount.
+const (
+	minStyleCount = 3
+	maxStyleCount = 10
+)
+
+// validateStyleCount checks the request's styleCount, if set.
+func validateStyleCount(req models.GenerateRequest) error {
+	if req.StyleCount != 0 && (req.StyleCount < minStyleCount || req.StyleCount > maxStyleCount) {
+		return fmt.Errorf("styleCount must be between %d and %d", minStyleCount, maxStyleCount)
+	}
+	return nil
+}
+
+// styleCount returns how many styles to suggest for req: its styleCount, or
+// the server's default.
+func styleCount(s *server.Server, req models.GenerateRequest) int {
+	if req.StyleCount != 0 {
+		return req.StyleCount
+	}
+	return int(s.Config().Suggestions.StyleCount)
+}
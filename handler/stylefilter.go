@@ -0,0 +1,74 @@
+// handler/stylefilter.go
+package handler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+)
+
+// styleTags are the tags the suggestions prompt lets the model give a style.
+var styleTags = []string{"formal", "semi-formal", "casual", "traditional", "modern", "bohemian", "minimalist", "glamorous", "vintage", "streetwear"}
+
+// colorFamilies group palette colors, so a filter can ask for e.g. neutral
+// styles without naming every neutral color.
+var colorFamilies = map[string][]string{
+	"neutral":  {"white", "ivory", "cream", "beige", "tan", "taupe", "khaki", "camel", "brown", "grey", "gray", "charcoal", "black", "nude", "stone"},
+	"pastel":   {"pastel", "blush", "lavender", "lilac", "mint", "powder", "baby", "peach", "sky"},
+	"warm":     {"red", "orange", "yellow", "coral", "rust", "terracotta", "mustard", "maroon", "burgundy", "crimson", "amber"},
+	"cool":     {"blue", "navy", "teal", "green", "emerald", "sage", "olive", "purple", "violet", "turquoise", "aqua"},
+	"metallic": {"gold", "silver", "bronze", "copper", "champagne", "metallic"},
+}
+
+// normalizeStyleTags lowercases tags and drops duplicates, failing on a tag
+// that is not in styleTags.
+func normalizeStyleTags(tags []string) ([]string, error) {
+	var out []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !slices.Contains(styleTags, tag) {
+			return nil, fmt.Errorf("unknown style tag %q", tag)
+		}
+		if !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// filterStyles returns the styles that have all of tags and, for each of
+// colors, a palette color that names it or belongs to the color family.
+func filterStyles(styles []models.Style, tags, colors []string) []models.Style {
+	out := []models.Style{}
+	for _, style := range styles {
+		if hasTags(style, tags) && hasColors(style, colors) {
+			out = append(out, style)
+		}
+	}
+	return out
+}
+
+func hasTags(style models.Style, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(style.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasColors(style models.Style, colors []string) bool {
+	for _, color := range colors {
+		color = strings.ToLower(strings.TrimSpace(color))
+		words := append([]string{color}, colorFamilies[color]...)
+		if !slices.ContainsFunc(style.Palette, func(c string) bool {
+			c = strings.ToLower(c)
+			return slices.ContainsFunc(words, func(w string) bool { return strings.Contains(c, w) })
+		}) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+// handler/styletext.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// Limits of a swap request's styleText.
+const (
+	minStyleTextLength = 3
+	maxStyleTextLength = 300
+	// maxSessionStyles caps how many custom styles a session can collect on
+	// top of its suggestions.
+	maxSessionStyles = 20
+	// maxSessionDislikes caps how many reasons for disliking styles a
+	// session keeps for its further suggestions.
+	maxSessionDislikes = 10
+)
+
+var (
+	errSessionExpired = errors.New("session expired")
+	errTooManyStyles  = fmt.Errorf("a session can have at most %d styles", maxSessionStyles)
+)
+
+// sanitizeStyleText cleans a user-written outfit description with
+// cleanPromptText and checks its length.
+func sanitizeStyleText(text string) (string, error) {
+	text = cleanPromptText(text)
+	if n := utf8.RuneCountInString(text); n < minStyleTextLength || n > maxStyleTextLength {
+		return "", fmt.Errorf("styleText must be between %d and %d characters", minStyleTextLength, maxStyleTextLength)
+	}
+	return text, nil
+}
+
+// cleanPromptText turns user-written text into a single line for an image
+// prompt: control and formatting characters are dropped and runs of
+// whitespace, including newlines, become one space.
+func cleanPromptText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// addSessionStyle adds a custom style to a cached session's styles, so later
+// swaps and the styles list can refer to it by index, and returns its index
+// along with the session's styles. A style the session already has keeps
+// its index.
+func addSessionStyle(s *server.Server, sessionID, style string) (int, []models.Style, error) {
+	s.CacheMutex.Lock()
+	defer s.CacheMutex.Unlock()
+	sessionData, found := s.SessionCache[sessionID]
+	if !found {
+		return 0, nil, errSessionExpired
+	}
+	if i := slices.IndexFunc(sessionData.Styles, func(have models.Style) bool { return have.Description == style }); i >= 0 {
+		return i, sessionData.Styles, nil
+	}
+	if len(sessionData.Styles) >= maxSessionStyles {
+		return 0, nil, errTooManyStyles
+	}
+	// Copy, since handlers hold the old slice outside the lock
+	sessionData.Styles = append(slices.Clip(sessionData.Styles), models.NewStyle(style))
+	s.SessionCache[sessionID] = sessionData
+	return len(sessionData.Styles) - 1, sessionData.Styles, nil
+}
+
+// appendSessionStyles adds new suggestions to a cached session's styles and
+// returns them with their indexes. Styles the session already has, by ID,
+// are skipped, and so are any past maxSessionStyles.
+func appendSessionStyles(s *server.Server, sessionID string, styles []models.Style) ([]models.IndexedStyle, error) {
+	s.CacheMutex.Lock()
+	defer s.CacheMutex.Unlock()
+	sessionData, found := s.SessionCache[sessionID]
+	if !found {
+		return nil, errSessionExpired
+	}
+	if len(sessionData.Styles) >= maxSessionStyles {
+		return nil, errTooManyStyles
+	}
+	// Copy, since handlers hold the old slice outside the lock
+	all := slices.Clip(sessionData.Styles)
+	added := make([]models.IndexedStyle, 0, len(styles))
+	for _, style := range styles {
+		if len(all) >= maxSessionStyles {
+			break
+		}
+		if style.Description == "" || slices.ContainsFunc(all, func(have models.Style) bool { return have.ID == style.ID }) {
+			continue
+		}
+		all = append(all, style)
+		added = append(added, models.IndexedStyle{Index: len(all) - 1, Style: style})
+	}
+	sessionData.Styles = all
+	s.SessionCache[sessionID] = sessionData
+	return added, nil
+}
+
+// replaceSessionStyles replaces a cached session's disliked styles with
+// replacements, in order, and records the reasons for the dislikes. It
+// returns the replacements with their indexes. A disliked style that changed
+// in the meantime is kept, and replacements the session already has are
+// skipped, so fewer styles than disliked may be replaced.
+func replaceSessionStyles(s *server.Server, sessionID string, disliked []models.IndexedStyle, replacements []models.Style, reasons []string) ([]models.IndexedStyle, error) {
+	s.CacheMutex.Lock()
+	defer s.CacheMutex.Unlock()
+	sessionData, found := s.SessionCache[sessionID]
+	if !found {
+		return nil, errSessionExpired
+	}
+	// Copy, since handlers hold the old slices outside the lock
+	all := slices.Clone(sessionData.Styles)
+	replaced := make([]models.IndexedStyle, 0, len(disliked))
+	next := 0
+	for _, d := range disliked {
+		if d.Index >= len(all) || all[d.Index].ID != d.ID {
+			continue
+		}
+		for next < len(replacements) && (replacements[next].Description == "" ||
+			slices.ContainsFunc(all, func(have models.Style) bool { return have.ID == replacements[next].ID })) {
+			next++
+		}
+		if next == len(replacements) {
+			break
+		}
+		all[d.Index] = replacements[next]
+		replaced = append(replaced, models.IndexedStyle{Index: d.Index, Style: replacements[next]})
+		next++
+	}
+	sessionData.Styles = all
+	avoid := slices.Clip(sessionData.Avoid)
+	for _, reason := range reasons {
+		if !slices.Contains(avoid, reason) {
+			avoid = append(avoid, reason)
+		}
+	}
+	// Keep the most recent dislikes, which bound the prompt
+	sessionData.Avoid = avoid[max(0, len(avoid)-maxSessionDislikes):]
+	s.SessionCache[sessionID] = sessionData
+	return replaced, nil
+}
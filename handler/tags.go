@@ -0,0 +1,129 @@
+// handler/tags.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// writeTagError maps tag update failures to HTTP responses.
+func writeTagError(s *server.Server, w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, looks.ErrInvalidTag) {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+	s.Logger.Error("Failed to update tags", "error", err)
+	apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update tags.")
+}
+
+// lookTagsHandler decodes a TagsRequest for one of the caller's looks and
+// applies update to it.
+func lookTagsHandler(s *server.Server, update func(r *http.Request, id string, tags []string) (*looks.Look, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.TagsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "A JSON body with tags is required.")
+			return
+		}
+		id := r.PathValue("id")
+		if _, ok := ownedLook(s, w, r, id); !ok {
+			return
+		}
+		look, err := update(r, id, req.Tags)
+		if err != nil {
+			writeTagError(s, w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lookResponse(look))
+	}
+}
+
+// SetLookTagsHandler handles PUT /api/v1/looks/{id}/tags, replacing a look's tags.
+func SetLookTagsHandler(s *server.Server) http.HandlerFunc {
+	return lookTagsHandler(s, func(r *http.Request, id string, tags []string) (*looks.Look, error) {
+		return s.Looks.SetTags(r.Context(), id, tags)
+	})
+}
+
+// AddLookTagsHandler handles POST /api/v1/looks/{id}/tags, adding tags to a look.
+func AddLookTagsHandler(s *server.Server) http.HandlerFunc {
+	return lookTagsHandler(s, func(r *http.Request, id string, tags []string) (*looks.Look, error) {
+		return s.Looks.AddTags(r.Context(), id, tags)
+	})
+}
+
+// RemoveLookTagHandler handles DELETE /api/v1/looks/{id}/tags/{tag}.
+func RemoveLookTagHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if _, ok := ownedLook(s, w, r, id); !ok {
+			return
+		}
+		look, err := s.Looks.RemoveTag(r.Context(), id, r.PathValue("tag"))
+		if err != nil {
+			writeTagError(s, w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lookResponse(look))
+	}
+}
+
+// ListTagsHandler handles GET /api/v1/tags, listing the tags on the caller's
+// looks, most used first.
+func ListTagsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tags, err := s.Looks.Tags(r.Context(), ownerKey(r))
+		if err != nil {
+			s.Logger.Error("Failed to list tags", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list tags.")
+			return
+		}
+		out := make([]models.TagResponse, 0, len(tags))
+		for _, t := range tags {
+			out = append(out, models.TagResponse{Tag: t.Tag, Count: t.Count})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// RenameTagHandler handles PUT /api/v1/tags/{tag}, renaming a tag on all of
+// the caller's looks.
+func RenameTagHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.RenameTagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "A JSON body with the new name is required.")
+			return
+		}
+		n, err := s.Looks.RenameTag(r.Context(), ownerKey(r), r.PathValue("tag"), req.Name)
+		if err != nil {
+			writeTagError(s, w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.RetagResponse{Looks: n})
+	}
+}
+
+// DeleteTagHandler handles DELETE /api/v1/tags/{tag}, removing a tag from all
+// of the caller's looks.
+func DeleteTagHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, err := s.Looks.DeleteTag(r.Context(), ownerKey(r), r.PathValue("tag"))
+		if err != nil {
+			writeTagError(s, w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.RetagResponse{Looks: n})
+	}
+}
@@ -0,0 +1,61 @@
+// handler/tenant.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sanjayshr/event-outfitter-backend/apikeys"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+type tenantContextKey struct{}
+
+// tenantFromRequest returns the tenant whose custom domain the request arrived on, if any.
+func tenantFromRequest(r *http.Request) *apikeys.Key {
+	key, _ := r.Context().Value(tenantContextKey{}).(*apikeys.Key)
+	return key
+}
+
+// hostname strips the port from a Host header value.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// TenantHost resolves requests arriving on a tenant's custom domain to that
+// tenant, so public pages served there only show the tenant's own looks.
+func TenantHost(s *server.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.APIKeys.ByDomain(r.Context(), hostname(r.Host))
+		if err != nil {
+			if !errors.Is(err, apikeys.ErrNotFound) {
+				s.Logger.Error("Failed to resolve tenant domain", "host", r.Host, "error", err)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), tenantContextKey{}, key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// publicURL builds an absolute URL for path. Looks owned by a tenant with a
+// custom domain link to that domain; everything else uses the configured
+// public base URL, falling back to a relative path when none is set.
+func publicURL(s *server.Server, r *http.Request, owner, path string) string {
+	if id, ok := strings.CutPrefix(owner, "key:"); ok {
+		if t := tenantFromRequest(r); t != nil && t.ID == id {
+			return "https://" + t.Domain + path
+		}
+		if domain, err := s.APIKeys.Domain(r.Context(), id); err == nil && domain != "" {
+			return "https://" + domain + path
+		}
+	}
+	return strings.TrimSuffix(s.Config().Server.PublicBaseURL, "/") + path
+}
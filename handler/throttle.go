@@ -0,0 +1,72 @@
+// handler/throttle.go
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/gemini"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// geminiRetryAfter is how long clients are asked to wait while Gemini is
+// rate limiting us.
+const geminiRetryAfter = 30 * time.Second
+
+// Reasons a request can be throttled, reported in ThrottledResponse.Reason.
+const (
+	reasonRateLimit   = "rate_limit"
+	reasonQuota       = "quota"
+	reasonSaturation  = "saturation"
+	reasonMaintenance = "maintenance"
+)
+
+// throttled builds the common body of a throttling response. retryAfter is
+// rounded up to whole seconds, and is at least one second.
+func throttled(r *http.Request, code, reason, message string, retryAfter time.Duration) models.ThrottledResponse {
+	return models.ThrottledResponse{
+		ErrorResponse:     apierror.New(r, code, message),
+		Reason:            reason,
+		RetryAfterSeconds: max(int(math.Ceil(retryAfter.Seconds())), 1),
+	}
+}
+
+// writeThrottled writes a throttling response with a Retry-After header that
+// matches the body's retryAfterSeconds. body must embed models.ThrottledResponse.
+func writeThrottled(w http.ResponseWriter, status int, t models.ThrottledResponse, body any) {
+	w.Header().Set("Retry-After", strconv.Itoa(t.RetryAfterSeconds))
+	apierror.WriteBody(w, status, body)
+}
+
+// writeSaturated writes a 503 saturation response if err means Gemini is rate
+// limiting us, and reports whether it did.
+func writeSaturated(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, gemini.ErrQuota) {
+		return false
+	}
+	t := throttled(r, apierror.CodeServiceSaturated, reasonSaturation,
+		"We're handling a lot of requests right now. Please try again shortly.",
+		geminiRetryAfter)
+	writeThrottled(w, http.StatusServiceUnavailable, t, t)
+	return true
+}
+
+// RequireAvailable rejects requests with a structured 503 while maintenance
+// mode is on. Health checks and read-only endpoints are left unwrapped so
+// infrastructure probes stay green.
+func RequireAvailable(s *server.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := s.Maintenance()
+		if !m.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		t := throttled(r, apierror.CodeMaintenance, reasonMaintenance, m.Message, m.RetryAfter)
+		writeThrottled(w, http.StatusServiceUnavailable, t, t)
+	})
+}
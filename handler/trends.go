@@ -0,0 +1,42 @@
+// handler/trends.go
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/trends"
+)
+
+// defaultTrendsLimit is how many entries each ranking returns by default.
+const defaultTrendsLimit = 10
+
+// TrendsHandler handles GET /api/v1/trends, reporting the most generated and
+// best-rated styles, themes and event types for an ISO week (?week=2025-W03,
+// default the current week).
+func TrendsHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, err := trends.ParseWeek(r.URL.Query().Get("week"))
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+			return
+		}
+		limit := defaultTrendsLimit
+		if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+			limit = min(n, 100)
+		}
+
+		report, err := s.Trends.Weekly(r.Context(), start, limit)
+		if err != nil {
+			s.Logger.Error("Failed to compute trends", "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to compute trends.")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,303 @@
+// handler/upload.go
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/gemini"
+	"github.com/sanjayshr/event-outfitter-backend/imageconv"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/tracing"
+	"github.com/sanjayshr/event-outfitter-backend/urlfetch"
+	_ "golang.org/x/image/webp"
+)
+
+// imageSignatures are the leading bytes of each accepted upload format.
+// WebP files start with "RIFF", a 4-byte size, then "WEBP".
+var imageSignatures = []struct {
+	format string
+	match  func([]byte) bool
+}{
+	{"png", func(b []byte) bool { return bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) }},
+	{"jpeg", func(b []byte) bool { return bytes.HasPrefix(b, []byte{0xFF, 0xD8, 0xFF}) }},
+	{"webp", func(b []byte) bool {
+		return len(b) >= 12 && bytes.Equal(b[:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP"))
+	}},
+}
+
+// errNotImage is returned by sniffImage for uploads that are not a PNG, JPEG
+// or WebP image.
+var errNotImage = errors.New("not a PNG, JPEG or WebP image")
+
+// conversionError is returned for uploads in a format that needs an external
+// converter, either because none is configured (Err is nil) or because the
+// conversion failed.
+type conversionError struct {
+	Format string
+	Err    error
+}
+
+func (e *conversionError) Error() string {
+	if e.Err == nil {
+		return e.Format + " photos are not supported without a converter"
+	}
+	return e.Format + " conversion failed: " + e.Err.Error()
+}
+
+func (e *conversionError) Unwrap() error { return e.Err }
+
+// readPhoto identifies an uploaded photo by its content and returns it with
+// its MIME type. HEIC photos, the iPhone default, and AVIF photos are
+// converted to JPEG first.
+func readPhoto(ctx context.Context, s *server.Server, data []byte) ([]byte, string, error) {
+	format, conv := "", (*imageconv.Converter)(nil)
+	switch {
+	case imageconv.IsHEIC(data):
+		format, conv = "HEIC", s.HEIC
+	case imageconv.IsAVIF(data):
+		format, conv = "AVIF", s.AVIFDecoder
+	}
+	if format != "" {
+		if conv == nil {
+			return nil, "", &conversionError{Format: format}
+		}
+		// Check the declared size first: the converter would otherwise decode
+		// a decompression bomb in full.
+		if width, height, ok := imageconv.Dimensions(data); ok {
+			if err := checkDimensions(s, image.Config{Width: width, Height: height}); err != nil {
+				return nil, "", err
+			}
+		}
+		converted, err := conv.Convert(ctx, data)
+		if err != nil {
+			return nil, "", &conversionError{Format: format, Err: err}
+		}
+		s.Logger.Info("Converted photo", "format", format, "originalBytes", len(data), "bytes", len(converted))
+		data = converted
+	}
+	mimeType, size, err := sniffImage(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkDimensions(s, size); err != nil {
+		return nil, "", err
+	}
+	return data, mimeType, nil
+}
+
+// dimensionError is returned for photos beyond the configured pixel limits.
+// Decoding such a photo could take more memory than the upload size suggests.
+type dimensionError struct {
+	Width, Height int
+	// Megapixels is set when the pixel count, rather than a side, is over the limit.
+	Megapixels bool
+}
+
+func (e *dimensionError) Error() string {
+	if e.Megapixels {
+		return fmt.Sprintf("photo has too many pixels (%dx%d)", e.Width, e.Height)
+	}
+	return fmt.Sprintf("photo is too large (%dx%d)", e.Width, e.Height)
+}
+
+// checkDimensions enforces MAX_IMAGE_DIMENSION and MAX_IMAGE_MEGAPIXELS.
+func checkDimensions(s *server.Server, size image.Config) error {
+	cfg := s.Config().Server
+	if limit := int(cfg.MaxImageDimension); limit > 0 && max(size.Width, size.Height) > limit {
+		return &dimensionError{Width: size.Width, Height: size.Height}
+	}
+	if limit := cfg.MaxImageMegapixels; limit > 0 && int64(size.Width)*int64(size.Height) > limit*1_000_000 {
+		return &dimensionError{Width: size.Width, Height: size.Height, Megapixels: true}
+	}
+	return nil
+}
+
+// writePhotoError maps readPhoto failures to HTTP responses.
+func writePhotoError(s *server.Server, w http.ResponseWriter, r *http.Request, err error) {
+	s.Logger.Warn("Rejected uploaded photo", "error", err)
+	var convErr *conversionError
+	var dimErr *dimensionError
+	switch {
+	case errors.As(err, &dimErr) && dimErr.Megapixels:
+		limit := s.Config().Server.MaxImageMegapixels
+		apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeImageTooManyPixels,
+			fmt.Sprintf("The photo has too many pixels. Please choose an image of at most %d megapixels.", limit),
+			map[string]any{"width": dimErr.Width, "height": dimErr.Height, "maxMegapixels": limit})
+	case errors.As(err, &dimErr):
+		limit := s.Config().Server.MaxImageDimension
+		apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeImageTooLarge,
+			fmt.Sprintf("The photo is too large. Please choose an image no wider or taller than %d pixels.", limit),
+			map[string]any{"width": dimErr.Width, "height": dimErr.Height, "maxDimension": limit})
+	case errors.As(err, &convErr) && convErr.Err == nil:
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidImage, convErr.Format+" photos are not supported. Please upload a JPEG, PNG or WebP image.")
+	case errors.As(err, &convErr):
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidImage, "The "+convErr.Format+" photo could not be converted. Please upload a JPEG, PNG or WebP image.")
+	default:
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidImage, "The uploaded file is not a PNG, JPEG, WebP, HEIC or AVIF image.")
+	}
+}
+
+// isPhotoError reports whether err is a readPhoto failure.
+func isPhotoError(err error) bool {
+	var convErr *conversionError
+	var dimErr *dimensionError
+	return errors.Is(err, errNotImage) || errors.As(err, &convErr) || errors.As(err, &dimErr)
+}
+
+// sniffImage identifies an upload from its content alone; the filename and
+// declared Content-Type are client-controlled and ignored. The signature must
+// match and the image header must decode to non-zero dimensions. It returns
+// the image's MIME type and dimensions.
+func sniffImage(data []byte) (string, image.Config, error) {
+	format := ""
+	for _, sig := range imageSignatures {
+		if sig.match(data) {
+			format = sig.format
+			break
+		}
+	}
+	if format == "" {
+		return "", image.Config{}, errNotImage
+	}
+	cfg, decoded, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", image.Config{}, fmt.Errorf("%w: %s header does not decode: %v", errNotImage, format, err)
+	}
+	if decoded != format {
+		return "", image.Config{}, fmt.Errorf("%w: %s signature but decodes as %s", errNotImage, format, decoded)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return "", image.Config{}, fmt.Errorf("%w: %s has no pixels (%dx%d)", errNotImage, format, cfg.Width, cfg.Height)
+	}
+	return "image/" + format, cfg, nil
+}
+
+// isJSONRequest reports whether the request body is JSON rather than a
+// multipart upload.
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+// writeFileTooLarge rejects an upload over MAX_UPLOAD_BYTES.
+func writeFileTooLarge(w http.ResponseWriter, r *http.Request, maxBytes int64) {
+	apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeFileTooLarge,
+		fmt.Sprintf("The uploaded file is too big. Please choose an image that is less than %dMB in size.", maxBytes>>20),
+		map[string]any{"maxBytes": maxBytes})
+}
+
+// fetchPhoto downloads the photo at a client-supplied imageUrl, writing an
+// error response if that is disabled or fails. The content is still checked
+// by readPhoto like an upload.
+func fetchPhoto(s *server.Server, w http.ResponseWriter, r *http.Request, imageURL string) ([]byte, bool) {
+	if s.ImageURLs == nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeNotConfigured, "Fetching photos from imageUrl is not enabled; upload the image instead.")
+		return nil, false
+	}
+	ctx, span := tracing.Start(r.Context(), "fetch image url")
+	data, err := s.ImageURLs.Fetch(ctx, imageURL)
+	tracing.End(span, err)
+	if err == nil {
+		return data, true
+	}
+
+	s.Logger.Warn("Failed to fetch photo from imageUrl", "error", err)
+	switch {
+	case errors.Is(err, urlfetch.ErrTooLarge):
+		writeFileTooLarge(w, r, s.Config().Server.MaxUploadBytes)
+	case errors.Is(err, urlfetch.ErrInvalidURL), errors.Is(err, urlfetch.ErrBlocked):
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "imageUrl must be a public http or https URL on the standard ports.")
+	case errors.Is(err, urlfetch.ErrNotImage):
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidImage, "imageUrl does not point to an image.")
+	default:
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeImageFetchFailed, "The photo at imageUrl could not be downloaded.")
+	}
+	return nil, false
+}
+
+// checkDimension is the longer side photos are reduced to for face detection
+// and moderation, which need far less detail than generation.
+const checkDimension = 768
+
+// checkModeration rejects a photo in a disallowed category, when moderation
+// is enabled, before any generation is run on it. The response details list
+// the categories. If the check itself fails, the photo is refused unless
+// MODERATION_FAIL_OPEN is set.
+func checkModeration(s *server.Server, w http.ResponseWriter, r *http.Request, photo []byte, mimeType string) bool {
+	cfg := s.Config().Moderation
+	if !cfg.Enabled {
+		return true
+	}
+	if small, err := reduceImage(photo, checkDimension, 85); err == nil && len(small) < len(photo) {
+		photo, mimeType = small, "image/jpeg"
+	}
+	ctx, span := tracing.Start(r.Context(), "moderate photo")
+	categories, err := s.Gemini.ModerateImage(ctx, photo, mimeType)
+	tracing.End(span, err)
+	if errors.Is(err, gemini.ErrSafetyBlocked) {
+		// Gemini refused to even look at it.
+		categories, err = []string{"blocked"}, nil
+	}
+	if err != nil {
+		if cfg.FailOpen {
+			s.Logger.Warn("Moderation check failed; accepting the photo", "error", err)
+			return true
+		}
+		s.Logger.Error("Moderation check failed; refusing the photo", "error", err)
+		writeGeminiError(s, w, r, err, "Could not check the photo. Please try again.")
+		return false
+	}
+	if len(categories) > 0 {
+		s.Logger.Warn("Rejected disallowed photo", "categories", categories, "client", clientKey(r))
+		apierror.WriteDetails(w, r, http.StatusUnprocessableEntity, apierror.CodeContentDisallowed,
+			"This photo can't be used. Please upload a different photo.", map[string]any{"categories": categories})
+		return false
+	}
+	return true
+}
+
+// checkFaces rejects a photo without a detectable face, when the face check
+// is enabled, so the user can pick a better photo before a generation is
+// spent on it. A face smaller than FACE_CHECK_MIN_PERCENT of the photo
+// height only adds an X-Photo-Warning header. If detection itself fails, the
+// photo is let through.
+func checkFaces(s *server.Server, w http.ResponseWriter, r *http.Request, photo []byte, mimeType string) bool {
+	cfg := s.Config().FaceCheck
+	if !cfg.Enabled {
+		return true
+	}
+	if small, err := reduceImage(photo, checkDimension, 85); err == nil && len(small) < len(photo) {
+		photo, mimeType = small, "image/jpeg"
+	}
+	ctx, span := tracing.Start(r.Context(), "detect faces")
+	faces, err := s.Gemini.DetectFaces(ctx, photo, mimeType)
+	tracing.End(span, err)
+	if err != nil {
+		s.Logger.Warn("Face check failed; accepting the photo", "error", err)
+		return true
+	}
+	if len(faces) == 0 {
+		s.Logger.Info("Rejected photo without a face")
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeNoFaceDetected, "We couldn't find a face in this photo. Please upload a clear, well-lit photo with your face visible.")
+		return false
+	}
+
+	largest := 0.0
+	for _, f := range faces {
+		largest = max(largest, f.Height())
+	}
+	if largest*100 < float64(cfg.MinFacePercent) {
+		s.Logger.Info("Face in photo is small", "faces", len(faces), "heightPercent", int(largest*100))
+		w.Header().Set("X-Photo-Warning", "small-face")
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+// handler/uploads.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/uploads"
+)
+
+// tusVersion is the tus protocol version the upload endpoints follow.
+const tusVersion = "1.0.0"
+
+// writeUploadHeaders sets the tus headers describing an upload's progress.
+func writeUploadHeaders(w http.ResponseWriter, info uploads.Info) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(info.Length, 10))
+	w.Header().Set("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+func uploadResponse(info uploads.Info) models.UploadResponse {
+	return models.UploadResponse{ID: info.ID, Length: info.Length, Offset: info.Offset, ExpiresAt: info.ExpiresAt}
+}
+
+func writeUploadError(s *server.Server, w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "Upload not found or expired. Please start a new upload.")
+	case errors.Is(err, uploads.ErrTooLarge):
+		writeFileTooLarge(w, r, s.Uploads.MaxBytes())
+	case errors.Is(err, uploads.ErrOffsetMismatch):
+		apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "Upload-Offset does not match the upload. Ask for the current offset with HEAD and resume from there.")
+	case errors.Is(err, uploads.ErrIncomplete):
+		apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "The upload is not finished yet.")
+	case errors.Is(err, uploads.ErrTooMany):
+		t := throttled(r, apierror.CodeRateLimited, reasonRateLimit,
+			"Too many unfinished uploads. Finish or delete one first.",
+			s.Uploads.RetryAfter(ownerKey(r)))
+		writeThrottled(w, http.StatusTooManyRequests, t, t)
+	case errors.Is(err, uploads.ErrFull):
+		t := throttled(r, apierror.CodeServiceSaturated, reasonSaturation,
+			"Too many uploads are in progress right now. Please try again shortly.",
+			s.Uploads.RetryAfter(""))
+		writeThrottled(w, http.StatusServiceUnavailable, t, t)
+	default:
+		s.Logger.Error("Upload failed", "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "The upload failed.")
+	}
+}
+
+// CreateUploadHandler handles POST /api/v1/uploads, starting a resumable
+// upload of Upload-Length bytes. The upload's URL is in Location.
+func CreateUploadHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil || length < 1 {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Upload-Length must be the photo's size in bytes.")
+			return
+		}
+		info, err := s.Uploads.Create(issueOwner(w, r), length)
+		if err != nil {
+			writeUploadError(s, w, r, err)
+			return
+		}
+
+		writeUploadHeaders(w, info)
+		w.Header().Set("Location", "/api/v1/uploads/"+info.ID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(uploadResponse(info))
+	}
+}
+
+// UploadStatusHandler handles HEAD /api/v1/uploads/{id}, reporting in
+// Upload-Offset how many bytes have arrived, so a client can resume.
+func UploadStatusHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, err := s.Uploads.Stat(r.PathValue("id"), ownerKey(r))
+		if err != nil {
+			// HEAD responses have no body; the status is all the client gets.
+			w.Header().Set("Tus-Resumable", tusVersion)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeUploadHeaders(w, info)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// AppendUploadHandler handles PATCH /api/v1/uploads/{id}, appending the body
+// at Upload-Offset. Bytes received before a dropped connection are kept.
+func AppendUploadHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+			apierror.Write(w, r, http.StatusUnsupportedMediaType, apierror.CodeBadRequest, "Chunks must be sent as application/offset+octet-stream.")
+			return
+		}
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Upload-Offset must be the number of bytes already uploaded.")
+			return
+		}
+		// A chunk may take longer than READ_TIMEOUT on a slow connection;
+		// the upload's size limit bounds it instead.
+		if err := http.NewResponseController(w).SetReadDeadline(time.Time{}); err != nil {
+			s.Logger.Warn("Failed to clear read deadline for upload", "error", err)
+		}
+
+		info, err := s.Uploads.Append(r.PathValue("id"), ownerKey(r), offset, r.Body)
+		if err != nil {
+			if info.ID != "" && !errors.Is(err, uploads.ErrTooLarge) {
+				// The connection dropped; the client resumes from Upload-Offset.
+				s.Logger.Info("Upload chunk interrupted", "uploadID", info.ID, "offset", info.Offset, "error", err)
+				return
+			}
+			writeUploadError(s, w, r, err)
+			return
+		}
+		writeUploadHeaders(w, info)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteUploadHandler handles DELETE /api/v1/uploads/{id}, discarding an upload.
+func DeleteUploadHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Uploads.Delete(r.PathValue("id"), ownerKey(r)); err != nil {
+			writeUploadError(s, w, r, err)
+			return
+		}
+		w.Header().Set("Tus-Resumable", tusVersion)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// uploadedPhoto returns the finished resumable upload referenced by a
+// generate request, writing an error response if it is missing or unfinished.
+func uploadedPhoto(s *server.Server, w http.ResponseWriter, r *http.Request, id string) ([]byte, bool) {
+	data, err := s.Uploads.Data(id, ownerKey(r))
+	if err != nil {
+		writeUploadError(s, w, r, err)
+		return nil, false
+	}
+	return data, true
+}
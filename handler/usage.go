@@ -0,0 +1,113 @@
+// handler/usage.go
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/auth"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/realip"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+)
+
+// clientKey identifies the caller for metering purposes. Authenticated callers
+// are tracked by their identity, such as API key ID; everyone else by client IP.
+// It must not decide ownership; use ownerKey for that.
+func clientKey(r *http.Request) string {
+	if id := auth.FromRequest(r); id != nil {
+		return id.Subject
+	}
+	return "ip:" + realip.FromRequest(r)
+}
+
+// UsageHandler handles the /api/v1/usage endpoint.
+func UsageHandler(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apierror.Write(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		key := clientKey(r)
+		counters, err := s.Usage.Get(r.Context(), key)
+		if err != nil {
+			s.Logger.Error("Failed to read usage", "client", key, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to read usage.")
+			return
+		}
+		quota, err := s.Usage.Quota(r.Context(), key)
+		if err != nil {
+			s.Logger.Error("Failed to read usage", "client", key, "error", err)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to read usage.")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.UsageResponse{
+			Generations:      counters.Generations,
+			Swaps:            counters.Swaps,
+			Previews:         counters.Previews,
+			Grades:           counters.Grades,
+			Suggestions:      counters.Suggestions,
+			Searches:         counters.Searches,
+			EstimatedCostUSD: counters.EstimatedCostUSD,
+			DailyLimit:       quota.Limit,
+			DailyUsed:        quota.Used,
+			DailyRemaining:   quota.Remaining,
+			ResetAt:          quota.ResetAt,
+		})
+	}
+}
+
+// checkQuota enforces the free-tier daily cap, reserving one of the caller's
+// generations so concurrent requests can't overspend it. The caller records
+// usage with the reservation once the generation succeeds and must release it
+// when done, which gives the generation back if it failed. Clients with an
+// active Stripe subscription may continue past the cap, in which case billable
+// is true and the caller must report the usage too. When the caller has no
+// generations left, it writes a 429 quota-exceeded response and ok is false.
+// If the allowance can't be checked, it writes a 500 and ok is false.
+func checkQuota(s *server.Server, w http.ResponseWriter, r *http.Request) (res *usage.Reservation, billable, ok bool) {
+	key := clientKey(r)
+	quota, res, ok, err := s.Usage.Allow(r.Context(), key)
+	if err != nil {
+		s.Logger.Error("Failed to check daily quota", "client", key, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check usage. Please try again.")
+		return nil, false, false
+	}
+	if ok {
+		return res, false, true
+	}
+	if s.Billing.Active(r.Context(), key) {
+		return res, true, true
+	}
+
+	s.Logger.Warn("Daily quota exceeded", "client", key, "limit", quota.Limit)
+	t := throttled(r, apierror.CodeQuotaExceeded, reasonQuota,
+		"You have used all of today's free generations. Please try again after the reset time.",
+		time.Until(quota.ResetAt))
+	writeThrottled(w, http.StatusTooManyRequests, t, models.QuotaExceededResponse{
+		ThrottledResponse: t,
+		Limit:             quota.Limit,
+		ResetAt:           quota.ResetAt,
+	})
+	return nil, false, false
+}
+
+// reportBillableUsage reports one paid generation to Stripe in the background,
+// so a slow billing API never delays the image response. record is the usage
+// record ID from the quota reservation, so retries never bill twice.
+func reportBillableUsage(s *server.Server, key, record string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.Billing.ReportUsage(ctx, key, 1, "usage-"+record); err != nil {
+			s.Logger.Error("Failed to report billable usage to Stripe", "client", key, "record", record, "error", err)
+		}
+	}()
+}
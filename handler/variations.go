@@ -0,0 +1,214 @@
+// handler/variations.go
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/hooks"
+	"github.com/sanjayshr/event-outfitter-backend/imagemeta"
+	"github.com/sanjayshr/event-outfitter-backend/metrics"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxVariations is the most renderings of a style one request can ask for.
+const maxVariations = 4
+
+// validateCount checks the count of a generate or swap request.
+func validateCount(count int) error {
+	if count < 0 || count > maxVariations {
+		return fmt.Errorf("count must be between 1 and %d", maxVariations)
+	}
+	return nil
+}
+
+// checkVariationQuota reserves the extra variations of a request, since each
+// one is an image call, and rejects the request if the client's free
+// allowance doesn't have that many left. checkQuota has already reserved one.
+func checkVariationQuota(s *server.Server, w http.ResponseWriter, r *http.Request, res *usage.Reservation, billable bool, count int) bool {
+	if billable || count <= 1 {
+		return true
+	}
+	quota, ok, err := res.Reserve(r.Context(), int64(count-1))
+	if err != nil {
+		s.Logger.Error("Failed to check daily quota", "client", clientKey(r), "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check usage. Please try again.")
+		return false
+	}
+	if ok {
+		return true
+	}
+	t := throttled(r, apierror.CodeQuotaExceeded, reasonQuota,
+		fmt.Sprintf("Only %d of today's free generations are left. Ask for fewer variations or try again after the reset time.", quota.Remaining+1),
+		time.Until(quota.ResetAt))
+	writeThrottled(w, http.StatusTooManyRequests, t, models.QuotaExceededResponse{
+		ThrottledResponse: t,
+		Limit:             quota.Limit,
+		ResetAt:           quota.ResetAt,
+	})
+	return false
+}
+
+// variationJob is a generate or swap call that asked for several renderings
+// of one style.
+type variationJob struct {
+	// pipeline names the call in the stage metrics.
+	pipeline    string
+	kind        usage.Kind
+	quota       *usage.Reservation
+	billable    bool
+	sessionID   string
+	sessionData server.SessionData
+	styleIndex  int
+	count       int
+	hookReq     *hooks.Request
+	// input is the photo after the pre-generation hooks.
+	input hooks.Image
+	// failure is the error message if no variation could be rendered.
+	failure string
+}
+
+// variation is one rendering, or the reason it failed.
+type variation struct {
+	img      []byte
+	mimeType string
+	err      error
+	// hookErr is set if err came from a post-generation hook.
+	hookErr bool
+}
+
+// writeVariations renders the job's variations in parallel and writes them
+// as JSON. Variations that fail are left out; the request only fails if
+// none could be rendered.
+func writeVariations(s *server.Server, w http.ResponseWriter, r *http.Request, timing *metrics.Timing, job variationJob) {
+	style := job.sessionData.Styles[job.styleIndex].Description
+	meta := generationMetadata(s, r, job.sessionID, job.sessionData.RequestData, job.hookReq.Style)
+
+	endImage := timing.Start(metrics.StageImage)
+	results := make([]variation, job.count)
+	var renders errgroup.Group
+	for i := range results {
+		renders.Go(func() error {
+			results[i] = renderVariation(s, r, job, style, meta)
+			return nil
+		})
+	}
+	renders.Wait()
+	endImage()
+
+	var rendered []variation
+	for i, v := range results {
+		if v.err != nil {
+			s.Logger.Warn("Failed to render variation", "sessionID", job.sessionID, "variation", i, "error", v.err)
+			continue
+		}
+		rendered = append(rendered, v)
+	}
+	if len(rendered) == 0 {
+		if results[0].hookErr {
+			writeHookError(s, w, r, results[0].err)
+			return
+		}
+		writeImageError(s, w, r, results[0].err, job.sessionID, job.sessionData.Styles, job.styleIndex, job.failure)
+		return
+	}
+
+	// The first rendering is the call itself; the others are extra image calls
+	for i := range rendered {
+		kind := job.kind
+		if i > 0 {
+			kind = usage.KindSwap
+		}
+		record := job.quota.Record(r.Context(), kind)
+		if job.billable {
+			reportBillableUsage(s, clientKey(r), record)
+		}
+	}
+
+	endStorage := timing.Start(metrics.StageStorage)
+	lookIDs := make([]string, len(rendered))
+	var storage errgroup.Group
+	for i, v := range rendered {
+		storage.Go(func() error {
+			lookIDs[i] = recordLook(s, r, job.sessionID, job.sessionData, style, v.img, v.mimeType)
+			return nil
+		})
+	}
+	storage.Wait()
+	endStorage()
+
+	endPostprocess := timing.Start(metrics.StagePostprocess)
+	resp := models.ImageResponse{
+		SessionID:  job.sessionID,
+		LookID:     lookIDs[0],
+		Styles:     job.sessionData.Styles,
+		StyleIndex: job.styleIndex,
+		MimeType:   rendered[0].mimeType,
+		Variations: make([]models.ImageVariation, len(rendered)),
+	}
+	for i, v := range rendered {
+		// adaptImage sets the same headers for every variation
+		hw := w
+		if i > 0 {
+			hw = headerSink{w, http.Header{}}
+		}
+		img, mimeType := adaptImage(s, hw, r, v.img, v.mimeType)
+		if len(img) != len(v.img) {
+			img = tagImage(s, r, img, mimeType, meta)
+		}
+		// A signed URL serves the stored look, so only if it is the image sent
+		link := models.ImageResponse{LookID: lookIDs[i]}
+		if len(img) == len(v.img) && job.sessionData.E2EEKeyID == "" {
+			signLookURL(s, &link, mimeType, "")
+		}
+		resp.Variations[i] = models.ImageVariation{
+			LookID:            lookIDs[i],
+			MimeType:          mimeType,
+			ImageURL:          link.ImageURL,
+			ImageURLExpiresAt: link.ImageURLExpiresAt,
+		}
+		if link.ImageURL == "" {
+			resp.Variations[i].Image = base64.StdEncoding.EncodeToString(img)
+		}
+	}
+	endPostprocess()
+	finishTiming(s, w, job.pipeline, timing)
+
+	s.Logger.Info("Rendered variations", "sessionID", job.sessionID, "styleIndex", job.styleIndex, "requested", job.count, "rendered", len(rendered))
+	w.Header().Set("X-Look-ID", resp.LookID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// renderVariation generates one rendering of style and runs it through the
+// post-generation hooks.
+func renderVariation(s *server.Server, r *http.Request, job variationJob, style string, meta imagemeta.Fields) variation {
+	req := job.sessionData.RequestData
+	img, mimeType, err := s.Gemini.GenerateImage(r.Context(), job.input.Data, job.input.MimeType, req.EventType, req.Venue, req.Theme, style, imageOptions(req), job.sessionData.References...)
+	s.Status.Observe(r.Context(), status.ComponentGemini, err)
+	if err != nil {
+		return variation{err: err}
+	}
+	img, mimeType = frameGenerated(s, req, img, mimeType)
+	output, err := s.Hooks.Post(r.Context(), job.hookReq, hooks.Image{Data: img, MimeType: mimeType})
+	if err != nil {
+		return variation{err: err, hookErr: true}
+	}
+	return variation{img: tagImage(s, r, output.Data, output.MimeType, meta), mimeType: output.MimeType}
+}
+
+// headerSink discards the headers set on it.
+type headerSink struct {
+	http.ResponseWriter
+	header http.Header
+}
+
+func (h headerSink) Header() http.Header { return h.header }
@@ -0,0 +1,69 @@
+// handler/wearer.go
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+)
+
+// The values a generate request can describe the person with, mapped to how
+// they read in the prompts.
+var (
+	genders = map[string]string{
+		"woman":      "woman",
+		"man":        "man",
+		"non-binary": "non-binary person",
+	}
+	bodyTypes = map[string]string{
+		"petite":    "petite",
+		"tall":      "tall",
+		"slim":      "slim",
+		"athletic":  "athletic",
+		"curvy":     "curvy",
+		"plus-size": "plus-size",
+	}
+	fitPreferences = map[string]string{
+		"fitted":    "fitted",
+		"relaxed":   "relaxed",
+		"oversized": "loose, oversized",
+	}
+	modestyLevels = map[string]string{
+		"moderate": "covering the shoulders and knees, with no plunging necklines",
+		"full":     "covering the arms to the wrists and the legs to the ankles, loose rather than figure-hugging, with no sheer fabrics",
+	}
+)
+
+// validateWearer checks that the request's gender, bodyType, fitPreference
+// and modesty, when set, are known values.
+func validateWearer(req models.GenerateRequest) error {
+	fields := []struct {
+		name, value string
+		known       map[string]string
+	}{
+		{"gender", req.Gender, genders},
+		{"bodyType", req.BodyType, bodyTypes},
+		{"fitPreference", req.FitPreference, fitPreferences},
+		{"modesty", req.Modesty, modestyLevels},
+	}
+	for _, f := range fields {
+		if _, ok := f.known[f.value]; f.value != "" && !ok {
+			return fmt.Errorf("%s must be one of %s", f.name, strings.Join(slices.Sorted(maps.Keys(f.known)), ", "))
+		}
+	}
+	return nil
+}
+
+// wearer returns the prompt phrases of what req says about the person.
+func wearer(req models.GenerateRequest) prompt.Wearer {
+	return prompt.Wearer{
+		Gender:   genders[req.Gender],
+		BodyType: bodyTypes[req.BodyType],
+		Fit:      fitPreferences[req.FitPreference],
+		Modesty:  modestyLevels[req.Modesty],
+	}
+}
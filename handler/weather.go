@@ -0,0 +1,55 @@
+// handler/weather.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/server"
+)
+
+// maxEventDateAhead is how far ahead an eventDate may be.
+const maxEventDateAhead = 366 * 24 * time.Hour
+
+var errEventDate = errors.New("eventDate must be a date as YYYY-MM-DD, from today up to a year ahead")
+
+// validateEventDate checks the request's eventDate, if any. A day of slack
+// before today allows for clients in time zones behind UTC.
+func validateEventDate(req models.GenerateRequest) error {
+	if req.EventDate == "" {
+		return nil
+	}
+	date, err := time.Parse(time.DateOnly, req.EventDate)
+	if err != nil {
+		return errEventDate
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if date.Before(today.AddDate(0, 0, -1)) || date.After(today.Add(maxEventDateAhead)) {
+		return errEventDate
+	}
+	return nil
+}
+
+// eventWeather summarizes the weather expected at the request's venue on its
+// eventDate for the suggestions prompt, e.g. "24-32°C, humid". It returns ""
+// if the lookup is disabled, there is no date or the lookup fails, since
+// suggestions don't depend on it.
+func eventWeather(ctx context.Context, s *server.Server, req models.GenerateRequest) string {
+	if s.Weather == nil || req.EventDate == "" {
+		return ""
+	}
+	date, err := time.Parse(time.DateOnly, req.EventDate)
+	if err != nil {
+		return ""
+	}
+	conditions, err := s.Weather.Lookup(ctx, req.Venue, date)
+	if err != nil {
+		s.Logger.Warn("Failed to look up the event's weather", "venue", req.Venue, "eventDate", req.EventDate, "error", err)
+		return ""
+	}
+	summary := conditions.Summary()
+	s.Logger.Info("Looked up the event's weather", "place", conditions.Place, "eventDate", req.EventDate, "weather", summary)
+	return summary
+}
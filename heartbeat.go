@@ -0,0 +1,187 @@
+// heartbeat.go
+package main
+
+import (
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statusTrailer carries the real status of a response whose 200 was already
+// sent by a whitespace heartbeat.
+const statusTrailer = "X-Response-Status"
+
+// heartbeatWriter lets a background ticker keep the connection busy while
+// the handler is still working. Headers are buffered until the final
+// response so the ticker never reads the map the handler is writing.
+type heartbeatWriter struct {
+	http.ResponseWriter
+	header http.Header
+	mode   string
+
+	mu      sync.Mutex
+	started bool
+	// committed is set once a whitespace beat has sent the 200 and status
+	// records what the handler meant to send instead.
+	committed bool
+	status    int
+}
+
+func (h *heartbeatWriter) Header() http.Header { return h.header }
+
+// start copies the buffered headers through. The caller must hold h.mu.
+func (h *heartbeatWriter) start() {
+	if !h.started {
+		h.started = true
+		if !h.committed {
+			maps.Copy(h.ResponseWriter.Header(), h.header)
+		}
+	}
+}
+
+func (h *heartbeatWriter) WriteHeader(code int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.committed {
+		if h.status == 0 && code >= 200 {
+			h.status = code
+		}
+		h.started = true
+		return
+	}
+	h.start()
+	h.ResponseWriter.WriteHeader(code)
+}
+
+func (h *heartbeatWriter) Write(b []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.committed && h.status == 0 {
+		h.status = http.StatusOK
+	}
+	h.start()
+	return h.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (h *heartbeatWriter) Unwrap() http.ResponseWriter { return h.ResponseWriter }
+
+// beat sends a keep-alive unless the final response has begun: an interim
+// 102 Processing response, or in whitespace mode a space ahead of the JSON
+// body, which commits the response to 200.
+func (h *heartbeatWriter) beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.started {
+		return
+	}
+	if h.mode != "whitespace" {
+		h.ResponseWriter.WriteHeader(http.StatusProcessing)
+		return
+	}
+	if !h.committed {
+		h.committed = true
+		header := h.ResponseWriter.Header()
+		header.Set("Content-Type", "application/json")
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Heartbeat", "whitespace")
+		header.Set("Trailer", statusTrailer)
+		h.ResponseWriter.WriteHeader(http.StatusOK)
+	}
+	h.ResponseWriter.Write([]byte(" "))
+	http.NewResponseController(h.ResponseWriter).Flush()
+}
+
+// finish stops any further beats. If a whitespace beat already sent the 200,
+// the handler's status and headers go out as trailers instead.
+func (h *heartbeatWriter) finish() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.start()
+	if !h.committed {
+		return
+	}
+	status := h.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := h.ResponseWriter.Header()
+	header.Set(statusTrailer, strconv.Itoa(status))
+	for k, v := range h.header {
+		if k != "Content-Type" && k != "Content-Length" {
+			header[http.TrailerPrefix+k] = v
+		}
+	}
+}
+
+// acceptsJSON reports whether the client named application/json in Accept,
+// the only responses a leading space can't corrupt.
+func acceptsJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "application/json") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok && k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// withHeartbeat keeps the connection busy every interval until the handler
+// responds, so proxies with idle timeouts shorter than a Gemini call keep it
+// open. The response carries X-Request-Timeout, the seconds the server will
+// keep working, as a hint for clients.
+//
+// In processing mode each beat is a 102 Processing interim response,
+// which HTTP clients skip, so the final response is unchanged.
+//
+// In whitespace mode, for proxies and clients that drop or reject
+// 1xx responses, the first beat sends 200 with a JSON content type and each
+// beat writes a space, which JSON parsers ignore. Only requests that accept
+// application/json get beats. Once the 200 is sent, the handler's status
+// arrives in the X-Response-Status trailer and its headers as trailers.
+//
+// A zero interval, or an HTTP/1.0 client, which can receive neither interim
+// responses nor trailers, disables it.
+func withHeartbeat(interval, timeout time.Duration, mode string, next http.Handler) http.Handler {
+	if interval <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.ProtoAtLeast(1, 1) || mode == "whitespace" && !acceptsJSON(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-Request-Timeout", strconv.Itoa(int(timeout.Seconds())))
+		hw := &heartbeatWriter{ResponseWriter: w, header: make(http.Header), mode: mode}
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					hw.beat()
+				}
+			}
+		}()
+		next.ServeHTTP(hw, r)
+
+		// Stop any further beats before the server finishes the response.
+		hw.finish()
+	})
+}
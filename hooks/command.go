@@ -0,0 +1,87 @@
+// hooks/command.go
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// rejectExitCode is the exit status a command uses to reject an image.
+const rejectExitCode = 2
+
+// Command is a hook that runs an external program. The image is written to
+// its stdin and the replacement image is read from its stdout; empty output
+// leaves the image unchanged. Request details are passed as DRESWAP_*
+// environment variables, which with PATH are the program's whole environment,
+// so it never sees the server's secrets. Exiting with status 2 rejects the image, any other
+// failure is an error.
+type Command struct {
+	Path    string
+	Args    []string
+	Timeout time.Duration
+}
+
+// ParseCommand splits a command line such as "/opt/hooks/watermark --corner br"
+// into a Command.
+func ParseCommand(cmdline string, timeout time.Duration) (*Command, error) {
+	fields := strings.Fields(cmdline)
+	if len(fields) == 0 {
+		return nil, errors.New("empty hook command")
+	}
+	return &Command{Path: fields[0], Args: fields[1:], Timeout: timeout}, nil
+}
+
+// PreGenerate implements PreGenerateHook.
+func (c *Command) PreGenerate(ctx context.Context, req *Request, img Image) (Image, error) {
+	return c.run(ctx, StagePre, req, img)
+}
+
+// PostGenerate implements PostGenerateHook.
+func (c *Command) PostGenerate(ctx context.Context, req *Request, img Image) (Image, error) {
+	return c.run(ctx, StagePost, req, img)
+}
+
+func (c *Command) run(ctx context.Context, stage string, req *Request, img Image) (Image, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Stdin = bytes.NewReader(img.Data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"DRESWAP_HOOK_STAGE=" + stage,
+		"DRESWAP_MIME_TYPE=" + img.MimeType,
+		"DRESWAP_OWNER=" + req.Owner,
+		"DRESWAP_SESSION_ID=" + req.SessionID,
+		"DRESWAP_EVENT_TYPE=" + req.EventType,
+		"DRESWAP_VENUE=" + req.Venue,
+		"DRESWAP_THEME=" + req.Theme,
+		"DRESWAP_STYLE=" + req.Style,
+	}
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == rejectExitCode {
+			return img, fmt.Errorf("%w: %s", ErrRejected, msg)
+		}
+		return img, fmt.Errorf("%s: %w: %s", c.Path, err, msg)
+	}
+	if stdout.Len() == 0 {
+		return img, nil
+	}
+	return Image{Data: stdout.Bytes(), MimeType: http.DetectContentType(stdout.Bytes())}, nil
+}
@@ -0,0 +1,124 @@
+// hooks/hooks.go
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ErrRejected is returned when a hook refuses to process an image, e.g. a
+// compliance filter. The request fails instead of returning an image.
+var ErrRejected = errors.New("image rejected by hook")
+
+// Stages at which hooks run.
+const (
+	StagePre  = "pre"
+	StagePost = "post"
+)
+
+// Image is an image passing through the pipeline.
+type Image struct {
+	Data     []byte
+	MimeType string
+}
+
+// Request describes the generation an image belongs to.
+type Request struct {
+	// Owner is the client the image belongs to, e.g. "key:<id>" for a tenant.
+	Owner     string
+	SessionID string
+	EventType string
+	Venue     string
+	Theme     string
+	Style     string
+}
+
+// PreGenerateHook processes the uploaded photo before it is sent to Gemini.
+type PreGenerateHook interface {
+	PreGenerate(ctx context.Context, req *Request, img Image) (Image, error)
+}
+
+// PostGenerateHook processes a generated image before it is stored and returned,
+// e.g. a watermarker or compliance stamper.
+type PostGenerateHook interface {
+	PostGenerate(ctx context.Context, req *Request, img Image) (Image, error)
+}
+
+// Pipeline runs the registered hooks in registration order. A nil Pipeline
+// passes images through unchanged.
+type Pipeline struct {
+	logger *slog.Logger
+	pre    []PreGenerateHook
+	post   []PostGenerateHook
+}
+
+// NewPipeline creates an empty Pipeline.
+func NewPipeline(logger *slog.Logger) *Pipeline {
+	return &Pipeline{logger: logger}
+}
+
+// AddPre appends a pre-generation hook.
+func (p *Pipeline) AddPre(h PreGenerateHook) {
+	p.pre = append(p.pre, h)
+}
+
+// AddPost appends a post-generation hook.
+func (p *Pipeline) AddPost(h PostGenerateHook) {
+	p.post = append(p.post, h)
+}
+
+// Register adds h at every stage it implements, as used for Go plugins.
+func (p *Pipeline) Register(h any) error {
+	pre, isPre := h.(PreGenerateHook)
+	post, isPost := h.(PostGenerateHook)
+	if !isPre && !isPost {
+		return fmt.Errorf("hook %T implements neither PreGenerate nor PostGenerate", h)
+	}
+	if isPre {
+		p.AddPre(pre)
+	}
+	if isPost {
+		p.AddPost(post)
+	}
+	return nil
+}
+
+// Len returns the number of registered hooks.
+func (p *Pipeline) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.pre) + len(p.post)
+}
+
+// Pre runs the pre-generation hooks over img.
+func (p *Pipeline) Pre(ctx context.Context, req *Request, img Image) (Image, error) {
+	if p == nil {
+		return img, nil
+	}
+	for _, h := range p.pre {
+		out, err := h.PreGenerate(ctx, req, img)
+		if err != nil {
+			return img, fmt.Errorf("pre-generation hook %T: %w", h, err)
+		}
+		img = out
+	}
+	return img, nil
+}
+
+// Post runs the post-generation hooks over img.
+func (p *Pipeline) Post(ctx context.Context, req *Request, img Image) (Image, error) {
+	if p == nil {
+		return img, nil
+	}
+	for _, h := range p.post {
+		out, err := h.PostGenerate(ctx, req, img)
+		if err != nil {
+			return img, fmt.Errorf("post-generation hook %T: %w", h, err)
+		}
+		img = out
+	}
+	return img, nil
+}
@@ -0,0 +1,29 @@
+// hooks/plugin.go
+package hooks
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// pluginSymbol is the exported variable a hook plugin must define.
+const pluginSymbol = "Hook"
+
+// LoadPlugin opens a Go plugin built with -buildmode=plugin and registers its
+// exported Hook variable, which must implement PreGenerateHook,
+// PostGenerateHook or both. Plugins must be built with the same Go version
+// and dependency versions as the server.
+func (p *Pipeline) LoadPlugin(path string) error {
+	plug, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open hook plugin %s: %w", path, err)
+	}
+	sym, err := plug.Lookup(pluginSymbol)
+	if err != nil {
+		return fmt.Errorf("hook plugin %s: %w", path, err)
+	}
+	if err := p.Register(sym); err != nil {
+		return fmt.Errorf("hook plugin %s: %w", path, err)
+	}
+	return nil
+}
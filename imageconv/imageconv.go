@@ -0,0 +1,226 @@
+// imageconv/imageconv.go
+//
+// Package imageconv converts images in formats the server cannot decode or
+// encode itself, such as the HEIC photos iPhones take by default, AVIF and WebP,
+// by running an external program.
+package imageconv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultMaxOutput bounds a conversion's output when Converter.MaxOutput is 0.
+const DefaultMaxOutput = 256 << 20
+
+// maxStderr is how much of a program's error output is kept for the error message.
+const maxStderr = 4 << 10
+
+// ErrOutputTooLarge is returned when a program writes more than its MaxOutput.
+var ErrOutputTooLarge = errors.New("converted image is too large")
+
+// heicBrands are the ISO BMFF brands of HEIF files holding HEVC-coded
+// images. Generic HEIF brands (mif1, msf1) are also used by AVIF files, so
+// they only count together with one of these as a compatible brand.
+var heicBrands = []string{"heic", "heix", "heim", "heis", "hevc", "hevx", "hevm", "hevs"}
+
+// avifBrands are the ISO BMFF brands of AVIF images and image sequences.
+var avifBrands = []string{"avif", "avis"}
+
+// IsHEIC reports whether data is a HEIC photo, i.e. an HEIF file of HEVC-coded images.
+func IsHEIC(data []byte) bool {
+	return hasBrand(data, heicBrands)
+}
+
+// IsAVIF reports whether data is an AVIF image.
+func IsAVIF(data []byte) bool {
+	return hasBrand(data, avifBrands) && !IsHEIC(data)
+}
+
+// IsWebP reports whether data is a WebP image.
+func IsWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// hasBrand reports whether data starts with an ftyp box whose major or
+// compatible brands include one of brands.
+func hasBrand(data []byte, brands []string) bool {
+	// An ftyp box: 4-byte size, "ftyp", major brand, minor version, compatible brands.
+	if len(data) < 16 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3]))
+	if size < 16 || size > len(data) {
+		return false
+	}
+	if slices.Contains(brands, string(data[8:12])) {
+		return true
+	}
+	for i := 16; i+4 <= size; i += 4 {
+		if slices.Contains(brands, string(data[i:i+4])) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dimensions returns the largest image size declared in an HEIF file's
+// item properties (its ispe boxes), so HEIC and AVIF photos can be checked
+// before they are converted. ok is false if data declares none.
+func Dimensions(data []byte) (width, height int, ok bool) {
+	meta, found := findBox(data, "meta")
+	if !found || len(meta) < 4 {
+		return 0, 0, false
+	}
+	// meta is a full box: version and flags come before its children.
+	iprp, found := findBox(meta[4:], "iprp")
+	if !found {
+		return 0, 0, false
+	}
+	ipco, found := findBox(iprp, "ipco")
+	if !found {
+		return 0, 0, false
+	}
+	eachBox(ipco, func(typ string, body []byte) {
+		// ispe: version and flags, then 32-bit width and height.
+		if typ != "ispe" || len(body) < 12 {
+			return
+		}
+		w := int(binary.BigEndian.Uint32(body[4:8]))
+		h := int(binary.BigEndian.Uint32(body[8:12]))
+		if !ok || int64(w)*int64(h) > int64(width)*int64(height) {
+			width, height, ok = w, h, true
+		}
+	})
+	return width, height, ok
+}
+
+// findBox returns the body of the first box of type typ in data.
+func findBox(data []byte, typ string) (body []byte, ok bool) {
+	eachBox(data, func(t string, b []byte) {
+		if !ok && t == typ {
+			body, ok = b, true
+		}
+	})
+	return body, ok
+}
+
+// eachBox calls fn with the type and body of each ISO BMFF box in data,
+// stopping at the first malformed one.
+func eachBox(data []byte, fn func(typ string, body []byte)) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		typ := string(data[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+			size, header = binary.BigEndian.Uint64(data[8:16]), 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return
+		}
+		fn(typ, data[header:size])
+		data = data[size:]
+	}
+}
+
+// Converter runs an external program that reads an image on stdin and writes
+// the converted image to stdout, such as "magick heic:- jpeg:-".
+type Converter struct {
+	Path    string
+	Args    []string
+	Timeout time.Duration
+	// MaxOutput bounds the converted image, in bytes; the program is stopped
+	// once it writes more. 0 means DefaultMaxOutput.
+	MaxOutput int64
+	// Slots, when set, limits how many conversions run at once: each takes a
+	// slot for as long as its program runs. Converters may share one.
+	Slots chan struct{}
+}
+
+// Parse splits a command line into a Converter.
+func Parse(cmdline string, timeout time.Duration) (*Converter, error) {
+	fields := strings.Fields(cmdline)
+	if len(fields) == 0 {
+		return nil, errors.New("empty converter command")
+	}
+	return &Converter{Path: fields[0], Args: fields[1:], Timeout: timeout}, nil
+}
+
+// Convert runs the program on data and returns its output.
+func (c *Converter) Convert(ctx context.Context, data []byte) ([]byte, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	if c.Slots != nil {
+		select {
+		case c.Slots <- struct{}{}:
+			defer func() { <-c.Slots }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: %w", c.Path, ctx.Err())
+		}
+	}
+
+	maxOutput := c.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = DefaultMaxOutput
+	}
+	stdout := &limitedBuffer{max: maxOutput, err: ErrOutputTooLarge}
+	stderr := &limitedBuffer{max: maxStderr}
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if stdout.full {
+			return nil, fmt.Errorf("%s: %w", c.Path, ErrOutputTooLarge)
+		}
+		return nil, fmt.Errorf("%s: %w: %s", c.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s: no output", c.Path)
+	}
+	return stdout.Bytes(), nil
+}
+
+// limitedBuffer keeps at most max bytes. Past that, writes fail with err, or
+// are silently dropped if err is nil. It doesn't embed bytes.Buffer, whose
+// ReadFrom would let io.Copy bypass the limit.
+type limitedBuffer struct {
+	buf  bytes.Buffer
+	max  int64
+	err  error
+	full bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	room := b.max - int64(b.buf.Len())
+	if int64(len(p)) <= room {
+		return b.buf.Write(p)
+	}
+	b.full = true
+	if b.err != nil {
+		return 0, b.err
+	}
+	b.buf.Write(p[:max(room, 0)])
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Len() int       { return b.buf.Len() }
+func (b *limitedBuffer) Bytes() []byte  { return b.buf.Bytes() }
+func (b *limitedBuffer) String() string { return b.buf.String() }
@@ -0,0 +1,141 @@
+// imagemeta/imagemeta.go
+//
+// Package imagemeta embeds the parameters of a generation in the XMP
+// metadata of the resulting PNG or JPEG, so an image shared as a file, e.g.
+// in a bug report about a bad generation, can be traced back to the request
+// that produced it.
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"hash/crc32"
+	"time"
+)
+
+// Fields are the generation parameters written to an image.
+type Fields struct {
+	EventType string
+	Venue     string
+	Theme     string
+	Style     string
+	// Model is the image model that generated the image.
+	Model string
+	// SessionRef identifies the session without being usable as one.
+	SessionRef string
+	RequestID  string
+	CreatedAt  time.Time
+}
+
+// ErrTooLarge is returned when the metadata does not fit in a JPEG segment.
+var ErrTooLarge = errors.New("metadata too large for a JPEG segment")
+
+const (
+	// xmpKeyword is the PNG iTXt keyword of an XMP packet.
+	xmpKeyword = "XML:com.adobe.xmp"
+	// xmpNamespace starts the JPEG APP1 segment of an XMP packet.
+	xmpNamespace = "http://ns.adobe.com/xap/1.0/\x00"
+	// aiGenerated is the IPTC digital source type of AI-generated images.
+	aiGenerated = "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Embed returns img with f written as an XMP packet. Images other than PNG
+// and JPEG, and images that already carry XMP, such as provenance metadata
+// added by the model, are returned unchanged.
+func Embed(img []byte, mimeType string, f Fields) ([]byte, error) {
+	switch mimeType {
+	case "image/png":
+		return embedPNG(img, packet(f))
+	case "image/jpeg":
+		return embedJPEG(img, packet(f))
+	}
+	return img, nil
+}
+
+// packet renders f as an XMP packet.
+func packet(f Fields) []byte {
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/"` +
+		` xmlns:Iptc4xmpExt="http://iptc.org/std/Iptc4xmpExt/2008-02-29/" xmlns:dreswap="https://dreswap.app/ns/1.0/"`)
+	attr := func(name, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString("\n " + name + `="`)
+		xml.EscapeText(&b, []byte(value))
+		b.WriteString(`"`)
+	}
+	attr("xmp:CreatorTool", "DreSwap")
+	if !f.CreatedAt.IsZero() {
+		attr("xmp:CreateDate", f.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	attr("Iptc4xmpExt:DigitalSourceType", aiGenerated)
+	attr("dreswap:EventType", f.EventType)
+	attr("dreswap:Venue", f.Venue)
+	attr("dreswap:Theme", f.Theme)
+	attr("dreswap:Style", f.Style)
+	attr("dreswap:Model", f.Model)
+	attr("dreswap:SessionRef", f.SessionRef)
+	attr("dreswap:RequestID", f.RequestID)
+	b.WriteString("/>\n</rdf:RDF></x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// embedPNG adds xmp as an iTXt chunk right after the IHDR chunk.
+func embedPNG(img, xmp []byte) ([]byte, error) {
+	// The signature, then IHDR: 4-byte length, "IHDR", 13 bytes of data and a CRC.
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(img) < ihdrEnd || !bytes.Equal(img[:8], pngSignature) || string(img[12:16]) != "IHDR" {
+		return nil, errors.New("not a PNG image")
+	}
+	if bytes.Contains(img, []byte("iTXt"+xmpKeyword)) {
+		return img, nil
+	}
+	// keyword, null separator, compression flag and method, empty language
+	// tag and translated keyword, each null-terminated, then the text.
+	data := append([]byte(xmpKeyword+"\x00\x00\x00\x00\x00"), xmp...)
+	chunk := make([]byte, 0, 12+len(data))
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, "iTXt"...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	out := make([]byte, 0, len(img)+len(chunk))
+	out = append(out, img[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, img[ihdrEnd:]...), nil
+}
+
+// embedJPEG adds xmp as an APP1 segment after the SOI marker and any JFIF
+// APP0 segment, which must come first.
+func embedJPEG(img, xmp []byte) ([]byte, error) {
+	if len(img) < 4 || img[0] != 0xFF || img[1] != 0xD8 {
+		return nil, errors.New("not a JPEG image")
+	}
+	if bytes.Contains(img, []byte(xmpNamespace)) {
+		return img, nil
+	}
+	segment := append([]byte(xmpNamespace), xmp...)
+	if len(segment)+2 > 0xFFFF {
+		return nil, ErrTooLarge
+	}
+	at := 2
+	if img[2] == 0xFF && img[3] == 0xE0 && len(img) >= 6 {
+		at = 4 + int(binary.BigEndian.Uint16(img[4:6]))
+		if at > len(img) {
+			return nil, errors.New("truncated JPEG image")
+		}
+	}
+	out := make([]byte, 0, len(img)+4+len(segment))
+	out = append(out, img[:at]...)
+	out = append(out, 0xFF, 0xE1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, img[at:]...), nil
+}
@@ -0,0 +1,338 @@
+// looks/bulk.go
+package looks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bulk actions on a user's history.
+const (
+	BulkArchive   = "archive"
+	BulkUnarchive = "unarchive"
+	BulkDelete    = "delete"
+)
+
+// Bulk job states.
+const (
+	BulkRunning = "running"
+	BulkDone    = "done"
+	// BulkFailed means the job stopped early because the store failed; the
+	// looks not yet processed were left unchanged.
+	BulkFailed = "failed"
+)
+
+const (
+	// maxBulkSelection caps how many looks one job may name explicitly.
+	maxBulkSelection = 1000
+	// bulkJobTTL is how long finished jobs stay available for progress polling.
+	bulkJobTTL = time.Hour
+	// MaxBulkJobsPerOwner is how many bulk jobs one owner may run at once.
+	MaxBulkJobsPerOwner = 2
+)
+
+var (
+	ErrInvalidBulkAction    = errors.New("invalid bulk action")
+	ErrInvalidBulkSelection = errors.New("invalid bulk selection")
+	ErrBulkJobNotFound      = errors.New("bulk job not found")
+	ErrTooManyBulkJobs      = errors.New("too many running bulk jobs")
+)
+
+// BulkSelection picks the looks a bulk job applies to: either explicit IDs or
+// every look created in [From, To). A zero bound is open.
+type BulkSelection struct {
+	IDs  []string
+	From time.Time
+	To   time.Time
+}
+
+// BulkJob tracks an asynchronous bulk operation on one owner's looks.
+type BulkJob struct {
+	ID        string
+	Owner     string
+	Action    string
+	Status    string
+	Total     int
+	Processed int
+	Failed    int
+	CreatedAt time.Time
+	// FinishedAt is set once every selected look has been processed, or the
+	// job failed.
+	FinishedAt *time.Time
+	// Error says why a failed job stopped.
+	Error string
+}
+
+// bulkJobs holds running and recently finished bulk jobs in memory.
+type bulkJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*BulkJob
+}
+
+// ownerIndex maps each owner to the IDs and creation times of their looks,
+// so one owner's history doesn't need a scan of every look.
+type ownerIndex struct {
+	mu      sync.RWMutex
+	looks   map[string]map[string]time.Time
+	ownerOf map[string]string
+}
+
+func newOwnerIndex() *ownerIndex {
+	return &ownerIndex{looks: make(map[string]map[string]time.Time), ownerOf: make(map[string]string)}
+}
+
+// add records look under its owner.
+func (o *ownerIndex) add(look *Look) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.looks[look.Owner] == nil {
+		o.looks[look.Owner] = make(map[string]time.Time)
+	}
+	o.looks[look.Owner][look.ID] = look.CreatedAt
+	o.ownerOf[look.ID] = look.Owner
+}
+
+// remove deletes id from the index.
+func (o *ownerIndex) remove(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	owner, ok := o.ownerOf[id]
+	if !ok {
+		return
+	}
+	delete(o.ownerOf, id)
+	if delete(o.looks[owner], id); len(o.looks[owner]) == 0 {
+		delete(o.looks, owner)
+	}
+}
+
+// ids returns the IDs of owner's looks created in [from, to). A zero bound is open.
+func (o *ownerIndex) ids(owner string, from, to time.Time) []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var out []string
+	for id, created := range o.looks[owner] {
+		if (!from.IsZero() && created.Before(from)) || (!to.IsZero() && !created.Before(to)) {
+			continue
+		}
+		out = append(out, id)
+	}
+	return out
+}
+
+// History returns owner's looks, newest first. Archived looks are only
+// included when archived is set, in which case only they are returned. If
+// tags are given, which must be normalized, only looks with all of them are
+// returned.
+func (r *Repository) History(ctx context.Context, owner string, archived bool, tags []string) ([]*Look, error) {
+	var out []*Look
+	for _, id := range r.owned.ids(owner, time.Time{}, time.Time{}) {
+		look, err := r.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if (look.ArchivedAt != nil) == archived && look.HasTags(tags) {
+			out = append(out, look)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// Archive hides a look from the owner's history without deleting it.
+func (r *Repository) Archive(ctx context.Context, id string) (*Look, error) {
+	return r.update(ctx, id, func(look *Look) error {
+		if look.ArchivedAt == nil {
+			now := time.Now().UTC()
+			look.ArchivedAt = &now
+		}
+		return nil
+	})
+}
+
+// Unarchive returns an archived look to the owner's history.
+func (r *Repository) Unarchive(ctx context.Context, id string) (*Look, error) {
+	return r.update(ctx, id, func(look *Look) error {
+		look.ArchivedAt = nil
+		return nil
+	})
+}
+
+// Delete permanently removes a look, its images and its index entry. Gallery
+// entries go with it.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ns := range []string{ImageNamespace, eventPhotoNamespace} {
+		if err := r.images.Delete(ctx, ns, id); err != nil {
+			return fmt.Errorf("failed to delete look image: %w", err)
+		}
+		if r.images != r.store {
+			if err := r.store.Delete(ctx, ns, id); err != nil {
+				return fmt.Errorf("failed to delete look image: %w", err)
+			}
+		}
+	}
+	if err := r.store.Delete(ctx, namespace, id); err != nil {
+		return err
+	}
+	r.index.Remove(id)
+	r.gallery.remove(id)
+	r.owned.remove(id)
+	return nil
+}
+
+// StartBulk validates a bulk action on owner's looks and runs it in the
+// background. Progress is available from BulkJob until an hour after it ends.
+// An owner may run MaxBulkJobsPerOwner jobs at once.
+func (r *Repository) StartBulk(owner, action string, sel BulkSelection) (*BulkJob, error) {
+	switch action {
+	case BulkArchive, BulkUnarchive, BulkDelete:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBulkAction, action)
+	}
+	if len(sel.IDs) == 0 && sel.From.IsZero() && sel.To.IsZero() {
+		return nil, fmt.Errorf("%w: lookIds or a date range is required", ErrInvalidBulkSelection)
+	}
+	if len(sel.IDs) > maxBulkSelection {
+		return nil, fmt.Errorf("%w: at most %d lookIds per job", ErrInvalidBulkSelection, maxBulkSelection)
+	}
+	if !sel.From.IsZero() && !sel.To.IsZero() && !sel.From.Before(sel.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidBulkSelection)
+	}
+
+	job := &BulkJob{
+		ID:        uuid.New().String(),
+		Owner:     owner,
+		Action:    action,
+		Status:    BulkRunning,
+		CreatedAt: time.Now().UTC(),
+	}
+	r.bulk.mu.Lock()
+	if r.bulk.jobs == nil {
+		r.bulk.jobs = make(map[string]*BulkJob)
+	}
+	running := 0
+	for id, j := range r.bulk.jobs {
+		if j.FinishedAt != nil && time.Since(*j.FinishedAt) > bulkJobTTL {
+			delete(r.bulk.jobs, id)
+		}
+		if j.Owner == owner && j.Status == BulkRunning {
+			running++
+		}
+	}
+	if running >= MaxBulkJobsPerOwner {
+		r.bulk.mu.Unlock()
+		return nil, ErrTooManyBulkJobs
+	}
+	r.bulk.jobs[job.ID] = job
+	snapshot := *job
+	r.bulk.mu.Unlock()
+
+	go r.runBulk(job, sel)
+	return &snapshot, nil
+}
+
+// BulkJobs returns snapshots of every owner's running and recently finished
+// bulk jobs, newest first, for operators.
+func (r *Repository) BulkJobs() []*BulkJob {
+	r.bulk.mu.Lock()
+	defer r.bulk.mu.Unlock()
+	out := make([]*BulkJob, 0, len(r.bulk.jobs))
+	for _, job := range r.bulk.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > bulkJobTTL {
+			continue
+		}
+		snapshot := *job
+		out = append(out, &snapshot)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// BulkJob returns a snapshot of an owner's bulk job.
+func (r *Repository) BulkJob(owner, id string) (*BulkJob, error) {
+	r.bulk.mu.Lock()
+	defer r.bulk.mu.Unlock()
+	job, ok := r.bulk.jobs[id]
+	if !ok || job.Owner != owner {
+		return nil, ErrBulkJobNotFound
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// runBulk resolves the selection and applies the job's action to each look.
+// Looks that are missing or belong to someone else count as failed; any other
+// error means the store is failing, so the job stops and is marked failed.
+func (r *Repository) runBulk(job *BulkJob, sel BulkSelection) {
+	ctx := context.Background()
+	update := func(fn func(*BulkJob)) {
+		r.bulk.mu.Lock()
+		defer r.bulk.mu.Unlock()
+		fn(job)
+	}
+	finish := func(status string, err error) {
+		update(func(j *BulkJob) {
+			now := time.Now().UTC()
+			j.Status, j.FinishedAt = status, &now
+			if err != nil {
+				j.Error = err.Error()
+			}
+		})
+	}
+
+	ids := sel.IDs
+	if len(ids) == 0 {
+		ids = r.owned.ids(job.Owner, sel.From, sel.To)
+	}
+	update(func(j *BulkJob) { j.Total = len(ids) })
+
+	for _, id := range ids {
+		err := r.applyBulk(ctx, job, id)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			r.logger.Error("Bulk job failed", "jobID", job.ID, "action", job.Action, "lookID", id, "error", err)
+			finish(BulkFailed, fmt.Errorf("look %s: %w", id, err))
+			return
+		}
+		if err != nil {
+			r.logger.Warn("Bulk action failed", "jobID", job.ID, "action", job.Action, "lookID", id, "error", err)
+		}
+		update(func(j *BulkJob) {
+			j.Processed++
+			if err != nil {
+				j.Failed++
+			}
+		})
+	}
+	finish(BulkDone, nil)
+	r.logger.Info("Bulk job finished", "jobID", job.ID, "action", job.Action, "total", len(ids))
+}
+
+func (r *Repository) applyBulk(ctx context.Context, job *BulkJob, id string) error {
+	look, err := r.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if look.Owner != job.Owner {
+		return ErrNotFound
+	}
+	switch job.Action {
+	case BulkArchive:
+		_, err = r.Archive(ctx, id)
+	case BulkUnarchive:
+		_, err = r.Unarchive(ctx, id)
+	case BulkDelete:
+		err = r.Delete(ctx, id)
+	}
+	return err
+}
@@ -0,0 +1,234 @@
+// looks/gallery.go
+package looks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImageNamespace holds the generated image bytes for each look, in the
+// repository's image store.
+const ImageNamespace = "look-images"
+
+// Gallery moderation states. A look enters GalleryPending when its owner opts in
+// to publishing; only approved looks are publicly visible.
+const (
+	GalleryNone     = ""
+	GalleryPending  = "pending"
+	GalleryApproved = "approved"
+	GalleryRejected = "rejected"
+)
+
+// Moderation actions available to admins.
+const (
+	ActionApprove   = "approve"
+	ActionReject    = "reject"
+	ActionFeature   = "feature"
+	ActionUnfeature = "unfeature"
+)
+
+var (
+	// ErrInvalidTransition is returned for moderation actions not allowed in the look's current state.
+	ErrInvalidTransition = errors.New("invalid gallery state transition")
+	// ErrNoImage is returned when a look has no stored image.
+	ErrNoImage = errors.New("look has no stored image")
+)
+
+// GalleryInfo holds a look's publication and moderation state.
+type GalleryInfo struct {
+	Status string `json:"status"`
+	// DisplayName is shown as the creator credit when ShowAttribution is set.
+	DisplayName     string     `json:"displayName,omitempty"`
+	ShowAttribution bool       `json:"showAttribution"`
+	Featured        bool       `json:"featured"`
+	SubmittedAt     time.Time  `json:"submittedAt"`
+	ModeratedAt     *time.Time `json:"moderatedAt,omitempty"`
+	ModerationNote  string     `json:"moderationNote,omitempty"`
+}
+
+// SaveImage stores the generated image for a look.
+func (r *Repository) SaveImage(ctx context.Context, id string, data []byte) error {
+	return r.images.Put(ctx, ImageNamespace, id, data)
+}
+
+// Image loads the generated image for a look.
+func (r *Repository) Image(ctx context.Context, id string) ([]byte, string, error) {
+	look, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := r.getImage(ctx, ImageNamespace, id)
+	if err != nil {
+		return nil, "", ErrNoImage
+	}
+	return data, look.MimeType, nil
+}
+
+// Submit opts a look into the public gallery, placing it in the moderation
+// queue. Resubmitting a look, even an approved one, takes it out of the
+// gallery until it is moderated again, since the display name may change.
+func (r *Repository) Submit(ctx context.Context, id, displayName string, showAttribution bool) (*Look, error) {
+	return r.update(ctx, id, func(look *Look) error {
+		look.Gallery = &GalleryInfo{
+			Status:          GalleryPending,
+			DisplayName:     displayName,
+			ShowAttribution: showAttribution,
+			SubmittedAt:     time.Now().UTC(),
+		}
+		look.Public = false
+		return nil
+	})
+}
+
+// Withdraw removes a look from the gallery and the moderation queue.
+func (r *Repository) Withdraw(ctx context.Context, id string) (*Look, error) {
+	return r.update(ctx, id, func(look *Look) error {
+		look.Gallery = nil
+		look.Public = false
+		return nil
+	})
+}
+
+// Moderate applies an admin action to a submitted look.
+func (r *Repository) Moderate(ctx context.Context, id, action, note string) (*Look, error) {
+	return r.update(ctx, id, func(look *Look) error {
+		if look.Gallery == nil {
+			return fmt.Errorf("%w: look was not submitted to the gallery", ErrInvalidTransition)
+		}
+
+		g := look.Gallery
+		switch action {
+		case ActionApprove:
+			g.Status = GalleryApproved
+		case ActionReject:
+			g.Status, g.Featured = GalleryRejected, false
+		case ActionFeature, ActionUnfeature:
+			if g.Status != GalleryApproved {
+				return fmt.Errorf("%w: only approved looks can be featured", ErrInvalidTransition)
+			}
+			g.Featured = action == ActionFeature
+		default:
+			return fmt.Errorf("%w: unknown action %q", ErrInvalidTransition, action)
+		}
+		now := time.Now().UTC()
+		g.ModeratedAt = &now
+		g.ModerationNote = note
+		look.Public = g.Status == GalleryApproved
+		return nil
+	})
+}
+
+// GalleryQuery filters gallery listings.
+type GalleryQuery struct {
+	// Status selects the moderation state; public listings always use GalleryApproved.
+	Status string
+	// Text matches case-insensitively against the style, event type, venue, theme and tags.
+	Text      string
+	EventType string
+	// Tag, if set, must be one of the look's tags.
+	Tag string
+	// Owner restricts results to one owner's looks, e.g. on a tenant's custom domain.
+	Owner    string
+	Page     int
+	PageSize int
+}
+
+// galleryIndex holds a copy of every look submitted to the gallery, without
+// its embedding, so listings are filtered and sorted in memory and only the
+// looks on the requested page are loaded from the store.
+type galleryIndex struct {
+	mu    sync.RWMutex
+	looks map[string]*Look
+}
+
+func newGalleryIndex() *galleryIndex {
+	return &galleryIndex{looks: make(map[string]*Look)}
+}
+
+// set adds, replaces or, if it left the gallery, removes look.
+func (g *galleryIndex) set(look *Look) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if look.Gallery == nil {
+		delete(g.looks, look.ID)
+		return
+	}
+	entry := *look
+	gallery := *look.Gallery
+	entry.Gallery = &gallery
+	entry.Tags = slices.Clone(look.Tags)
+	entry.Embedding = nil
+	g.looks[look.ID] = &entry
+}
+
+// remove deletes id from the index.
+func (g *galleryIndex) remove(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.looks, id)
+}
+
+// withStatus returns the indexed looks in the given moderation state.
+func (g *galleryIndex) withStatus(status string) []*Look {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var out []*Look
+	for _, look := range g.looks {
+		if look.Gallery.Status == status {
+			out = append(out, look)
+		}
+	}
+	return out
+}
+
+// Gallery returns one page of looks matching q, featured looks first and then
+// newest first, along with the total number of matches.
+func (r *Repository) Gallery(ctx context.Context, q GalleryQuery) ([]*Look, int, error) {
+	text := strings.ToLower(q.Text)
+	var matches []*Look
+	for _, look := range r.gallery.withStatus(q.Status) {
+		if q.Owner != "" && look.Owner != q.Owner {
+			continue
+		}
+		if q.EventType != "" && !strings.EqualFold(look.EventType, q.EventType) {
+			continue
+		}
+		if q.Tag != "" && !slices.Contains(look.Tags, foldTag(q.Tag)) {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(strings.Join(append([]string{look.Style, look.EventType, look.Venue, look.Theme}, look.Tags...), " ")), text) {
+			continue
+		}
+		matches = append(matches, look)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i].Gallery, matches[j].Gallery
+		if a.Featured != b.Featured {
+			return a.Featured
+		}
+		return a.SubmittedAt.After(b.SubmittedAt)
+	})
+
+	total := len(matches)
+	start := min((q.Page-1)*q.PageSize, total)
+	end := min(start+q.PageSize, total)
+	page := make([]*Look, 0, end-start)
+	for _, entry := range matches[start:end] {
+		look, err := r.Get(ctx, entry.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		page = append(page, look)
+	}
+	return page, total, nil
+}
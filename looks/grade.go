@@ -0,0 +1,49 @@
+// looks/grade.go
+package looks
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// eventPhotoNamespace holds the real event photo a user uploaded for a look.
+const eventPhotoNamespace = "look-event-photos"
+
+// RealismGrade records how closely the user's real event outfit matched the look.
+type RealismGrade struct {
+	Score       int       `json:"score"`
+	Summary     string    `json:"summary"`
+	Matches     []string  `json:"matches,omitempty"`
+	Differences []string  `json:"differences,omitempty"`
+	MimeType    string    `json:"mimeType"`
+	GradedAt    time.Time `json:"gradedAt"`
+}
+
+// SetGrade stores the event photo for a look along with its realism grade,
+// replacing any earlier one.
+func (r *Repository) SetGrade(ctx context.Context, id string, photo []byte, grade *RealismGrade) (*Look, error) {
+	return r.update(ctx, id, func(look *Look) error {
+		if err := r.images.Put(ctx, eventPhotoNamespace, id, photo); err != nil {
+			return fmt.Errorf("failed to store event photo: %w", err)
+		}
+		look.Grade = grade
+		return nil
+	})
+}
+
+// EventPhoto loads the event photo uploaded for a look.
+func (r *Repository) EventPhoto(ctx context.Context, id string) ([]byte, string, error) {
+	look, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if look.Grade == nil {
+		return nil, "", ErrNoImage
+	}
+	data, err := r.getImage(ctx, eventPhotoNamespace, id)
+	if err != nil {
+		return nil, "", ErrNoImage
+	}
+	return data, look.Grade.MimeType, nil
+}
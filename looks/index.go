@@ -0,0 +1,107 @@
+// looks/index.go
+package looks
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Index is an in-memory vector index supporting cosine-similarity search.
+// It uses exact (brute force) search, which is fast enough for tens of
+// thousands of looks and avoids an external vector database.
+type Index struct {
+	mu      sync.RWMutex
+	vectors map[string][]float32
+}
+
+// Hit is a search result.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// NewIndex creates an empty Index.
+func NewIndex() *Index {
+	return &Index{vectors: make(map[string][]float32)}
+}
+
+// Add inserts or replaces the vector for id. Vectors are normalized on insert.
+func (i *Index) Add(id string, vector []float32) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.vectors[id] = normalize(vector)
+}
+
+// Remove deletes id from the index.
+func (i *Index) Remove(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.vectors, id)
+}
+
+// Len returns the number of indexed vectors.
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.vectors)
+}
+
+// Search returns the k vectors most similar to query, best first.
+func (i *Index) Search(query []float32, k int) []Hit {
+	hits, _ := i.SearchFunc(query, k, func(Hit) (bool, error) { return true, nil })
+	return hits
+}
+
+// SearchFunc returns the k vectors most similar to query for which keep
+// reports true, best first. keep is called in order of similarity, without
+// the index locked, until k hits are kept or it returns an error.
+func (i *Index) SearchFunc(query []float32, k int, keep func(Hit) (bool, error)) ([]Hit, error) {
+	q := normalize(query)
+
+	i.mu.RLock()
+	hits := make([]Hit, 0, len(i.vectors))
+	for id, v := range i.vectors {
+		if len(v) != len(q) {
+			continue
+		}
+		var dot float64
+		for j := range v {
+			dot += float64(v[j]) * float64(q[j])
+		}
+		hits = append(hits, Hit{ID: id, Score: dot})
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(hits, func(a, b int) bool { return hits[a].Score > hits[b].Score })
+	kept := hits[:0]
+	for _, hit := range hits {
+		if len(kept) == k {
+			break
+		}
+		ok, err := keep(hit)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			kept = append(kept, hit)
+		}
+	}
+	return kept, nil
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for j, x := range v {
+		out[j] = float32(float64(x) / norm)
+	}
+	return out
+}
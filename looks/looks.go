@@ -0,0 +1,247 @@
+// looks/looks.go
+package looks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+// namespace is the store namespace holding look records.
+const namespace = "looks"
+
+// ErrNotFound is returned when a look does not exist.
+var ErrNotFound = errors.New("look not found")
+
+// Look is a single generated outfit: the style that was rendered and the event it was for.
+type Look struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	SessionID string    `json:"sessionId"`
+	EventType string    `json:"eventType"`
+	Venue     string    `json:"venue"`
+	Theme     string    `json:"theme"`
+	Style     string    `json:"style"`
+	MimeType  string    `json:"mimeType"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Public looks are visible to everyone, i.e. approved gallery entries.
+	Public bool `json:"public"`
+	// Rating is the owner's 1-5 star rating, 0 if unrated.
+	Rating int `json:"rating,omitempty"`
+	// Tags are the owner's labels for the look, normalized and sorted.
+	Tags []string `json:"tags,omitempty"`
+	// Gallery is set once the owner submits the look to the public gallery.
+	Gallery *GalleryInfo `json:"gallery,omitempty"`
+	// Grade is set once the owner uploads a photo from the actual event.
+	Grade *RealismGrade `json:"grade,omitempty"`
+	// ArchivedAt is set while the look is archived out of the owner's history.
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+	// RefinedFrom is the look this one was edited from with Instruction.
+	RefinedFrom string `json:"refinedFrom,omitempty"`
+	Instruction string `json:"instruction,omitempty"`
+	// Embedding is the vector of Style used for similarity search.
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Repository persists looks to the store and keeps an in-memory vector index
+// of their style embeddings and indexes of the gallery and of each owner's looks.
+type Repository struct {
+	logger *slog.Logger
+	store  store.Store
+	// images holds generated images and event photos; it is store unless
+	// UseImageStore moved them, e.g. to object storage.
+	images  store.Store
+	index   *Index
+	gallery *galleryIndex
+	owned   *ownerIndex
+	bulk    bulkJobs
+
+	mu sync.Mutex
+}
+
+// NewRepository creates a Repository and loads the existing looks into its
+// indexes.
+func NewRepository(ctx context.Context, logger *slog.Logger, st store.Store) (*Repository, error) {
+	repo := &Repository{logger: logger, store: st, images: st, index: NewIndex(), gallery: newGalleryIndex(), owned: newOwnerIndex()}
+	ids, err := st.List(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list looks: %w", err)
+	}
+	for _, id := range ids {
+		look, err := repo.Get(ctx, id)
+		if err != nil {
+			logger.Error("Failed to load look", "id", id, "error", err)
+			continue
+		}
+		if len(look.Embedding) > 0 {
+			repo.index.Add(look.ID, look.Embedding)
+		}
+		repo.gallery.set(look)
+		repo.owned.add(look)
+	}
+	logger.Info("Loaded look index", "looks", len(ids), "indexed", repo.index.Len())
+	return repo, nil
+}
+
+// UseImageStore keeps new generated images and event photos in images
+// instead of the repository's store. Images stored before the switch are
+// still read from the store.
+func (r *Repository) UseImageStore(images store.Store) {
+	r.images = images
+}
+
+// getImage loads an image from the image store, falling back to the store
+// for images saved before UseImageStore.
+func (r *Repository) getImage(ctx context.Context, ns, id string) ([]byte, error) {
+	data, err := r.images.Get(ctx, ns, id)
+	if errors.Is(err, store.ErrNotFound) && r.images != r.store {
+		data, err = r.store.Get(ctx, ns, id)
+	}
+	return data, err
+}
+
+// Create assigns an ID to look and stores it.
+func (r *Repository) Create(ctx context.Context, look *Look) error {
+	look.ID = uuid.New().String()
+	look.CreatedAt = time.Now().UTC()
+	return r.Save(ctx, look)
+}
+
+// Save stores look and updates its entries in the repository's indexes.
+func (r *Repository) Save(ctx context.Context, look *Look) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save(ctx, look)
+}
+
+// save is Save for callers holding r.mu.
+func (r *Repository) save(ctx context.Context, look *Look) error {
+	if err := store.PutJSON(ctx, r.store, namespace, look.ID, look); err != nil {
+		return err
+	}
+	if len(look.Embedding) > 0 {
+		r.index.Add(look.ID, look.Embedding)
+	}
+	r.gallery.set(look)
+	r.owned.add(look)
+	return nil
+}
+
+// Get loads a look by ID.
+func (r *Repository) Get(ctx context.Context, id string) (*Look, error) {
+	var look Look
+	if err := store.GetJSON(ctx, r.store, namespace, id, &look); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &look, nil
+}
+
+// update loads a look, applies change and saves it, holding r.mu throughout
+// so concurrent changes to the same look are not lost and a deleted look is
+// not written back. If change fails, nothing is saved.
+func (r *Repository) update(ctx context.Context, id string, change func(*Look) error) (*Look, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	look, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(look); err != nil {
+		return nil, err
+	}
+	return look, r.save(ctx, look)
+}
+
+// SetEmbedding attaches an embedding to an existing look.
+func (r *Repository) SetEmbedding(ctx context.Context, id string, embedding []float32) error {
+	_, err := r.update(ctx, id, func(look *Look) error {
+		look.Embedding = embedding
+		return nil
+	})
+	return err
+}
+
+// All returns every stored look.
+func (r *Repository) All(ctx context.Context) ([]*Look, error) {
+	ids, err := r.store.List(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Look, 0, len(ids))
+	for _, id := range ids {
+		look, err := r.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, look)
+	}
+	return out, nil
+}
+
+// Rate records the owner's 1-5 star rating for a look.
+func (r *Repository) Rate(ctx context.Context, id string, rating int) (*Look, error) {
+	if rating < 1 || rating > 5 {
+		return nil, fmt.Errorf("rating must be between 1 and 5")
+	}
+	return r.update(ctx, id, func(look *Look) error {
+		look.Rating = rating
+		return nil
+	})
+}
+
+// Match is a look returned by similarity search with its cosine similarity score.
+type Match struct {
+	Look  *Look
+	Score float64
+}
+
+// Similar returns up to limit looks most similar to the query vector that are
+// visible under the filter. The filter is applied while scanning the index, so
+// matches ranked below other users' looks are still found.
+func (r *Repository) Similar(ctx context.Context, query []float32, limit int, filter func(*Look) bool) ([]Match, error) {
+	var matches []Match
+	_, err := r.index.SearchFunc(query, limit, func(hit Hit) (bool, error) {
+		look, err := r.Get(ctx, hit.ID)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		if err != nil || !filter(look) {
+			return false, err
+		}
+		matches = append(matches, Match{Look: look, Score: hit.Score})
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// BySession returns owner's looks from one generation session, oldest first.
+func (r *Repository) BySession(ctx context.Context, owner, sessionID string) ([]*Look, error) {
+	all, err := r.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Look
+	for _, look := range all {
+		if look.Owner == owner && look.SessionID == sessionID {
+			out = append(out, look)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
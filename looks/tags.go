@@ -0,0 +1,193 @@
+// looks/tags.go
+package looks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on user-defined tags.
+const (
+	MaxTags      = 20
+	MaxTagLength = 32
+)
+
+// ErrInvalidTag is returned for tags that are empty, too long, contain
+// characters other than letters, digits, spaces, hyphens and underscores, or
+// would exceed MaxTags on a look.
+var ErrInvalidTag = errors.New("invalid tag")
+
+// foldTag lowercases a tag and collapses its whitespace, so lookups match
+// stored tags without rejecting invalid input.
+func foldTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// NormalizeTag folds a tag and checks it is valid.
+func NormalizeTag(tag string) (string, error) {
+	tag = foldTag(tag)
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", fmt.Errorf("%w: tags must be 1-%d characters", ErrInvalidTag, MaxTagLength)
+	}
+	for _, c := range tag {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != ' ' && c != '-' && c != '_' {
+			return "", fmt.Errorf("%w: %q may only contain letters, digits, spaces, hyphens and underscores", ErrInvalidTag, tag)
+		}
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes each tag and returns them sorted without duplicates.
+func NormalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		norm, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, norm)
+	}
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if len(out) > MaxTags {
+		return nil, fmt.Errorf("%w: a look can have at most %d tags", ErrInvalidTag, MaxTags)
+	}
+	return out, nil
+}
+
+// HasTags reports whether the look carries every one of tags, which must be normalized.
+func (l *Look) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(l.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetTags replaces a look's tags.
+func (r *Repository) SetTags(ctx context.Context, id string, tags []string) (*Look, error) {
+	norm, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return r.update(ctx, id, func(look *Look) error {
+		look.Tags = norm
+		return nil
+	})
+}
+
+// AddTags adds tags to a look, keeping the ones it already has.
+func (r *Repository) AddTags(ctx context.Context, id string, tags []string) (*Look, error) {
+	return r.update(ctx, id, func(look *Look) error {
+		norm, err := NormalizeTags(append(slices.Clone(look.Tags), tags...))
+		if err != nil {
+			return err
+		}
+		look.Tags = norm
+		return nil
+	})
+}
+
+// RemoveTag removes one tag from a look. Removing a tag it does not have is not an error.
+func (r *Repository) RemoveTag(ctx context.Context, id, tag string) (*Look, error) {
+	tag = foldTag(tag)
+	return r.update(ctx, id, func(look *Look) error {
+		look.Tags = slices.DeleteFunc(look.Tags, func(t string) bool { return t == tag })
+		return nil
+	})
+}
+
+// TagCount is a tag and the number of looks carrying it.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// Tags returns the tags on owner's looks, most used first.
+func (r *Repository) Tags(ctx context.Context, owner string) ([]TagCount, error) {
+	all, err := r.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, look := range all {
+		if look.Owner != owner {
+			continue
+		}
+		for _, tag := range look.Tags {
+			counts[tag]++
+		}
+	}
+	out := make([]TagCount, 0, len(counts))
+	for tag, n := range counts {
+		out = append(out, TagCount{Tag: tag, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Tag < out[j].Tag
+	})
+	return out, nil
+}
+
+// RenameTag renames a tag on all of owner's looks, merging it into to where a
+// look already has both. It returns the number of looks changed.
+func (r *Repository) RenameTag(ctx context.Context, owner, from, to string) (int, error) {
+	to, err := NormalizeTag(to)
+	if err != nil {
+		return 0, err
+	}
+	return r.retag(ctx, owner, from, func(tags []string) []string {
+		tags = append(tags, to)
+		slices.Sort(tags)
+		return slices.Compact(tags)
+	})
+}
+
+// DeleteTag removes a tag from all of owner's looks and returns the number of
+// looks changed.
+func (r *Repository) DeleteTag(ctx context.Context, owner, tag string) (int, error) {
+	return r.retag(ctx, owner, tag, func(tags []string) []string { return tags })
+}
+
+// errUnchanged makes update skip the save when a look no longer needs changing.
+var errUnchanged = errors.New("look unchanged")
+
+// retag removes tag from each of owner's looks that has it, applies retagged
+// to the remaining tags, and saves the look.
+func (r *Repository) retag(ctx context.Context, owner, tag string, retagged func([]string) []string) (int, error) {
+	tag = foldTag(tag)
+	all, err := r.All(ctx)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, look := range all {
+		if look.Owner != owner || !slices.Contains(look.Tags, tag) {
+			continue
+		}
+		_, err := r.update(ctx, look.ID, func(look *Look) error {
+			if !slices.Contains(look.Tags, tag) {
+				return errUnchanged
+			}
+			look.Tags = retagged(slices.DeleteFunc(look.Tags, func(t string) bool { return t == tag }))
+			return nil
+		})
+		switch {
+		case errors.Is(err, errUnchanged), errors.Is(err, ErrNotFound):
+			continue
+		case err != nil:
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
@@ -2,30 +2,79 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/http/pprof"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/sanjayshr/event-outfitter-backend/alert"
+	"github.com/sanjayshr/event-outfitter-backend/apierror"
+	"github.com/sanjayshr/event-outfitter-backend/apikeys"
+	"github.com/sanjayshr/event-outfitter-backend/auth"
+	"github.com/sanjayshr/event-outfitter-backend/billing"
+	"github.com/sanjayshr/event-outfitter-backend/captcha"
+	"github.com/sanjayshr/event-outfitter-backend/config"
+	"github.com/sanjayshr/event-outfitter-backend/gemini"
 	"github.com/sanjayshr/event-outfitter-backend/handler"
+	"github.com/sanjayshr/event-outfitter-backend/hooks"
+	"github.com/sanjayshr/event-outfitter-backend/imageconv"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/objectstore"
+	"github.com/sanjayshr/event-outfitter-backend/presets"
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+	"github.com/sanjayshr/event-outfitter-backend/provenance"
+	"github.com/sanjayshr/event-outfitter-backend/ready"
+	"github.com/sanjayshr/event-outfitter-backend/realip"
+	"github.com/sanjayshr/event-outfitter-backend/requestid"
 	"github.com/sanjayshr/event-outfitter-backend/server"
+	"github.com/sanjayshr/event-outfitter-backend/shopping"
+	"github.com/sanjayshr/event-outfitter-backend/signing"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/store"
+	"github.com/sanjayshr/event-outfitter-backend/tracing"
+	"github.com/sanjayshr/event-outfitter-backend/trends"
+	"github.com/sanjayshr/event-outfitter-backend/urlfetch"
+	"github.com/sanjayshr/event-outfitter-backend/weather"
+	"golang.org/x/crypto/acme/autocert"
 )
 
-// enableCORS is a middleware that adds CORS headers to the response.
-func enableCORS(next http.Handler) http.Handler {
+// enableCORS is a middleware that adds CORS headers to the response. The
+// policy is read on every request so config reloads take effect immediately.
+// Origins outside the policy are still allowed if an API key is bound to them.
+func enableCORS(currentPolicy func() config.CORSConfig, keyOrigin func(context.Context, string) bool, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := currentPolicy()
+
 		// Set CORS headers
+		w.Header().Add("Vary", "Origin")
 		origin := r.Header.Get("Origin")
-		if origin == "https://dreswap-ui.vercel.app" || origin == "http://localhost:3000" {
+		if origin != "" && (policy.AllowsOrigin(origin) || keyOrigin(r.Context(), origin)) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if policy.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Session-ID")
-		w.Header().Set("Access-Control-Expose-Headers", "X-Session-ID")
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
+			if policy.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+			}
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -35,39 +84,506 @@ func enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// securityHeaders adds the configured security headers to every response.
+// Browsers ignore HSTS over plain HTTP, so it is only sent when the request
+// came in over TLS, directly or through a trusted proxy setting
+// X-Forwarded-Proto.
+func securityHeaders(cfg config.HeadersConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		set := func(name, value string) {
+			if value != "" {
+				h.Set(name, value)
+			}
+		}
+		if realip.SchemeFromRequest(r) == "https" {
+			set("Strict-Transport-Security", cfg.HSTS)
+		}
+		set("X-Content-Type-Options", cfg.ContentTypeOptions)
+		set("Referrer-Policy", cfg.ReferrerPolicy)
+		set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		set("X-Frame-Options", cfg.FrameOptions)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// flagDegraded marks responses with X-Degraded-Mode while the persistent store
+// is unavailable and the server is running on in-memory storage only.
+func flagDegraded(st *store.Resilient, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if st.Degraded() {
+			w.Header().Set("X-Degraded-Mode", "storage")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withTimeout gives a route its own write deadline in place of the server-wide
+// WriteTimeout, and cancels the request context when it passes.
+func withTimeout(d time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(d)
+		if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to set request deadline.")
+			return
+		}
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func main() {
-	// Initialize structured logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	// Initialize structured logger. The level can be changed at runtime by admins.
+	logLevel := new(slog.LevelVar)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	logLevel.Set(cfg.Log.SlogLevel())
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	fileStore, err := store.NewFileStore(cfg.Store.Dir)
+	if err != nil {
+		logger.Error("Failed to open store", "dir", cfg.Store.Dir, "error", err)
+		os.Exit(1)
+	}
+
+	// Operator alerts such as storage outages go to the log and the optional webhook.
+	notifier := alert.NewNotifier(logger, cfg.Alerts.WebhookURL)
+
+	// If the store becomes unavailable, keep serving from memory instead of failing requests.
+	st := store.NewResilient(logger, fileStore)
+	st.OnStateChange = func(degraded bool, err error) {
+		if degraded {
+			notifier.Alert("Persistent store unavailable; running in degraded in-memory mode", "error", err)
+		} else {
+			notifier.Alert("Persistent store recovered; degraded mode cleared")
+		}
+	}
+	go st.Run(context.Background())
+
+	// A prompt template that lost a placeholder would quietly degrade every generation.
+	if err := prompt.Validate(); err != nil {
+		logger.Error("Invalid prompt template", "error", err)
+		os.Exit(1)
+	}
+	geminiClient, err := gemini.NewClient(context.Background(), logger, cfg.Gemini)
+	if err != nil {
+		logger.Error("Failed to create Gemini client", "error", err)
+		os.Exit(1)
+	}
+
+	// Fail fast on a bad key or model name rather than on the first user request.
+	if !cfg.Gemini.SkipStartupCheck {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err := geminiClient.Ping(ctx)
+		cancel()
+		if err != nil {
+			logger.Error("Gemini startup check failed; verify GEMINI_API_KEY and model names", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Gemini startup check passed")
+	}
+
+	s := server.NewServer(cfg, logger, st, geminiClient)
+	// Model throughput for the live activity view comes from every Gemini call.
+	geminiClient.OnCall(s.Activity.ObserveModel)
+	s.UseLogLevel(logLevel)
+
+	// Track dependency health for /api/v1/status. Gemini is observed passively
+	// from real calls; the store is probed because its failures are otherwise silent.
+	s.Status.Register(status.ComponentGemini, status.StateOutage)
+	s.Status.Register(status.ComponentStore, status.StateDegraded)
+	s.Status.AddProbe(status.ComponentStore, st.Ping)
+	go s.Status.Run(context.Background(), time.Minute)
+
+	// Short link hits are counted in memory and saved in batches.
+	go s.Links.Run(context.Background(), 10*time.Second)
+
+	// /readyz checks the same dependencies actively. Gemini checks are cached
+	// since they are API calls; a rate-limited Gemini is still reachable, and
+	// every instance would be equally affected, so it doesn't fail readiness.
+	s.Ready = ready.NewChecker(cfg.Server.ReadyTimeout)
+	s.Ready.Add(status.ComponentGemini, cfg.Server.ReadyGeminiInterval, func(ctx context.Context) error {
+		if err := s.Gemini.Ping(ctx); err != nil && !errors.Is(err, gemini.ErrQuota) {
+			return err
+		}
+		return nil
+	})
+	s.Ready.Add(status.ComponentStore, 0, st.Ping)
+
+	s.Looks, err = looks.NewRepository(context.Background(), logger, st)
+	if err != nil {
+		logger.Error("Failed to load looks", "error", err)
+		os.Exit(1)
+	}
+	// Generated images go to a bucket, when configured, and are served from
+	// it with signed URLs instead of streaming through this server.
+	if objects := cfg.Store.Objects; objects.Enabled() {
+		s.Objects, err = objectstore.New(objects.Endpoint, objects.Region, objects.Bucket, objects.AccessKeyID, objects.SecretAccessKey)
+		if err != nil {
+			logger.Error("Invalid object storage configuration", "error", err)
+			os.Exit(1)
+		}
+		s.Looks.UseImageStore(s.Objects)
+		logger.Info("Storing generated images in object storage", "endpoint", objects.Endpoint, "bucket", objects.Bucket)
+	}
+	s.Trends = trends.NewAggregator(s.Looks)
+
+	// Keep style suggestions for popular presets warm so common requests skip
+	// the suggestion call. The most requested presets are added over time.
+	s.Presets = presets.NewCache(logger, presets.ParsePresets(cfg.Presets.Warm))
+	go s.Presets.Run(context.Background(), cfg.Presets.RefreshInterval, func(ctx context.Context, p presets.Preset) ([]models.Style, error) {
+		return s.Gemini.GetStyleSuggestions(ctx, p.EventType, p.Venue, p.Theme, gemini.SuggestionOptions{Count: int(cfg.Suggestions.StyleCount)})
+	})
+	// Every runtime configuration is versioned, starting with this one.
+	s.RecordStartupVersion(context.Background())
+
+	// Stripe metered billing for generations beyond the free tier.
+	s.Billing = billing.NewStripe(logger, st,
+		cfg.Billing.StripeSecretKey,
+		cfg.Billing.StripePriceID,
+		cfg.Billing.SuccessURL,
+		cfg.Billing.CancelURL,
+	)
+
+	// Bot verification on /generate, enabled by a captcha secret.
+	s.Captcha, err = captcha.NewVerifier(cfg.Security.CaptchaProvider, cfg.Security.CaptchaSecret)
+	if err != nil {
+		logger.Error("Invalid captcha configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// HEIC uploads from iPhones are converted to JPEG by an external program.
+	if cmdline := cfg.Preprocess.HEICConverter; cmdline != "" {
+		s.HEIC, err = imageconv.Parse(cmdline, cfg.Preprocess.HEICTimeout)
+		if err != nil {
+			logger.Error("Invalid HEIC converter", "command", cmdline, "error", err)
+			os.Exit(1)
+		}
+	}
+	// Integrations that already host photos can pass an imageUrl instead of uploading.
+	if cfg.ImageURL.Enabled {
+		s.ImageURLs = urlfetch.New(cfg.Server.MaxUploadBytes, cfg.ImageURL.Timeout, cfg.ImageURL.AllowedHosts, cfg.ImageURL.DeniedHosts)
+	}
+	// Suggestions can take the weather at the venue on the event's date into account.
+	if cfg.Weather.Enabled {
+		s.Weather = weather.New(cfg.Weather.Timeout, cfg.Weather.CacheTTL)
+	}
+	// Suggested styles link to products when a product search is configured.
+	if cfg.Shopping.SearchURL != "" {
+		s.Shopping, err = shopping.New(cfg.Shopping.SearchURL, cfg.Shopping.APIKey, cfg.Shopping.Timeout, cfg.Shopping.CacheTTL, int(cfg.Shopping.ProductsPerItem))
+		if err != nil {
+			logger.Error("Invalid shopping configuration", "error", err)
+			os.Exit(1)
+		}
+	}
+	// AVIF is read and written the same way, for clients that save bandwidth with it.
+	if cmdline := cfg.AVIF.Converter; cmdline != "" {
+		s.AVIFDecoder, err = imageconv.Parse(cmdline, cfg.AVIF.Timeout)
+		if err != nil {
+			logger.Error("Invalid AVIF converter", "command", cmdline, "error", err)
+			os.Exit(1)
+		}
+	}
+	if cmdline := cfg.AVIF.Encoder; cmdline != "" {
+		s.AVIFEncoder, err = imageconv.Parse(cmdline, cfg.AVIF.Timeout)
+		if err != nil {
+			logger.Error("Invalid AVIF encoder", "command", cmdline, "error", err)
+			os.Exit(1)
+		}
+	}
+	if cmdline := cfg.Output.WebPEncoder; cmdline != "" {
+		s.WebPEncoder, err = imageconv.Parse(cmdline, cfg.Output.WebPTimeout)
+		if err != nil {
+			logger.Error("Invalid WebP encoder", "command", cmdline, "error", err)
+			os.Exit(1)
+		}
+	}
+	// Conversions decode whole images in another process, so they share a cap on
+	// how many run at once, and their output can't be larger than the biggest
+	// photo accepted, as 4-byte pixels.
+	slots := make(chan struct{}, cfg.Preprocess.MaxConversions)
+	for _, conv := range []*imageconv.Converter{s.HEIC, s.AVIFDecoder, s.AVIFEncoder, s.WebPEncoder} {
+		if conv == nil {
+			continue
+		}
+		conv.Slots = slots
+		if mp := cfg.Server.MaxImageMegapixels; mp > 0 {
+			conv.MaxOutput = mp * 4_000_000
+		}
+	}
+	// Content credentials mark results as AI-generated for platforms that check them.
+	if cmdline := cfg.C2PA.Command; cmdline != "" {
+		s.Provenance, err = provenance.Parse(cmdline, cfg.C2PA.Timeout, provenance.Credentials{
+			SignCert:   cfg.C2PA.SignCert,
+			PrivateKey: cfg.C2PA.PrivateKey,
+			Alg:        cfg.C2PA.Alg,
+			TSAURL:     cfg.C2PA.TSAURL,
+		})
+		if err != nil {
+			logger.Error("Invalid C2PA command", "command", cmdline, "error", err)
+			os.Exit(1)
+		}
+		if cfg.C2PA.SignCert == "" {
+			logger.Warn("C2PA manifests are signed with the tool's test certificate; set C2PA_SIGN_CERT and C2PA_PRIVATE_KEY")
+		}
+	}
+
+	// Image hooks let deployments watermark, filter or stamp images without forking.
+	s.Hooks = hooks.NewPipeline(logger)
+	for _, path := range cfg.Hooks.Plugins {
+		if err := s.Hooks.LoadPlugin(path); err != nil {
+			logger.Error("Failed to load hook plugin", "error", err)
+			os.Exit(1)
+		}
+	}
+	hookCommand := func(cmdline string) *hooks.Command {
+		cmd, err := hooks.ParseCommand(cmdline, cfg.Hooks.Timeout)
+		if err != nil {
+			logger.Error("Invalid hook command", "command", cmdline, "error", err)
+			os.Exit(1)
+		}
+		return cmd
+	}
+	for _, cmdline := range cfg.Hooks.Pre {
+		s.Hooks.AddPre(hookCommand(cmdline))
+	}
+	for _, cmdline := range cfg.Hooks.Post {
+		s.Hooks.AddPost(hookCommand(cmdline))
+	}
+	if n := s.Hooks.Len(); n > 0 {
+		logger.Info("Registered image hooks", "count", n)
+	}
 
-	s := server.NewServer(logger)
+	// The signing secret is shared with the frontend; when set, the
+	// expensive endpoints only accept HMAC-signed requests. Partner backends
+	// may sign with their API key's own signing secret instead.
+	verifier := signing.NewVerifier(logger, cfg.Security.SigningSecret, cfg.Server.MaxUploadBytes, cfg.Security.SignatureMaxSkew)
+	if !verifier.Enabled() {
+		logger.Warn("REQUEST_SIGNING_SECRET not set; request signing is disabled")
+	}
+
+	// Client routes accept any configured authentication method; new
+	// providers only need registering here.
+	chain := auth.NewChain(logger, cfg.Security.RequireAPIKey)
+	chain.Register(auth.MethodAPIKey, auth.NewAPIKey(s.APIKeys))
+	chain.Register(auth.MethodHMAC, auth.NewHMAC(s.APIKeys, verifier))
+	if jwt := cfg.Security.JWT; jwt.Enabled() {
+		chain.Register(auth.MethodJWT, auth.NewJWT(auth.JWTConfig{
+			Issuer:   jwt.Issuer,
+			Audience: jwt.Audience,
+			JWKSURL:  jwt.JWKSURL,
+			Secret:   jwt.Secret,
+			Scopes:   jwt.Scopes,
+		}))
+	}
+	s.Auth = chain
+	clientMethods := []string{auth.MethodAPIKey, auth.MethodHMAC, auth.MethodJWT}
 
 	// Use the new ServeMux for pattern-based routing
 	mux := http.NewServeMux()
 
 	// Register handlers
-	mux.HandleFunc("POST /api/v1/generate", handler.GenerateHandler(s))
-	mux.HandleFunc("POST /api/v1/swap-style", handler.SwapStyleHandler(s)) // New endpoint
-	mux.HandleFunc("GET /api/v1/styles", handler.GetStylesHandler(s))      // New endpoint
+	generate := func(h http.Handler) http.Handler { return chain.Require(apikeys.ScopeGenerate, clientMethods, h) }
+	read := func(h http.Handler) http.Handler { return chain.Require(apikeys.ScopeRead, clientMethods, h) }
+	admin := func(h http.Handler) http.Handler { return handler.RequireAdmin(s, h) }
+	slow := func(h http.Handler) http.Handler {
+		return withTimeout(cfg.Server.GenerateTimeout, withHeartbeat(cfg.Server.HeartbeatInterval, cfg.Server.GenerateTimeout, cfg.Server.HeartbeatMode, h))
+	}
+	// Maintenance mode only stops the endpoints that call Gemini.
+	available := func(h http.Handler) http.Handler { return handler.RequireAvailable(s, h) }
+
+	mux.Handle("POST /api/v1/generate", available(slow(verifier.Require(generate(handler.GenerateHandler(s))))))
+	mux.Handle("POST /api/v1/uploads", generate(handler.CreateUploadHandler(s)))
+	mux.Handle("HEAD /api/v1/uploads/{id}", generate(handler.UploadStatusHandler(s)))
+	mux.Handle("PATCH /api/v1/uploads/{id}", generate(handler.AppendUploadHandler(s)))
+	mux.Handle("DELETE /api/v1/uploads/{id}", generate(handler.DeleteUploadHandler(s)))
+	mux.Handle("POST /api/v1/swap-style", available(slow(verifier.Require(generate(handler.SwapStyleHandler(s)))))) // New endpoint
+	mux.Handle("POST /api/v1/refine", available(slow(verifier.Require(generate(handler.RefineHandler(s))))))
+	mux.Handle("POST /api/v1/accessories", available(slow(verifier.Require(generate(handler.AddAccessoriesHandler(s))))))
+	mux.HandleFunc("GET /api/v1/accessories", handler.AccessoriesHandler(s))
+	mux.Handle("POST /api/v1/previews", available(slow(verifier.Require(generate(handler.PreviewsHandler(s))))))
+	mux.Handle("GET /api/v1/styles", read(handler.GetStylesHandler(s))) // New endpoint
+	mux.Handle("POST /api/v1/styles/more", available(slow(verifier.Require(generate(handler.MoreStylesHandler(s))))))
+	mux.Handle("POST /api/v1/styles/regenerate", available(slow(verifier.Require(generate(handler.RegenerateStylesHandler(s))))))
+	mux.Handle("GET /api/v1/usage", read(handler.UsageHandler(s)))
+	mux.HandleFunc("GET /api/v1/status", handler.StatusHandler(s))
+	mux.HandleFunc("GET /api/v1/capabilities", handler.CapabilitiesHandler(s))
+	mux.Handle("POST /api/v1/e2ee/keys", read(handler.KeyExchangeHandler(s)))
+	mux.Handle("GET /api/v1/result", read(handler.ResultHandler(s)))
+	mux.Handle("GET /api/v1/history", read(handler.GenerationHistoryHandler(s)))
+	mux.Handle("GET /api/v1/looks", read(handler.HistoryHandler(s)))
+	mux.Handle("GET /api/v1/sessions", read(handler.SessionHistoryHandler(s)))
+	mux.Handle("GET /api/v1/sessions/{id}", read(handler.GetSessionHandler(s)))
+	mux.Handle("PUT /api/v1/sessions/{id}", read(handler.UpdateSessionHandler(s)))
+	mux.Handle("POST /api/v1/sessions/{id}/refresh", read(handler.RefreshSessionHandler(s)))
+	mux.Handle("GET /api/v1/sessions/{id}/favorites", read(handler.SessionFavoritesHandler(s)))
+	mux.Handle("PUT /api/v1/sessions/{id}/favorites/styles/{styleId}", read(handler.FavoriteStyleHandler(s)))
+	mux.Handle("DELETE /api/v1/sessions/{id}/favorites/styles/{styleId}", read(handler.UnfavoriteStyleHandler(s)))
+	mux.Handle("PUT /api/v1/sessions/{id}/favorites/looks/{lookId}", read(handler.FavoriteLookHandler(s)))
+	mux.Handle("DELETE /api/v1/sessions/{id}/favorites/looks/{lookId}", read(handler.UnfavoriteLookHandler(s)))
+	mux.Handle("GET /api/v1/favorites", read(handler.FavoritesHandler(s)))
+	mux.Handle("GET /api/v1/sessions/{id}/export.zip", slow(read(handler.ExportSessionHandler(s))))
+	mux.Handle("GET /api/v1/export", slow(read(handler.ExportHandler(s))))
+	mux.Handle("POST /api/v1/looks/bulk", read(handler.BulkLooksHandler(s)))
+	mux.Handle("GET /api/v1/looks/bulk/{id}", read(handler.BulkJobHandler(s)))
+	mux.Handle("POST /api/v1/looks/similar", available(read(handler.SimilarLooksHandler(s))))
+	mux.Handle("POST /api/v1/looks/{id}/rating", read(handler.RateLookHandler(s)))
+	mux.Handle("GET /api/v1/looks/{id}/image", read(handler.LookImageHandler(s)))
+	mux.Handle("PUT /api/v1/looks/{id}/tags", read(handler.SetLookTagsHandler(s)))
+	mux.Handle("POST /api/v1/looks/{id}/tags", read(handler.AddLookTagsHandler(s)))
+	mux.Handle("DELETE /api/v1/looks/{id}/tags/{tag}", read(handler.RemoveLookTagHandler(s)))
+	mux.Handle("GET /api/v1/tags", read(handler.ListTagsHandler(s)))
+	mux.Handle("PUT /api/v1/tags/{tag}", read(handler.RenameTagHandler(s)))
+	mux.Handle("DELETE /api/v1/tags/{tag}", read(handler.DeleteTagHandler(s)))
+	mux.Handle("POST /api/v1/looks/{id}/event-photo", available(slow(verifier.Require(generate(handler.GradeEventPhotoHandler(s))))))
+	mux.Handle("GET /api/v1/looks/{id}/event-photo", read(handler.EventPhotoHandler(s)))
+	mux.HandleFunc("GET /api/v1/trends", handler.TrendsHandler(s))
+	mux.Handle("POST /api/v1/gallery", read(handler.PublishLookHandler(s)))
+	mux.Handle("DELETE /api/v1/gallery/{id}", read(handler.UnpublishLookHandler(s)))
+	mux.HandleFunc("GET /api/v1/gallery", handler.GalleryHandler(s))
+	mux.HandleFunc("GET /api/v1/gallery/{id}/image", handler.GalleryImageHandler(s))
+	mux.Handle("POST /api/v1/billing/checkout", read(handler.CreateCheckoutHandler(s)))
+	mux.Handle("GET /api/v1/billing/checkout/{id}", read(handler.CheckoutStatusHandler(s)))
+	mux.Handle("POST /api/v1/links", read(handler.CreateShortLinkHandler(s)))
+	mux.Handle("GET /api/v1/links/{code}", read(handler.ShortLinkStatsHandler(s)))
+	mux.HandleFunc("GET /s/{code}", handler.RedirectShortLinkHandler(s))
+	mux.Handle("POST /api/v1/looks/{id}/share", read(handler.CreateShareHandler(s)))
+	mux.Handle("DELETE /api/v1/shares/{token}", read(handler.DeleteShareHandler(s)))
+	mux.HandleFunc("GET /share/{token}", handler.SharedImageHandler(s))
+	mux.HandleFunc("GET /api/v1/share/{token}/qr", handler.ShareQRHandler(s))
+
+	// Admin API, authenticated with ADMIN_TOKEN
+	mux.Handle("POST /admin/api-keys", admin(handler.CreateAPIKeyHandler(s)))
+	mux.Handle("GET /admin/api-keys", admin(handler.ListAPIKeysHandler(s)))
+	mux.Handle("DELETE /admin/api-keys/{id}", admin(handler.RevokeAPIKeyHandler(s)))
+	mux.Handle("POST /admin/api-keys/{id}/rotate", admin(handler.RotateAPIKeyHandler(s)))
+	mux.Handle("PUT /admin/api-keys/{id}/suspension", admin(handler.SuspendAPIKeyHandler(s)))
+	mux.Handle("DELETE /admin/api-keys/{id}/suspension", admin(handler.ResumeAPIKeyHandler(s)))
+	mux.Handle("PUT /admin/api-keys/{id}/domain", admin(handler.SetAPIKeyDomainHandler(s)))
+	mux.Handle("PUT /admin/api-keys/{id}/origins", admin(handler.SetAPIKeyOriginsHandler(s)))
+	mux.Handle("POST /admin/api-keys/{id}/signing-secret", admin(handler.IssueSigningSecretHandler(s)))
+	mux.Handle("GET /admin/gallery", admin(handler.AdminGalleryHandler(s)))
+	mux.Handle("GET /admin/gallery/{id}/image", admin(handler.AdminGalleryImageHandler(s)))
+	mux.Handle("POST /admin/gallery/{id}/moderate", admin(handler.ModerateLookHandler(s)))
+	mux.Handle("GET /admin/sessions", admin(handler.ListSessionsHandler(s)))
+	mux.Handle("DELETE /admin/sessions/{id}", admin(handler.EvictSessionHandler(s)))
+	mux.Handle("GET /admin/cache/stats", admin(handler.CacheStatsHandler(s)))
+	mux.Handle("POST /admin/cache/flush", admin(handler.FlushCacheHandler(s)))
+	mux.Handle("GET /admin/jobs", admin(handler.ListJobsHandler(s)))
+	mux.Handle("GET /admin/jobs/{id}", admin(handler.GetJobHandler(s)))
+	mux.Handle("POST /admin/retention/run", admin(handler.RunRetentionHandler(s)))
+	mux.Handle("PUT /admin/presets", admin(handler.UpdatePresetHandler(s)))
+	mux.Handle("POST /admin/config/reload", admin(handler.ReloadConfigHandler(s)))
+	mux.Handle("GET /admin/config/versions", admin(handler.ListConfigVersionsHandler(s)))
+	mux.Handle("GET /admin/config/versions/{version}", admin(handler.GetConfigVersionHandler(s)))
+	mux.Handle("GET /admin/config/versions/{version}/diff", admin(handler.DiffConfigVersionHandler(s)))
+	mux.Handle("POST /admin/config/versions/{version}/rollback", admin(handler.RollbackConfigHandler(s)))
+	mux.Handle("GET /admin/maintenance", admin(handler.GetMaintenanceHandler(s)))
+	mux.Handle("PUT /admin/maintenance", admin(handler.SetMaintenanceHandler(s)))
+	mux.Handle("GET /admin/log-level", admin(handler.GetLogLevelHandler(s)))
+	mux.Handle("PUT /admin/log-level", admin(handler.SetLogLevelHandler(s)))
+	mux.Handle("GET /metrics", admin(handler.MetricsHandler(s)))
+	mux.Handle("GET /admin/activity", admin(handler.ActivityHandler(s)))
+	mux.Handle("GET /admin/activity/stream", admin(handler.ActivityStreamHandler(s)))
+
+	// Runtime profiles, e.g. when cached session images balloon memory. The
+	// index also serves the named profiles such as /debug/pprof/heap.
+	mux.Handle("GET /debug/pprof/", admin(http.HandlerFunc(pprof.Index)))
+	mux.Handle("GET /debug/pprof/cmdline", admin(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("GET /debug/pprof/profile", admin(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("GET /debug/pprof/symbol", admin(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("POST /debug/pprof/symbol", admin(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("GET /debug/pprof/trace", admin(http.HandlerFunc(pprof.Trace)))
+	mux.Handle("GET /debug/vars", admin(expvar.Handler()))
 
 	// A simple health check endpoint
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	mux.HandleFunc("GET /readyz", handler.ReadyHandler(s))
+
+	// Trusted proxies are the CIDRs whose forwarding headers we believe,
+	// e.g. the load balancer in front of the app.
+	ipResolver, err := realip.NewResolver(cfg.Server.TrustedProxies)
+	if err != nil {
+		logger.Error("Invalid trusted proxy configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// SIGHUP reloads runtime settings without dropping in-memory sessions.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			applied, restartRequired, err := s.ReloadConfig(context.Background())
+			if err != nil {
+				logger.Error("Config reload failed; keeping current settings", "error", err)
+				continue
+			}
+			logger.Info("Reloaded config", "applied", applied, "restartRequired", restartRequired)
+		}
+	}()
 
 	// Configure the HTTP server
 	srv := &http.Server{
-		Addr:         ":8081",
-		Handler:      enableCORS(mux),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		Addr:         cfg.Server.Addr,
+		Handler:      tracing.Middleware(requestid.Middleware(ipResolver.Middleware(securityHeaders(cfg.Headers, flagDegraded(st, enableCORS(s.CORS, s.APIKeys.OriginAllowed, handler.TenantHost(s, s.Activity.Middleware(tracing.Route(mux))))))))),
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
-	logger.Info("Starting server", "address", srv.Addr)
-	err := srv.ListenAndServe()
+	if cfg.TLS.Autocert {
+		// Certificates are issued on demand, but only for the configured hosts
+		// and domains assigned to a tenant.
+		m := &autocert.Manager{
+			Prompt: autocert.AcceptTOS,
+			Cache:  autocert.DirCache(cfg.TLS.CacheDir),
+			Email:  cfg.TLS.Email,
+			HostPolicy: func(ctx context.Context, host string) error {
+				if slices.Contains(cfg.TLS.Hosts, host) {
+					return nil
+				}
+				if _, err := s.APIKeys.ByDomain(ctx, host); err != nil {
+					return fmt.Errorf("host %q is not a tenant domain: %w", host, err)
+				}
+				return nil
+			},
+		}
+		srv.TLSConfig = m.TLSConfig()
+
+		// Answer ACME challenges and redirect everything else to HTTPS.
+		go func() {
+			logger.Info("Starting ACME HTTP listener", "address", cfg.TLS.HTTPAddr)
+			if err := http.ListenAndServe(cfg.TLS.HTTPAddr, m.HTTPHandler(nil)); err != nil {
+				logger.Error("ACME HTTP listener failed", "error", err)
+			}
+		}()
+
+		logger.Info("Starting TLS server", "address", srv.Addr, "autocert", true)
+		err = srv.ListenAndServeTLS("", "")
+	} else if cfg.TLS.CertFile != "" {
+		logger.Info("Starting TLS server", "address", srv.Addr, "certFile", cfg.TLS.CertFile)
+		err = srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	} else {
+		logger.Info("Starting server", "address", srv.Addr)
+		err = srv.ListenAndServe()
+	}
 	if err != nil {
 		logger.Error("Server failed to start", "error", err)
+		shutdownTracing(context.Background())
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,162 @@
+// metrics/metrics.go
+//
+// Package metrics times the stages of the generation pipeline. Each request
+// records a Timing, which is returned to the client as a Server-Timing header
+// and aggregated into per-stage histograms exposed in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pipeline stages. Requests are processed synchronously, so there is no
+// queue wait stage.
+const (
+	StagePreprocess  = "preprocess"
+	StageSuggestions = "suggestions"
+	StageImage       = "image"
+	StagePostprocess = "postprocess"
+	StageStorage     = "storage"
+)
+
+// buckets are the histogram upper bounds in seconds, spanning fast cache hits
+// to the slowest image generations.
+var buckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 45, 60, 120}
+
+// Timing collects the stage durations of one request. It is safe for
+// concurrent use, so stages that overlap can be timed from their own
+// goroutines; their durations may then add up to more than the total.
+type Timing struct {
+	start time.Time
+
+	mu     sync.Mutex
+	stages []string
+	totals map[string]time.Duration
+}
+
+// NewTiming starts timing a request.
+func NewTiming() *Timing {
+	return &Timing{start: time.Now(), totals: make(map[string]time.Duration)}
+}
+
+// Start begins timing stage and returns the function that ends it. A stage
+// timed more than once, such as preprocessing before and after a model call,
+// accumulates.
+func (t *Timing) Start(stage string) func() {
+	begin := time.Now()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, ok := t.totals[stage]; !ok {
+			t.stages = append(t.stages, stage)
+		}
+		t.totals[stage] += time.Since(begin)
+	}
+}
+
+// Total is the time since the request started.
+func (t *Timing) Total() time.Duration {
+	return time.Since(t.start)
+}
+
+// ServerTiming formats the stages and total as a Server-Timing header value.
+func (t *Timing) ServerTiming() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parts := make([]string, 0, len(t.stages)+1)
+	for _, stage := range t.stages {
+		parts = append(parts, serverTimingEntry(stage, t.totals[stage]))
+	}
+	parts = append(parts, serverTimingEntry("total", t.Total()))
+	return strings.Join(parts, ", ")
+}
+
+func serverTimingEntry(name string, d time.Duration) string {
+	return name + ";dur=" + strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', 1, 64)
+}
+
+type stageKey struct {
+	pipeline, stage string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Stages aggregates stage durations across requests into histograms.
+type Stages struct {
+	mu    sync.Mutex
+	hists map[stageKey]*histogram
+}
+
+// NewStages creates an empty set of histograms.
+func NewStages() *Stages {
+	return &Stages{hists: make(map[stageKey]*histogram)}
+}
+
+// Record adds a finished request's stages, and its total as the "total" stage,
+// to the histograms of pipeline (e.g. "generate" or "swap").
+func (s *Stages) Record(pipeline string, t *Timing) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for stage, d := range t.totals {
+		s.observe(stageKey{pipeline, stage}, d)
+	}
+	s.observe(stageKey{pipeline, "total"}, t.Total())
+}
+
+func (s *Stages) observe(key stageKey, d time.Duration) {
+	h, ok := s.hists[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		s.hists[key] = h
+	}
+	seconds := d.Seconds()
+	if i, _ := slices.BinarySearch(buckets, seconds); i < len(buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// WritePrometheus writes the histograms in the Prometheus text exposition format.
+func (s *Stages) WritePrometheus(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]stageKey, 0, len(s.hists))
+	for key := range s.hists {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b stageKey) int {
+		return strings.Compare(a.pipeline+"\x00"+a.stage, b.pipeline+"\x00"+b.stage)
+	})
+
+	var b strings.Builder
+	b.WriteString("# HELP dreswap_stage_duration_seconds Duration of generation pipeline stages.\n")
+	b.WriteString("# TYPE dreswap_stage_duration_seconds histogram\n")
+	for _, key := range keys {
+		h := s.hists[key]
+		labels := fmt.Sprintf("pipeline=%q,stage=%q", key.pipeline, key.stage)
+		var cumulative uint64
+		for i, upper := range buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "dreswap_stage_duration_seconds_bucket{%s,le=%q} %d\n", labels, strconv.FormatFloat(upper, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "dreswap_stage_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "dreswap_stage_duration_seconds_sum{%s} %g\n", labels, h.sum)
+		fmt.Fprintf(&b, "dreswap_stage_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -1,14 +1,809 @@
 // models/models.go
+//
+//go:generate go run ../cmd/tsgen -in models.go -out ../sdk/typescript/models.ts
 package models
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
 // GenerateRequest defines the structure for the JSON data sent from the frontend.
 type GenerateRequest struct {
 	EventType string `json:"eventType"`
 	Venue     string `json:"venue"`
 	Theme     string `json:"theme"`
+	// EventDate is the day of the event, as YYYY-MM-DD, so suggestions can
+	// suit the weather expected at the venue. Optional.
+	EventDate string `json:"eventDate,omitempty"`
+	// Name and Notes optionally label the session, e.g. "Goa wedding - option A".
+	Name  string `json:"name,omitempty"`
+	Notes string `json:"notes,omitempty"`
+	// ImageURL is fetched by the server instead of uploading the photo, when
+	// IMAGE_URL_ENABLED is set.
+	ImageURL string `json:"imageUrl,omitempty"`
+	// UploadID references a finished resumable upload instead of the image part.
+	UploadID string `json:"uploadId,omitempty"`
+	// AspectRatio frames every image of the session as "portrait" (4:5),
+	// "square" (1:1) or "story" (9:16); empty keeps the model's framing.
+	AspectRatio string `json:"aspectRatio,omitempty"`
+	// MaxDimension caps the longer side of every image of the session, in
+	// pixels; 0 keeps the model's resolution.
+	MaxDimension int `json:"maxDimension,omitempty"`
+	// KeepBackground only changes the clothing in every image of the session,
+	// keeping the photo's own background, lighting and framing.
+	KeepBackground bool `json:"keepBackground,omitempty"`
+	// Language is the BCP 47 tag of the language to write style suggestions
+	// in, e.g. "es" or "pt-BR". It defaults to the request's Accept-Language;
+	// images are always prompted in English.
+	Language string `json:"language,omitempty"`
+	// StyleTags narrows the session's suggestions to styles with all of these
+	// tags, e.g. ["formal"]. Unknown tags are rejected.
+	StyleTags []string `json:"styleTags,omitempty"`
+	// StyleCount is how many styles to suggest, from 3 to 10. It defaults to
+	// the server's STYLE_COUNT, and also sizes the session's further
+	// suggestions.
+	StyleCount int `json:"styleCount,omitempty"`
+	// Gender ("woman", "man" or "non-binary"), BodyType ("petite", "tall",
+	// "slim", "athletic", "curvy" or "plus-size"), FitPreference ("fitted",
+	// "relaxed" or "oversized") and Modesty ("moderate" or "full") describe
+	// the person, so suggestions and images are tailored to them rather than
+	// guessed. All are optional.
+	Gender        string `json:"gender,omitempty"`
+	BodyType      string `json:"bodyType,omitempty"`
+	FitPreference string `json:"fitPreference,omitempty"`
+	Modesty       string `json:"modesty,omitempty"`
+	// Count asks for up to 4 variations of the first style at once; they are
+	// returned as JSON in ImageResponse.Variations.
+	Count int `json:"count,omitempty"`
+	// PromptSuffix is appended to the prompt of every image of the session as
+	// extra instructions, for experiments. It needs the "prompt" scope.
+	PromptSuffix string `json:"promptSuffix,omitempty"`
+}
+
+// UploadResponse describes a resumable upload.
+type UploadResponse struct {
+	ID        string    `json:"id"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Style is an outfit suggestion of a session. Description is what the image
+// is generated from. Name, Tags (e.g. "formal", "casual", "bohemian") and
+// Palette (color names) help clients build a picker, and are empty for
+// styles the user or an admin wrote. ID is derived from the description, so
+// an outfit has the same ID in every session.
+type Style struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags,omitempty"`
+	Palette     []string `json:"palette,omitempty"`
+	// Items are the outfit's main garments and accessories as product search
+	// terms, e.g. "white linen shirt".
+	Items []string `json:"items,omitempty"`
+	// Products are items to buy the look with, when shopping is configured.
+	Products []Product `json:"products,omitempty"`
+	// Translation is the name and description in the session's language,
+	// for display, if it isn't English.
+	Translation *StyleTranslation `json:"translation,omitempty"`
+}
+
+// Product is a purchasable item matching one of a style's items.
+type Product struct {
+	// Item is the style's item the product was found for.
+	Item     string  `json:"item"`
+	Title    string  `json:"title"`
+	URL      string  `json:"url"`
+	Price    float64 `json:"price,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+	Retailer string  `json:"retailer,omitempty"`
+	ImageURL string  `json:"imageUrl,omitempty"`
+}
+
+// StyleTranslation is a style's name and description in another language.
+type StyleTranslation struct {
+	Language    string `json:"language"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description"`
+}
+
+// StyleID returns the ID of the style with the given description, ignoring
+// case and extra whitespace.
+func StyleID(description string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(strings.ToLower(description)), " ")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// NewStyle returns a style with only a description, such as one the user or
+// an admin wrote.
+func NewStyle(description string) Style {
+	return Style{ID: StyleID(description), Description: description}
+}
+
+// NewStyles returns a style for each description, as NewStyle does.
+func NewStyles(descriptions []string) []Style {
+	styles := make([]Style, len(descriptions))
+	for i, d := range descriptions {
+		styles[i] = NewStyle(d)
+	}
+	return styles
+}
+
+// Descriptions returns the descriptions of styles.
+func Descriptions(styles []Style) []string {
+	out := make([]string, len(styles))
+	for i, s := range styles {
+		out[i] = s.Description
+	}
+	return out
 }
 
 // SwapStyleRequest defines the structure for the JSON data sent for swapping styles.
+// StyleID, if set, picks the style by ID instead of StyleIndex. StyleText, if
+// set, is the client's own outfit description, used instead of either and
+// added to the session's styles.
 type SwapStyleRequest struct {
-	StyleIndex int `json:"styleIndex"`
-}
\ No newline at end of file
+	StyleIndex int    `json:"styleIndex"`
+	StyleID    string `json:"styleId,omitempty"`
+	StyleText  string `json:"styleText,omitempty"`
+	// Count asks for up to 4 variations of the style, as for GenerateRequest.
+	Count int `json:"count,omitempty"`
+}
+
+// RefineRequest asks POST /api/v1/refine to edit a look of the session as
+// Instruction says, e.g. "make it more formal", or to replace only its
+// Component, e.g. "shoes", with Change, e.g. "white sneakers". LookID
+// defaults to the session's most recent look.
+type RefineRequest struct {
+	LookID      string `json:"lookId,omitempty"`
+	Instruction string `json:"instruction,omitempty"`
+	Component   string `json:"component,omitempty"`
+	Change      string `json:"change,omitempty"`
+}
+
+// IndexedStyle is a style of a session with its index, which /swap-style
+// takes as styleIndex.
+type IndexedStyle struct {
+	Index int `json:"index"`
+	Style
+}
+
+// MoreStylesResponse lists the suggestions POST /api/v1/styles/more added to
+// the session.
+type MoreStylesResponse struct {
+	Styles []IndexedStyle `json:"styles"`
+}
+
+// DislikedStyle names a style of the session the user disliked, by
+// StyleIndex or StyleID, and optionally why, e.g. "no floral prints".
+type DislikedStyle struct {
+	StyleIndex *int   `json:"styleIndex,omitempty"`
+	StyleID    string `json:"styleId,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// RegenerateStylesRequest asks POST /api/v1/styles/regenerate to replace
+// the Disliked styles of the session with new suggestions.
+type RegenerateStylesRequest struct {
+	Disliked []DislikedStyle `json:"disliked"`
+}
+
+// RegenerateStylesResponse lists the replacements, each with the index of
+// the style it replaced.
+type RegenerateStylesResponse struct {
+	Styles []IndexedStyle `json:"styles"`
+}
+
+// AccessoriesRequest asks POST /api/v1/accessories to add accessories to a
+// look of the session: Types from GET /api/v1/accessories and Text
+// describing more in the user's words, e.g. "pearl drop earrings". LookID
+// defaults to the session's most recent look.
+type AccessoriesRequest struct {
+	LookID string   `json:"lookId,omitempty"`
+	Types  []string `json:"types,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Accessory is an accessory type listed by GET /api/v1/accessories.
+type Accessory struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ImageResponse is returned by /generate, /swap-style, /refine and
+// /accessories in place of the raw image when the request sends Accept:
+// application/json. Image is the base64-encoded image. With Accept:
+// multipart/mixed, it is the first part, without Image, followed by the raw
+// image as the second part.
+//
+// With object storage, ImageURL is a signed URL of the stored image, valid
+// until ImageURLExpiresAt, and JSON responses carry it instead of Image.
+//
+// When several variations were requested, the response is always JSON, the
+// images are in Variations, and LookID and MimeType are those of the first.
+type ImageResponse struct {
+	SessionID         string           `json:"sessionId"`
+	LookID            string           `json:"lookId"`
+	Styles            []Style          `json:"styles"`
+	StyleIndex        int              `json:"styleIndex"`
+	MimeType          string           `json:"mimeType"`
+	Image             string           `json:"image,omitempty"`
+	ImageURL          string           `json:"imageUrl,omitempty"`
+	ImageURLExpiresAt *time.Time       `json:"imageUrlExpiresAt,omitempty"`
+	Variations        []ImageVariation `json:"variations,omitempty"`
+}
+
+// ImageVariation is one rendering of the style of a request for several
+// variations. Image is base64-encoded, or empty with ImageURL set under the
+// same rules as ImageResponse.
+type ImageVariation struct {
+	LookID            string     `json:"lookId"`
+	MimeType          string     `json:"mimeType"`
+	Image             string     `json:"image,omitempty"`
+	ImageURL          string     `json:"imageUrl,omitempty"`
+	ImageURLExpiresAt *time.Time `json:"imageUrlExpiresAt,omitempty"`
+}
+
+// PartialResultResponse is returned by /generate and /swap-style in place of
+// the image, with the X-Partial-Result: text-only header, when the image call
+// failed and the text-only fallback is enabled. Outfit is the description of
+// the style at StyleIndex; Failure says why no image was produced. Partial
+// results do not count towards the daily quota.
+type PartialResultResponse struct {
+	SessionID  string        `json:"sessionId"`
+	Styles     []Style       `json:"styles"`
+	StyleIndex int           `json:"styleIndex"`
+	Outfit     string        `json:"outfit"`
+	Failure    ErrorResponse `json:"failure"`
+}
+
+// SessionResponse describes one of the caller's generation sessions. Active
+// is false once the session has expired from memory and can no longer be
+// used for swaps; its looks remain in the history.
+type SessionResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	EventType string    `json:"eventType"`
+	Venue     string    `json:"venue"`
+	Theme     string    `json:"theme"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// PresetUpdatedAt is set when the preset's style suggestions changed after
+	// the session was created; POST /sessions/{id}/refresh picks them up.
+	PresetUpdatedAt *time.Time `json:"presetUpdatedAt,omitempty"`
+}
+
+// UpdateSessionRequest renames a session or replaces its notes. Omitted
+// fields keep their current values; an empty string clears them.
+type UpdateSessionRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Notes *string `json:"notes,omitempty"`
+}
+
+// Favorite is a style or look the user shortlisted in a session. Kind is
+// "style" or "look" and ID the style's or look's ID. Description is the
+// style's, or for a look the style it shows; ImageURL is the look's image.
+type Favorite struct {
+	Kind        string    `json:"kind"`
+	ID          string    `json:"id"`
+	SessionID   string    `json:"sessionId"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"imageUrl,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// FavoritesResponse lists favorites, most recently added first.
+type FavoritesResponse struct {
+	Favorites []Favorite `json:"favorites"`
+}
+
+// StylePreview is a low-resolution render of one of the session's styles.
+// Image is a base64-encoded JPEG, or empty with Error set if the preview
+// could not be rendered.
+type StylePreview struct {
+	StyleIndex int            `json:"styleIndex"`
+	StyleID    string         `json:"styleId"`
+	Style      string         `json:"style"`
+	MimeType   string         `json:"mimeType,omitempty"`
+	Image      string         `json:"image,omitempty"`
+	Error      *ErrorResponse `json:"error,omitempty"`
+}
+
+// PreviewsResponse is returned by /previews with a preview of every style in
+// the session, in style order.
+type PreviewsResponse struct {
+	SessionID string         `json:"sessionId"`
+	Previews  []StylePreview `json:"previews"`
+}
+
+// UsageResponse reports the caller's accumulated usage.
+type UsageResponse struct {
+	Generations      int64   `json:"generations"`
+	Swaps            int64   `json:"swaps"`
+	Previews         int64   `json:"previews"`
+	Grades           int64   `json:"grades"`
+	Suggestions      int64   `json:"suggestions"`
+	Searches         int64   `json:"searches"`
+	EstimatedCostUSD float64 `json:"estimatedCostUsd"`
+
+	// Daily free-tier quota. DailyLimit is 0 when no cap is configured.
+	DailyLimit     int64     `json:"dailyLimit"`
+	DailyUsed      int64     `json:"dailyUsed"`
+	DailyRemaining int64     `json:"dailyRemaining"`
+	ResetAt        time.Time `json:"resetAt"`
+}
+
+// ErrorResponse is the body of every error response. Code is a stable,
+// machine-readable code such as SESSION_EXPIRED or UPSTREAM_FAILED; Message is
+// for humans. RequestID matches the X-Request-ID response header.
+type ErrorResponse struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	RequestID string         `json:"requestId,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// ThrottledResponse is the body of every response rejected for rate limit,
+// quota, saturation or maintenance, so clients can back off uniformly.
+type ThrottledResponse struct {
+	ErrorResponse
+	// Reason is one of rate_limit, quota, saturation or maintenance.
+	Reason            string `json:"reason"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds"`
+}
+
+// QuotaExceededResponse is returned with 429 when a client exhausts its daily free quota.
+type QuotaExceededResponse struct {
+	ThrottledResponse
+	Limit   int64     `json:"limit"`
+	ResetAt time.Time `json:"resetAt"`
+}
+
+// ReadinessResponse is the body of /readyz. Ready is false, and the status
+// 503, if any dependency check failed.
+type ReadinessResponse struct {
+	Ready  bool             `json:"ready"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// ReadinessCheck is the result of checking one dependency. CheckedAt may be
+// in the past for checks whose results are cached. Failure details are only
+// logged, since the endpoint is public.
+type ReadinessCheck struct {
+	Name       string    `json:"name"`
+	OK         bool      `json:"ok"`
+	CheckedAt  time.Time `json:"checkedAt"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// KeyExchangeResponse is the server half of an end-to-end encryption key
+// exchange. PublicKey is the base64-encoded X25519 public key.
+type KeyExchangeResponse struct {
+	KeyID     string    `json:"keyId"`
+	PublicKey string    `json:"publicKey"`
+	Algorithm string    `json:"algorithm"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// E2EECapability advertises the end-to-end encryption mode.
+type E2EECapability struct {
+	// Mode is off, optional or required.
+	Mode      string `json:"mode"`
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+// CapabilitiesResponse lists optional features clients can negotiate.
+type CapabilitiesResponse struct {
+	E2EE E2EECapability `json:"e2ee"`
+}
+
+// CheckoutResponse returns the hosted Stripe Checkout page for upgrading past the free tier.
+type CheckoutResponse struct {
+	URL       string `json:"url"`
+	SessionID string `json:"sessionId"`
+}
+
+// CheckoutStatusResponse reports the state of a Stripe Checkout session.
+type CheckoutStatusResponse struct {
+	Status        string `json:"status"`
+	PaymentStatus string `json:"paymentStatus"`
+	Active        bool   `json:"active"`
+}
+
+// SimilarLooksRequest searches for looks similar to a style description or an existing look.
+type SimilarLooksRequest struct {
+	StyleText string `json:"styleText,omitempty"`
+	LookID    string `json:"lookId,omitempty"`
+	// Scope is "mine", "public", or "" for both.
+	Scope string `json:"scope,omitempty"`
+	// Tag, if set, restricts results to looks with this tag.
+	Tag   string `json:"tag,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// LookResponse describes a generated look.
+type LookResponse struct {
+	ID        string    `json:"id"`
+	EventType string    `json:"eventType"`
+	Venue     string    `json:"venue"`
+	Theme     string    `json:"theme"`
+	Style     string    `json:"style"`
+	Public    bool      `json:"public"`
+	Rating    int       `json:"rating,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Score     float64   `json:"score,omitempty"`
+	// Grade compares the look with the photo from the actual event, once uploaded.
+	Grade      *RealismGrade `json:"grade,omitempty"`
+	ArchivedAt *time.Time    `json:"archivedAt,omitempty"`
+	// RefinedFrom is the look this one was edited from with Instruction,
+	// through POST /api/v1/refine or /api/v1/accessories.
+	RefinedFrom string `json:"refinedFrom,omitempty"`
+	Instruction string `json:"instruction,omitempty"`
+}
+
+// HistoryEntry is an image generated in a session, as listed by
+// GET /api/v1/history.
+type HistoryEntry struct {
+	LookID string `json:"lookId"`
+	Style  string `json:"style"`
+	// StyleIndex is the style's index in the session's styles, or -1 if it
+	// is no longer among them.
+	StyleIndex int    `json:"styleIndex"`
+	MimeType   string `json:"mimeType"`
+	// ImageURL is where the owner downloads the image, at
+	// GET /api/v1/looks/{id}/image; empty if the image is not stored, as in
+	// end-to-end encrypted sessions.
+	ImageURL string `json:"imageUrl,omitempty"`
+	// RefinedFrom is the look this one was edited from with Instruction.
+	RefinedFrom string    `json:"refinedFrom,omitempty"`
+	Instruction string    `json:"instruction,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// HistoryResponse lists the images generated in a session, oldest first.
+type HistoryResponse struct {
+	SessionID string         `json:"sessionId"`
+	Images    []HistoryEntry `json:"images"`
+}
+
+// SessionExport is the metadata.json written alongside the images in a
+// session's ZIP export.
+type SessionExport struct {
+	SessionID  string              `json:"sessionId"`
+	EventType  string              `json:"eventType"`
+	Venue      string              `json:"venue"`
+	Theme      string              `json:"theme"`
+	ExportedAt time.Time           `json:"exportedAt"`
+	Looks      []SessionExportLook `json:"looks"`
+}
+
+// SessionExportLook is one look in a session export. File is the image's path
+// inside the archive, empty if the image was not stored.
+type SessionExportLook struct {
+	LookResponse
+	File string `json:"file,omitempty"`
+}
+
+// BulkLooksRequest archives, unarchives or deletes looks in bulk, selected
+// either by ID or by creation date range.
+type BulkLooksRequest struct {
+	// Action is archive, unarchive or delete.
+	Action  string     `json:"action"`
+	LookIDs []string   `json:"lookIds,omitempty"`
+	From    *time.Time `json:"from,omitempty"`
+	To      *time.Time `json:"to,omitempty"`
+}
+
+// BulkJobResponse reports the progress of a bulk operation.
+type BulkJobResponse struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	// Status is running, done or failed.
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Failed     int        `json:"failed"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// Error says why a failed job stopped.
+	Error string `json:"error,omitempty"`
+}
+
+// RealismGrade scores how close the user's real event outfit came to the look.
+type RealismGrade struct {
+	Score       int       `json:"score"`
+	Summary     string    `json:"summary"`
+	Matches     []string  `json:"matches,omitempty"`
+	Differences []string  `json:"differences,omitempty"`
+	GradedAt    time.Time `json:"gradedAt"`
+}
+
+// CreateShortLinkRequest shortens a share, poll or referral URL.
+type CreateShortLinkRequest struct {
+	Target string `json:"target"`
+	// Kind is share, poll or referral; defaults to share.
+	Kind string `json:"kind,omitempty"`
+	// TTLSeconds expires the link after this many seconds; 0 keeps it forever.
+	TTLSeconds int64 `json:"ttlSeconds,omitempty"`
+}
+
+// ShortLinkResponse describes a short link and its redirect statistics.
+type ShortLinkResponse struct {
+	Code      string     `json:"code"`
+	ShortURL  string     `json:"shortUrl"`
+	Kind      string     `json:"kind"`
+	Target    string     `json:"target"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Hits      int64      `json:"hits"`
+	LastHitAt *time.Time `json:"lastHitAt,omitempty"`
+}
+
+// CreateShareRequest shares a look's image publicly.
+type CreateShareRequest struct {
+	// TTLSeconds expires the share after this many seconds; 0 uses the
+	// default of seven days.
+	TTLSeconds int64 `json:"ttlSeconds,omitempty"`
+}
+
+// ShareResponse describes a public share of a look's image.
+type ShareResponse struct {
+	Token string `json:"token"`
+	// URL serves the image to anyone, at GET /share/{token}.
+	URL string `json:"url"`
+	// ShortURL is a /s/{code} link to URL, short enough for SMS and print.
+	ShortURL  string    `json:"shortUrl,omitempty"`
+	LookID    string    `json:"lookId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	Views     int64     `json:"views"`
+}
+
+// RateLookRequest rates a look from 1 to 5 stars.
+type RateLookRequest struct {
+	Rating int `json:"rating"`
+}
+
+// TagsRequest sets or adds tags on a look.
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TagResponse is one of the caller's tags and the number of looks carrying it.
+type TagResponse struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// RenameTagRequest renames a tag on all of the caller's looks.
+type RenameTagRequest struct {
+	Name string `json:"name"`
+}
+
+// RetagResponse reports how many looks a tag rename or delete changed.
+type RetagResponse struct {
+	Looks int `json:"looks"`
+}
+
+// CreateAPIKeyRequest is the admin request to issue a new API key.
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// Scopes defaults to all scopes when empty.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// SessionSummary describes an active generation session for operators.
+type SessionSummary struct {
+	ID         string `json:"id"`
+	EventType  string `json:"eventType"`
+	Venue      string `json:"venue"`
+	Theme      string `json:"theme"`
+	Styles     int    `json:"styles"`
+	ImageBytes int    `json:"imageBytes"`
+}
+
+// UpdatePresetRequest replaces the cached style suggestions for a preset.
+// Without styles, fresh suggestions are fetched from Gemini.
+type UpdatePresetRequest struct {
+	EventType string   `json:"eventType"`
+	Venue     string   `json:"venue"`
+	Theme     string   `json:"theme"`
+	Styles    []string `json:"styles,omitempty"`
+}
+
+// UpdatePresetResponse reports a preset's new suggestions and how many recent
+// sessions were flagged for regeneration.
+type UpdatePresetResponse struct {
+	Styles   []Style `json:"styles"`
+	Sessions int     `json:"sessions"`
+}
+
+// CacheStatsResponse reports the in-memory session cache counters since
+// startup. Entries and Bytes are current values; the rest only grow.
+type CacheStatsResponse struct {
+	Entries   int64 `json:"entries"`
+	Creations int64 `json:"creations"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	// Bytes is the size of the uploaded images held by cached sessions.
+	Bytes int64 `json:"bytes"`
+	// HitRatio is hits / (hits + misses), or 0 before any lookup.
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// FlushCacheResponse reports how many cached entries were dropped.
+type FlushCacheResponse struct {
+	Presets int `json:"presets"`
+}
+
+// AdminBulkJob is a bulk job as listed to operators, with its owner.
+type AdminBulkJob struct {
+	BulkJobResponse
+	Owner string `json:"owner"`
+}
+
+// RetentionRunResponse reports how many expired records a retention run deleted.
+type RetentionRunResponse struct {
+	ShortLinks int `json:"shortLinks"`
+	Incidents  int `json:"incidents"`
+}
+
+// ReloadConfigResponse lists the settings applied by a config reload and
+// those that changed but need a restart.
+type ReloadConfigResponse struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// ConfigVersionResponse describes a recorded runtime configuration. Settings
+// is only included when a single version is requested.
+type ConfigVersionResponse struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Source is what applied it: startup, reload, preset or rollback.
+	Source         string `json:"source"`
+	RolledBackFrom int    `json:"rolledBackFrom,omitempty"`
+	Settings       any    `json:"settings,omitempty"`
+}
+
+// ConfigChange is one setting that differs between two config versions.
+type ConfigChange struct {
+	Path string `json:"path"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
+
+// ConfigDiffResponse lists the changes from one config version to another.
+// From is 0 when the version is compared with the current settings.
+type ConfigDiffResponse struct {
+	From    int            `json:"from"`
+	To      int            `json:"to"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// RollbackConfigResponse reports the version a rollback recorded and the
+// settings it changed.
+type RollbackConfigResponse struct {
+	Version        int      `json:"version"`
+	RolledBackFrom int      `json:"rolledBackFrom"`
+	Applied        []string `json:"applied"`
+}
+
+// MaintenanceRequest switches maintenance mode. Message and RetryAfterSeconds
+// keep their current values when omitted.
+type MaintenanceRequest struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message,omitempty"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
+// MaintenanceResponse reports the maintenance mode state.
+type MaintenanceResponse struct {
+	Enabled           bool      `json:"enabled"`
+	Message           string    `json:"message"`
+	RetryAfterSeconds int       `json:"retryAfterSeconds"`
+	Since             time.Time `json:"since"`
+}
+
+// LogLevelRequest changes the server's minimum log level.
+type LogLevelRequest struct {
+	// Level is debug, info, warn or error.
+	Level string `json:"level"`
+}
+
+// LogLevelResponse reports the server's minimum log level.
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// SetDomainRequest is the admin request to assign a tenant's custom domain.
+type SetDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// SetOriginsRequest is the admin request to bind an API key to browser origins.
+type SetOriginsRequest struct {
+	Origins []string `json:"origins"`
+}
+
+// APIKeyResponse describes an API key. Key holds the secret and is only
+// returned when the key is created or rotated.
+type APIKeyResponse struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Prefix         string   `json:"prefix"`
+	Scopes         []string `json:"scopes"`
+	Domain         string   `json:"domain,omitempty"`
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
+	// HasSigningSecret is set once a signing secret has been issued.
+	HasSigningSecret bool       `json:"hasSigningSecret,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	RotatedAt        *time.Time `json:"rotatedAt,omitempty"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	// Suspension is set while the key is suspended.
+	Suspension *APIKeySuspension `json:"suspension,omitempty"`
+	Key        string            `json:"key,omitempty"`
+	// SigningSecret is only returned when it is issued.
+	SigningSecret string `json:"signingSecret,omitempty"`
+}
+
+// APIKeySuspension says why a key is suspended and, if timed, when it is
+// re-enabled automatically.
+type APIKeySuspension struct {
+	Reason      string     `json:"reason"`
+	SuspendedAt time.Time  `json:"suspendedAt"`
+	Until       *time.Time `json:"until,omitempty"`
+}
+
+// SuspendAPIKeyRequest suspends a key. Without until, the key stays
+// suspended until it is resumed.
+type SuspendAPIKeyRequest struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// PublishLookRequest opts a look into the public gallery.
+type PublishLookRequest struct {
+	LookID string `json:"lookId"`
+	// DisplayName is credited on the gallery entry when ShowAttribution is true.
+	DisplayName     string `json:"displayName,omitempty"`
+	ShowAttribution bool   `json:"showAttribution"`
+}
+
+// ModerateLookRequest is an admin moderation action: approve, reject, feature or unfeature.
+type ModerateLookRequest struct {
+	Action string `json:"action"`
+	Note   string `json:"note,omitempty"`
+}
+
+// GalleryItem is a look as shown in the gallery.
+type GalleryItem struct {
+	ID          string    `json:"id"`
+	EventType   string    `json:"eventType"`
+	Venue       string    `json:"venue"`
+	Theme       string    `json:"theme"`
+	Style       string    `json:"style"`
+	Tags        []string  `json:"tags,omitempty"`
+	ImageURL    string    `json:"imageUrl"`
+	Status      string    `json:"status"`
+	Featured    bool      `json:"featured"`
+	Attribution string    `json:"attribution,omitempty"`
+	SubmittedAt time.Time `json:"submittedAt"`
+}
+
+// GalleryPage is one page of gallery results.
+type GalleryPage struct {
+	Items    []GalleryItem `json:"items"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"pageSize"`
+	Total    int           `json:"total"`
+}
@@ -0,0 +1,312 @@
+// objectstore/objectstore.go
+//
+// Package objectstore keeps generated images in an S3-compatible bucket, such
+// as Amazon S3, Google Cloud Storage (with HMAC keys), Cloudflare R2 or MinIO,
+// and signs short-lived URLs for downloading them straight from the bucket or
+// a CDN in front of it. Requests are signed with AWS Signature Version 4, so
+// no cloud SDK is needed.
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+// MaxURLTTL is the longest lifetime SigV4 allows for a signed URL.
+const MaxURLTTL = 7 * 24 * time.Hour
+
+const (
+	algorithm       = "AWS4-HMAC-SHA256"
+	service         = "s3"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// Bucket is an S3-compatible bucket. It implements store.Store, with each
+// namespace as a key prefix, so it can hold any store namespace.
+type Bucket struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	// now is the signing clock.
+	now func() time.Time
+}
+
+var _ store.Store = (*Bucket)(nil)
+
+// New creates a Bucket reached at endpoint (e.g. https://s3.eu-west-1.amazonaws.com
+// or https://storage.googleapis.com) with path-style URLs.
+func New(endpoint, region, bucket, accessKey, secretKey string) (*Bucket, error) {
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid object storage endpoint %q", endpoint)
+	}
+	return &Bucket{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		now:       time.Now,
+	}, nil
+}
+
+// objectURL is the path-style URL of namespace/key, or of the bucket if
+// namespace is empty.
+func (b *Bucket) objectURL(namespace, key string) *url.URL {
+	u := *b.endpoint
+	u.Path = u.Path + "/" + b.bucket
+	if namespace != "" {
+		u.Path += "/" + namespace + "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	return &u
+}
+
+// Get downloads namespace/key.
+func (b *Bucket) Get(ctx context.Context, namespace, key string) ([]byte, error) {
+	res, err := b.do(ctx, http.MethodGet, b.objectURL(namespace, key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, store.ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError("get", namespace, key, res)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// Exists reports whether namespace/key is stored, without downloading it.
+func (b *Bucket) Exists(ctx context.Context, namespace, key string) (bool, error) {
+	res, err := b.do(ctx, http.MethodHead, b.objectURL(namespace, key), nil, nil)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, statusError("head", namespace, key, res)
+}
+
+// Put uploads value as namespace/key.
+func (b *Bucket) Put(ctx context.Context, namespace, key string, value []byte) error {
+	res, err := b.do(ctx, http.MethodPut, b.objectURL(namespace, key), nil, value)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return statusError("put", namespace, key, res)
+	}
+	return nil
+}
+
+// Delete removes namespace/key. Deleting a missing object succeeds.
+func (b *Bucket) Delete(ctx context.Context, namespace, key string) error {
+	res, err := b.do(ctx, http.MethodDelete, b.objectURL(namespace, key), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return statusError("delete", namespace, key, res)
+	}
+	return nil
+}
+
+// List returns the keys in namespace.
+func (b *Bucket) List(ctx context.Context, namespace string) ([]string, error) {
+	prefix := namespace + "/"
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		res, err := b.do(ctx, http.MethodGet, b.objectURL("", ""), query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if res.StatusCode != http.StatusOK {
+			err = statusError("list", namespace, "", res)
+		} else {
+			err = xml.NewDecoder(res.Body).Decode(&page)
+		}
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(c.Key, prefix))
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// SignedURL returns a URL that downloads namespace/key for ttl, served with
+// the given Content-Type and, if not empty, Content-Disposition.
+func (b *Bucket) SignedURL(namespace, key, contentType, disposition string, ttl time.Duration) string {
+	u := b.objectURL(namespace, key)
+	now := b.now().UTC()
+	query := url.Values{
+		"X-Amz-Algorithm":     {algorithm},
+		"X-Amz-Credential":    {b.accessKey + "/" + b.scope(now)},
+		"X-Amz-Date":          {now.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.Itoa(int(ttl.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if contentType != "" {
+		query.Set("response-content-type", contentType)
+	}
+	if disposition != "" {
+		query.Set("response-content-disposition", disposition)
+	}
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", b.signature(now, canonical))
+	u.RawQuery = canonicalQuery(query)
+	return u.String()
+}
+
+// do sends a request signed in the Authorization header.
+func (b *Bucket) do(ctx context.Context, method string, u *url.URL, query url.Values, body []byte) (*http.Response, error) {
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	now := b.now().UTC()
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, b.accessKey, b.scope(now), signedHeaders, b.signature(now, canonical)))
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("object storage request failed: %w", err)
+	}
+	return res, nil
+}
+
+func (b *Bucket) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + b.region + "/" + service + "/aws4_request"
+}
+
+// signature signs a canonical request with the key derived for its day.
+func (b *Bucket) signature(now time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{algorithm, now.Format("20060102T150405Z"), b.scope(now), sha256Hex([]byte(canonicalRequest))}, "\n")
+	key := []byte("AWS4" + b.secretKey)
+	for _, part := range []string{now.Format("20060102"), b.region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// escape percent-encodes everything but the RFC 3986 unreserved characters,
+// as SigV4 requires.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// escapePath escapes each segment of path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = escape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery encodes query sorted by key, with SigV4 escaping.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func statusError(op, namespace, key string, res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return fmt.Errorf("object storage %s %s/%s: status %d: %s", op, namespace, key, res.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,218 @@
+// palette/palette.go
+//
+// Package palette analyzes an uploaded photo for the person's skin tone and
+// the photo's dominant colors, and recommends colors that flatter the skin
+// tone, so style suggestions can favor them. The analysis runs locally on a
+// downsampled grid of pixels and takes a few milliseconds.
+package palette
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"slices"
+	"sort"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// gridSize is how many pixels are sampled along the longer side.
+	gridSize = 160
+	// minSkinShare is the share of the central samples that must look like
+	// skin for a skin tone to be reported.
+	minSkinShare = 0.02
+	// minDominantShare is the share of the samples a color must cover to be
+	// dominant.
+	minDominantShare = 0.05
+	// maxDominant caps the dominant colors reported.
+	maxDominant = 3
+)
+
+// Analysis is what a photo shows of the person's coloring.
+type Analysis struct {
+	// SkinTone is light, medium or deep, and Undertone warm, cool or
+	// neutral; both are empty if no skin was found.
+	SkinTone  string `json:"skinTone,omitempty"`
+	Undertone string `json:"undertone,omitempty"`
+	// Dominant are the photo's main colors other than skin, as color names,
+	// most common first.
+	Dominant []string `json:"dominant,omitempty"`
+	// Recommended are colors that flatter the skin tone; empty if no skin
+	// was found.
+	Recommended []string `json:"recommended,omitempty"`
+}
+
+// recommendations are flattering colors by undertone and skin tone.
+var recommendations = map[string]map[string][]string{
+	"warm": {
+		"light":  {"peach", "coral", "camel", "warm ivory", "olive green"},
+		"medium": {"terracotta", "mustard", "olive green", "teal", "cream"},
+		"deep":   {"burnt orange", "gold", "emerald", "chocolate brown", "ivory"},
+	},
+	"cool": {
+		"light":  {"powder blue", "lavender", "rose pink", "soft grey", "navy"},
+		"medium": {"sapphire", "berry", "emerald", "plum", "crisp white"},
+		"deep":   {"cobalt", "fuchsia", "ruby red", "pure white", "black"},
+	},
+	"neutral": {
+		"light":  {"dusty rose", "jade", "soft white", "taupe", "navy"},
+		"medium": {"teal", "blush", "soft white", "burgundy", "charcoal"},
+		"deep":   {"emerald", "cranberry", "white", "cobalt", "gold"},
+	},
+}
+
+// namedColors are the names dominant colors are reported with.
+var namedColors = []struct {
+	name    string
+	r, g, b uint8
+}{
+	{"black", 20, 20, 20}, {"charcoal", 60, 60, 65}, {"grey", 128, 128, 128}, {"light grey", 200, 200, 200}, {"white", 245, 245, 245},
+	{"ivory", 240, 234, 214}, {"beige", 215, 195, 160}, {"tan", 190, 150, 100}, {"brown", 110, 70, 40}, {"dark brown", 60, 40, 25},
+	{"red", 200, 30, 40}, {"burgundy", 110, 20, 40}, {"pink", 240, 150, 180}, {"coral", 245, 120, 90}, {"orange", 240, 140, 30},
+	{"mustard", 210, 170, 40}, {"yellow", 245, 225, 60}, {"olive", 110, 110, 40}, {"green", 50, 140, 60}, {"dark green", 25, 70, 40},
+	{"mint", 170, 230, 200}, {"teal", 20, 130, 130}, {"sky blue", 140, 190, 235}, {"blue", 40, 90, 200}, {"navy", 25, 35, 80},
+	{"lavender", 190, 170, 230}, {"purple", 110, 50, 140},
+}
+
+// Analyze decodes a JPEG, PNG or WebP photo and analyzes it.
+func Analyze(data []byte) (Analysis, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Analysis{}, err
+	}
+	return AnalyzeImage(img), nil
+}
+
+// bucket accumulates the samples of one quantized color.
+type bucket struct {
+	r, g, b float64
+	n       int
+}
+
+// AnalyzeImage analyzes img. Skin is only looked for in the center of the
+// photo, where the person usually is, since sand, wood and walls can have
+// skin-like colors.
+func AnalyzeImage(img image.Image) Analysis {
+	bounds := img.Bounds()
+	step := max(1, max(bounds.Dx(), bounds.Dy())/gridSize)
+	var skin bucket
+	central, total := 0, 0
+	buckets := map[int]*bucket{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			total++
+			fx := float64(x-bounds.Min.X) / float64(bounds.Dx())
+			fy := float64(y-bounds.Min.Y) / float64(bounds.Dy())
+			if fx >= 0.25 && fx < 0.75 && fy >= 0.1 && fy < 0.7 {
+				central++
+				if isSkin(c.R, c.G, c.B) {
+					skin.add(c)
+					continue
+				}
+			}
+			key := int(c.R>>5)<<6 | int(c.G>>5)<<3 | int(c.B>>5)
+			if buckets[key] == nil {
+				buckets[key] = &bucket{}
+			}
+			buckets[key].add(c)
+		}
+	}
+
+	var a Analysis
+	if central > 0 && float64(skin.n)/float64(central) >= minSkinShare {
+		l, aStar, bStar := skin.lab()
+		switch {
+		case l >= 70:
+			a.SkinTone = "light"
+		case l >= 45:
+			a.SkinTone = "medium"
+		default:
+			a.SkinTone = "deep"
+		}
+		switch hue := math.Atan2(bStar, aStar) * 180 / math.Pi; {
+		case hue > 58:
+			a.Undertone = "warm"
+		case hue < 50:
+			a.Undertone = "cool"
+		default:
+			a.Undertone = "neutral"
+		}
+		a.Recommended = recommendations[a.Undertone][a.SkinTone]
+	}
+
+	sorted := make([]*bucket, 0, len(buckets))
+	for _, b := range buckets {
+		sorted = append(sorted, b)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].n > sorted[j].n })
+	for _, b := range sorted {
+		if len(a.Dominant) == maxDominant || float64(b.n)/float64(total) < minDominantShare {
+			break
+		}
+		if name := b.name(); !slices.Contains(a.Dominant, name) {
+			a.Dominant = append(a.Dominant, name)
+		}
+	}
+	return a
+}
+
+func (b *bucket) add(c color.NRGBA) {
+	b.r += float64(c.R)
+	b.g += float64(c.G)
+	b.b += float64(c.B)
+	b.n++
+}
+
+// lab returns the bucket's mean color in CIELAB.
+func (b *bucket) lab() (l, aStar, bStar float64) {
+	n := float64(b.n)
+	return lab(b.r/n, b.g/n, b.b/n)
+}
+
+// name returns the name of the named color closest to the bucket's mean.
+func (b *bucket) name() string {
+	l, aStar, bStar := b.lab()
+	best, bestDist := "", math.Inf(1)
+	for _, c := range namedColors {
+		cl, ca, cb := lab(float64(c.r), float64(c.g), float64(c.b))
+		if d := (l-cl)*(l-cl) + (aStar-ca)*(aStar-ca) + (bStar-cb)*(bStar-cb); d < bestDist {
+			best, bestDist = c.name, d
+		}
+	}
+	return best
+}
+
+// isSkin reports whether an sRGB color is in the YCbCr range typical of
+// human skin.
+func isSkin(r, g, b uint8) bool {
+	y, cb, cr := color.RGBToYCbCr(r, g, b)
+	return y >= 40 && cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+}
+
+// lab converts an sRGB color with 0-255 channels to CIELAB under D65.
+func lab(r, g, b float64) (l, aStar, bStar float64) {
+	linear := func(c float64) float64 {
+		c /= 255
+		if c <= 0.04045 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	lr, lg, lb := linear(r), linear(g), linear(b)
+	x := (0.4124*lr + 0.3576*lg + 0.1805*lb) / 0.95047
+	y := 0.2126*lr + 0.7152*lg + 0.0722*lb
+	z := (0.0193*lr + 0.1192*lg + 0.9505*lb) / 1.08883
+	f := func(t float64) float64 {
+		if t > 0.008856 {
+			return math.Cbrt(t)
+		}
+		return 7.787*t + 16.0/116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
@@ -0,0 +1,269 @@
+// presets/presets.go
+package presets
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+)
+
+const (
+	// entryTTL is how long cached suggestions are served before they are considered stale.
+	entryTTL = 24 * time.Hour
+	// warmTopN is how many of the most requested presets are refreshed on each warm run.
+	warmTopN = 20
+	// maxTracked caps the presets whose popularity is counted, since any
+	// client can request new ones.
+	maxTracked = 1000
+)
+
+// Preset is an event/venue/theme combination.
+type Preset struct {
+	EventType string
+	Venue     string
+	Theme     string
+}
+
+// key normalizes a preset so trivially different spellings share a cache entry.
+func (p Preset) key() string {
+	norm := func(s string) string { return strings.Join(strings.Fields(strings.ToLower(s)), " ") }
+	return norm(p.EventType) + "|" + norm(p.Venue) + "|" + norm(p.Theme)
+}
+
+// Equal reports whether p and q name the same preset, ignoring case and extra whitespace.
+func (p Preset) Equal(q Preset) bool {
+	return p.key() == q.key()
+}
+
+// ParsePresets parses presets written as "eventType|venue|theme".
+// Malformed entries are skipped.
+func ParsePresets(entries []string) []Preset {
+	var out []Preset
+	for _, entry := range entries {
+		parts := strings.Split(entry, "|")
+		if len(parts) != 3 {
+			continue
+		}
+		out = append(out, Preset{
+			EventType: strings.TrimSpace(parts[0]),
+			Venue:     strings.TrimSpace(parts[1]),
+			Theme:     strings.TrimSpace(parts[2]),
+		})
+	}
+	return out
+}
+
+// FetchFunc produces style suggestions for a preset, normally by calling Gemini.
+type FetchFunc func(ctx context.Context, p Preset) ([]models.Style, error)
+
+type entry struct {
+	styles    []models.Style
+	fetchedAt time.Time
+}
+
+// Cache holds precomputed style suggestions for popular presets and tracks
+// how often each preset is requested so the most popular ones can be kept warm.
+type Cache struct {
+	logger *slog.Logger
+	// seeds are always kept warm regardless of observed popularity.
+	seeds []Preset
+
+	mu      sync.Mutex
+	entries map[string]entry
+	// popularity counts the requests of each tracked preset, halved on every
+	// warm run so presets that fall out of favor are forgotten.
+	popularity map[string]int
+	presets    map[string]Preset
+	// pinned are presets an admin set suggestions for, which are kept
+	// however rarely they are requested.
+	pinned map[string]bool
+}
+
+// NewCache creates a Cache that always warms the given seed presets.
+func NewCache(logger *slog.Logger, seeds []Preset) *Cache {
+	return &Cache{
+		logger:     logger,
+		seeds:      seeds,
+		entries:    make(map[string]entry),
+		popularity: make(map[string]int),
+		presets:    make(map[string]Preset),
+		pinned:     make(map[string]bool),
+	}
+}
+
+// track starts counting the popularity of a preset, making room by
+// forgetting the least requested one if maxTracked are tracked already.
+// The caller must hold c.mu.
+func (c *Cache) track(k string, p Preset) {
+	if _, ok := c.presets[k]; ok {
+		return
+	}
+	if len(c.presets) >= maxTracked {
+		least := ""
+		for other := range c.presets {
+			if least == "" || c.popularity[other] < c.popularity[least] {
+				least = other
+			}
+		}
+		c.forget(least)
+	}
+	c.presets[k] = p
+}
+
+// forget stops tracking a preset and drops its suggestions unless they are
+// a seed's or pinned. The caller must hold c.mu.
+func (c *Cache) forget(k string) {
+	delete(c.popularity, k)
+	delete(c.presets, k)
+	if !c.pinned[k] && !c.isSeed(k) {
+		delete(c.entries, k)
+	}
+}
+
+func (c *Cache) isSeed(k string) bool {
+	for _, p := range c.seeds {
+		if p.key() == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns cached suggestions for a preset and counts the request towards its popularity.
+func (c *Cache) Get(p Preset) ([]models.Style, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := p.key()
+	c.track(k, p)
+	c.popularity[k]++
+	e, ok := c.entries[k]
+	if !ok || time.Since(e.fetchedAt) > entryTTL {
+		return nil, false
+	}
+	return append([]models.Style(nil), e.styles...), true
+}
+
+// Peek returns cached suggestions for a preset without counting the request
+// towards its popularity. Stale entries are returned too.
+func (c *Cache) Peek(p Preset) ([]models.Style, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[p.key()]
+	if !ok {
+		return nil, false
+	}
+	return append([]models.Style(nil), e.styles...), true
+}
+
+// Put stores suggestions for a preset. They are dropped once the preset is
+// no longer among the tracked ones, unless it is a seed.
+func (c *Cache) Put(p Preset, styles []models.Style) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := p.key()
+	c.track(k, p)
+	c.entries[k] = entry{styles: append([]models.Style(nil), styles...), fetchedAt: time.Now()}
+}
+
+// Pin stores suggestions an admin set for a preset, which are kept until
+// Delete however rarely the preset is requested.
+func (c *Cache) Pin(p Preset, styles []models.Style) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := p.key()
+	c.pinned[k] = true
+	c.entries[k] = entry{styles: append([]models.Style(nil), styles...), fetchedAt: time.Now()}
+}
+
+// Delete drops the cached suggestions for a preset and unpins it.
+func (c *Cache) Delete(p Preset) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, p.key())
+	delete(c.pinned, p.key())
+}
+
+// Flush drops all cached suggestions and returns how many were dropped.
+// Popularity counts are kept so the next warm-up refills the same presets.
+func (c *Cache) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.entries)
+	c.entries = make(map[string]entry)
+	return n
+}
+
+// popular returns the seed presets followed by the most requested ones. It
+// then halves every preset's count and forgets those that drop to zero
+// outside the returned set, so the tracked presets don't grow without bound.
+func (c *Cache) popular() []Preset {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, len(c.popularity))
+	for k := range c.popularity {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return c.popularity[keys[i]] > c.popularity[keys[j]] })
+	if len(keys) > warmTopN {
+		keys = keys[:warmTopN]
+	}
+
+	out := append([]Preset(nil), c.seeds...)
+	seen := make(map[string]bool)
+	for _, p := range c.seeds {
+		seen[p.key()] = true
+	}
+	for _, k := range keys {
+		if !seen[k] {
+			out = append(out, c.presets[k])
+		}
+		seen[k] = true
+	}
+
+	for k := range c.presets {
+		if c.popularity[k] /= 2; c.popularity[k] == 0 && !seen[k] {
+			c.forget(k)
+		}
+	}
+	return out
+}
+
+// Warm refreshes suggestions for the seed and most popular presets.
+func (c *Cache) Warm(ctx context.Context, fetch FetchFunc) {
+	presets := c.popular()
+	warmed := 0
+	for _, p := range presets {
+		if ctx.Err() != nil {
+			return
+		}
+		styles, err := fetch(ctx, p)
+		if err != nil || len(styles) == 0 {
+			c.logger.Warn("Failed to warm preset suggestions", "preset", p, "error", err)
+			continue
+		}
+		c.Put(p, styles)
+		warmed++
+	}
+	c.logger.Info("Warmed preset suggestion cache", "presets", len(presets), "warmed", warmed)
+}
+
+// Run warms the cache immediately and then every interval until ctx is cancelled.
+func (c *Cache) Run(ctx context.Context, interval time.Duration, fetch FetchFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.Warm(ctx, fetch)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,425 @@
+// prompt/prompt.go
+//
+// Package prompt builds the prompts sent to Gemini from typed inputs. The
+// templates name their placeholders, and Load checks that every template
+// renders and uses each of its inputs, so a template edit, built in or from
+// the prompts config, that drops or misnames a placeholder fails the boot or
+// reload instead of producing a prompt with "%!s(MISSING)" or a missing venue
+// in production.
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"text/template"
+)
+
+// DefaultStyleCount is how many outfits the suggestions prompt asks for
+// unless told otherwise.
+const DefaultStyleCount = 5
+
+// Event is the occasion the user is dressing for.
+type Event struct {
+	EventType string
+	Venue     string
+	Theme     string
+}
+
+// Wearer is what the user told us about the person the outfits are for.
+// Empty fields are left to the model.
+type Wearer struct {
+	// Gender, e.g. "woman", so it need not be guessed.
+	Gender string
+	// BodyType is a figure to flatter, e.g. "petite".
+	BodyType string
+	// Fit is the preferred fit of the garments, e.g. "relaxed".
+	Fit string
+	// Modesty is the coverage every outfit must keep, e.g. "covering the
+	// shoulders and knees".
+	Modesty string
+}
+
+// Coloring is what the uploaded photo shows of the person's coloring.
+type Coloring struct {
+	// SkinTone describes the skin, e.g. "warm medium", or is empty if the
+	// photo showed none.
+	SkinTone string
+	// Flattering are colors that flatter the skin tone.
+	Flattering []string
+	// PhotoColors are the photo's dominant colors other than skin.
+	PhotoColors []string
+}
+
+// ImageInput is the input of the image generation prompt.
+type ImageInput struct {
+	Event
+	Wearer
+	// Style is the outfit description to dress the people in.
+	Style string
+	// References is how many more photos of the person, from other angles,
+	// follow the photo to restyle.
+	References int
+	// AspectRatio is the frame to compose the image for, as width:height
+	// (e.g. "4:5"), or empty to leave it to the model.
+	AspectRatio string
+	// Suffix is a trusted client's extra instructions, appended last.
+	Suffix string
+	// KeepBackground only changes the clothing, keeping the photo's own
+	// background, lighting and framing. AspectRatio is not asked for then.
+	KeepBackground bool
+}
+
+// RefineInput is the input of the prompt that edits a generated look.
+type RefineInput struct {
+	Event
+	// Style is the outfit description the look was first generated from;
+	// earlier refinements may have changed it since.
+	Style string
+	// Instruction is the user's requested change, e.g. "make it more formal".
+	Instruction string
+	// References is how many more photos of the person follow the look.
+	References int
+	// Suffix is a trusted client's extra instructions, appended last.
+	Suffix string
+}
+
+// SuggestionsInput is the input of the style suggestions prompt.
+type SuggestionsInput struct {
+	Event
+	Wearer
+	Coloring
+	// Count is how many outfits to suggest; zero asks for
+	// DefaultStyleCount.
+	Count int
+	// Tags are style tags every suggestion must fit, e.g. "formal".
+	Tags []string
+	// Weather is the expected weather at the venue on the day, e.g.
+	// "24-32°C, humid", or empty if unknown.
+	Weather string
+	// Language is the name of the language to translate the suggestions
+	// into, e.g. "Spanish", or empty for English only.
+	Language string
+	// Avoid are the user's reasons for disliking earlier suggestions, e.g.
+	// "no floral prints", which the new ones must respect.
+	Avoid []string
+	// Exclude are suggestions the user already has, which the new ones must
+	// differ from.
+	Exclude []string
+}
+
+// GradeInput is the input of the realism grading prompt.
+type GradeInput struct {
+	Event
+	// Style is the outfit the generated look shows.
+	Style string
+}
+
+// imageTemplate is a detailed, professional prompt based on the prompt guide.
+// It instructs the model to perform an image-to-image task, preserving the subject
+// while transforming the context (outfit and background), or with
+// KeepBackground only the outfit.
+const imageTemplate = `{{if .KeepBackground}}
+A photorealistic edit of the provided photo that changes only the people's clothing.
+The outfit is for a '{{.EventType}}' at '{{.Venue}}' with the theme '{{.Theme}}', but the photo's setting stays as it is.
+
+**CRITICAL INSTRUCTION:** Dress the people in a very specific, stylish, high-fashion outfit that perfectly matches this detailed description: {{.Style}}.
+
+Keep the original background, lighting, camera angle, framing and pose exactly as they are; do not move the people to a new place.
+Light the outfit with the photo's existing light so it blends in naturally.
+Preserve the people's faces and features from the original photo.
+{{else}}
+A photorealistic close-up portrait of the people from the provided image.
+Place them in a new context for a '{{.EventType}}' at '{{.Venue}}' with the theme '{{.Theme}}'.
+
+**CRITICAL INSTRUCTION:** Dress the people in a very specific, stylish, high-fashion outfit that perfectly matches this detailed description: {{.Style}}.
+
+Ensure the background, lighting, and mood are photorealistic and match the event.
+Preserve the people's faces and features from the original photo. Style and pose can be changed to fit the outfit.
+The final image should be captured with an 85mm portrait lens with a soft, blurred background.
+{{end}}{{if or .Gender .BodyType .Fit .Modesty}}
+Tailor the outfit to the person:{{if .Gender}}
+- they are a {{.Gender}}{{end}}{{if .BodyType}}
+- choose cuts that flatter a {{.BodyType}} body type{{end}}{{if .Fit}}
+- they prefer a {{.Fit}} fit{{end}}{{if .Modesty}}
+- keep it modest, {{.Modesty}}{{end}}
+{{end}}{{if .References}}
+The first provided image is the photo to restyle. The {{.References}} images after it show the same person from other angles; use them only as a reference to keep the face and features faithful, not as a source of pose, outfit or background.
+{{end}}{{if and .AspectRatio (not .KeepBackground)}}
+Compose the image for a {{.AspectRatio}} (width:height) frame, with the people fully in the shot and centered.
+{{end}}{{if .Suffix}}
+{{.Suffix}}
+{{end}}`
+
+// refineTemplate edits a previously generated look rather than restyling
+// the original photo, so everything the instruction doesn't touch is kept.
+const refineTemplate = `
+The provided image is a photorealistic outfit look for a '{{.EventType}}' at '{{.Venue}}' with the theme '{{.Theme}}'. The outfit started from this description: {{.Style}}.
+
+**CRITICAL INSTRUCTION:** Edit this image as follows: {{.Instruction}}.
+
+Change only what the instruction asks for. Keep the people's faces, features and pose, and the background, lighting and framing, exactly as they are.
+The result must remain a photorealistic photo that fits the event.
+{{if .References}}
+The {{.References}} images after the first show the same person from other angles; use them only as a reference to keep the face and features faithful.
+{{end}}{{if .Suffix}}
+{{.Suffix}}
+{{end}}`
+
+// suggestionsTemplate asks for Count outfits as a JSON array of objects,
+// for the Wearer if known, fitting Tags, their Coloring and the Weather,
+// respecting the dislikes in Avoid and different from any in Exclude. The descriptions stay in
+// English for the image prompt, with a translation into Language.
+const suggestionsTemplate = `{{if .Gender}}For a {{.Gender}} going to an event '{{.EventType}}' at location '{{.Venue}}' with the theme '{{.Theme}}', generate a JSON array of {{.Count}} distinct and creative outfits for them.{{else}}Based on the person in the user's photo, identify their likely gender. Then, for an event '{{.EventType}}' at location '{{.Venue}}' with the theme '{{.Theme}}', generate a JSON array of {{.Count}} distinct and creative outfits for them.{{end}}
+Each outfit is an object with:
+- "name": a short, catchy title of 2 to 5 words
+- "description": a specific and evocative fashion apparel description
+- "tags": 1 to 3 of formal, semi-formal, casual, traditional, modern, bohemian, minimalist, glamorous, vintage, streetwear
+- "palette": the 2 to 4 main colors, as plain color names
+- "items": the 2 to 4 main garments and accessories, each as a short product search term
+Example for a man: [{"name": "Coastal Linen Ease", "description": "a crisp white linen shirt with tailored khaki shorts and leather sandals", "tags": ["casual", "minimalist"], "palette": ["white", "khaki", "tan"], "items": ["white linen shirt", "khaki chino shorts", "tan leather sandals"]}].
+Example for a woman: [{"name": "Boho Garden Party", "description": "bohemian chic with a crochet top and a flowy tiered skirt", "tags": ["bohemian", "casual"], "palette": ["cream", "terracotta", "sage"], "items": ["cream crochet top", "terracotta tiered maxi skirt"]}].{{if .Tags}}
+
+The user only wants outfits that fit all of these tags, so include each of them in every outfit's "tags": {{range $i, $tag := .Tags}}{{if $i}}, {{end}}{{$tag}}{{end}}.{{end}}{{if or .BodyType .Fit .Modesty}}
+
+Tailor every outfit to the person:{{if .BodyType}}
+- choose cuts that flatter a {{.BodyType}} body type{{end}}{{if .Fit}}
+- they prefer a {{.Fit}} fit{{end}}{{if .Modesty}}
+- keep it modest, {{.Modesty}}{{end}}{{end}}{{if .SkinTone}}
+
+Their photo shows a {{.SkinTone}} skin tone. In most outfits' palettes, favor colors that flatter it: {{range $i, $color := .Flattering}}{{if $i}}, {{end}}{{$color}}{{end}}.{{end}}{{if .PhotoColors}}
+
+The main colors in their photo, which may include their hair and current clothes, are: {{range $i, $color := .PhotoColors}}{{if $i}}, {{end}}{{$color}}{{end}}. Choose palettes that complement them.{{end}}{{if .Weather}}
+
+The weather expected at the venue on the day is {{.Weather}}. Choose fabrics, layers and footwear that are comfortable in it, especially if the event is outdoors.{{end}}{{if .Language}}
+
+The user reads {{.Language}}. Keep "name", "description", "tags" and "palette" in English, and add to every outfit a "translation" object with its "name" and "description" translated into {{.Language}}, e.g. {"name": "...", "description": "..."}.{{end}}{{if .Avoid}}
+
+The user disliked earlier outfits for these reasons, so no new outfit may have what they disliked:{{range .Avoid}}
+- {{.}}{{end}}{{end}}{{if .Exclude}}
+
+The user has already seen these outfits. Every new description must be clearly different from all of them, in garments, colors and overall style, not a rewording:
+{{range .Exclude}}- {{.}}
+{{end}}{{end}}`
+
+// gradeTemplate asks the model to compare the generated look (first image)
+// with the photo taken at the event (second image).
+const gradeTemplate = `
+The first image is an AI-generated outfit preview for a '{{.EventType}}' at '{{.Venue}}' with the theme '{{.Theme}}', showing this outfit: {{.Style}}.
+The second image is a real photo of the same person or people at the event.
+
+Compare the real outfit with the generated look and grade how closely the person recreated it.
+Focus on the clothing: garments, colors, fabrics, silhouette and accessories. Ignore differences in lighting, background and image quality.
+Be encouraging and playful, like a friendly fashion judge.
+
+Respond with JSON only, in this shape:
+{"score": <integer 0-100>, "summary": "<one or two sentences>", "matches": ["<what they nailed>"], "differences": ["<what differed>"]}
+`
+
+// samePersonTemplate asks whether the photos of a multi-photo upload show
+// the same person.
+const samePersonTemplate = `Do all the provided images show the same person?
+Compare facial features, not clothing, pose, lighting or background.
+Respond with JSON only, in this shape:
+{"samePerson": <true or false>, "reason": "<one short sentence>"}`
+
+// facesTemplate asks for the bounding box of every face, for the upload check.
+const facesTemplate = `Detect every human face in the image.
+Respond with JSON only: an array with one entry per face, in this shape:
+[{"box_2d": [ymin, xmin, ymax, xmax]}]
+with coordinates normalized to 0-1000. Respond with [] if there is no face.`
+
+// moderationTemplate asks which disallowed categories an upload falls into.
+// The category names are the ones gemini.ModerateImage reports.
+const moderationTemplate = `You are a content moderator for a fashion app that restyles the outfits of people in uploaded photos.
+Classify the image against these categories:
+- "sexual": nudity or sexually explicit content
+- "minor_sexualized": a child shown in a sexualized way
+- "violence": gore, graphic injury or weapons aimed at people
+- "hate": hate symbols or hateful gestures
+- "self_harm": depictions of self-harm
+Respond with JSON only, in this shape:
+{"categories": ["<category>"]}
+listing every category that applies, or {"categories": []} if none does.`
+
+// spec is a template together with a sample input whose values are all
+// distinct, so Validate can tell which inputs the rendered prompt uses.
+type spec struct {
+	name   string
+	tmpl   *template.Template
+	sample any
+	// fields are the sample values every rendering must contain.
+	fields []string
+}
+
+// defaults are the built-in templates by name. The prompts config can
+// replace any of them.
+var defaults = map[string]string{
+	"image":       imageTemplate,
+	"suggestions": suggestionsTemplate,
+	"grade":       gradeTemplate,
+	"refine":      refineTemplate,
+	"faces":       facesTemplate,
+	"samePerson":  samePersonTemplate,
+	"moderation":  moderationTemplate,
+}
+
+// Set is a parsed and validated set of templates.
+type Set struct {
+	image, suggestions, grade, refine, faces, samePerson, moderation *template.Template
+}
+
+// current is the set the builders render, swapped by Use.
+var current atomic.Pointer[Set]
+
+func init() {
+	set, err := Load(nil)
+	if err != nil {
+		panic(err)
+	}
+	current.Store(set)
+}
+
+// Load parses the built-in templates with overrides, by name, replacing
+// them, and validates the result like Validate. It fails on unknown names.
+func Load(overrides map[string]string) (*Set, error) {
+	for name := range overrides {
+		if _, ok := defaults[name]; !ok {
+			return nil, fmt.Errorf("unknown prompt %q", name)
+		}
+	}
+	parsed := make(map[string]*template.Template, len(defaults))
+	for name, text := range defaults {
+		if override, ok := overrides[name]; ok {
+			text = override
+		}
+		// Prompts are plain text; missingkey=error also rejects map inputs
+		// lacking a placeholder, should a template ever be given one.
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt %q: %w", name, err)
+		}
+		parsed[name] = tmpl
+	}
+	set := &Set{
+		image:       parsed["image"],
+		suggestions: parsed["suggestions"],
+		grade:       parsed["grade"],
+		refine:      parsed["refine"],
+		faces:       parsed["faces"],
+		samePerson:  parsed["samePerson"],
+		moderation:  parsed["moderation"],
+	}
+	if err := validate(set.specs()); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Use makes set the templates that prompts are built from, e.g. on config
+// reload. Prompts being built keep the set they started with.
+func Use(set *Set) {
+	current.Store(set)
+}
+
+var sampleEvent = Event{EventType: "<eventType>", Venue: "<venue>", Theme: "<theme>"}
+
+var sampleWearer = Wearer{Gender: "<gender>", BodyType: "<bodyType>", Fit: "<fit>", Modesty: "<modesty>"}
+
+func (set *Set) specs() []spec {
+	image, suggestions, grade, refine := set.image, set.suggestions, set.grade, set.refine
+	return []spec{
+		{"image", image, ImageInput{Event: sampleEvent, Style: "<style>"}, []string{"<eventType>", "<venue>", "<theme>", "<style>"}},
+		{"suggestions", suggestions, SuggestionsInput{Event: sampleEvent, Count: 7}, []string{"<eventType>", "<venue>", "<theme>", "7 distinct"}},
+		{"suggestions with tags", suggestions, SuggestionsInput{Event: sampleEvent, Tags: []string{"<tag1>", "<tag2>"}}, []string{"<eventType>", "<tag1>", "<tag2>"}},
+		{"suggestions with coloring", suggestions, SuggestionsInput{Event: sampleEvent, Coloring: Coloring{SkinTone: "<skinTone>", Flattering: []string{"<flattering1>", "<flattering2>"}, PhotoColors: []string{"<photoColor>"}}}, []string{"<eventType>", "<skinTone>", "<flattering1>", "<flattering2>", "<photoColor>"}},
+		{"suggestions with weather", suggestions, SuggestionsInput{Event: sampleEvent, Weather: "<weather>"}, []string{"<eventType>", "<weather>"}},
+		{"suggestions with language", suggestions, SuggestionsInput{Event: sampleEvent, Language: "<language>"}, []string{"<eventType>", "<language>"}},
+		{"suggestions for a wearer", suggestions, SuggestionsInput{Event: sampleEvent, Wearer: sampleWearer}, []string{"<eventType>", "<venue>", "<theme>", "<gender>", "<bodyType>", "<fit>", "<modesty>"}},
+		{"suggestions with dislikes", suggestions, SuggestionsInput{Event: sampleEvent, Avoid: []string{"<avoid1>", "<avoid2>"}}, []string{"<eventType>", "<avoid1>", "<avoid2>"}},
+		{"suggestions with exclusions", suggestions, SuggestionsInput{Event: sampleEvent, Exclude: []string{"<exclude1>", "<exclude2>"}}, []string{"<eventType>", "<exclude1>", "<exclude2>"}},
+		{"grade", grade, GradeInput{Event: sampleEvent, Style: "<style>"}, []string{"<eventType>", "<venue>", "<theme>", "<style>"}},
+		{"image with references", image, ImageInput{Event: sampleEvent, Style: "<style>", References: 2}, []string{"<eventType>", "<venue>", "<theme>", "<style>", "The 2 images"}},
+		{"image with aspect ratio", image, ImageInput{Event: sampleEvent, Style: "<style>", AspectRatio: "<aspectRatio>"}, []string{"<eventType>", "<venue>", "<theme>", "<style>", "<aspectRatio>"}},
+		{"refine", refine, RefineInput{Event: sampleEvent, Style: "<style>", Instruction: "<instruction>"}, []string{"<eventType>", "<venue>", "<theme>", "<style>", "<instruction>"}},
+		{"refine with references", refine, RefineInput{Event: sampleEvent, Style: "<style>", Instruction: "<instruction>", References: 2}, []string{"<instruction>", "The 2 images"}},
+		{"image for a wearer", image, ImageInput{Event: sampleEvent, Style: "<style>", Wearer: sampleWearer}, []string{"<style>", "<gender>", "<bodyType>", "<fit>", "<modesty>"}},
+		{"image keeping the background", image, ImageInput{Event: sampleEvent, Style: "<style>", KeepBackground: true}, []string{"<eventType>", "<venue>", "<theme>", "<style>", "original background"}},
+		{"image with suffix", image, ImageInput{Event: sampleEvent, Style: "<style>", Suffix: "<suffix>"}, []string{"<style>", "<suffix>"}},
+		{"refine with suffix", refine, RefineInput{Event: sampleEvent, Style: "<style>", Instruction: "<instruction>", Suffix: "<suffix>"}, []string{"<instruction>", "<suffix>"}},
+		{"faces", set.faces, nil, nil},
+		{"samePerson", set.samePerson, nil, nil},
+		{"moderation", set.moderation, nil, nil},
+	}
+}
+
+// Validate renders every built-in template with a sample input and checks
+// that it succeeds and uses every input.
+func Validate() error {
+	_, err := Load(nil)
+	return err
+}
+
+func validate(specs []spec) error {
+	for _, s := range specs {
+		out, err := render(s.tmpl, s.sample)
+		if err != nil {
+			return err
+		}
+		for _, field := range s.fields {
+			if !strings.Contains(out, field) {
+				return fmt.Errorf("prompt %q does not use its %s input", s.name, strings.Trim(field, "<>"))
+			}
+		}
+	}
+	return nil
+}
+
+func render(tmpl *template.Template, data any) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %q: %w", tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// Image builds the prompt that restyles the user's photo.
+func Image(in ImageInput) (string, error) {
+	return render(current.Load().image, in)
+}
+
+// Refine builds the prompt that edits a generated look.
+func Refine(in RefineInput) (string, error) {
+	return render(current.Load().refine, in)
+}
+
+// StyleSuggestions builds the prompt that asks for outfit descriptions.
+func StyleSuggestions(in SuggestionsInput) (string, error) {
+	if in.Count == 0 {
+		in.Count = DefaultStyleCount
+	}
+	return render(current.Load().suggestions, in)
+}
+
+// Faces builds the prompt that locates the faces in an uploaded photo.
+func Faces() (string, error) {
+	return render(current.Load().faces, nil)
+}
+
+// SamePerson builds the prompt that checks whether several photos show the
+// same person.
+func SamePerson() (string, error) {
+	return render(current.Load().samePerson, nil)
+}
+
+// Moderation builds the prompt that classifies an uploaded photo for
+// disallowed content.
+func Moderation() (string, error) {
+	return render(current.Load().moderation, nil)
+}
+
+// Grade builds the prompt that compares a generated look with the real
+// event photo.
+func Grade(in GradeInput) (string, error) {
+	return render(current.Load().grade, in)
+}
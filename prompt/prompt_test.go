@@ -0,0 +1,165 @@
+package prompt
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var goldenEvent = Event{EventType: "Wedding", Venue: "Goa, India", Theme: "South style wedding"}
+
+var goldenWearer = Wearer{Gender: "woman", BodyType: "petite", Fit: "relaxed", Modesty: "covering the shoulders and knees"}
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func() (string, error)
+	}{
+		{"image", func() (string, error) {
+			return Image(ImageInput{Event: goldenEvent, Style: "an ivory silk saree with a gold zari border"})
+		}},
+		{"suggestions", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent})
+		}},
+		{"suggestions-tags", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent, Tags: []string{"formal", "traditional"}})
+		}},
+		{"suggestions-coloring", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent, Coloring: Coloring{SkinTone: "warm medium", Flattering: []string{"terracotta", "mustard", "olive green", "teal", "cream"}, PhotoColors: []string{"black", "white"}}})
+		}},
+		{"suggestions-weather", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent, Weather: "24-32°C, humid, rain likely"})
+		}},
+		{"suggestions-language", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent, Language: "Spanish"})
+		}},
+		{"suggestions-wearer", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent, Wearer: goldenWearer})
+		}},
+		{"suggestions-avoid", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent, Avoid: []string{"no floral prints", "too casual"}, Exclude: []string{"a floral maxi dress with sandals"}})
+		}},
+		{"suggestions-count", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent, Count: 8, Wearer: Wearer{Gender: "man"}})
+		}},
+		{"suggestions-exclude", func() (string, error) {
+			return StyleSuggestions(SuggestionsInput{Event: goldenEvent, Exclude: []string{"an ivory silk saree with a gold zari border", "a cream linen kurta with white churidar"}})
+		}},
+		{"grade", func() (string, error) {
+			return Grade(GradeInput{Event: goldenEvent, Style: "an ivory silk saree with a gold zari border"})
+		}},
+		{"image-references", func() (string, error) {
+			return Image(ImageInput{Event: goldenEvent, Style: "an ivory silk saree with a gold zari border", References: 2})
+		}},
+		{"image-aspect-ratio", func() (string, error) {
+			return Image(ImageInput{Event: goldenEvent, Style: "an ivory silk saree with a gold zari border", AspectRatio: "9:16"})
+		}},
+		{"refine", func() (string, error) {
+			return Refine(RefineInput{Event: goldenEvent, Style: "an ivory silk saree with a gold zari border", Instruction: "change the saree to emerald green"})
+		}},
+		{"image-wearer", func() (string, error) {
+			return Image(ImageInput{Event: goldenEvent, Style: "an ivory silk saree with a gold zari border", Wearer: goldenWearer})
+		}},
+		{"image-keep-background", func() (string, error) {
+			return Image(ImageInput{Event: goldenEvent, Style: "an ivory silk saree with a gold zari border", KeepBackground: true, AspectRatio: "9:16"})
+		}},
+		{"image-suffix", func() (string, error) {
+			return Image(ImageInput{Event: goldenEvent, Style: "an ivory silk saree with a gold zari border", Suffix: "Use warm golden-hour lighting."})
+		}},
+		{"faces", Faces},
+		{"same-person", SamePerson},
+		{"moderation", Moderation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.render()
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test ./prompt -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("prompt differs from %s (run go test ./prompt -update if intended)\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		wantErr   string
+	}{
+		{"override", map[string]string{"grade": "Grade a '{{.EventType}}' at '{{.Venue}}' ({{.Theme}}): {{.Style}}."}, ""},
+		{"unknown prompt", map[string]string{"greeting": "Hello."}, `unknown prompt "greeting"`},
+		{"syntax error", map[string]string{"image": "{{if .Style}}"}, `invalid prompt "image"`},
+		{"unused input", map[string]string{"grade": "Grade a '{{.EventType}}' at '{{.Venue}}': {{.Style}}."}, "does not use its theme input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := Load(tt.overrides)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer Use(current.Load())
+			Use(set)
+			got, err := Grade(GradeInput{Event: goldenEvent, Style: "a saree"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "Grade a 'Wedding' at 'Goa, India' (South style wedding): a saree."; got != want {
+				t.Errorf("Grade() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsBrokenTemplates(t *testing.T) {
+	tests := []struct {
+		name, text, wantErr string
+	}{
+		{"unused input", "An event '{{.EventType}}' with the theme '{{.Theme}}'.", "does not use its venue input"},
+		{"unknown placeholder", "An event '{{.EventType}}' at '{{.Location}}' with the theme '{{.Theme}}'.", "can't evaluate field Location"},
+		{"printf placeholder", "An event '%s' at '%s' with the theme '%s'.", "does not use its eventType input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broken := spec{
+				name:   "broken",
+				tmpl:   template.Must(template.New("broken").Parse(tt.text)),
+				sample: sampleEvent,
+				fields: []string{"<eventType>", "<venue>", "<theme>"},
+			}
+			err := validate([]spec{broken})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,446 @@
+// qrcode/qrcode.go
+//
+// Package qrcode encodes short texts, such as share links, as QR codes. It
+// implements the subset of ISO/IEC 18004 the app needs: byte mode at error
+// correction level M, versions 1 to 10, which holds up to 213 bytes, with
+// the mask chosen by the standard's penalty rules.
+package qrcode
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+// MaxLength is the most bytes a code can hold.
+const MaxLength = 213
+
+// quietZone is the light border around a code, in modules, that readers
+// need to find it.
+const quietZone = 4
+
+// ErrTooLong is returned by Encode for texts over MaxLength bytes.
+var ErrTooLong = errors.New("text too long for a QR code")
+
+// version describes the level M error correction of one QR code version:
+// ecLen codewords per block, for blocks1 blocks of data1 data codewords
+// followed by blocks2 blocks of data1+1.
+type version struct {
+	ecLen, blocks1, data1, blocks2 int
+	// align are the alignment pattern center coordinates.
+	align []int
+}
+
+var versions = []version{
+	1:  {10, 1, 16, 0, nil},
+	2:  {16, 1, 28, 0, []int{6, 18}},
+	3:  {26, 1, 44, 0, []int{6, 22}},
+	4:  {18, 2, 32, 0, []int{6, 26}},
+	5:  {24, 2, 43, 0, []int{6, 30}},
+	6:  {16, 4, 27, 0, []int{6, 34}},
+	7:  {18, 4, 31, 0, []int{6, 22, 38}},
+	8:  {22, 2, 38, 2, []int{6, 24, 42}},
+	9:  {22, 3, 36, 2, []int{6, 26, 46}},
+	10: {26, 4, 43, 1, []int{6, 28, 50}},
+}
+
+// dataLen is how many data codewords the version holds.
+func (v version) dataLen() int {
+	return v.blocks1*v.data1 + v.blocks2*(v.data1+1)
+}
+
+// Code is an encoded QR code.
+type Code struct {
+	// Size is the width and height in modules, without the quiet zone.
+	Size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// Dark reports whether the module at column x and row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode encodes text in the smallest version that holds it.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	for n := 1; n < len(versions); n++ {
+		countBits := 8
+		if n >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*versions[n].dataLen() {
+			return encode(n, countBits, data), nil
+		}
+	}
+	return nil, ErrTooLong
+}
+
+func encode(n, countBits int, data []byte) *Code {
+	c := unmasked(n, countBits, data)
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // masks are their own inverse
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c
+}
+
+// unmasked draws the function patterns and data of a version n code, before
+// a mask is applied.
+func unmasked(n, countBits int, data []byte) *Code {
+	v := versions[n]
+	size := 17 + 4*n
+	c := &Code{Size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range size {
+		c.modules[y] = make([]bool, size)
+		c.function[y] = make([]bool, size)
+	}
+	c.drawFunctionPatterns(n, v)
+	c.drawCodewords(interleave(v, dataCodewords(v, countBits, data)))
+	return c
+}
+
+// dataCodewords encodes data in byte mode and pads it to the version's
+// capacity.
+func dataCodewords(v version, countBits int, data []byte) []byte {
+	var bits []bool
+	appendBits := func(value, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, value>>i&1 == 1)
+		}
+	}
+	appendBits(0b0100, 4)
+	appendBits(len(data), countBits)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	capacity := 8 * v.dataLen()
+	appendBits(0, min(4, capacity-len(bits)))
+	appendBits(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		appendBits(pad, 8)
+	}
+
+	out := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// interleave splits data into the version's blocks, adds each block's
+// error correction and interleaves the blocks' codewords.
+func interleave(v version, data []byte) []byte {
+	divisor := rsDivisor(v.ecLen)
+	var blocks, ecc [][]byte
+	for i := range v.blocks1 + v.blocks2 {
+		n := v.data1
+		if i >= v.blocks1 {
+			n++
+		}
+		blocks = append(blocks, data[:n])
+		ecc = append(ecc, rsRemainder(data[:n], divisor))
+		data = data[n:]
+	}
+	var out []byte
+	for i := range v.data1 + 1 {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := range v.ecLen {
+		for _, e := range ecc {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given
+// degree, without its leading term, highest power first.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords of data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) modulo the QR code polynomial 0x11D.
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// set sets a function module, which masks and data leave alone.
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns(n int, v version) {
+	for i := range c.Size {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	for _, p := range [][2]int{{3, 3}, {c.Size - 4, 3}, {3, c.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := p[0]+dx, p[1]+dy
+				if x >= 0 && x < c.Size && y >= 0 && y < c.Size {
+					dist := max(abs(dx), abs(dy))
+					c.set(x, y, dist != 2 && dist != 4)
+				}
+			}
+		}
+	}
+	last := len(v.align) - 1
+	for i, ax := range v.align {
+		for j, ay := range v.align {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue // these overlap the finder patterns
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(ax+dx, ay+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+	// Reserve the format areas; drawFormat fills them in
+	c.drawFormat(0)
+	if n >= 7 {
+		rem := n
+		for range 12 {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := n<<12 | rem
+		for i := range 18 {
+			dark := bits>>i&1 == 1
+			a, b := c.Size-11+i%3, i/3
+			c.set(a, b, dark)
+			c.set(b, a, dark)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information: level M and the
+// mask.
+func (c *Code) drawFormat(mask int) {
+	data := 0b00<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := range 6 {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+	for i := range 8 {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true)
+}
+
+// drawCodewords places the codewords in the standard zigzag, two columns at
+// a time from the bottom right, skipping the function modules.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		for vert := range c.Size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if !c.function[y][x] && i < len(codewords)*8 {
+					c.modules[y][x] = codewords[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !c.function[y][x] {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code is to read with the standard's four
+// rules: runs of one color, 2x2 blocks, finder-like patterns and the
+// balance of dark and light.
+func (c *Code) penalty() int {
+	penalty, dark := 0, 0
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for _, columns := range []bool{false, true} {
+		for a := range c.Size {
+			line := make([]bool, c.Size)
+			for b := range c.Size {
+				if columns {
+					line[b] = c.modules[b][a]
+				} else {
+					line[b] = c.modules[a][b]
+				}
+			}
+			run := 1
+			for b := 1; b <= c.Size; b++ {
+				if b < c.Size && line[b] == line[b-1] {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += 3 + run - 5
+				}
+				run = 1
+			}
+			for b := 0; b+11 <= c.Size; b++ {
+				for _, pattern := range finderLike {
+					if equal(line[b:b+11], pattern) {
+						penalty += 40
+					}
+				}
+			}
+		}
+	}
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				m := c.modules[y][x]
+				if c.modules[y-1][x] == m && c.modules[y][x-1] == m && c.modules[y-1][x-1] == m {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	// 10 points for every 5% the dark share is away from 50%
+	penalty += abs(dark*20-total*10) / total * 10
+	return penalty
+}
+
+// Image renders the code with its quiet zone, each module scale pixels wide.
+func (c *Code) Image(scale int) *image.Gray {
+	width := (c.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.modules[y][x] {
+				continue
+			}
+			for dy := range scale {
+				for dx := range scale {
+					img.SetGray((x+quietZone)*scale+dx, (y+quietZone)*scale+dy, color.Gray{})
+				}
+			}
+		}
+	}
+	return img
+}
+
+// Modules returns how many modules wide the code is with its quiet zone.
+func (c *Code) Modules() int {
+	return c.Size + 2*quietZone
+}
+
+func equal(a, b []bool) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
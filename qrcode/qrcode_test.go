@@ -0,0 +1,114 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The golden files were made with Kazuhiko Arase's QR code generator, an
+// independent implementation, at the mask Encode picks. Each holds one row
+// of modules per line, '#' for dark.
+
+func render(c *Code) string {
+	var b strings.Builder
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.Dark(x, y) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func checkGolden(t *testing.T, name string, c *Code) {
+	t.Helper()
+	want, err := os.ReadFile(filepath.Join("testdata", name+".golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := render(c); got != string(want) {
+		t.Errorf("%s: modules differ from the golden file\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"version-1", "a"},
+		{"version-3", "https://example.com/s/x7Kp2Qa"},
+		{"version-4", "https://dreswap.example/share/FY72YfxpJ6sHY29e1mZGfvGEkLq865cT"},
+		// Versions 7 and up also carry version information
+		{"version-7", "https://api.dreswap.example/api/v1/gallery?q=ivory+silk+saree&eventType=wedding&tag=traditional&page=2&pageSize=50"},
+		{"version-9", "https://api.dreswap.example/api/v1/gallery?q=ivory+silk+saree&eventType=wedding&tag=traditional&tag=gold&page=2&pageSize=50&utm_source=print&utm_medium=qr"},
+		// Version 10 has a 16-bit count and blocks of two lengths
+		{"version-10", "https://q.example/" + strings.Repeat("abcdefghij", 19)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.name, c)
+		})
+	}
+}
+
+func TestMasks(t *testing.T) {
+	for mask := range 8 {
+		c := unmasked(3, 8, []byte("https://example.com/s/x7Kp2Qa"))
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		checkGolden(t, fmt.Sprintf("mask-%d", mask), c)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	// Bytes each version holds at level M
+	capacity := []int{1: 14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+	for n := 1; n < len(capacity); n++ {
+		c, err := Encode(strings.Repeat("a", capacity[n]))
+		if err != nil {
+			t.Fatalf("version %d: %v", n, err)
+		}
+		if want := 17 + 4*n; c.Size != want {
+			t.Errorf("%d bytes: size %d, want %d", capacity[n], c.Size, want)
+		}
+		c, err = Encode(strings.Repeat("a", capacity[n]+1))
+		if n == len(capacity)-1 {
+			if !errors.Is(err, ErrTooLong) {
+				t.Errorf("%d bytes: error %v, want ErrTooLong", capacity[n]+1, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d bytes: %v", capacity[n]+1, err)
+		}
+		if want := 17 + 4*(n+1); c.Size != want {
+			t.Errorf("%d bytes: size %d, want %d", capacity[n]+1, c.Size, want)
+		}
+	}
+	if capacity[len(capacity)-1] != MaxLength {
+		t.Errorf("MaxLength is %d, want %d", MaxLength, capacity[len(capacity)-1])
+	}
+}
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at version 1-M, from the standard's worked example
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction codewords %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+// ready/ready.go
+//
+// Package ready answers readiness probes by checking the server's
+// dependencies, so load balancers stop routing to an instance that cannot
+// serve requests.
+package ready
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of one dependency check.
+type Result struct {
+	Name      string
+	Err       error
+	CheckedAt time.Time
+	Duration  time.Duration
+}
+
+type check struct {
+	name string
+	// ttl is how long a result is reused before the dependency is checked again.
+	ttl time.Duration
+	fn  func(context.Context) error
+
+	// mu serializes checks, so concurrent probes share one call.
+	mu   sync.Mutex
+	last *Result
+}
+
+// Checker runs the registered dependency checks.
+type Checker struct {
+	timeout time.Duration
+	checks  []*check
+}
+
+// NewChecker creates a Checker whose checks each get timeout to complete.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check. Results are cached for ttl, which keeps probes cheap
+// for dependencies that cost money or quota to call; 0 checks on every probe.
+// Checks must be added before the Checker is used.
+func (c *Checker) Add(name string, ttl time.Duration, fn func(context.Context) error) {
+	c.checks = append(c.checks, &check{name: name, ttl: ttl, fn: fn})
+}
+
+// Check runs all checks in parallel and reports whether every one passed,
+// along with the results in registration order.
+func (c *Checker) Check(ctx context.Context) (bool, []Result) {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, chk)
+		}()
+	}
+	wg.Wait()
+
+	ok := true
+	for _, res := range results {
+		ok = ok && res.Err == nil
+	}
+	return ok, results
+}
+
+func (c *Checker) run(ctx context.Context, chk *check) Result {
+	chk.mu.Lock()
+	defer chk.mu.Unlock()
+	if chk.last != nil && time.Since(chk.last.CheckedAt) < chk.ttl {
+		return *chk.last
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := chk.fn(checkCtx)
+	res := Result{Name: chk.name, Err: err, CheckedAt: start, Duration: time.Since(start)}
+	// A probe abandoned by its caller says nothing about the dependency.
+	if ctx.Err() == nil {
+		chk.last = &res
+	}
+	return res
+}
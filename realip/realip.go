@@ -0,0 +1,159 @@
+// realip/realip.go
+package realip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type (
+	contextKey struct{}
+	schemeKey  struct{}
+)
+
+// Resolver determines the real client IP and scheme of a request. Forwarding
+// headers (X-Forwarded-For, X-Real-IP, X-Forwarded-Proto) are honored only
+// when the request arrives from one of the trusted proxy networks; otherwise
+// they are trivially spoofable.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver parses a list of trusted proxy CIDRs or bare IPs. An empty list
+// trusts no proxies, so the TCP peer address is always used.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, entry := range trustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", entry)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			entry = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy CIDR %q: %w", entry, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+// fromTrustedProxy reports whether the TCP peer is a trusted proxy.
+func (r *Resolver) fromTrustedProxy(req *http.Request) bool {
+	peer, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		peer = req.RemoteAddr
+	}
+	ip := net.ParseIP(peer)
+	return ip != nil && r.isTrusted(ip)
+}
+
+func (r *Resolver) isTrusted(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that originated the request.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	peer, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		peer = req.RemoteAddr
+	}
+	peerIP := net.ParseIP(peer)
+	if peerIP == nil || !r.isTrusted(peerIP) {
+		return peer
+	}
+
+	// Walk X-Forwarded-For from the nearest hop outwards; the first address
+	// that is not one of our proxies is the client.
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// A malformed hop means the chain can't be trusted beyond this point.
+			break
+		}
+		if !r.isTrusted(ip) || i == 0 {
+			return ip.String()
+		}
+	}
+
+	if realIP := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP"))); realIP != nil {
+		return realIP.String()
+	}
+	return peer
+}
+
+// Scheme returns the scheme the client used, http or https. Behind a trusted
+// proxy that terminates TLS, it is the first X-Forwarded-Proto value.
+func (r *Resolver) Scheme(req *http.Request) string {
+	if req.TLS != nil {
+		return "https"
+	}
+	if r.fromTrustedProxy(req) {
+		proto, _, _ := strings.Cut(req.Header.Get("X-Forwarded-Proto"), ",")
+		if proto = strings.ToLower(strings.TrimSpace(proto)); proto == "https" {
+			return proto
+		}
+	}
+	return "http"
+}
+
+// Middleware stores the resolved client IP and scheme in the request context.
+func (r *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), contextKey{}, r.ClientIP(req))
+		ctx = context.WithValue(ctx, schemeKey{}, r.Scheme(req))
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// SchemeFromRequest returns the scheme resolved by Middleware, falling back
+// to whether the connection itself used TLS when the middleware is not
+// installed.
+func SchemeFromRequest(req *http.Request) string {
+	if scheme, ok := req.Context().Value(schemeKey{}).(string); ok {
+		return scheme
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// FromRequest returns the client IP resolved by Middleware, falling back to
+// the TCP peer address when the middleware is not installed.
+func FromRequest(req *http.Request) string {
+	if ip, ok := req.Context().Value(contextKey{}).(string); ok {
+		return ip
+	}
+	peer, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return peer
+}
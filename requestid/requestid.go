@@ -0,0 +1,56 @@
+// requestid/requestid.go
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header carries the request ID on requests and responses.
+const Header = "X-Request-ID"
+
+// maxLength bounds client-supplied IDs so they cannot bloat logs and responses.
+const maxLength = 128
+
+type contextKey struct{}
+
+// Middleware assigns every request an ID, echoed in the X-Request-ID response
+// header. An ID sent by the client or an upstream proxy is kept if it is
+// well-formed, so one ID can follow a request across services.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(Header, id)
+		ctx := context.WithValue(r.Context(), contextKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns the ID assigned by Middleware, or "" outside of it.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromRequest returns the request's ID.
+func FromRequest(r *http.Request) string {
+	return FromContext(r.Context())
+}
+
+// valid accepts non-empty IDs of printable ASCII without spaces.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
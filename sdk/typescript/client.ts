@@ -0,0 +1,480 @@
+// sdk/typescript/client.ts
+//
+// Thin TypeScript client for the DreSwap API. Models are generated from the Go
+// structs in models/models.go; run `go generate ./models` after changing them.
+import type {
+  Accessory,
+  AccessoriesRequest,
+  DislikedStyle,
+  GenerateRequest,
+  ImageResponse,
+  IndexedStyle,
+  GalleryItem,
+  GalleryPage,
+  HistoryEntry,
+  HistoryResponse,
+  LookResponse,
+  MoreStylesResponse,
+  PartialResultResponse,
+  PreviewsResponse,
+  PublishLookRequest,
+  RefineRequest,
+  RegenerateStylesRequest,
+  RegenerateStylesResponse,
+  SessionResponse,
+  Style,
+  SwapStyleRequest,
+  CreateShareRequest,
+  CreateShortLinkRequest,
+  ErrorResponse,
+  Favorite,
+  FavoritesResponse,
+  ShareResponse,
+  ShortLinkResponse,
+  TagResponse,
+  ThrottledResponse,
+  UpdateSessionRequest,
+  UsageResponse,
+} from "./models";
+
+export * from "./models";
+
+export interface ClientOptions {
+  baseUrl: string;
+  apiKey?: string;
+  /**
+   * Identifies an anonymous caller's sessions and looks: 32-128 base64url
+   * characters. Generated if omitted; persist `clientToken` to reuse it.
+   */
+  clientToken?: string;
+  /** Maximum retries of throttled requests and failed GETs. Default 3. */
+  maxRetries?: number;
+  /** Longest single wait before retrying, in seconds. Default 60. */
+  maxRetryWaitSeconds?: number;
+}
+
+/**
+ * A non-2xx response. `code` is the machine-readable error code, e.g.
+ * `SESSION_EXPIRED`. `throttled` is set for rate limit, quota, saturation and
+ * maintenance.
+ */
+export class DreSwapError extends Error {
+  constructor(
+    readonly status: number,
+    message: string,
+    readonly body?: ErrorResponse,
+    readonly throttled?: ThrottledResponse,
+  ) {
+    super(message);
+  }
+
+  get code(): string | undefined {
+    return this.body?.code;
+  }
+
+  get requestId(): string | undefined {
+    return this.body?.requestId;
+  }
+}
+
+export interface GeneratedImage {
+  /** Null when the server's text-only fallback answered a failed image call. */
+  image: Blob | null;
+  lookId: string | null;
+  /** The look's session, when the response was JSON. */
+  sessionId?: string;
+  /** The session's style suggestions, returned with the first look by generate. */
+  styles?: Style[];
+  partial?: PartialResultResponse;
+}
+
+/**
+ * Reads a /generate or /swap-style response, which is an image, an image
+ * with its session details as JSON, or a text-only partial result.
+ */
+async function generatedImage(res: Response): Promise<GeneratedImage> {
+  if (res.headers.get("X-Partial-Result")) {
+    const partial: PartialResultResponse = await res.json();
+    return { image: null, lookId: null, sessionId: partial.sessionId, partial };
+  }
+  if (res.headers.get("Content-Type")?.startsWith("application/json")) {
+    const body: ImageResponse | PartialResultResponse = await res.json();
+    // After a whitespace heartbeat, X-Partial-Result arrives as a trailer.
+    if ("failure" in body) return { image: null, lookId: null, sessionId: body.sessionId, partial: body };
+    const bytes = Uint8Array.from(atob(body.image ?? ""), (c) => c.charCodeAt(0));
+    return { image: new Blob([bytes], { type: body.mimeType }), lookId: body.lookId, sessionId: body.sessionId, styles: body.styles };
+  }
+  return { image: await res.blob(), lookId: res.headers.get("X-Look-ID") };
+}
+
+/** Generates a random 256-bit client token, base64url-encoded. */
+function newClientToken(): string {
+  const bytes = crypto.getRandomValues(new Uint8Array(32));
+  return btoa(String.fromCharCode(...bytes)).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+const sleep = (ms: number) => new Promise((resolve) => setTimeout(resolve, ms));
+
+export class DreSwapClient {
+  private readonly baseUrl: string;
+  private readonly maxRetries: number;
+  private readonly maxRetryWaitSeconds: number;
+  readonly clientToken: string;
+
+  constructor(private readonly options: ClientOptions) {
+    this.baseUrl = options.baseUrl.replace(/\/$/, "");
+    this.clientToken = options.clientToken ?? newClientToken();
+    this.maxRetries = options.maxRetries ?? 3;
+    this.maxRetryWaitSeconds = options.maxRetryWaitSeconds ?? 60;
+  }
+
+  /**
+   * Sends a request, retrying throttled requests (rejected before any work was
+   * done) and failed GETs. Generations are never retried after a server error,
+   * since they may already have been charged.
+   */
+  private async request(path: string, init: RequestInit = {}, sessionId?: string): Promise<Response> {
+    const headers = new Headers(init.headers);
+    if (this.options.apiKey) headers.set("X-API-Key", this.options.apiKey);
+    headers.set("X-Client-Token", this.clientToken);
+    if (sessionId) headers.set("X-Session-ID", sessionId);
+    const idempotent = (init.method ?? "GET") === "GET";
+
+    let backoff = 1;
+    for (let attempt = 0; ; attempt++) {
+      let res: Response;
+      try {
+        res = await fetch(this.baseUrl + path, { ...init, headers });
+      } catch (err) {
+        if (!idempotent || attempt >= this.maxRetries) throw err;
+        await sleep(backoff * 1000);
+        backoff *= 2;
+        continue;
+      }
+      // A whitespace heartbeat sends 200 before the handler has answered.
+      const heartbeat = res.ok && res.headers.get("X-Heartbeat") === "whitespace";
+      if (res.ok && !heartbeat) return res;
+
+      const text = await res.text();
+      let body: ErrorResponse | undefined;
+      let throttled: ThrottledResponse | undefined;
+      try {
+        const parsed = JSON.parse(text);
+        if (parsed && typeof parsed.code === "string") body = parsed;
+        if (parsed && typeof parsed.reason === "string") throttled = parsed;
+      } catch {
+        // Plain-text error body, e.g. from a proxy.
+      }
+      if (heartbeat && typeof body?.message !== "string") {
+        return new Response(text, { status: res.status, statusText: res.statusText, headers: res.headers });
+      }
+      // fetch can't read the X-Response-Status trailer, so an error after a
+      // heartbeat is reported as a server error.
+      const error = new DreSwapError(heartbeat ? 500 : res.status, body?.message ?? text.trim(), body, throttled);
+
+      let wait: number | undefined;
+      if (throttled && throttled.retryAfterSeconds <= this.maxRetryWaitSeconds) {
+        wait = throttled.retryAfterSeconds;
+      } else if (!throttled && idempotent && res.status >= 500) {
+        wait = backoff;
+      }
+      if (wait === undefined || attempt >= this.maxRetries) throw error;
+      await sleep(wait * 1000);
+      backoff *= 2;
+    }
+  }
+
+  /**
+   * Uploads a photo and returns the first look plus a session for follow-up calls.
+   * Up to two `references`, more photos of the same person from other angles, improve likeness.
+   */
+  async generate(photo: Blob, filename: string, req: GenerateRequest, references: Blob[] = []): Promise<{ session: Session; look: GeneratedImage }> {
+    const form = new FormData();
+    form.append("data", JSON.stringify(req));
+    form.append("image", photo, filename);
+    references.forEach((ref, i) => form.append("reference", ref, `reference-${i + 1}`));
+    // JSON returns the styles with the look, and survives proxies that mangle binary bodies.
+    const res = await this.request("/api/v1/generate", { method: "POST", headers: { Accept: "application/json" }, body: form });
+    const look = await generatedImage(res);
+    return { session: new Session(this, res.headers.get("X-Session-ID") ?? look.sessionId ?? ""), look };
+  }
+
+  /** Like generate, but the server fetches the photo from `req.imageUrl` instead of an upload. */
+  async generateFromUrl(req: GenerateRequest & { imageUrl: string }): Promise<{ session: Session; look: GeneratedImage }> {
+    const res = await this.request("/api/v1/generate", {
+      method: "POST",
+      headers: { "Content-Type": "application/json", Accept: "application/json" },
+      body: JSON.stringify(req),
+    });
+    const look = await generatedImage(res);
+    return { session: new Session(this, res.headers.get("X-Session-ID") ?? look.sessionId ?? ""), look };
+  }
+
+  /** Returns a handle to an existing session. */
+  resume(sessionId: string): Session {
+    return new Session(this, sessionId);
+  }
+
+  /** Lists the caller's looks, newest first, narrowed to looks with all of `tags`. */
+  async history(...tags: string[]): Promise<LookResponse[]> {
+    const query = new URLSearchParams(tags.map((tag) => ["tag", tag]));
+    return (await this.request(`/api/v1/looks${tags.length ? `?${query}` : ""}`)).json();
+  }
+
+  /** Replaces a look's tags. */
+  async setTags(lookId: string, tags: string[]): Promise<LookResponse> {
+    const res = await this.request(`/api/v1/looks/${encodeURIComponent(lookId)}/tags`, {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ tags }),
+    });
+    return res.json();
+  }
+
+  /** Lists the accessory types `addAccessories` accepts. */
+  async accessories(): Promise<Accessory[]> {
+    return (await this.request("/api/v1/accessories")).json();
+  }
+
+  /** Lists the tags on the caller's looks, most used first. */
+  async tags(): Promise<TagResponse[]> {
+    return (await this.request("/api/v1/tags")).json();
+  }
+
+  /** Lists the caller's sessions, newest first. */
+  async sessions(): Promise<SessionResponse[]> {
+    return (await this.request("/api/v1/sessions")).json();
+  }
+
+  async usage(): Promise<UsageResponse> {
+    return (await this.request("/api/v1/usage")).json();
+  }
+
+  async gallery(page = 1, pageSize = 20): Promise<GalleryPage> {
+    return (await this.request(`/api/v1/gallery?page=${page}&pageSize=${pageSize}`)).json();
+  }
+
+  /** Lists the favorites of all the caller's sessions, most recently added first. */
+  async favorites(): Promise<Favorite[]> {
+    const body: FavoritesResponse = await (await this.request("/api/v1/favorites")).json();
+    return body.favorites;
+  }
+
+  /** Submits a look to the public gallery; it is listed once approved. */
+  async publishLook(req: PublishLookRequest): Promise<GalleryItem> {
+    const res = await this.request("/api/v1/gallery", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(req),
+    });
+    return res.json();
+  }
+
+  async shareLook(lookId: string, req: CreateShareRequest = {}): Promise<ShareResponse> {
+    const res = await this.request(`/api/v1/looks/${encodeURIComponent(lookId)}/share`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(req),
+    });
+    return res.json();
+  }
+
+  /** Renders a share's link as a QR code PNG, about size pixels wide. */
+  async shareQr(token: string, size = 512): Promise<Blob> {
+    return (await this.request(`/api/v1/share/${encodeURIComponent(token)}/qr?size=${size}`)).blob();
+  }
+
+  async createShortLink(req: CreateShortLinkRequest): Promise<ShortLinkResponse> {
+    const res = await this.request("/api/v1/links", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(req),
+    });
+    return res.json();
+  }
+
+  /** @internal */
+  async sessionRequest(sessionId: string, path: string, init?: RequestInit): Promise<Response> {
+    return this.request(path, init, sessionId);
+  }
+}
+
+/** A generation session: the uploaded photo and its suggested styles. */
+export class Session {
+  constructor(
+    private readonly client: DreSwapClient,
+    readonly id: string,
+  ) {}
+
+  /**
+   * Lists the session's styles, optionally only those with all of the tags,
+   * e.g. "formal", and a palette color matching each of the colors, a color
+   * name or a family such as "neutral".
+   */
+  async styles(filter: { tags?: string[]; colors?: string[] } = {}): Promise<Style[]> {
+    const query = new URLSearchParams();
+    filter.tags?.forEach((tag) => query.append("tag", tag));
+    filter.colors?.forEach((color) => query.append("color", color));
+    const path = query.size > 0 ? `/api/v1/styles?${query}` : "/api/v1/styles";
+    return (await this.client.sessionRequest(this.id, path)).json();
+  }
+
+  /**
+   * Asks for more style suggestions, different from the session's current
+   * ones, and returns those added to the session with their indexes.
+   */
+  async moreStyles(): Promise<IndexedStyle[]> {
+    const res = await this.client.sessionRequest(this.id, "/api/v1/styles/more", { method: "POST" });
+    const body: MoreStylesResponse = await res.json();
+    return body.styles;
+  }
+
+  /**
+   * Replaces the styles the user disliked with new suggestions, which
+   * respect the reasons given, and returns the replacements with the indexes
+   * of the styles they replaced.
+   */
+  async regenerateStyles(disliked: DislikedStyle[]): Promise<IndexedStyle[]> {
+    const req: RegenerateStylesRequest = { disliked };
+    const res = await this.client.sessionRequest(this.id, "/api/v1/styles/regenerate", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(req),
+    });
+    const body: RegenerateStylesResponse = await res.json();
+    return body.styles;
+  }
+
+  async swap(styleIndex: number): Promise<GeneratedImage> {
+    return this.swapStyle({ styleIndex });
+  }
+
+  /** Renders the style with the given ID, as listed by `styles()`. */
+  async swapId(styleId: string): Promise<GeneratedImage> {
+    return this.swapStyle({ styleIndex: 0, styleId });
+  }
+
+  /** Renders an outfit the user described, adding it to the session's styles. */
+  async swapText(styleText: string): Promise<GeneratedImage> {
+    return this.swapStyle({ styleIndex: 0, styleText });
+  }
+
+  private async swapStyle(req: SwapStyleRequest): Promise<GeneratedImage> {
+    const res = await this.client.sessionRequest(this.id, "/api/v1/swap-style", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(req),
+    });
+    return generatedImage(res);
+  }
+
+  /**
+   * Edits a look of the session as the instruction says, starting from the
+   * look rather than the original photo. Without lookId, the session's most
+   * recent look is refined.
+   */
+  async refine(instruction: string, lookId?: string): Promise<GeneratedImage> {
+    return this.refineLook({ instruction, lookId });
+  }
+
+  /**
+   * Replaces one component of a look, e.g. "shoes", with the change, e.g.
+   * "white sneakers", keeping the rest of the outfit. Without lookId, the
+   * session's most recent look is used.
+   */
+  async refineComponent(component: string, change: string, lookId?: string): Promise<GeneratedImage> {
+    return this.refineLook({ component, change, lookId });
+  }
+
+  private async refineLook(req: RefineRequest): Promise<GeneratedImage> {
+    const res = await this.client.sessionRequest(this.id, "/api/v1/refine", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(req),
+    });
+    return generatedImage(res);
+  }
+
+  /**
+   * Fetches the session's most recent look again, e.g. after a page refresh
+   * or on another device, without regenerating it.
+   */
+  async result(): Promise<GeneratedImage> {
+    return generatedImage(await this.client.sessionRequest(this.id, "/api/v1/result"));
+  }
+
+  /** Lists the images generated in the session, oldest first. */
+  async history(): Promise<HistoryEntry[]> {
+    const body: HistoryResponse = await (await this.client.sessionRequest(this.id, "/api/v1/history")).json();
+    return body.images;
+  }
+
+  /**
+   * Layers accessories onto a look of the session without regenerating the
+   * outfit: types from `accessories()`, plus text describing more in the
+   * user's words. Without lookId, the session's most recent look is used.
+   */
+  async addAccessories(types: string[], text?: string, lookId?: string): Promise<GeneratedImage> {
+    const req: AccessoriesRequest = { types, text, lookId };
+    const res = await this.client.sessionRequest(this.id, "/api/v1/accessories", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(req),
+    });
+    return generatedImage(res);
+  }
+
+  /** Renames the session or replaces its notes. */
+  async update(req: UpdateSessionRequest): Promise<SessionResponse> {
+    const res = await this.client.sessionRequest(this.id, `/api/v1/sessions/${encodeURIComponent(this.id)}`, {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(req),
+    });
+    return res.json();
+  }
+
+  /**
+   * Replaces the session's style suggestions with its preset's current ones
+   * after an admin updated them (see `presetUpdatedAt` in `sessions()`).
+   * `swap` to one of the returned styles to regenerate.
+   */
+  async refresh(): Promise<Style[]> {
+    const path = `/api/v1/sessions/${encodeURIComponent(this.id)}/refresh`;
+    return (await this.client.sessionRequest(this.id, path, { method: "POST" })).json();
+  }
+
+  /** Lists the styles and looks shortlisted in the session, most recently added first. */
+  async favorites(): Promise<Favorite[]> {
+    return this.favorite("GET", "");
+  }
+
+  /** Shortlists the session's style with the given ID. */
+  async favoriteStyle(styleId: string): Promise<Favorite[]> {
+    return this.favorite("PUT", `/styles/${encodeURIComponent(styleId)}`);
+  }
+
+  async unfavoriteStyle(styleId: string): Promise<Favorite[]> {
+    return this.favorite("DELETE", `/styles/${encodeURIComponent(styleId)}`);
+  }
+
+  /** Shortlists an image generated in the session. */
+  async favoriteLook(lookId: string): Promise<Favorite[]> {
+    return this.favorite("PUT", `/looks/${encodeURIComponent(lookId)}`);
+  }
+
+  async unfavoriteLook(lookId: string): Promise<Favorite[]> {
+    return this.favorite("DELETE", `/looks/${encodeURIComponent(lookId)}`);
+  }
+
+  private async favorite(method: string, suffix: string): Promise<Favorite[]> {
+    const path = `/api/v1/sessions/${encodeURIComponent(this.id)}/favorites${suffix}`;
+    const body: FavoritesResponse = await (await this.client.sessionRequest(this.id, path, { method })).json();
+    return body.favorites;
+  }
+
+  /** Renders a low-resolution preview of every style; pick one and `swap` to it at full quality. */
+  async previews(): Promise<PreviewsResponse> {
+    return (await this.client.sessionRequest(this.id, "/api/v1/previews", { method: "POST" })).json();
+  }
+}
@@ -0,0 +1,872 @@
+// Code generated by tsgen from models.go. DO NOT EDIT.
+
+/** GenerateRequest defines the structure for the JSON data sent from the frontend. */
+export interface GenerateRequest {
+  eventType: string;
+  venue: string;
+  theme: string;
+  /**
+   * EventDate is the day of the event, as YYYY-MM-DD, so suggestions can
+   * suit the weather expected at the venue. Optional.
+   */
+  eventDate?: string;
+  /** Name and Notes optionally label the session, e.g. "Goa wedding - option A". */
+  name?: string;
+  notes?: string;
+  /**
+   * ImageURL is fetched by the server instead of uploading the photo, when
+   * IMAGE_URL_ENABLED is set.
+   */
+  imageUrl?: string;
+  /** UploadID references a finished resumable upload instead of the image part. */
+  uploadId?: string;
+  /**
+   * AspectRatio frames every image of the session as "portrait" (4:5),
+   * "square" (1:1) or "story" (9:16); empty keeps the model's framing.
+   */
+  aspectRatio?: string;
+  /**
+   * MaxDimension caps the longer side of every image of the session, in
+   * pixels; 0 keeps the model's resolution.
+   */
+  maxDimension?: number;
+  /**
+   * KeepBackground only changes the clothing in every image of the session,
+   * keeping the photo's own background, lighting and framing.
+   */
+  keepBackground?: boolean;
+  /**
+   * Language is the BCP 47 tag of the language to write style suggestions
+   * in, e.g. "es" or "pt-BR". It defaults to the request's Accept-Language;
+   * images are always prompted in English.
+   */
+  language?: string;
+  /**
+   * StyleTags narrows the session's suggestions to styles with all of these
+   * tags, e.g. ["formal"]. Unknown tags are rejected.
+   */
+  styleTags?: string[];
+  /**
+   * StyleCount is how many styles to suggest, from 3 to 10. It defaults to
+   * the server's STYLE_COUNT, and also sizes the session's further
+   * suggestions.
+   */
+  styleCount?: number;
+  /**
+   * Gender ("woman", "man" or "non-binary"), BodyType ("petite", "tall",
+   * "slim", "athletic", "curvy" or "plus-size"), FitPreference ("fitted",
+   * "relaxed" or "oversized") and Modesty ("moderate" or "full") describe
+   * the person, so suggestions and images are tailored to them rather than
+   * guessed. All are optional.
+   */
+  gender?: string;
+  bodyType?: string;
+  fitPreference?: string;
+  modesty?: string;
+  /**
+   * Count asks for up to 4 variations of the first style at once; they are
+   * returned as JSON in ImageResponse.Variations.
+   */
+  count?: number;
+  /**
+   * PromptSuffix is appended to the prompt of every image of the session as
+   * extra instructions, for experiments. It needs the "prompt" scope.
+   */
+  promptSuffix?: string;
+}
+
+/** UploadResponse describes a resumable upload. */
+export interface UploadResponse {
+  id: string;
+  length: number;
+  offset: number;
+  expiresAt: string;
+}
+
+/**
+ * Style is an outfit suggestion of a session. Description is what the image
+ * is generated from. Name, Tags (e.g. "formal", "casual", "bohemian") and
+ * Palette (color names) help clients build a picker, and are empty for
+ * styles the user or an admin wrote. ID is derived from the description, so
+ * an outfit has the same ID in every session.
+ */
+export interface Style {
+  id: string;
+  name?: string;
+  description: string;
+  tags?: string[];
+  palette?: string[];
+  /**
+   * Items are the outfit's main garments and accessories as product search
+   * terms, e.g. "white linen shirt".
+   */
+  items?: string[];
+  /** Products are items to buy the look with, when shopping is configured. */
+  products?: Product[];
+  /**
+   * Translation is the name and description in the session's language,
+   * for display, if it isn't English.
+   */
+  translation?: StyleTranslation;
+}
+
+/** Product is a purchasable item matching one of a style's items. */
+export interface Product {
+  /** Item is the style's item the product was found for. */
+  item: string;
+  title: string;
+  url: string;
+  price?: number;
+  currency?: string;
+  retailer?: string;
+  imageUrl?: string;
+}
+
+/** StyleTranslation is a style's name and description in another language. */
+export interface StyleTranslation {
+  language: string;
+  name?: string;
+  description: string;
+}
+
+/**
+ * SwapStyleRequest defines the structure for the JSON data sent for swapping styles.
+ * StyleID, if set, picks the style by ID instead of StyleIndex. StyleText, if
+ * set, is the client's own outfit description, used instead of either and
+ * added to the session's styles.
+ */
+export interface SwapStyleRequest {
+  styleIndex: number;
+  styleId?: string;
+  styleText?: string;
+  /** Count asks for up to 4 variations of the style, as for GenerateRequest. */
+  count?: number;
+}
+
+/**
+ * RefineRequest asks POST /api/v1/refine to edit a look of the session as
+ * Instruction says, e.g. "make it more formal", or to replace only its
+ * Component, e.g. "shoes", with Change, e.g. "white sneakers". LookID
+ * defaults to the session's most recent look.
+ */
+export interface RefineRequest {
+  lookId?: string;
+  instruction?: string;
+  component?: string;
+  change?: string;
+}
+
+/**
+ * IndexedStyle is a style of a session with its index, which /swap-style
+ * takes as styleIndex.
+ */
+export interface IndexedStyle extends Style {
+  index: number;
+}
+
+/**
+ * MoreStylesResponse lists the suggestions POST /api/v1/styles/more added to
+ * the session.
+ */
+export interface MoreStylesResponse {
+  styles: IndexedStyle[];
+}
+
+/**
+ * DislikedStyle names a style of the session the user disliked, by
+ * StyleIndex or StyleID, and optionally why, e.g. "no floral prints".
+ */
+export interface DislikedStyle {
+  styleIndex?: number;
+  styleId?: string;
+  reason?: string;
+}
+
+/**
+ * RegenerateStylesRequest asks POST /api/v1/styles/regenerate to replace
+ * the Disliked styles of the session with new suggestions.
+ */
+export interface RegenerateStylesRequest {
+  disliked: DislikedStyle[];
+}
+
+/**
+ * RegenerateStylesResponse lists the replacements, each with the index of
+ * the style it replaced.
+ */
+export interface RegenerateStylesResponse {
+  styles: IndexedStyle[];
+}
+
+/**
+ * AccessoriesRequest asks POST /api/v1/accessories to add accessories to a
+ * look of the session: Types from GET /api/v1/accessories and Text
+ * describing more in the user's words, e.g. "pearl drop earrings". LookID
+ * defaults to the session's most recent look.
+ */
+export interface AccessoriesRequest {
+  lookId?: string;
+  types?: string[];
+  text?: string;
+}
+
+/** Accessory is an accessory type listed by GET /api/v1/accessories. */
+export interface Accessory {
+  id: string;
+  name: string;
+}
+
+/**
+ * ImageResponse is returned by /generate, /swap-style, /refine and
+ * /accessories in place of the raw image when the request sends Accept:
+ * application/json. Image is the base64-encoded image. With Accept:
+ * multipart/mixed, it is the first part, without Image, followed by the raw
+ * image as the second part.
+ * 
+ * With object storage, ImageURL is a signed URL of the stored image, valid
+ * until ImageURLExpiresAt, and JSON responses carry it instead of Image.
+ * 
+ * When several variations were requested, the response is always JSON, the
+ * images are in Variations, and LookID and MimeType are those of the first.
+ */
+export interface ImageResponse {
+  sessionId: string;
+  lookId: string;
+  styles: Style[];
+  styleIndex: number;
+  mimeType: string;
+  image?: string;
+  imageUrl?: string;
+  imageUrlExpiresAt?: string;
+  variations?: ImageVariation[];
+}
+
+/**
+ * ImageVariation is one rendering of the style of a request for several
+ * variations. Image is base64-encoded, or empty with ImageURL set under the
+ * same rules as ImageResponse.
+ */
+export interface ImageVariation {
+  lookId: string;
+  mimeType: string;
+  image?: string;
+  imageUrl?: string;
+  imageUrlExpiresAt?: string;
+}
+
+/**
+ * PartialResultResponse is returned by /generate and /swap-style in place of
+ * the image, with the X-Partial-Result: text-only header, when the image call
+ * failed and the text-only fallback is enabled. Outfit is the description of
+ * the style at StyleIndex; Failure says why no image was produced. Partial
+ * results do not count towards the daily quota.
+ */
+export interface PartialResultResponse {
+  sessionId: string;
+  styles: Style[];
+  styleIndex: number;
+  outfit: string;
+  failure: ErrorResponse;
+}
+
+/**
+ * SessionResponse describes one of the caller's generation sessions. Active
+ * is false once the session has expired from memory and can no longer be
+ * used for swaps; its looks remain in the history.
+ */
+export interface SessionResponse {
+  id: string;
+  name?: string;
+  notes?: string;
+  eventType: string;
+  venue: string;
+  theme: string;
+  active: boolean;
+  createdAt: string;
+  updatedAt: string;
+  /**
+   * PresetUpdatedAt is set when the preset's style suggestions changed after
+   * the session was created; POST /sessions/{id}/refresh picks them up.
+   */
+  presetUpdatedAt?: string;
+}
+
+/**
+ * UpdateSessionRequest renames a session or replaces its notes. Omitted
+ * fields keep their current values; an empty string clears them.
+ */
+export interface UpdateSessionRequest {
+  name?: string;
+  notes?: string;
+}
+
+/**
+ * Favorite is a style or look the user shortlisted in a session. Kind is
+ * "style" or "look" and ID the style's or look's ID. Description is the
+ * style's, or for a look the style it shows; ImageURL is the look's image.
+ */
+export interface Favorite {
+  kind: string;
+  id: string;
+  sessionId: string;
+  name?: string;
+  description: string;
+  imageUrl?: string;
+  createdAt: string;
+}
+
+/** FavoritesResponse lists favorites, most recently added first. */
+export interface FavoritesResponse {
+  favorites: Favorite[];
+}
+
+/**
+ * StylePreview is a low-resolution render of one of the session's styles.
+ * Image is a base64-encoded JPEG, or empty with Error set if the preview
+ * could not be rendered.
+ */
+export interface StylePreview {
+  styleIndex: number;
+  styleId: string;
+  style: string;
+  mimeType?: string;
+  image?: string;
+  error?: ErrorResponse;
+}
+
+/**
+ * PreviewsResponse is returned by /previews with a preview of every style in
+ * the session, in style order.
+ */
+export interface PreviewsResponse {
+  sessionId: string;
+  previews: StylePreview[];
+}
+
+/** UsageResponse reports the caller's accumulated usage. */
+export interface UsageResponse {
+  generations: number;
+  swaps: number;
+  previews: number;
+  grades: number;
+  suggestions: number;
+  searches: number;
+  estimatedCostUsd: number;
+  /** Daily free-tier quota. DailyLimit is 0 when no cap is configured. */
+  dailyLimit: number;
+  dailyUsed: number;
+  dailyRemaining: number;
+  resetAt: string;
+}
+
+/**
+ * ErrorResponse is the body of every error response. Code is a stable,
+ * machine-readable code such as SESSION_EXPIRED or UPSTREAM_FAILED; Message is
+ * for humans. RequestID matches the X-Request-ID response header.
+ */
+export interface ErrorResponse {
+  code: string;
+  message: string;
+  requestId?: string;
+  details?: Record<string, unknown>;
+}
+
+/**
+ * ThrottledResponse is the body of every response rejected for rate limit,
+ * quota, saturation or maintenance, so clients can back off uniformly.
+ */
+export interface ThrottledResponse extends ErrorResponse {
+  /** Reason is one of rate_limit, quota, saturation or maintenance. */
+  reason: string;
+  retryAfterSeconds: number;
+}
+
+/** QuotaExceededResponse is returned with 429 when a client exhausts its daily free quota. */
+export interface QuotaExceededResponse extends ThrottledResponse {
+  limit: number;
+  resetAt: string;
+}
+
+/**
+ * ReadinessResponse is the body of /readyz. Ready is false, and the status
+ * 503, if any dependency check failed.
+ */
+export interface ReadinessResponse {
+  ready: boolean;
+  checks: ReadinessCheck[];
+}
+
+/**
+ * ReadinessCheck is the result of checking one dependency. CheckedAt may be
+ * in the past for checks whose results are cached. Failure details are only
+ * logged, since the endpoint is public.
+ */
+export interface ReadinessCheck {
+  name: string;
+  ok: boolean;
+  checkedAt: string;
+  durationMs: number;
+}
+
+/**
+ * KeyExchangeResponse is the server half of an end-to-end encryption key
+ * exchange. PublicKey is the base64-encoded X25519 public key.
+ */
+export interface KeyExchangeResponse {
+  keyId: string;
+  publicKey: string;
+  algorithm: string;
+  expiresAt: string;
+}
+
+/** E2EECapability advertises the end-to-end encryption mode. */
+export interface E2EECapability {
+  /** Mode is off, optional or required. */
+  mode: string;
+  algorithm?: string;
+}
+
+/** CapabilitiesResponse lists optional features clients can negotiate. */
+export interface CapabilitiesResponse {
+  e2ee: E2EECapability;
+}
+
+/** CheckoutResponse returns the hosted Stripe Checkout page for upgrading past the free tier. */
+export interface CheckoutResponse {
+  url: string;
+  sessionId: string;
+}
+
+/** CheckoutStatusResponse reports the state of a Stripe Checkout session. */
+export interface CheckoutStatusResponse {
+  status: string;
+  paymentStatus: string;
+  active: boolean;
+}
+
+/** SimilarLooksRequest searches for looks similar to a style description or an existing look. */
+export interface SimilarLooksRequest {
+  styleText?: string;
+  lookId?: string;
+  /** Scope is "mine", "public", or "" for both. */
+  scope?: string;
+  /** Tag, if set, restricts results to looks with this tag. */
+  tag?: string;
+  limit?: number;
+}
+
+/** LookResponse describes a generated look. */
+export interface LookResponse {
+  id: string;
+  eventType: string;
+  venue: string;
+  theme: string;
+  style: string;
+  public: boolean;
+  rating?: number;
+  tags?: string[];
+  createdAt: string;
+  score?: number;
+  /** Grade compares the look with the photo from the actual event, once uploaded. */
+  grade?: RealismGrade;
+  archivedAt?: string;
+  /**
+   * RefinedFrom is the look this one was edited from with Instruction,
+   * through POST /api/v1/refine or /api/v1/accessories.
+   */
+  refinedFrom?: string;
+  instruction?: string;
+}
+
+/**
+ * HistoryEntry is an image generated in a session, as listed by
+ * GET /api/v1/history.
+ */
+export interface HistoryEntry {
+  lookId: string;
+  style: string;
+  /**
+   * StyleIndex is the style's index in the session's styles, or -1 if it
+   * is no longer among them.
+   */
+  styleIndex: number;
+  mimeType: string;
+  /**
+   * ImageURL is where the owner downloads the image, at
+   * GET /api/v1/looks/{id}/image; empty if the image is not stored, as in
+   * end-to-end encrypted sessions.
+   */
+  imageUrl?: string;
+  /** RefinedFrom is the look this one was edited from with Instruction. */
+  refinedFrom?: string;
+  instruction?: string;
+  createdAt: string;
+}
+
+/** HistoryResponse lists the images generated in a session, oldest first. */
+export interface HistoryResponse {
+  sessionId: string;
+  images: HistoryEntry[];
+}
+
+/**
+ * SessionExport is the metadata.json written alongside the images in a
+ * session's ZIP export.
+ */
+export interface SessionExport {
+  sessionId: string;
+  eventType: string;
+  venue: string;
+  theme: string;
+  exportedAt: string;
+  looks: SessionExportLook[];
+}
+
+/**
+ * SessionExportLook is one look in a session export. File is the image's path
+ * inside the archive, empty if the image was not stored.
+ */
+export interface SessionExportLook extends LookResponse {
+  file?: string;
+}
+
+/**
+ * BulkLooksRequest archives, unarchives or deletes looks in bulk, selected
+ * either by ID or by creation date range.
+ */
+export interface BulkLooksRequest {
+  /** Action is archive, unarchive or delete. */
+  action: string;
+  lookIds?: string[];
+  from?: string;
+  to?: string;
+}
+
+/** BulkJobResponse reports the progress of a bulk operation. */
+export interface BulkJobResponse {
+  id: string;
+  action: string;
+  /** Status is running, done or failed. */
+  status: string;
+  total: number;
+  processed: number;
+  failed: number;
+  createdAt: string;
+  finishedAt?: string;
+  /** Error says why a failed job stopped. */
+  error?: string;
+}
+
+/** RealismGrade scores how close the user's real event outfit came to the look. */
+export interface RealismGrade {
+  score: number;
+  summary: string;
+  matches?: string[];
+  differences?: string[];
+  gradedAt: string;
+}
+
+/** CreateShortLinkRequest shortens a share, poll or referral URL. */
+export interface CreateShortLinkRequest {
+  target: string;
+  /** Kind is share, poll or referral; defaults to share. */
+  kind?: string;
+  /** TTLSeconds expires the link after this many seconds; 0 keeps it forever. */
+  ttlSeconds?: number;
+}
+
+/** ShortLinkResponse describes a short link and its redirect statistics. */
+export interface ShortLinkResponse {
+  code: string;
+  shortUrl: string;
+  kind: string;
+  target: string;
+  expiresAt?: string;
+  createdAt: string;
+  hits: number;
+  lastHitAt?: string;
+}
+
+/** CreateShareRequest shares a look's image publicly. */
+export interface CreateShareRequest {
+  /**
+   * TTLSeconds expires the share after this many seconds; 0 uses the
+   * default of seven days.
+   */
+  ttlSeconds?: number;
+}
+
+/** ShareResponse describes a public share of a look's image. */
+export interface ShareResponse {
+  token: string;
+  /** URL serves the image to anyone, at GET /share/{token}. */
+  url: string;
+  /** ShortURL is a /s/{code} link to URL, short enough for SMS and print. */
+  shortUrl?: string;
+  lookId: string;
+  expiresAt: string;
+  createdAt: string;
+  views: number;
+}
+
+/** RateLookRequest rates a look from 1 to 5 stars. */
+export interface RateLookRequest {
+  rating: number;
+}
+
+/** TagsRequest sets or adds tags on a look. */
+export interface TagsRequest {
+  tags: string[];
+}
+
+/** TagResponse is one of the caller's tags and the number of looks carrying it. */
+export interface TagResponse {
+  tag: string;
+  count: number;
+}
+
+/** RenameTagRequest renames a tag on all of the caller's looks. */
+export interface RenameTagRequest {
+  name: string;
+}
+
+/** RetagResponse reports how many looks a tag rename or delete changed. */
+export interface RetagResponse {
+  looks: number;
+}
+
+/** CreateAPIKeyRequest is the admin request to issue a new API key. */
+export interface CreateAPIKeyRequest {
+  name: string;
+  /** Scopes defaults to all scopes when empty. */
+  scopes?: string[];
+}
+
+/** SessionSummary describes an active generation session for operators. */
+export interface SessionSummary {
+  id: string;
+  eventType: string;
+  venue: string;
+  theme: string;
+  styles: number;
+  imageBytes: number;
+}
+
+/**
+ * UpdatePresetRequest replaces the cached style suggestions for a preset.
+ * Without styles, fresh suggestions are fetched from Gemini.
+ */
+export interface UpdatePresetRequest {
+  eventType: string;
+  venue: string;
+  theme: string;
+  styles?: string[];
+}
+
+/**
+ * UpdatePresetResponse reports a preset's new suggestions and how many recent
+ * sessions were flagged for regeneration.
+ */
+export interface UpdatePresetResponse {
+  styles: Style[];
+  sessions: number;
+}
+
+/**
+ * CacheStatsResponse reports the in-memory session cache counters since
+ * startup. Entries and Bytes are current values; the rest only grow.
+ */
+export interface CacheStatsResponse {
+  entries: number;
+  creations: number;
+  hits: number;
+  misses: number;
+  evictions: number;
+  /** Bytes is the size of the uploaded images held by cached sessions. */
+  bytes: number;
+  /** HitRatio is hits / (hits + misses), or 0 before any lookup. */
+  hitRatio: number;
+}
+
+/** FlushCacheResponse reports how many cached entries were dropped. */
+export interface FlushCacheResponse {
+  presets: number;
+}
+
+/** AdminBulkJob is a bulk job as listed to operators, with its owner. */
+export interface AdminBulkJob extends BulkJobResponse {
+  owner: string;
+}
+
+/** RetentionRunResponse reports how many expired records a retention run deleted. */
+export interface RetentionRunResponse {
+  shortLinks: number;
+  incidents: number;
+}
+
+/**
+ * ReloadConfigResponse lists the settings applied by a config reload and
+ * those that changed but need a restart.
+ */
+export interface ReloadConfigResponse {
+  applied: string[];
+  restartRequired: string[];
+}
+
+/**
+ * ConfigVersionResponse describes a recorded runtime configuration. Settings
+ * is only included when a single version is requested.
+ */
+export interface ConfigVersionResponse {
+  version: number;
+  createdAt: string;
+  /** Source is what applied it: startup, reload, preset or rollback. */
+  source: string;
+  rolledBackFrom?: number;
+  settings?: unknown;
+}
+
+/** ConfigChange is one setting that differs between two config versions. */
+export interface ConfigChange {
+  path: string;
+  from: unknown;
+  to: unknown;
+}
+
+/**
+ * ConfigDiffResponse lists the changes from one config version to another.
+ * From is 0 when the version is compared with the current settings.
+ */
+export interface ConfigDiffResponse {
+  from: number;
+  to: number;
+  changes: ConfigChange[];
+}
+
+/**
+ * RollbackConfigResponse reports the version a rollback recorded and the
+ * settings it changed.
+ */
+export interface RollbackConfigResponse {
+  version: number;
+  rolledBackFrom: number;
+  applied: string[];
+}
+
+/**
+ * MaintenanceRequest switches maintenance mode. Message and RetryAfterSeconds
+ * keep their current values when omitted.
+ */
+export interface MaintenanceRequest {
+  enabled: boolean;
+  message?: string;
+  retryAfterSeconds?: number;
+}
+
+/** MaintenanceResponse reports the maintenance mode state. */
+export interface MaintenanceResponse {
+  enabled: boolean;
+  message: string;
+  retryAfterSeconds: number;
+  since: string;
+}
+
+/** LogLevelRequest changes the server's minimum log level. */
+export interface LogLevelRequest {
+  /** Level is debug, info, warn or error. */
+  level: string;
+}
+
+/** LogLevelResponse reports the server's minimum log level. */
+export interface LogLevelResponse {
+  level: string;
+}
+
+/** SetDomainRequest is the admin request to assign a tenant's custom domain. */
+export interface SetDomainRequest {
+  domain: string;
+}
+
+/** SetOriginsRequest is the admin request to bind an API key to browser origins. */
+export interface SetOriginsRequest {
+  origins: string[];
+}
+
+/**
+ * APIKeyResponse describes an API key. Key holds the secret and is only
+ * returned when the key is created or rotated.
+ */
+export interface APIKeyResponse {
+  id: string;
+  name: string;
+  prefix: string;
+  scopes: string[];
+  domain?: string;
+  allowedOrigins?: string[];
+  /** HasSigningSecret is set once a signing secret has been issued. */
+  hasSigningSecret?: boolean;
+  createdAt: string;
+  rotatedAt?: string;
+  revokedAt?: string;
+  /** Suspension is set while the key is suspended. */
+  suspension?: APIKeySuspension;
+  key?: string;
+  /** SigningSecret is only returned when it is issued. */
+  signingSecret?: string;
+}
+
+/**
+ * APIKeySuspension says why a key is suspended and, if timed, when it is
+ * re-enabled automatically.
+ */
+export interface APIKeySuspension {
+  reason: string;
+  suspendedAt: string;
+  until?: string;
+}
+
+/**
+ * SuspendAPIKeyRequest suspends a key. Without until, the key stays
+ * suspended until it is resumed.
+ */
+export interface SuspendAPIKeyRequest {
+  reason: string;
+  until?: string;
+}
+
+/** PublishLookRequest opts a look into the public gallery. */
+export interface PublishLookRequest {
+  lookId: string;
+  /** DisplayName is credited on the gallery entry when ShowAttribution is true. */
+  displayName?: string;
+  showAttribution: boolean;
+}
+
+/** ModerateLookRequest is an admin moderation action: approve, reject, feature or unfeature. */
+export interface ModerateLookRequest {
+  action: string;
+  note?: string;
+}
+
+/** GalleryItem is a look as shown in the gallery. */
+export interface GalleryItem {
+  id: string;
+  eventType: string;
+  venue: string;
+  theme: string;
+  style: string;
+  tags?: string[];
+  imageUrl: string;
+  status: string;
+  featured: boolean;
+  attribution?: string;
+  submittedAt: string;
+}
+
+/** GalleryPage is one page of gallery results. */
+export interface GalleryPage {
+  items: GalleryItem[];
+  page: number;
+  pageSize: number;
+  total: number;
+}
@@ -0,0 +1,26 @@
+// server/loglevel.go
+package server
+
+import "log/slog"
+
+// LogLevel returns the current minimum log level.
+func (s *Server) LogLevel() slog.Level {
+	if s.logLevel == nil {
+		return slog.LevelInfo
+	}
+	return s.logLevel.Level()
+}
+
+// SetLogLevel changes the minimum level of Logger. It is a no-op unless
+// Logger was built on the LevelVar given to UseLogLevel.
+func (s *Server) SetLogLevel(level slog.Level) {
+	if s.logLevel != nil {
+		s.logLevel.Set(level)
+	}
+}
+
+// UseLogLevel attaches the LevelVar that controls Logger's handler, so the
+// level can be changed at runtime.
+func (s *Server) UseLogLevel(level *slog.LevelVar) {
+	s.logLevel = level
+}
@@ -0,0 +1,44 @@
+// server/maintenance.go
+package server
+
+import (
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/config"
+)
+
+// Maintenance is the current maintenance mode state.
+type Maintenance struct {
+	Enabled    bool
+	Message    string
+	RetryAfter time.Duration
+	// Since is when maintenance mode was last switched on or off.
+	Since time.Time
+}
+
+// Maintenance returns the current maintenance mode state.
+func (s *Server) Maintenance() Maintenance {
+	return *s.maintenance.Load()
+}
+
+// SetMaintenance switches maintenance mode on or off. An empty message or zero
+// retryAfter keeps the current value.
+func (s *Server) SetMaintenance(enabled bool, message string, retryAfter time.Duration) Maintenance {
+	cur := s.Maintenance()
+	next := Maintenance{Enabled: enabled, Message: cur.Message, RetryAfter: cur.RetryAfter, Since: cur.Since}
+	if message != "" {
+		next.Message = message
+	}
+	if retryAfter > 0 {
+		next.RetryAfter = retryAfter
+	}
+	if enabled != cur.Enabled {
+		next.Since = time.Now().UTC()
+	}
+	s.maintenance.Store(&next)
+	return next
+}
+
+func newMaintenance(cfg config.MaintenanceConfig) *Maintenance {
+	return &Maintenance{Enabled: cfg.Enabled, Message: cfg.Message, RetryAfter: cfg.RetryAfter, Since: time.Now().UTC()}
+}
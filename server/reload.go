@@ -0,0 +1,250 @@
+// server/reload.go
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/sanjayshr/event-outfitter-backend/config"
+	"github.com/sanjayshr/event-outfitter-backend/configversions"
+	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/presets"
+	"github.com/sanjayshr/event-outfitter-backend/prompt"
+)
+
+// Config returns the configuration in effect. Reloads replace it rather than
+// change it, so a request should read it once and keep the pointer.
+func (s *Server) Config() *config.Config {
+	return s.config.Load()
+}
+
+// CORS returns the current CORS policy, which can change on config reload.
+func (s *Server) CORS() config.CORSConfig {
+	return *s.cors.Load()
+}
+
+// restartSettings are the parts of the configuration that are only read at
+// startup, to build servers, clients and middleware, so a reload can't
+// change them.
+var restartSettings = []struct {
+	name  string
+	field func(*config.Config) any
+}{
+	{"server", func(c *config.Config) any { return &c.Server }},
+	{"headers", func(c *config.Config) any { return &c.Headers }},
+	{"gemini.apiKey", func(c *config.Config) any { return &c.Gemini.APIKey }},
+	{"store", func(c *config.Config) any { return &c.Store }},
+	{"security", func(c *config.Config) any { return &c.Security }},
+	{"billing", func(c *config.Config) any { return &c.Billing }},
+	{"alerts", func(c *config.Config) any { return &c.Alerts }},
+	{"presets", func(c *config.Config) any { return &c.Presets }},
+	{"tls", func(c *config.Config) any { return &c.TLS }},
+	{"hooks", func(c *config.Config) any { return &c.Hooks }},
+	{"tracing", func(c *config.Config) any { return &c.Tracing }},
+	{"preprocess.heicConverter", func(c *config.Config) any { return &c.Preprocess.HEICConverter }},
+	{"preprocess.heicTimeout", func(c *config.Config) any { return &c.Preprocess.HEICTimeout }},
+	{"preprocess.maxConversions", func(c *config.Config) any { return &c.Preprocess.MaxConversions }},
+	{"avif", func(c *config.Config) any { return &c.AVIF }},
+	{"output.webpEncoder", func(c *config.Config) any { return &c.Output.WebPEncoder }},
+	{"output.webpTimeout", func(c *config.Config) any { return &c.Output.WebPTimeout }},
+	{"c2pa", func(c *config.Config) any { return &c.C2PA }},
+	{"imageUrl", func(c *config.Config) any { return &c.ImageURL }},
+	{"weather", func(c *config.Config) any { return &c.Weather }},
+	{"shopping", func(c *config.Config) any { return &c.Shopping }},
+}
+
+// ReloadConfig reloads the configuration and swaps it in, including the
+// prompt templates; the config sections that changed are returned in applied.
+// Settings that need more than a new value, such as the free-tier limit,
+// Gemini model names, the CORS policy, maintenance mode and the log level,
+// are applied to the services that hold them. In-memory sessions are kept.
+// Settings in restartSettings, such as the listen address, storage and
+// secrets, keep their current value until a restart; the names of any that
+// changed are returned in restartRequired. If anything was applied, the
+// result is recorded as a new config version.
+func (s *Server) ReloadConfig(ctx context.Context) (applied, restartRequired []string, err error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reload configuration: %w", err)
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	old := s.Config()
+
+	for _, setting := range restartSettings {
+		before := reflect.ValueOf(setting.field(old)).Elem()
+		after := reflect.ValueOf(setting.field(cfg)).Elem()
+		if !reflect.DeepEqual(before.Interface(), after.Interface()) {
+			restartRequired = append(restartRequired, setting.name)
+			after.Set(before)
+		}
+	}
+	slices.Sort(restartRequired)
+
+	next := configversions.FromConfig(*cfg)
+	next.Presets = maps.Clone(s.presetPushes)
+	s.applySettings(*cfg, next)
+	applied = changedSections(old, s.Config())
+
+	if len(applied) > 0 {
+		s.recordVersion(ctx, configversions.SourceReload, 0)
+	}
+	return applied, restartRequired, nil
+}
+
+// Settings returns the runtime settings currently applied.
+func (s *Server) Settings() configversions.Settings {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	return s.settings()
+}
+
+func (s *Server) settings() configversions.Settings {
+	settings := configversions.FromConfig(*s.Config())
+	settings.Presets = maps.Clone(s.presetPushes)
+	return settings
+}
+
+// RecordStartupVersion records the configuration the process started with,
+// unless it matches the latest version.
+func (s *Server) RecordStartupVersion(ctx context.Context) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.recordVersion(ctx, configversions.SourceStartup, 0)
+}
+
+// PushPreset stores admin-chosen style suggestions for a preset and records
+// their descriptions as a new config version.
+func (s *Server) PushPreset(ctx context.Context, p presets.Preset, styles []models.Style) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.Presets.Pin(p, styles)
+	s.setPresetPush(p, models.Descriptions(styles))
+	s.recordVersion(ctx, configversions.SourcePreset, 0)
+}
+
+// setPresetPush remembers the styles pushed for p, replacing any earlier
+// push for a different spelling of the same preset. Nil styles forget it.
+func (s *Server) setPresetPush(p presets.Preset, styles []string) {
+	for k := range s.presetPushes {
+		if q := presets.ParsePresets([]string{k}); len(q) == 1 && q[0].Equal(p) {
+			delete(s.presetPushes, k)
+		}
+	}
+	if styles != nil {
+		s.presetPushes[p.EventType+"|"+p.Venue+"|"+p.Theme] = append([]string(nil), styles...)
+	}
+}
+
+// RollbackConfig applies the settings of config version n and records them
+// as a new version. It returns the new version and the settings that changed.
+func (s *Server) RollbackConfig(ctx context.Context, n int) (*configversions.Version, []string, error) {
+	target, err := s.Versions.Get(ctx, n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	applied := s.applySettings(*s.Config(), target.Settings)
+	v, _, err := s.Versions.Record(ctx, configversions.SourceRollback, n, s.settings())
+	if err != nil {
+		return nil, applied, err
+	}
+	return v, applied, nil
+}
+
+// recordVersion stores the current settings as a config version. Failures
+// are logged; the settings are already in effect.
+func (s *Server) recordVersion(ctx context.Context, source string, rolledBackFrom int) {
+	v, created, err := s.Versions.Record(ctx, source, rolledBackFrom, s.settings())
+	if err != nil {
+		s.Logger.Error("Failed to record config version", "source", source, "error", err)
+		return
+	}
+	if created {
+		s.Logger.Info("Recorded config version", "version", v.Number, "source", source)
+	}
+}
+
+// applySettings makes cfg with next applied the configuration in effect,
+// applies the runtime settings that differ from the current ones to the
+// services holding them, and returns their names. The caller holds reloadMu.
+func (s *Server) applySettings(cfg config.Config, next configversions.Settings) (applied []string) {
+	cur := s.settings()
+	next.ApplyTo(&cfg)
+
+	if next.FreeDailyLimit != cur.FreeDailyLimit {
+		s.Usage.SetDailyLimit(next.FreeDailyLimit)
+		applied = append(applied, "usage.freeDailyLimit")
+	}
+	if next.ImageModel != cur.ImageModel || next.TextModel != cur.TextModel || next.EmbeddingModel != cur.EmbeddingModel {
+		s.Gemini.SetModels(cfg.Gemini)
+		applied = append(applied, "gemini models")
+	}
+	if !reflect.DeepEqual(next.CORS, cur.CORS) {
+		cors := cfg.CORS
+		s.cors.Store(&cors)
+		applied = append(applied, "cors")
+	}
+	if next.Maintenance != cur.Maintenance {
+		s.SetMaintenance(cfg.Maintenance.Enabled, cfg.Maintenance.Message, cfg.Maintenance.RetryAfter)
+		applied = append(applied, "maintenance")
+	}
+	if next.LogLevel != cur.LogLevel {
+		s.SetLogLevel(cfg.Log.SlogLevel())
+		applied = append(applied, "log.level")
+	}
+	if !maps.Equal(next.Prompts, cur.Prompts) {
+		// Reloaded prompts were checked with the config, but a rolled back
+		// version may predate a change to the templates' inputs.
+		if set, err := prompt.Load(next.Prompts); err != nil {
+			s.Logger.Error("Keeping current prompts", "error", err)
+			cfg.Prompts = maps.Clone(cur.Prompts)
+		} else {
+			prompt.Use(set)
+			applied = append(applied, "prompts")
+		}
+	}
+
+	if !reflect.DeepEqual(next.Presets, cur.Presets) {
+		// Presets pushed since are dropped so they are fetched afresh.
+		for k := range cur.Presets {
+			if _, ok := next.Presets[k]; !ok {
+				for _, p := range presets.ParsePresets([]string{k}) {
+					s.Presets.Delete(p)
+					s.setPresetPush(p, nil)
+				}
+			}
+		}
+		for k, styles := range next.Presets {
+			for _, p := range presets.ParsePresets([]string{k}) {
+				s.Presets.Pin(p, models.NewStyles(styles))
+				s.setPresetPush(p, styles)
+			}
+		}
+		applied = append(applied, "presets")
+	}
+
+	// Remember what was applied so the next reload diffs against it.
+	s.config.Store(&cfg)
+	return applied
+}
+
+// changedSections returns the YAML names of the top-level config sections
+// that differ between a and b.
+func changedSections(a, b *config.Config) []string {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	var changed []string
+	for i := range va.NumField() {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("yaml"), ",")
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -4,33 +4,178 @@ package server
 import (
 	"log/slog"
 	"sync"
+	"sync/atomic"
 
+	"github.com/sanjayshr/event-outfitter-backend/activity"
+	"github.com/sanjayshr/event-outfitter-backend/apikeys"
+	"github.com/sanjayshr/event-outfitter-backend/auth"
+	"github.com/sanjayshr/event-outfitter-backend/billing"
+	"github.com/sanjayshr/event-outfitter-backend/captcha"
+	"github.com/sanjayshr/event-outfitter-backend/config"
+	"github.com/sanjayshr/event-outfitter-backend/configversions"
+	"github.com/sanjayshr/event-outfitter-backend/e2ee"
+	"github.com/sanjayshr/event-outfitter-backend/gemini"
+	"github.com/sanjayshr/event-outfitter-backend/hooks"
+	"github.com/sanjayshr/event-outfitter-backend/imageconv"
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+	"github.com/sanjayshr/event-outfitter-backend/metrics"
 	"github.com/sanjayshr/event-outfitter-backend/models"
+	"github.com/sanjayshr/event-outfitter-backend/objectstore"
+	"github.com/sanjayshr/event-outfitter-backend/palette"
+	"github.com/sanjayshr/event-outfitter-backend/presets"
+	"github.com/sanjayshr/event-outfitter-backend/provenance"
+	"github.com/sanjayshr/event-outfitter-backend/ready"
+	"github.com/sanjayshr/event-outfitter-backend/sessions"
+	"github.com/sanjayshr/event-outfitter-backend/shares"
+	"github.com/sanjayshr/event-outfitter-backend/shopping"
+	"github.com/sanjayshr/event-outfitter-backend/shortlinks"
+	"github.com/sanjayshr/event-outfitter-backend/status"
+	"github.com/sanjayshr/event-outfitter-backend/store"
+	"github.com/sanjayshr/event-outfitter-backend/trends"
+	"github.com/sanjayshr/event-outfitter-backend/uploads"
+	"github.com/sanjayshr/event-outfitter-backend/urlfetch"
+	"github.com/sanjayshr/event-outfitter-backend/usage"
+	"github.com/sanjayshr/event-outfitter-backend/weather"
 )
 
 // SessionData holds all relevant data for a user's style generation session.
 type SessionData struct {
-	Styles      []string
+	Styles      []models.Style
 	ImageData   []byte
 	MimeType    string
 	RequestData models.GenerateRequest // Original request data
+	// References are extra photos of the person from other angles, sent with
+	// ImageData on every generation. They are never encrypted.
+	References []gemini.Reference
+	// E2EEKeyID is set when ImageData is encrypted with the session's key.
+	E2EEKeyID string
+	// Coloring is the palette analysis of the photo, if enabled, which
+	// further suggestions for the session reuse.
+	Coloring palette.Analysis
+	// Avoid are the user's reasons for disliking suggestions, which further
+	// suggestions for the session respect.
+	Avoid []string
+	// Owner owns the session's looks; see handler.ownerKey.
+	Owner string
 }
 
 // Server holds dependencies for our application, like the logger and session cache.
 type Server struct {
 	Logger *slog.Logger
+	// Gemini is the shared client for all model calls.
+	Gemini *gemini.Client
+
+	// Store persists state that must outlive a process, such as usage counters.
+	Store store.Store
+	// APIKeys manages client API keys.
+	APIKeys *apikeys.Manager
+
+	// Usage tracks generations, swaps and estimated cost per client.
+	Usage *usage.Meter
+	// Status tracks dependency health and incidents for the public status page.
+	Status *status.Tracker
+	// Looks stores generated looks and indexes their style embeddings for similarity search.
+	Looks *looks.Repository
+	// Trends aggregates weekly generation and rating statistics.
+	Trends *trends.Aggregator
+	// Presets caches style suggestions for popular event/venue/theme combinations.
+	Presets *presets.Cache
+	// Billing reports paid usage beyond the free tier to Stripe. Disabled when unconfigured.
+	Billing *billing.Stripe
+	// Captcha verifies bot-protection tokens on /generate. Nil or unconfigured disables it.
+	Captcha *captcha.Verifier
+	// E2EE holds the per-session keys for end-to-end encrypted uploads.
+	E2EE *e2ee.Manager
+	// Hooks runs deployment-specific image processing around each generation. Nil disables it.
+	Hooks *hooks.Pipeline
+	// Objects holds generated images in a bucket and signs their download
+	// URLs; nil if images stay in Store.
+	Objects *objectstore.Bucket
+	// Links serves /s/{code} short links for share, poll and referral URLs.
+	Links *shortlinks.Service
+	// Shares serves /share/{token} links to single looks' images.
+	Shares *shares.Service
+	// Uploads holds resumable photo uploads until /generate uses them.
+	Uploads *uploads.Manager
+	// ImageURLs fetches photos from a generate request's imageUrl; nil if disabled.
+	ImageURLs *urlfetch.Fetcher
+	// Weather looks up the conditions at a venue on an event's date; nil if
+	// disabled.
+	Weather *weather.Client
+	// Shopping finds products for the items of suggested styles; nil if not
+	// configured.
+	Shopping *shopping.Client
+	// HEIC converts HEIC uploads to JPEG; nil if no converter is configured.
+	HEIC *imageconv.Converter
+	// AVIFDecoder converts AVIF uploads to JPEG and AVIFEncoder encodes results
+	// as AVIF for clients that accept it; each is nil if not configured.
+	AVIFDecoder *imageconv.Converter
+	AVIFEncoder *imageconv.Converter
+	// WebPEncoder encodes results as WebP for clients that ask for it; nil if
+	// not configured.
+	WebPEncoder *imageconv.Converter
+	// Provenance attaches C2PA content credentials to generated images; nil
+	// if not configured.
+	Provenance *provenance.Signer
+	// Sessions persists session names and notes, which outlive the in-memory session.
+	Sessions *sessions.Service
+	// Auth authenticates client requests with the configured methods.
+	Auth *auth.Chain
+	// Stages aggregates generation pipeline stage timings for /metrics.
+	Stages *metrics.Stages
+	// Versions records each applied runtime configuration for diff and rollback.
+	Versions *configversions.History
+	// Activity keeps the rolling live traffic view for the ops dashboard.
+	Activity *activity.Monitor
+	// Ready checks dependencies for /readyz.
+	Ready *ready.Checker
 
 	// sessionCache stores all session data for active sessions.
 	// Key: sessionID (string), Value: SessionData
-	SessionCache map[string]SessionData
-	CacheMutex   sync.Mutex
+	// Handlers go through CacheSession, CachedSession and EvictSession, which
+	// keep the counters reported at /admin/cache/stats.
+	SessionCache  map[string]SessionData
+	CacheMutex    sync.Mutex
+	cacheCounters cacheCounters
+	// cacheEntries mirrors len(SessionCache) so stats can be read without the lock.
+	cacheEntries atomic.Int64
+
+	// config is the configuration in effect, replaced as a whole on reload;
+	// cors is its CORS policy, read on every request.
+	config      atomic.Pointer[config.Config]
+	cors        atomic.Pointer[config.CORSConfig]
+	maintenance atomic.Pointer[Maintenance]
+	logLevel    *slog.LevelVar
+	reloadMu    sync.Mutex
+	// presetPushes are the style suggestions admins pushed, by
+	// "eventType|venue|theme", as captured by config versions.
+	presetPushes map[string][]string
 }
 
 // NewServer creates and initializes a new Server instance.
-func NewServer(logger *slog.Logger) *Server {
-	return &Server{
+func NewServer(cfg *config.Config, logger *slog.Logger, st store.Store, geminiClient *gemini.Client) *Server {
+	s := &Server{
 		Logger:       logger,
+		Gemini:       geminiClient,
+		Store:        st,
+		APIKeys:      apikeys.NewManager(st),
+		Usage:        usage.NewMeter(logger, st, cfg.Usage.FreeDailyLimit),
+		Status:       status.NewTracker(logger, st),
+		Links:        shortlinks.NewService(logger, st),
+		Shares:       shares.NewService(logger, st),
+		Sessions:     sessions.NewService(st),
+		E2EE:         e2ee.NewManager(cfg.Security.E2EEKeyTTL),
+		Stages:       metrics.NewStages(),
+		Versions:     configversions.NewHistory(st),
+		Uploads:      uploads.NewManager(cfg.Server.MaxUploadBytes, cfg.Server.UploadMemoryBytes, cfg.Server.UploadTTL),
+		Activity:     activity.NewMonitor(),
 		SessionCache: make(map[string]SessionData),
+		presetPushes: make(map[string][]string),
 	}
+	s.config.Store(cfg)
+	cors := cfg.CORS
+	s.cors.Store(&cors)
+	s.maintenance.Store(newMaintenance(cfg.Maintenance))
+	s.publishCacheVars()
+	return s
 }
-
@@ -0,0 +1,104 @@
+// server/sessioncache.go
+package server
+
+import "expvar"
+
+// cacheVars publishes the session cache counters at /debug/vars as
+// "sessionCache". expvar names are process-wide, so the last Server created
+// owns them.
+var cacheVars = expvar.NewMap("sessionCache")
+
+// cacheCounters counts session cache activity since startup.
+type cacheCounters struct {
+	creations expvar.Int
+	hits      expvar.Int
+	misses    expvar.Int
+	evictions expvar.Int
+	// bytes is the size of the uploaded images held by cached sessions.
+	bytes expvar.Int
+}
+
+// CacheStats is a snapshot of the session cache counters.
+type CacheStats struct {
+	Entries   int64
+	Creations int64
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Bytes     int64
+}
+
+// size is the size of the photos the session holds.
+func (d SessionData) size() int64 {
+	n := len(d.ImageData)
+	for _, ref := range d.References {
+		n += len(ref.Data)
+	}
+	return int64(n)
+}
+
+// publishCacheVars points the expvar map at s's counters.
+func (s *Server) publishCacheVars() {
+	cacheVars.Set("creations", &s.cacheCounters.creations)
+	cacheVars.Set("hits", &s.cacheCounters.hits)
+	cacheVars.Set("misses", &s.cacheCounters.misses)
+	cacheVars.Set("evictions", &s.cacheCounters.evictions)
+	cacheVars.Set("bytes", &s.cacheCounters.bytes)
+	cacheVars.Set("entries", expvar.Func(func() any { return s.cacheEntries.Load() }))
+}
+
+// CacheSession stores a new session, or replaces the session with the same ID.
+func (s *Server) CacheSession(id string, data SessionData) {
+	s.CacheMutex.Lock()
+	defer s.CacheMutex.Unlock()
+	if old, ok := s.SessionCache[id]; ok {
+		s.cacheCounters.bytes.Add(-old.size())
+	} else {
+		s.cacheCounters.creations.Add(1)
+	}
+	s.SessionCache[id] = data
+	s.cacheCounters.bytes.Add(data.size())
+	s.cacheEntries.Store(int64(len(s.SessionCache)))
+}
+
+// CachedSession looks up a session for a client request, counting a hit or
+// a miss. Operator views read SessionCache directly so they don't skew the
+// counters.
+func (s *Server) CachedSession(id string) (SessionData, bool) {
+	s.CacheMutex.Lock()
+	data, ok := s.SessionCache[id]
+	s.CacheMutex.Unlock()
+	if ok {
+		s.cacheCounters.hits.Add(1)
+	} else {
+		s.cacheCounters.misses.Add(1)
+	}
+	return data, ok
+}
+
+// EvictSession drops a session, returning it if it was cached.
+func (s *Server) EvictSession(id string) (SessionData, bool) {
+	s.CacheMutex.Lock()
+	defer s.CacheMutex.Unlock()
+	data, ok := s.SessionCache[id]
+	if !ok {
+		return SessionData{}, false
+	}
+	delete(s.SessionCache, id)
+	s.cacheCounters.evictions.Add(1)
+	s.cacheCounters.bytes.Add(-data.size())
+	s.cacheEntries.Store(int64(len(s.SessionCache)))
+	return data, true
+}
+
+// CacheStats returns the session cache counters.
+func (s *Server) CacheStats() CacheStats {
+	return CacheStats{
+		Entries:   s.cacheEntries.Load(),
+		Creations: s.cacheCounters.creations.Value(),
+		Hits:      s.cacheCounters.hits.Value(),
+		Misses:    s.cacheCounters.misses.Value(),
+		Evictions: s.cacheCounters.evictions.Value(),
+		Bytes:     s.cacheCounters.bytes.Value(),
+	}
+}
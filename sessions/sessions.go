@@ -0,0 +1,264 @@
+// sessions/sessions.go
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+// namespace is the store namespace holding session records by session ID.
+const namespace = "sessions"
+
+// Limits on user-supplied text, in characters.
+const (
+	MaxNameLength  = 100
+	MaxNotesLength = 2000
+)
+
+// MaxFavorites is how many styles and looks a session can shortlist.
+const MaxFavorites = 50
+
+// Favorite kinds.
+const (
+	FavoriteStyle = "style"
+	FavoriteLook  = "look"
+)
+
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrTooLong  = errors.New("session name or notes too long")
+
+	ErrTooManyFavorites = fmt.Errorf("a session can have at most %d favorites", MaxFavorites)
+)
+
+// Record is the persisted description of a generation session. The uploaded
+// photo and style suggestions are only held in memory, so a record outlives
+// the session it describes and keeps its looks findable by name.
+type Record struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	Name      string    `json:"name,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	EventType string    `json:"eventType"`
+	Venue     string    `json:"venue"`
+	Theme     string    `json:"theme"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// PresetUpdatedAt is set when an admin changed the style suggestions for
+	// the session's preset after it was created, and cleared once the session
+	// picks them up.
+	PresetUpdatedAt *time.Time `json:"presetUpdatedAt,omitempty"`
+	// Favorites are the styles and looks the user shortlisted, oldest first.
+	Favorites []Favorite `json:"favorites,omitempty"`
+}
+
+// Favorite is a style or look the user shortlisted in a session. Styles are
+// only held in memory with the session, so a favorite keeps the style's name
+// and description; for a look, Description is the style it shows.
+type Favorite struct {
+	Kind        string    `json:"kind"`
+	ID          string    `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Validate checks a session name and notes against the length limits.
+func Validate(name, notes string) error {
+	if n := utf8.RuneCountInString(name); n > MaxNameLength {
+		return fmt.Errorf("%w: name is %d characters, at most %d are allowed", ErrTooLong, n, MaxNameLength)
+	}
+	if n := utf8.RuneCountInString(notes); n > MaxNotesLength {
+		return fmt.Errorf("%w: notes are %d characters, at most %d are allowed", ErrTooLong, n, MaxNotesLength)
+	}
+	return nil
+}
+
+// Service stores session records.
+type Service struct {
+	store store.Store
+	// mu serializes read-modify-write updates of a record.
+	mu sync.Mutex
+}
+
+// NewService creates a Service backed by st.
+func NewService(st store.Store) *Service {
+	return &Service{store: st}
+}
+
+// Create stores a record for a new session. ID and Owner must be set.
+func (s *Service) Create(ctx context.Context, rec *Record) error {
+	if err := Validate(rec.Name, rec.Notes); err != nil {
+		return err
+	}
+	rec.CreatedAt = time.Now().UTC()
+	rec.UpdatedAt = rec.CreatedAt
+	if err := store.PutJSON(ctx, s.store, namespace, rec.ID, rec); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// Get loads a record by session ID.
+func (s *Service) Get(ctx context.Context, id string) (*Record, error) {
+	var rec Record
+	if err := store.GetJSON(ctx, s.store, namespace, id, &rec); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Update renames a session and replaces its notes. Nil fields are left unchanged.
+func (s *Service) Update(ctx context.Context, id string, name, notes *string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		rec.Name = *name
+	}
+	if notes != nil {
+		rec.Notes = *notes
+	}
+	if err := Validate(rec.Name, rec.Notes); err != nil {
+		return nil, err
+	}
+	rec.UpdatedAt = time.Now().UTC()
+	if err := store.PutJSON(ctx, s.store, namespace, rec.ID, rec); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return rec, nil
+}
+
+// AddFavorite adds fav to the session's favorites. Adding a favorite again
+// leaves the record unchanged.
+func (s *Service) AddFavorite(ctx context.Context, id string, fav Favorite) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(rec.Favorites, func(f Favorite) bool { return f.Kind == fav.Kind && f.ID == fav.ID }) {
+		return rec, nil
+	}
+	if len(rec.Favorites) >= MaxFavorites {
+		return nil, ErrTooManyFavorites
+	}
+	fav.CreatedAt = time.Now().UTC()
+	rec.Favorites = append(rec.Favorites, fav)
+	if err := store.PutJSON(ctx, s.store, namespace, rec.ID, rec); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return rec, nil
+}
+
+// RemoveFavorite removes the favorite of the given kind and ID, if the
+// session has it.
+func (s *Service) RemoveFavorite(ctx context.Context, id, kind, favID string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	n := len(rec.Favorites)
+	rec.Favorites = slices.DeleteFunc(rec.Favorites, func(f Favorite) bool { return f.Kind == kind && f.ID == favID })
+	if len(rec.Favorites) == n {
+		return rec, nil
+	}
+	if err := store.PutJSON(ctx, s.store, namespace, rec.ID, rec); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return rec, nil
+}
+
+// MarkPresetUpdated flags the records created since the given time that
+// match, recording that their preset's suggestions changed, and returns them.
+func (s *Service) MarkPresetUpdated(ctx context.Context, since time.Time, match func(*Record) bool) ([]*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, err := s.store.List(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	var out []*Record
+	for _, id := range ids {
+		rec, err := s.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rec.CreatedAt.Before(since) || !match(rec) {
+			continue
+		}
+		rec.PresetUpdatedAt = &now
+		if err := store.PutJSON(ctx, s.store, namespace, rec.ID, rec); err != nil {
+			return nil, fmt.Errorf("failed to save session: %w", err)
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// ClearPresetUpdated clears the flag set by MarkPresetUpdated.
+func (s *Service) ClearPresetUpdated(ctx context.Context, id string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.PresetUpdatedAt == nil {
+		return rec, nil
+	}
+	rec.PresetUpdatedAt = nil
+	if err := store.PutJSON(ctx, s.store, namespace, rec.ID, rec); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return rec, nil
+}
+
+// List returns owner's session records, newest first.
+func (s *Service) List(ctx context.Context, owner string) ([]*Record, error) {
+	ids, err := s.store.List(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Record
+	for _, id := range ids {
+		rec, err := s.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rec.Owner == owner {
+			out = append(out, rec)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
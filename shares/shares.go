@@ -0,0 +1,171 @@
+// shares/shares.go
+package shares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+// namespace is the store namespace holding shares by token.
+const namespace = "shares"
+
+// tokenBytes is the entropy of a token. Tokens grant access to an image
+// without any other credential, so unlike short link codes they must not be
+// guessable.
+const tokenBytes = 24
+
+var (
+	ErrNotFound = errors.New("share not found")
+	ErrExpired  = errors.New("share expired")
+)
+
+// Share grants anyone with its token access to one look's image until it
+// expires.
+type Share struct {
+	Token  string `json:"token"`
+	LookID string `json:"lookId"`
+	Owner  string `json:"owner"`
+	// ShortCode is the /s/{code} short link to the share, if one was made.
+	ShortCode string    `json:"shortCode,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Views counts the times the shared image was served.
+	Views int64 `json:"views"`
+}
+
+// Expired reports whether the share has passed its expiry.
+func (s *Share) Expired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// Service creates and resolves shares persisted in a store.
+type Service struct {
+	logger *slog.Logger
+	store  store.Store
+
+	// locks serializes updates of each share, so concurrent views of a share
+	// don't lose counts while views of different shares don't wait on each
+	// other.
+	locksMu sync.Mutex
+	locks   map[string]*shareLock
+}
+
+// shareLock is the lock of one share and the number of callers holding or
+// waiting for it.
+type shareLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewService creates a Service backed by st.
+func NewService(logger *slog.Logger, st store.Store) *Service {
+	return &Service{logger: logger, store: st, locks: make(map[string]*shareLock)}
+}
+
+// lock locks the share with token and returns its unlock function.
+func (s *Service) lock(token string) func() {
+	s.locksMu.Lock()
+	l, ok := s.locks[token]
+	if !ok {
+		l = &shareLock{}
+		s.locks[token] = l
+	}
+	l.refs++
+	s.locksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		s.locksMu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(s.locks, token)
+		}
+		s.locksMu.Unlock()
+	}
+}
+
+// Create stores a new share of the look that expires after ttl.
+func (s *Service) Create(ctx context.Context, lookID, owner string, ttl time.Duration) (*Share, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	share := &Share{
+		Token:     base64.RawURLEncoding.EncodeToString(b),
+		LookID:    lookID,
+		Owner:     owner,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := store.PutJSON(ctx, s.store, namespace, share.Token, share); err != nil {
+		return nil, fmt.Errorf("failed to save share: %w", err)
+	}
+	return share, nil
+}
+
+// SetShortCode records the code of the share's /s/{code} short link.
+func (s *Service) SetShortCode(ctx context.Context, token, code string) (*Share, error) {
+	defer s.lock(token)()
+
+	share, err := s.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	share.ShortCode = code
+	if err := store.PutJSON(ctx, s.store, namespace, share.Token, share); err != nil {
+		return nil, fmt.Errorf("failed to save share: %w", err)
+	}
+	return share, nil
+}
+
+// Path is where the share serves the image.
+func (s *Share) Path() string {
+	return "/share/" + s.Token
+}
+
+// Get returns a share by token, including expired ones.
+func (s *Service) Get(ctx context.Context, token string) (*Share, error) {
+	var share Share
+	if err := store.GetJSON(ctx, s.store, namespace, token, &share); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+// Resolve returns the share for token and records the view. A view that
+// can't be recorded is only logged, since the image can still be served.
+func (s *Service) Resolve(ctx context.Context, token string) (*Share, error) {
+	defer s.lock(token)()
+
+	share, err := s.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if share.Expired() {
+		return nil, ErrExpired
+	}
+	share.Views++
+	if err := store.PutJSON(ctx, s.store, namespace, share.Token, share); err != nil {
+		s.logger.Warn("Failed to record share view", "lookID", share.LookID, "error", err)
+	}
+	return share, nil
+}
+
+// Delete revokes a share. It waits for views in progress, which would
+// otherwise save the share again.
+func (s *Service) Delete(ctx context.Context, token string) error {
+	defer s.lock(token)()
+	return s.store.Delete(ctx, namespace, token)
+}
@@ -0,0 +1,151 @@
+// shopping/shopping.go
+//
+// Package shopping finds products to buy a suggested look with. It queries
+// a configurable product search API or retailer feed over HTTP: the search
+// URL has a {query} placeholder and must answer with JSON of the form
+//
+//	{"products": [{"title": "...", "url": "...", "price": 49.99, "currency": "USD", "retailer": "...", "imageUrl": "..."}]}
+//
+// so any retailer can be plugged in with a thin adapter. Results are cached
+// per query, since suggestions for popular events repeat.
+package shopping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/models"
+)
+
+// Query is the placeholder substituted in the search URL.
+const Query = "{query}"
+
+// Client searches a product feed.
+type Client struct {
+	searchURL string
+	apiKey    string
+	// perItem is how many products are kept per search.
+	perItem int
+	ttl     time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	results map[string]result
+}
+
+type result struct {
+	products  []models.Product
+	fetchedAt time.Time
+}
+
+// New creates a Client for the search URL, which must reference {query}.
+// An apiKey, if set, is sent as a bearer token. Each search takes at most
+// timeout, keeps perItem products and is cached for ttl.
+func New(searchURL, apiKey string, timeout, ttl time.Duration, perItem int) (*Client, error) {
+	if !strings.Contains(searchURL, Query) {
+		return nil, fmt.Errorf("shopping search URL does not reference %s", Query)
+	}
+	u, err := url.Parse(strings.ReplaceAll(searchURL, Query, "q"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("shopping search URL must be an http or https URL")
+	}
+	return &Client{
+		searchURL: searchURL,
+		apiKey:    apiKey,
+		perItem:   perItem,
+		ttl:       ttl,
+		client:    &http.Client{Timeout: timeout},
+		results:   make(map[string]result),
+	}, nil
+}
+
+// Search returns up to the configured number of products for item, e.g.
+// "white linen shirt". Products without a title or an http(s) link are
+// skipped.
+func (c *Client) Search(ctx context.Context, item string) ([]models.Product, error) {
+	key := strings.Join(strings.Fields(strings.ToLower(item)), " ")
+	c.mu.Lock()
+	cached, ok := c.results[key]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < c.ttl {
+		return cached.products, nil
+	}
+
+	products, err := c.search(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, r := range c.results {
+		if time.Since(r.fetchedAt) >= c.ttl {
+			delete(c.results, k)
+		}
+	}
+	c.results[key] = result{products: products, fetchedAt: time.Now()}
+	return products, nil
+}
+
+// searchResponse is the response the search URL must give.
+type searchResponse struct {
+	Products []struct {
+		Title    string  `json:"title"`
+		URL      string  `json:"url"`
+		Price    float64 `json:"price"`
+		Currency string  `json:"currency"`
+		Retailer string  `json:"retailer"`
+		ImageURL string  `json:"imageUrl"`
+	} `json:"products"`
+}
+
+func (c *Client) search(ctx context.Context, item string) ([]models.Product, error) {
+	endpoint := strings.ReplaceAll(c.searchURL, Query, url.QueryEscape(item))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach product search: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("product search returned %s", res.Status)
+	}
+	var out searchResponse
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode product search response: %w", err)
+	}
+
+	products := []models.Product{}
+	for _, p := range out.Products {
+		if len(products) == c.perItem {
+			break
+		}
+		link, err := url.Parse(p.URL)
+		if strings.TrimSpace(p.Title) == "" || err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			continue
+		}
+		products = append(products, models.Product{
+			Item:     item,
+			Title:    strings.TrimSpace(p.Title),
+			URL:      p.URL,
+			Price:    p.Price,
+			Currency: strings.ToUpper(p.Currency),
+			Retailer: p.Retailer,
+			ImageURL: p.ImageURL,
+		})
+	}
+	return products, nil
+}
@@ -0,0 +1,358 @@
+// shortlinks/shortlinks.go
+package shortlinks
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+// namespace is the store namespace holding links by code.
+const namespace = "shortlinks"
+
+// Link kinds, used to break down redirect counts by channel.
+const (
+	KindShare    = "share"
+	KindPoll     = "poll"
+	KindReferral = "referral"
+)
+
+// Kinds lists every supported link kind.
+var Kinds = []string{KindShare, KindPoll, KindReferral}
+
+// codeAlphabet omits look-alike characters so codes survive being read aloud or retyped.
+const (
+	codeAlphabet = "23456789abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	codeLength   = 7
+	// shareCodeLength is used for share links, which stand in for a private
+	// share token and so must not be guessable (about 69 bits).
+	shareCodeLength = 12
+)
+
+// expiredRetention is how long expired links are kept, answering 410 rather
+// than 404, before Run deletes them.
+const expiredRetention = 30 * 24 * time.Hour
+
+// cleanupInterval is how often Run looks for expired links to delete.
+const cleanupInterval = time.Hour
+
+// Misses allowed per client within missWindow before /s/ lookups are refused,
+// so codes can't be enumerated.
+const (
+	maxMisses  = 20
+	missWindow = 10 * time.Minute
+)
+
+var (
+	ErrNotFound    = errors.New("short link not found")
+	ErrExpired     = errors.New("short link expired")
+	ErrInvalidKind = errors.New("invalid short link kind")
+)
+
+// pendingHits are redirects not yet written to the store.
+type pendingHits struct {
+	n    int64
+	last time.Time
+}
+
+type missCount struct {
+	n     int
+	since time.Time
+}
+
+// Link maps a short code to a long target URL.
+type Link struct {
+	Code   string `json:"code"`
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Owner  string `json:"owner"`
+	// ExpiresAt is nil for links that never expire.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	// Hits counts redirects served.
+	Hits      int64      `json:"hits"`
+	LastHitAt *time.Time `json:"lastHitAt,omitempty"`
+}
+
+// Expired reports whether the link has passed its expiry.
+func (l *Link) Expired() bool {
+	return l.ExpiresAt != nil && time.Now().After(*l.ExpiresAt)
+}
+
+// Service creates and resolves short links persisted in a store.
+type Service struct {
+	logger *slog.Logger
+	store  store.Store
+	// mu serializes writes to stored links: creating them and flushing hits.
+	mu sync.Mutex
+
+	// hits counts redirects by code until the next flush, so a redirect
+	// doesn't have to wait for a store write.
+	hitsMu sync.Mutex
+	hits   map[string]*pendingHits
+
+	missMu     sync.Mutex
+	misses     map[string]*missCount
+	lastPruned time.Time
+}
+
+// NewService creates a Service backed by st.
+func NewService(logger *slog.Logger, st store.Store) *Service {
+	return &Service{logger: logger, store: st, hits: make(map[string]*pendingHits), misses: make(map[string]*missCount)}
+}
+
+// newCode returns a random code of n characters. Bytes past the largest
+// multiple of the alphabet size are rejected so every character is equally
+// likely.
+func newCode(n int) (string, error) {
+	const limit = 256 - 256%len(codeAlphabet)
+	code := make([]byte, 0, n)
+	b := make([]byte, n)
+	for len(code) < n {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) < limit && len(code) < n {
+				code = append(code, codeAlphabet[int(c)%len(codeAlphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// Create stores a new link to target. A zero ttl means the link never expires.
+func (s *Service) Create(ctx context.Context, kind, target, owner string, ttl time.Duration) (*Link, error) {
+	if !slices.Contains(Kinds, kind) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidKind, kind)
+	}
+	link := &Link{
+		Kind:      kind,
+		Target:    target,
+		Owner:     owner,
+		CreatedAt: time.Now().UTC(),
+	}
+	if ttl > 0 {
+		expires := link.CreatedAt.Add(ttl)
+		link.ExpiresAt = &expires
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Retry on the rare collision rather than overwriting someone else's link.
+	length := codeLength
+	if kind == KindShare {
+		length = shareCodeLength
+	}
+	for range 5 {
+		code, err := newCode(length)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.store.Get(ctx, namespace, code); errors.Is(err, store.ErrNotFound) {
+			link.Code = code
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if link.Code == "" {
+		return nil, errors.New("failed to allocate a unique short link code")
+	}
+	if err := store.PutJSON(ctx, s.store, namespace, link.Code, link); err != nil {
+		return nil, fmt.Errorf("failed to save short link: %w", err)
+	}
+	return link, nil
+}
+
+// Get returns a link by code, including expired ones. Its hits include those
+// not yet flushed.
+func (s *Service) Get(ctx context.Context, code string) (*Link, error) {
+	link, err := s.load(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	s.hitsMu.Lock()
+	if p, ok := s.hits[code]; ok {
+		link.addHits(p)
+	}
+	s.hitsMu.Unlock()
+	return link, nil
+}
+
+func (s *Service) load(ctx context.Context, code string) (*Link, error) {
+	var link Link
+	if err := store.GetJSON(ctx, s.store, namespace, code, &link); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (l *Link) addHits(p *pendingHits) {
+	l.Hits += p.n
+	if l.LastHitAt == nil || p.last.After(*l.LastHitAt) {
+		last := p.last
+		l.LastHitAt = &last
+	}
+}
+
+// Resolve returns the link for code and records the redirect. Hits are
+// written to the store by Run.
+func (s *Service) Resolve(ctx context.Context, code string) (*Link, error) {
+	link, err := s.load(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if link.Expired() {
+		return nil, ErrExpired
+	}
+	s.hitsMu.Lock()
+	p, ok := s.hits[code]
+	if !ok {
+		p = &pendingHits{}
+		s.hits[code] = p
+	}
+	p.n++
+	p.last = time.Now().UTC()
+	s.hitsMu.Unlock()
+	return link, nil
+}
+
+// Run writes pending hits to the store every interval, and deletes links that
+// expired more than 30 days ago, until ctx is cancelled.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var cleaned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			s.Flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+		}
+		s.Flush(ctx)
+		if time.Since(cleaned) >= cleanupInterval {
+			if _, err := s.Cleanup(ctx); err != nil {
+				s.logger.Warn("Failed to clean up short links", "error", err)
+			}
+			cleaned = time.Now()
+		}
+	}
+}
+
+// Flush writes pending hits to the store. Hits that fail to save are kept
+// for the next flush.
+func (s *Service) Flush(ctx context.Context) {
+	s.hitsMu.Lock()
+	hits := s.hits
+	s.hits = make(map[string]*pendingHits)
+	s.hitsMu.Unlock()
+
+	for code, p := range hits {
+		err := s.addHits(ctx, code, p)
+		if err == nil || errors.Is(err, ErrNotFound) {
+			continue
+		}
+		s.logger.Warn("Failed to record short link hits", "code", code, "hits", p.n, "error", err)
+		s.hitsMu.Lock()
+		// Hits since the swap are newer, so only the count needs merging.
+		if q, ok := s.hits[code]; ok {
+			q.n += p.n
+		} else {
+			s.hits[code] = p
+		}
+		s.hitsMu.Unlock()
+	}
+}
+
+func (s *Service) addHits(ctx context.Context, code string, p *pendingHits) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, err := s.load(ctx, code)
+	if err != nil {
+		return err
+	}
+	link.addHits(p)
+	return store.PutJSON(ctx, s.store, namespace, code, link)
+}
+
+// Cleanup deletes links that expired more than 30 days ago and returns how
+// many it deleted. Run calls it hourly.
+func (s *Service) Cleanup(ctx context.Context) (int, error) {
+	codes, err := s.store.List(ctx, namespace)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list short links: %w", err)
+	}
+	cutoff := time.Now().Add(-expiredRetention)
+	deleted := 0
+	for _, code := range codes {
+		link, err := s.load(ctx, code)
+		if err != nil || link.ExpiresAt == nil || link.ExpiresAt.After(cutoff) {
+			continue
+		}
+		if err := s.store.Delete(ctx, namespace, code); err != nil {
+			s.logger.Warn("Failed to delete expired short link", "code", code, "error", err)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		s.logger.Info("Deleted expired short links", "deleted", deleted)
+	}
+	return deleted, nil
+}
+
+// MissRetryAfter reports how long client must wait before resolving another
+// code, or zero if it hasn't used up its misses.
+func (s *Service) MissRetryAfter(client string) time.Duration {
+	s.missMu.Lock()
+	defer s.missMu.Unlock()
+	m, ok := s.misses[client]
+	if !ok || m.n < maxMisses {
+		return 0
+	}
+	wait := time.Until(m.since.Add(missWindow))
+	if wait <= 0 {
+		delete(s.misses, client)
+		return 0
+	}
+	return wait
+}
+
+// RecordMiss counts a lookup by client of a code that doesn't exist or has
+// expired.
+func (s *Service) RecordMiss(client string) {
+	s.missMu.Lock()
+	defer s.missMu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastPruned) > missWindow {
+		for k, m := range s.misses {
+			if now.Sub(m.since) > missWindow {
+				delete(s.misses, k)
+			}
+		}
+		s.lastPruned = now
+	}
+	m, ok := s.misses[client]
+	if !ok || now.Sub(m.since) > missWindow {
+		m = &missCount{since: now}
+		s.misses[client] = m
+	}
+	m.n++
+}
+
+// Delete removes a link.
+func (s *Service) Delete(ctx context.Context, code string) error {
+	return s.store.Delete(ctx, namespace, code)
+}
@@ -0,0 +1,145 @@
+package signing
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignRequest(t *testing.T) {
+	// Computed independently with Python's hmac and hashlib.
+	const want = "10604a755b11546d76bb739b1f79fef423811e9933f9c83c45cd91dd07f9af62"
+	got := SignRequest([]byte("secret"), http.MethodPost, "/api/v1/generate?x=1", "1700000000", "abc123", []byte(`{"a":1}`))
+	if got != want {
+		t.Errorf("SignRequest = %s, want %s", got, want)
+	}
+}
+
+func newTestVerifier(secret string) *Verifier {
+	return NewVerifier(slog.New(slog.NewTextHandler(io.Discard, nil)), secret, 1<<20, 5*time.Minute)
+}
+
+// signedRequest builds a request signed with secret.
+func signedRequest(secret, method, target, body, timestamp, nonce string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set(SignatureHeader, SignRequest([]byte(secret), method, r.URL.RequestURI(), timestamp, nonce, []byte(body)))
+	return r
+}
+
+func TestVerifyKeyed(t *testing.T) {
+	const secret = "dss_test"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		want    error
+	}{
+		{"valid", func() *http.Request {
+			return signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "n-valid")
+		}, nil},
+		{"stale timestamp", func() *http.Request {
+			return signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, stale, "n-stale")
+		}, ErrExpiredSignature},
+		{"missing nonce", func() *http.Request {
+			return signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "")
+		}, ErrMissingSignature},
+		{"nonce too long", func() *http.Request {
+			return signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, strings.Repeat("n", maxNonceLen+1))
+		}, ErrMissingSignature},
+		{"wrong secret", func() *http.Request {
+			return signedRequest("dss_other", http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "n-secret")
+		}, ErrInvalidSignature},
+		{"tampered body", func() *http.Request {
+			r := signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "n-body")
+			r.Body = io.NopCloser(strings.NewReader(`{"a":2}`))
+			return r
+		}, ErrInvalidSignature},
+		{"other path", func() *http.Request {
+			r := signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "n-path")
+			r.URL.Path = "/api/v1/swap-style"
+			return r
+		}, ErrInvalidSignature},
+		{"other method", func() *http.Request {
+			r := signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "n-method")
+			r.Method = http.MethodPut
+			return r
+		}, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier("")
+			err := v.VerifyKeyed(httptest.NewRecorder(), tt.request(), "key-1", []byte(secret))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("VerifyKeyed = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyKeyedReplay(t *testing.T) {
+	const secret = "dss_test"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	v := newTestVerifier("")
+	verify := func(keyID, signWith, nonce string) error {
+		r := signedRequest(signWith, http.MethodPost, "/api/v1/generate", `{}`, now, nonce)
+		return v.VerifyKeyed(httptest.NewRecorder(), r, keyID, []byte(secret))
+	}
+
+	// A forged request must not burn the nonce for the real one.
+	if err := verify("key-1", "dss_forged", "n1"); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("forged request = %v, want ErrInvalidSignature", err)
+	}
+	if err := verify("key-1", secret, "n1"); err != nil {
+		t.Fatalf("first request = %v, want nil", err)
+	}
+	if err := verify("key-1", secret, "n1"); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("replayed request = %v, want ErrReplayed", err)
+	}
+	// Nonces are tracked per key.
+	if err := verify("key-2", secret, "n1"); err != nil {
+		t.Fatalf("same nonce for another key = %v, want nil", err)
+	}
+}
+
+func TestRequireReplay(t *testing.T) {
+	const secret = "frontend-secret"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	v := newTestVerifier(secret)
+	h := v.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"a":1}` {
+			t.Errorf("next got body %q", body)
+		}
+	}))
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		want    int
+	}{
+		{"valid", signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "r1"), http.StatusOK},
+		{"replayed", signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "r1"), http.StatusUnauthorized},
+		{"replayed on another route", signedRequest(secret, http.MethodPost, "/api/v1/refine", `{"a":1}`, now, "r1"), http.StatusUnauthorized},
+		{"new nonce", signedRequest(secret, http.MethodPost, "/api/v1/generate", `{"a":1}`, now, "r2"), http.StatusOK},
+		{"unsigned", httptest.NewRequest(http.MethodPost, "/api/v1/generate", strings.NewReader(`{"a":1}`)), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tt.request)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+// status/status.go
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+// Components tracked by the server.
+const (
+	ComponentGemini = "gemini"
+	ComponentStore  = "store"
+)
+
+// Component states, ordered from best to worst.
+const (
+	StateOperational = "operational"
+	StateDegraded    = "degraded"
+	StateOutage      = "outage"
+)
+
+const (
+	// incidentNamespace is the store namespace holding incident records.
+	incidentNamespace = "incidents"
+	// historySize is the number of recent checks kept per component.
+	historySize = 100
+	// failureThreshold is how many consecutive failed checks open an outage,
+	// so a single bad request doesn't page anyone.
+	failureThreshold = 3
+	// maxIncidents bounds the incidents returned by Summary.
+	maxIncidents = 20
+	// incidentCacheTTL is how long Summary reuses the incidents it loaded, so
+	// a busy status page doesn't list the store on every hit. Incidents opened
+	// or resolved by this instance show up at once.
+	incidentCacheTTL = 30 * time.Second
+	// incidentRetention is how long resolved incidents are kept.
+	incidentRetention = 90 * 24 * time.Hour
+	// pruneInterval is how often Run deletes old incidents.
+	pruneInterval = time.Hour
+)
+
+// Check is a single health observation of a component.
+type Check struct {
+	At time.Time `json:"at"`
+	OK bool      `json:"ok"`
+}
+
+// Incident is a period during which a component was not operational.
+type Incident struct {
+	ID         string     `json:"id"`
+	Component  string     `json:"component"`
+	State      string     `json:"state"`
+	Message    string     `json:"message"`
+	StartedAt  time.Time  `json:"startedAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// ComponentStatus summarizes a component's current state and recent reliability.
+type ComponentStatus struct {
+	Name          string    `json:"name"`
+	State         string    `json:"state"`
+	LastCheckedAt time.Time `json:"lastCheckedAt"`
+	// Uptime is the fraction of recent checks that succeeded.
+	Uptime float64 `json:"uptime"`
+}
+
+// Summary is the public status page payload.
+type Summary struct {
+	State      string            `json:"state"`
+	Components []ComponentStatus `json:"components"`
+	Incidents  []Incident        `json:"incidents"`
+}
+
+type component struct {
+	// failState is the state entered after failureThreshold consecutive failures.
+	failState  string
+	state      string
+	failures   int
+	history    []Check
+	incidentID string
+}
+
+// Tracker records dependency health checks over time and opens/resolves incidents
+// as components change state. Incidents are persisted to the store.
+type Tracker struct {
+	logger *slog.Logger
+	store  store.Store
+
+	mu         sync.Mutex
+	components map[string]*component
+	probes     map[string]func(context.Context) error
+	// incidents caches the most recent incidents, loaded at incidentsAt.
+	// changes counts incidents opened or resolved, so a load that raced one
+	// isn't cached.
+	incidents   []Incident
+	incidentsAt time.Time
+	changes     int
+	lastPruned  time.Time
+}
+
+// NewTracker creates a Tracker persisting incidents to st.
+func NewTracker(logger *slog.Logger, st store.Store) *Tracker {
+	return &Tracker{
+		logger:     logger,
+		store:      st,
+		components: make(map[string]*component),
+		probes:     make(map[string]func(context.Context) error),
+	}
+}
+
+// Register declares a component and the state it enters when failing, e.g.
+// StateDegraded for a dependency we can work around and StateOutage otherwise.
+func (t *Tracker) Register(name, failState string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(name).failState = failState
+}
+
+// AddProbe registers an active health check run periodically by Run.
+func (t *Tracker) AddProbe(name string, probe func(context.Context) error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.probes[name] = probe
+	t.get(name)
+}
+
+// get returns the named component, creating it if needed. The caller must hold t.mu.
+func (t *Tracker) get(name string) *component {
+	c, ok := t.components[name]
+	if !ok {
+		c = &component{state: StateOperational, failState: StateOutage}
+		t.components[name] = c
+	}
+	return c
+}
+
+// Observe records the outcome of a call to a component. Passive observations
+// let us track providers like Gemini without paying for synthetic checks.
+// Calls cancelled by the client hanging up say nothing about the component
+// and are ignored.
+func (t *Tracker) Observe(ctx context.Context, name string, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+	t.mu.Lock()
+	c := t.get(name)
+	c.history = append(c.history, Check{At: time.Now().UTC(), OK: err == nil})
+	if len(c.history) > historySize {
+		c.history = c.history[len(c.history)-historySize:]
+	}
+	if err == nil {
+		c.failures = 0
+	} else {
+		c.failures++
+	}
+	failures, failState := c.failures, c.failState
+	t.mu.Unlock()
+
+	switch {
+	case err == nil:
+		t.SetState(ctx, name, StateOperational, "")
+	case failures >= failureThreshold:
+		t.SetState(ctx, name, failState, err.Error())
+	}
+}
+
+// SetState forces a component into a state, opening or resolving incidents.
+func (t *Tracker) SetState(ctx context.Context, name, state, message string) {
+	t.mu.Lock()
+	c := t.get(name)
+	if c.state == state {
+		t.mu.Unlock()
+		return
+	}
+	prevIncident := c.incidentID
+	c.state = state
+	c.incidentID = ""
+	now := time.Now().UTC()
+	var opened *Incident
+	if state != StateOperational {
+		opened = &Incident{
+			ID:        fmt.Sprintf("%s-%d", name, now.UnixNano()),
+			Component: name,
+			State:     state,
+			Message:   message,
+			StartedAt: now,
+		}
+		c.incidentID = opened.ID
+	}
+	t.incidentsAt = time.Time{}
+	t.changes++
+	t.mu.Unlock()
+
+	if prevIncident != "" {
+		var inc Incident
+		if err := store.GetJSON(ctx, t.store, incidentNamespace, prevIncident, &inc); err == nil {
+			inc.ResolvedAt = &now
+			t.save(ctx, &inc)
+		}
+		t.logger.Info("Incident resolved", "component", name, "incident", prevIncident)
+	}
+	if opened != nil {
+		t.save(ctx, opened)
+		t.logger.Warn("Incident opened", "component", name, "state", state, "message", message)
+	}
+}
+
+func (t *Tracker) save(ctx context.Context, inc *Incident) {
+	if err := store.PutJSON(ctx, t.store, incidentNamespace, inc.ID, inc); err != nil {
+		t.logger.Error("Failed to persist incident", "incident", inc.ID, "error", err)
+	}
+}
+
+// Run executes the registered probes every interval until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		t.mu.Lock()
+		probes := make(map[string]func(context.Context) error, len(t.probes))
+		for name, p := range t.probes {
+			probes[name] = p
+		}
+		t.mu.Unlock()
+
+		for name, probe := range probes {
+			probeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			t.Observe(ctx, name, probe(probeCtx))
+			cancel()
+		}
+		if time.Since(t.lastPruned) >= pruneInterval {
+			if _, err := t.Prune(ctx); err != nil {
+				t.logger.Error("Failed to prune incidents", "error", err)
+			}
+			t.lastPruned = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Summary returns the current state of every component and the most recent incidents.
+func (t *Tracker) Summary(ctx context.Context) (Summary, error) {
+	t.mu.Lock()
+	sum := Summary{State: StateOperational}
+	for name, c := range t.components {
+		cs := ComponentStatus{Name: name, State: c.state, Uptime: 1}
+		if n := len(c.history); n > 0 {
+			ok := 0
+			for _, check := range c.history {
+				if check.OK {
+					ok++
+				}
+			}
+			cs.Uptime = float64(ok) / float64(n)
+			cs.LastCheckedAt = c.history[n-1].At
+		}
+		sum.Components = append(sum.Components, cs)
+		if severity(c.state) > severity(sum.State) {
+			sum.State = c.state
+		}
+	}
+	incidents, fresh, changes := t.incidents, time.Since(t.incidentsAt) < incidentCacheTTL, t.changes
+	t.mu.Unlock()
+	sort.Slice(sum.Components, func(i, j int) bool { return sum.Components[i].Name < sum.Components[j].Name })
+
+	if !fresh {
+		all, err := t.loadIncidents(ctx)
+		if err != nil {
+			return sum, err
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].StartedAt.After(all[j].StartedAt) })
+		incidents = all[:min(len(all), maxIncidents)]
+		t.mu.Lock()
+		if t.changes == changes {
+			t.incidents, t.incidentsAt = incidents, time.Now()
+		}
+		t.mu.Unlock()
+	}
+	sum.Incidents = slices.Clone(incidents)
+	return sum, nil
+}
+
+// loadIncidents reads every stored incident.
+func (t *Tracker) loadIncidents(ctx context.Context) ([]Incident, error) {
+	ids, err := t.store.List(ctx, incidentNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	var out []Incident
+	for _, id := range ids {
+		var inc Incident
+		if err := store.GetJSON(ctx, t.store, incidentNamespace, id, &inc); err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				t.logger.Error("Failed to load incident", "incident", id, "error", err)
+			}
+			continue
+		}
+		out = append(out, inc)
+	}
+	return out, nil
+}
+
+// Prune deletes incidents resolved more than 90 days ago and returns how
+// many it deleted. Run calls it hourly.
+func (t *Tracker) Prune(ctx context.Context) (int, error) {
+	all, err := t.loadIncidents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-incidentRetention)
+	deleted := 0
+	for _, inc := range all {
+		if inc.ResolvedAt == nil || inc.ResolvedAt.After(cutoff) {
+			continue
+		}
+		if err := t.store.Delete(ctx, incidentNamespace, inc.ID); err != nil {
+			t.logger.Error("Failed to delete incident", "incident", inc.ID, "error", err)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		t.logger.Info("Deleted old incidents", "deleted", deleted)
+	}
+	return deleted, nil
+}
+
+func severity(state string) int {
+	switch state {
+	case StateDegraded:
+		return 1
+	case StateOutage:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+// store/file.go
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore persists values as individual files under a root directory,
+// one subdirectory per namespace.
+type FileStore struct {
+	root string
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &FileStore{root: dir}, nil
+}
+
+func (f *FileStore) path(namespace, key string) string {
+	return filepath.Join(f.root, url.PathEscape(namespace), url.PathEscape(key))
+}
+
+// Get reads the value stored under namespace/key.
+func (f *FileStore) Get(ctx context.Context, namespace, key string) ([]byte, error) {
+	data, err := os.ReadFile(f.path(namespace, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Put writes the value atomically by writing a temp file and renaming it into place.
+func (f *FileStore) Put(ctx context.Context, namespace, key string, value []byte) error {
+	dir := filepath.Join(f.root, url.PathEscape(namespace))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(namespace, key))
+}
+
+// Delete removes namespace/key. Deleting a missing key is not an error.
+func (f *FileStore) Delete(ctx context.Context, namespace, key string) error {
+	err := os.Remove(f.path(namespace, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// List returns all keys stored in a namespace.
+func (f *FileStore) List(ctx context.Context, namespace string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(f.root, url.PathEscape(namespace)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".tmp-") {
+			continue
+		}
+		key, err := url.PathUnescape(e.Name())
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
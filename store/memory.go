@@ -0,0 +1,68 @@
+// store/memory.go
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-process Store, useful for development and as a fallback.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string]map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string]map[string][]byte)}
+}
+
+// Get returns a copy of the value stored under namespace/key.
+func (m *MemoryStore) Get(ctx context.Context, namespace, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[namespace][key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), v...), nil
+}
+
+// Put stores a copy of value under namespace/key.
+func (m *MemoryStore) Put(ctx context.Context, namespace, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ns, ok := m.data[namespace]
+	if !ok {
+		ns = make(map[string][]byte)
+		m.data[namespace] = ns
+	}
+	ns[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete removes namespace/key.
+func (m *MemoryStore) Delete(ctx context.Context, namespace, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data[namespace], key)
+	return nil
+}
+
+// List returns all keys in a namespace.
+func (m *MemoryStore) List(ctx context.Context, namespace string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.data[namespace]))
+	for k := range m.data[namespace] {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Reset discards all stored values.
+func (m *MemoryStore) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data = make(map[string]map[string][]byte)
+}
@@ -0,0 +1,265 @@
+// store/resilient.go
+package store
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// probeInterval is how often a degraded Resilient store checks whether the primary has recovered.
+const probeInterval = 30 * time.Second
+
+// replayRounds is how many times recovery replays the writes made while it
+// was replaying before it gives up until the next probe.
+const replayRounds = 5
+
+// Resilient wraps a primary Store and falls back to in-memory storage when the
+// primary fails, so requests keep working (without durability) during an outage.
+// Writes made while degraded are replayed into the primary once it recovers.
+type Resilient struct {
+	primary  Store
+	fallback *MemoryStore
+	logger   *slog.Logger
+
+	// OnStateChange, if set, is called when the store enters or leaves degraded mode.
+	OnStateChange func(degraded bool, err error)
+
+	mu       sync.RWMutex
+	degraded bool
+	// pending records the keys written to the fallback while degraded, with
+	// the sequence number of their last write.
+	pending map[[2]string]uint64
+	seq     uint64
+}
+
+// NewResilient wraps primary with an in-memory fallback.
+func NewResilient(logger *slog.Logger, primary Store) *Resilient {
+	return &Resilient{
+		primary:  primary,
+		fallback: NewMemoryStore(),
+		logger:   logger,
+		pending:  make(map[[2]string]uint64),
+	}
+}
+
+// Degraded reports whether the store is currently serving from memory.
+func (r *Resilient) Degraded() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.degraded
+}
+
+// markDegraded switches to the fallback after a primary failure.
+func (r *Resilient) markDegraded(err error) {
+	r.mu.Lock()
+	if r.degraded {
+		r.mu.Unlock()
+		return
+	}
+	r.degraded = true
+	r.mu.Unlock()
+
+	r.logger.Error("Primary store unavailable, degrading to in-memory storage", "error", err)
+	if r.OnStateChange != nil {
+		r.OnStateChange(true, err)
+	}
+}
+
+// Run probes the primary store while degraded and restores it when healthy.
+// It blocks until ctx is cancelled.
+func (r *Resilient) Run(ctx context.Context) {
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if r.Degraded() {
+				r.tryRecover(ctx)
+			}
+		}
+	}
+}
+
+// Ping checks that the primary store accepts reads and writes.
+func (r *Resilient) Ping(ctx context.Context) error {
+	return Ping(ctx, r.primary)
+}
+
+// Ping writes and reads back a probe key to verify s is usable.
+func Ping(ctx context.Context, s Store) error {
+	if err := s.Put(ctx, "_health", "probe", []byte(time.Now().UTC().Format(time.RFC3339))); err != nil {
+		return err
+	}
+	_, err := s.Get(ctx, "_health", "probe")
+	return err
+}
+
+// tryRecover replays the writes made while degraded into the primary and
+// leaves degraded mode. The replay runs without the lock, so requests keep
+// being served from memory meanwhile; writes they make are replayed in the
+// next round.
+func (r *Resilient) tryRecover(ctx context.Context) {
+	if err := r.Ping(ctx); err != nil {
+		return
+	}
+
+	for round := 0; ; round++ {
+		r.mu.RLock()
+		snapshot := make(map[[2]string]uint64, len(r.pending))
+		for k, seq := range r.pending {
+			snapshot[k] = seq
+		}
+		r.mu.RUnlock()
+
+		if len(snapshot) > 0 && round == replayRounds {
+			r.logger.Warn("Writes kept arriving during degraded-mode replay; staying degraded", "pending", len(snapshot))
+			return
+		}
+		for k, seq := range snapshot {
+			if err := r.replay(ctx, k); err != nil {
+				r.logger.Error("Failed to replay degraded-mode write; staying degraded", "namespace", k[0], "key", k[1], "error", err)
+				return
+			}
+			r.mu.Lock()
+			// A key written again since the snapshot is replayed next round
+			if r.pending[k] == seq {
+				delete(r.pending, k)
+			}
+			r.mu.Unlock()
+		}
+
+		r.mu.Lock()
+		if len(r.pending) == 0 {
+			r.degraded = false
+			r.fallback.Reset()
+			r.mu.Unlock()
+			break
+		}
+		r.mu.Unlock()
+	}
+
+	r.logger.Info("Primary store recovered, leaving degraded mode")
+	if r.OnStateChange != nil {
+		r.OnStateChange(false, nil)
+	}
+}
+
+// replay copies a key written while degraded from the fallback to the primary.
+func (r *Resilient) replay(ctx context.Context, k [2]string) error {
+	value, err := r.fallback.Get(ctx, k[0], k[1])
+	if errors.Is(err, ErrNotFound) {
+		return r.primary.Delete(ctx, k[0], k[1])
+	}
+	if err != nil {
+		return err
+	}
+	return r.primary.Put(ctx, k[0], k[1], value)
+}
+
+// Get reads from the primary, or from memory while degraded.
+func (r *Resilient) Get(ctx context.Context, namespace, key string) ([]byte, error) {
+	if r.Degraded() {
+		if v, err := r.fallback.Get(ctx, namespace, key); err == nil {
+			return v, nil
+		}
+		r.mu.RLock()
+		_, written := r.pending[[2]string{namespace, key}]
+		r.mu.RUnlock()
+		if written {
+			return nil, ErrNotFound
+		}
+		// Best effort: the primary may still serve reads.
+		if v, err := r.primary.Get(ctx, namespace, key); err == nil {
+			return v, nil
+		}
+		return nil, ErrNotFound
+	}
+	v, err := r.primary.Get(ctx, namespace, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.markDegraded(err)
+		return nil, ErrNotFound
+	}
+	return v, err
+}
+
+// Put writes to the primary, or to memory while degraded.
+func (r *Resilient) Put(ctx context.Context, namespace, key string, value []byte) error {
+	if !r.Degraded() {
+		err := r.primary.Put(ctx, namespace, key, value)
+		if err == nil {
+			return nil
+		}
+		r.markDegraded(err)
+	}
+	r.mu.Lock()
+	if !r.degraded {
+		// Recovered meanwhile; the fallback is no longer read.
+		r.mu.Unlock()
+		return r.Put(ctx, namespace, key, value)
+	}
+	defer r.mu.Unlock()
+	r.seq++
+	r.pending[[2]string{namespace, key}] = r.seq
+	return r.fallback.Put(ctx, namespace, key, value)
+}
+
+// Delete removes from the primary, or from memory while degraded.
+func (r *Resilient) Delete(ctx context.Context, namespace, key string) error {
+	if !r.Degraded() {
+		err := r.primary.Delete(ctx, namespace, key)
+		if err == nil {
+			return nil
+		}
+		r.markDegraded(err)
+	}
+	r.mu.Lock()
+	if !r.degraded {
+		r.mu.Unlock()
+		return r.Delete(ctx, namespace, key)
+	}
+	defer r.mu.Unlock()
+	r.seq++
+	r.pending[[2]string{namespace, key}] = r.seq
+	return r.fallback.Delete(ctx, namespace, key)
+}
+
+// List merges keys from the primary (when reachable) and the fallback.
+// Keys written while degraded are listed as the fallback has them, so keys
+// deleted meanwhile don't reappear from the primary.
+func (r *Resilient) List(ctx context.Context, namespace string) ([]string, error) {
+	var keys []string
+	if !r.Degraded() {
+		primaryKeys, err := r.primary.List(ctx, namespace)
+		if err == nil {
+			return primaryKeys, nil
+		}
+		r.markDegraded(err)
+	} else if primaryKeys, err := r.primary.List(ctx, namespace); err == nil {
+		keys = primaryKeys
+	}
+
+	r.mu.RLock()
+	keys = slices.DeleteFunc(keys, func(k string) bool {
+		_, written := r.pending[[2]string{namespace, k}]
+		return written
+	})
+	r.mu.RUnlock()
+
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		seen[k] = true
+	}
+	memKeys, _ := r.fallback.List(ctx, namespace)
+	for _, k := range memKeys {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
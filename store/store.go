@@ -0,0 +1,43 @@
+// store/store.go
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned when the requested key does not exist.
+var ErrNotFound = errors.New("store: not found")
+
+// Store is a minimal namespaced key/value store used to persist application state
+// such as usage counters. Values are opaque bytes; use GetJSON/PutJSON for structs.
+type Store interface {
+	Get(ctx context.Context, namespace, key string) ([]byte, error)
+	Put(ctx context.Context, namespace, key string, value []byte) error
+	Delete(ctx context.Context, namespace, key string) error
+	// List returns all keys in a namespace, in no particular order.
+	List(ctx context.Context, namespace string) ([]string, error)
+}
+
+// GetJSON loads the value stored under namespace/key and unmarshals it into v.
+func GetJSON(ctx context.Context, s Store, namespace, key string, v any) error {
+	data, err := s.Get(ctx, namespace, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s/%s: %w", namespace, key, err)
+	}
+	return nil
+}
+
+// PutJSON marshals v and stores it under namespace/key.
+func PutJSON(ctx context.Context, s Store, namespace, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", namespace, key, err)
+	}
+	return s.Put(ctx, namespace, key, data)
+}
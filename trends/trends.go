@@ -0,0 +1,217 @@
+// trends/trends.go
+package trends
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sanjayshr/event-outfitter-backend/looks"
+)
+
+const (
+	// cacheTTL bounds how stale a computed weekly report may be.
+	cacheTTL = 10 * time.Minute
+	// minRatings is how many ratings a style needs before it can rank as best-rated.
+	minRatings = 2
+	// maxWeeksBack is how far back reports can be requested. It also bounds
+	// the cache to one report per week in the window.
+	maxWeeksBack = 52
+)
+
+// Entry is a ranked value with how often it was generated and its average rating.
+type Entry struct {
+	Value         string  `json:"value"`
+	Count         int     `json:"count"`
+	Ratings       int     `json:"ratings"`
+	AverageRating float64 `json:"averageRating,omitempty"`
+}
+
+// Report summarizes what was generated during one ISO week. Generations
+// counts every look; the rankings only count looks approved for the public
+// gallery, since styles and themes are free text users don't expect to see
+// published otherwise.
+type Report struct {
+	Week        string    `json:"week"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Generations int       `json:"generations"`
+	Styles      []Entry   `json:"styles"`
+	Themes      []Entry   `json:"themes"`
+	EventTypes  []Entry   `json:"eventTypes"`
+	BestRated   []Entry   `json:"bestRated"`
+}
+
+// Aggregator computes weekly trend reports from recorded looks.
+type Aggregator struct {
+	looks *looks.Repository
+
+	mu    sync.Mutex
+	cache map[string]cachedReport
+}
+
+type cachedReport struct {
+	report   Report
+	computed time.Time
+}
+
+// NewAggregator creates an Aggregator over the given looks.
+func NewAggregator(repo *looks.Repository) *Aggregator {
+	return &Aggregator{looks: repo, cache: make(map[string]cachedReport)}
+}
+
+// ParseWeek parses an ISO week like "2025-W03" and returns the Monday it starts on.
+// An empty string means the current week. Only the current week and the 52
+// before it are accepted.
+func ParseWeek(week string) (time.Time, error) {
+	now := time.Now().UTC()
+	current := time.Date(now.Year(), now.Month(), now.Day()-(int(now.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	if week == "" {
+		return current, nil
+	}
+	var year, wk int
+	if _, err := fmt.Sscanf(week, "%d-W%d", &year, &wk); err != nil || wk < 1 || wk > 53 {
+		return time.Time{}, fmt.Errorf("invalid ISO week %q, expected e.g. 2025-W03", week)
+	}
+	// January 4th is always in ISO week 1.
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	week1 := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	start := week1.AddDate(0, 0, 7*(wk-1))
+	if y, w := start.ISOWeek(); y != year || w != wk {
+		return time.Time{}, fmt.Errorf("invalid ISO week %q: %d has no week %d", week, year, wk)
+	}
+	if start.After(current) || start.Before(current.AddDate(0, 0, -7*maxWeeksBack)) {
+		return time.Time{}, fmt.Errorf("week %q is outside the last %d weeks", week, maxWeeksBack)
+	}
+	return start, nil
+}
+
+// Weekly returns the report for the ISO week starting at start, limiting each ranking to limit entries.
+func (a *Aggregator) Weekly(ctx context.Context, start time.Time, limit int) (Report, error) {
+	year, wk := start.ISOWeek()
+	key := fmt.Sprintf("%d-W%02d", year, wk)
+
+	a.mu.Lock()
+	cached, ok := a.cache[key]
+	a.mu.Unlock()
+	if !ok || time.Since(cached.computed) > cacheTTL {
+		report, err := a.compute(ctx, key, start)
+		if err != nil {
+			return Report{}, err
+		}
+		cached = cachedReport{report: report, computed: time.Now()}
+		a.mu.Lock()
+		for k, c := range a.cache {
+			if time.Since(c.computed) > cacheTTL {
+				delete(a.cache, k)
+			}
+		}
+		a.cache[key] = cached
+		a.mu.Unlock()
+	}
+
+	r := cached.report
+	r.Styles = truncate(r.Styles, limit)
+	r.Themes = truncate(r.Themes, limit)
+	r.EventTypes = truncate(r.EventTypes, limit)
+	r.BestRated = truncate(r.BestRated, limit)
+	return r, nil
+}
+
+func (a *Aggregator) compute(ctx context.Context, key string, start time.Time) (Report, error) {
+	all, err := a.looks.All(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	end := start.AddDate(0, 0, 7)
+	report := Report{Week: key, Start: start, End: end}
+	styles, themes, events := newCounter(), newCounter(), newCounter()
+	for _, l := range all {
+		if l.CreatedAt.Before(start) || !l.CreatedAt.Before(end) {
+			continue
+		}
+		report.Generations++
+		if !l.Public {
+			continue
+		}
+		styles.add(l.Style, l.Rating)
+		themes.add(l.Theme, l.Rating)
+		events.add(l.EventType, l.Rating)
+	}
+
+	report.Styles = styles.byCount()
+	report.Themes = themes.byCount()
+	report.EventTypes = events.byCount()
+	report.BestRated = styles.byRating()
+	return report, nil
+}
+
+func truncate(entries []Entry, limit int) []Entry {
+	if limit > 0 && len(entries) > limit {
+		return entries[:limit]
+	}
+	return entries
+}
+
+// counter tallies values case-insensitively, remembering the first spelling seen.
+type counter struct {
+	entries map[string]*Entry
+	sums    map[string]int
+}
+
+func newCounter() *counter {
+	return &counter{entries: make(map[string]*Entry), sums: make(map[string]int)}
+}
+
+func (c *counter) add(value string, rating int) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	k := strings.ToLower(value)
+	e, ok := c.entries[k]
+	if !ok {
+		e = &Entry{Value: value}
+		c.entries[k] = e
+	}
+	e.Count++
+	if rating > 0 {
+		e.Ratings++
+		c.sums[k] += rating
+		e.AverageRating = float64(c.sums[k]) / float64(e.Ratings)
+	}
+}
+
+func (c *counter) byCount() []Entry {
+	out := make([]Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+func (c *counter) byRating() []Entry {
+	var out []Entry
+	for _, e := range c.entries {
+		if e.Ratings >= minRatings {
+			out = append(out, *e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AverageRating != out[j].AverageRating {
+			return out[i].AverageRating > out[j].AverageRating
+		}
+		return out[i].Ratings > out[j].Ratings
+	})
+	return out
+}
@@ -0,0 +1,230 @@
+// uploads/uploads.go
+//
+// Package uploads holds resumable photo uploads in the style of the tus
+// protocol: a client declares the size, sends the bytes in as many chunks as
+// its connection allows, asks for the offset after a dropped connection, and
+// finally references the finished upload by ID. Uploads are held in memory,
+// like sessions, and expire if they are not used.
+package uploads
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// MaxPendingPerOwner is how many unfinished or unused uploads one client may hold.
+const MaxPendingPerOwner = 10
+
+var (
+	ErrNotFound = errors.New("upload not found")
+	// ErrTooLarge is returned for a declared length over the limit, or for
+	// data beyond the declared length.
+	ErrTooLarge = errors.New("upload is too large")
+	// ErrOffsetMismatch is returned when a chunk does not start where the
+	// upload ends, e.g. because the client missed that an earlier chunk arrived.
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	// ErrIncomplete is returned when an upload is used before all bytes arrived.
+	ErrIncomplete = errors.New("upload is incomplete")
+	ErrTooMany    = errors.New("too many pending uploads")
+	// ErrFull is returned when a new upload would take the pending uploads
+	// past the memory limit.
+	ErrFull = errors.New("upload memory is full")
+)
+
+// Info describes an upload's progress.
+type Info struct {
+	ID        string
+	Length    int64
+	Offset    int64
+	ExpiresAt time.Time
+}
+
+// Complete reports whether all bytes have arrived.
+func (i Info) Complete() bool {
+	return i.Offset == i.Length
+}
+
+type upload struct {
+	owner     string
+	length    int64
+	data      []byte
+	expiresAt time.Time
+	// writing is set while a chunk is being received, so concurrent chunks
+	// for the same upload are refused rather than interleaved.
+	writing bool
+}
+
+func (u *upload) info(id string) Info {
+	return Info{ID: id, Length: u.length, Offset: int64(len(u.data)), ExpiresAt: u.expiresAt}
+}
+
+// Manager holds uploads. It is safe for concurrent use.
+type Manager struct {
+	maxBytes int64
+	maxTotal int64
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	uploads map[string]*upload
+	// total is the combined declared length of the held uploads, which is
+	// reserved up front so uploads can't outgrow maxTotal as chunks arrive.
+	total int64
+}
+
+// NewManager creates a Manager for uploads of at most maxBytes each and
+// maxTotal combined, which expire ttl after their last chunk.
+func NewManager(maxBytes, maxTotal int64, ttl time.Duration) *Manager {
+	return &Manager{maxBytes: maxBytes, maxTotal: maxTotal, ttl: ttl, uploads: make(map[string]*upload)}
+}
+
+// MaxBytes is the largest upload accepted.
+func (m *Manager) MaxBytes() int64 {
+	return m.maxBytes
+}
+
+// get returns a live upload owned by owner. The caller holds mu.
+func (m *Manager) get(id, owner string) (*upload, error) {
+	u, ok := m.uploads[id]
+	if !ok || u.owner != owner {
+		return nil, ErrNotFound
+	}
+	if time.Now().After(u.expiresAt) {
+		m.remove(id)
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+// remove drops an upload and releases its bytes. The caller holds mu.
+func (m *Manager) remove(id string) {
+	if u, ok := m.uploads[id]; ok {
+		m.total -= u.length
+		delete(m.uploads, id)
+	}
+}
+
+// prune drops expired uploads. The caller holds mu.
+func (m *Manager) prune() {
+	now := time.Now()
+	for id, u := range m.uploads {
+		if now.After(u.expiresAt) {
+			m.remove(id)
+		}
+	}
+}
+
+// RetryAfter estimates how long until owner's oldest upload expires, or with
+// an empty owner anyone's, freeing room for a new one.
+func (m *Manager) RetryAfter(owner string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wait := m.ttl
+	for _, u := range m.uploads {
+		if owner == "" || u.owner == owner {
+			wait = min(wait, time.Until(u.expiresAt))
+		}
+	}
+	return wait
+}
+
+// Create starts an upload of length bytes for owner.
+func (m *Manager) Create(owner string, length int64) (Info, error) {
+	if length > m.maxBytes {
+		return Info{}, ErrTooLarge
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	pending := 0
+	for _, u := range m.uploads {
+		if u.owner == owner {
+			pending++
+		}
+	}
+	if pending >= MaxPendingPerOwner {
+		return Info{}, ErrTooMany
+	}
+	if m.total+length > m.maxTotal {
+		return Info{}, ErrFull
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	u := &upload{owner: owner, length: length, expiresAt: time.Now().Add(m.ttl)}
+	m.uploads[id] = u
+	m.total += length
+	return u.info(id), nil
+}
+
+// Stat returns an upload's progress.
+func (m *Manager) Stat(id, owner string) (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.get(id, owner)
+	if err != nil {
+		return Info{}, err
+	}
+	return u.info(id), nil
+}
+
+// Append adds the chunk read from r at offset, which must be the upload's
+// current offset. Bytes that arrive before r fails are kept, so the client
+// can resume from the returned offset.
+func (m *Manager) Append(id, owner string, offset int64, r io.Reader) (Info, error) {
+	m.mu.Lock()
+	u, err := m.get(id, owner)
+	if err == nil && (u.writing || offset != int64(len(u.data))) {
+		err = ErrOffsetMismatch
+	}
+	if err != nil {
+		m.mu.Unlock()
+		return Info{}, err
+	}
+	u.writing = true
+	remaining := u.length - offset
+	m.mu.Unlock()
+
+	// Read one byte more than remains to detect data past the declared length.
+	chunk, readErr := io.ReadAll(io.LimitReader(r, remaining+1))
+	if int64(len(chunk)) > remaining {
+		chunk, readErr = chunk[:remaining], ErrTooLarge
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u.writing = false
+	u.data = append(u.data, chunk...)
+	u.expiresAt = time.Now().Add(m.ttl)
+	return u.info(id), readErr
+}
+
+// Data returns a complete upload's bytes. The upload is kept until Delete,
+// so a failed request that used it can be retried.
+func (m *Manager) Data(id, owner string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.get(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(u.data)) != u.length {
+		return nil, ErrIncomplete
+	}
+	return u.data, nil
+}
+
+// Delete drops an upload.
+func (m *Manager) Delete(id, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.get(id, owner); err != nil {
+		return err
+	}
+	m.remove(id)
+	return nil
+}
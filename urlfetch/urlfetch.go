@@ -0,0 +1,201 @@
+// urlfetch/urlfetch.go
+//
+// Package urlfetch downloads photos from client-supplied URLs. Since the URL
+// is untrusted, the fetch is confined to public addresses on the standard web
+// ports, so it cannot reach the server's own network or cloud metadata
+// endpoints (SSRF), and is bounded in size and time.
+package urlfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRedirects is how many redirects a fetch follows.
+const maxRedirects = 3
+
+var (
+	// ErrInvalidURL is returned for URLs that are not absolute http(s) URLs on
+	// a standard port, or whose host is not allowed.
+	ErrInvalidURL = errors.New("invalid image URL")
+	// ErrBlocked is returned when the host resolves to a private, loopback or
+	// otherwise non-public address.
+	ErrBlocked = errors.New("image URL resolves to a non-public address")
+	// ErrTooLarge is returned when the image exceeds the size limit.
+	ErrTooLarge = errors.New("image is too large")
+	// ErrNotImage is returned when the response is not declared as an image.
+	ErrNotImage = errors.New("URL does not point to an image")
+	// ErrFailed is returned when the image could not be downloaded.
+	ErrFailed = errors.New("failed to fetch image")
+)
+
+// blockedPrefixes are non-public ranges not covered by the netip predicates.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, which can reach IPv4 internals
+}
+
+// publicAddr reports whether a connection to addr stays on the public internet.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetcher downloads images from public URLs.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+	// allowedHosts, if set, limits fetches to these hosts and their subdomains.
+	allowedHosts []string
+	// deniedHosts are never fetched from, nor their subdomains, even if allowed.
+	deniedHosts []string
+}
+
+// New creates a Fetcher that downloads at most maxBytes within timeout. An
+// empty allowedHosts allows any public host not in deniedHosts.
+func New(maxBytes int64, timeout time.Duration, allowedHosts, deniedHosts []string) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		// Check the address actually dialed, after DNS resolution, so a host
+		// that resolves to an internal address, or rebinds to one, is refused.
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlocked, address)
+			}
+			return nil
+		},
+	}
+	f := &Fetcher{maxBytes: maxBytes, allowedHosts: normalizeHosts(allowedHosts), deniedHosts: normalizeHosts(deniedHosts)}
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy: it would dial internal addresses on our behalf.
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("%w: too many redirects", ErrFailed)
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+func normalizeHosts(hosts []string) []string {
+	var out []string
+	for _, h := range hosts {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// matchesHost reports whether host is one of hosts or a subdomain of one.
+func matchesHost(host string, hosts []string) bool {
+	return slices.ContainsFunc(hosts, func(h string) bool {
+		return host == h || strings.HasSuffix(host, "."+h)
+	})
+}
+
+// checkURL rejects URLs the fetcher must not follow before any connection is made.
+func (f *Fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be http or https", ErrInvalidURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials are not allowed", ErrInvalidURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrInvalidURL)
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		return fmt.Errorf("%w: port %s is not allowed", ErrInvalidURL, port)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrBlocked, host)
+	}
+	if matchesHost(host, f.deniedHosts) || (len(f.allowedHosts) > 0 && !matchesHost(host, f.allowedHosts)) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrInvalidURL, host)
+	}
+	return nil
+}
+
+// Fetch downloads the image at rawURL. The caller still has to verify the
+// content, since the declared Content-Type is only a first check.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() {
+		return nil, fmt.Errorf("%w: must be an absolute URL", ErrInvalidURL)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	req.Header.Set("Accept", "image/*")
+	res, err := f.client.Do(req)
+	if err != nil {
+		// Redirect and dial checks surface wrapped in a *url.Error.
+		for _, sentinel := range []error{ErrInvalidURL, ErrBlocked} {
+			if errors.Is(err, sentinel) {
+				return nil, err
+			}
+		}
+		return nil, fmt.Errorf("%w: %w", ErrFailed, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrFailed, res.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("%w: content type %q", ErrNotImage, res.Header.Get("Content-Type"))
+	}
+	if res.ContentLength > f.maxBytes {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, res.ContentLength)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailed, err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, f.maxBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,293 @@
+// usage/usage.go
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sanjayshr/event-outfitter-backend/store"
+)
+
+// namespace is the store namespace that holds persisted usage counters.
+const namespace = "usage"
+
+// Kind identifies a billable operation.
+type Kind string
+
+const (
+	// KindGeneration is a full /generate call: one suggestion call plus one image call.
+	KindGeneration Kind = "generation"
+	// KindSwap is a /swap-style call: one image call.
+	KindSwap Kind = "swap"
+	// KindPreviews is a /previews call: one low-resolution image call per style.
+	KindPreviews Kind = "previews"
+	// KindGrade is an event photo grade: one multimodal text call.
+	KindGrade Kind = "grade"
+	// KindSuggestions is a /styles/more or /styles/regenerate call: one
+	// suggestion call.
+	KindSuggestions Kind = "suggestions"
+	// KindSearch is a similar looks search by text: one embedding call.
+	KindSearch Kind = "search"
+)
+
+// Estimated Gemini cost in USD per model call, used for display purposes only.
+const (
+	suggestionCallCostUSD = 0.0005
+	embedCallCostUSD      = 0.00001
+	imageCallCostUSD      = 0.039
+	// gradeCallCostUSD covers the two images a grade sends.
+	gradeCallCostUSD = 0.002
+	// previewStyles is the usual number of styles in a session.
+	previewStyles = 5
+)
+
+// estimatedCost maps each operation to its estimated Gemini cost.
+var estimatedCost = map[Kind]float64{
+	KindGeneration:  suggestionCallCostUSD + imageCallCostUSD,
+	KindSwap:        imageCallCostUSD,
+	KindPreviews:    previewStyles * imageCallCostUSD,
+	KindGrade:       gradeCallCostUSD,
+	KindSuggestions: suggestionCallCostUSD,
+	KindSearch:      embedCallCostUSD,
+}
+
+// Counters holds the accumulated usage for a single API key or user.
+type Counters struct {
+	Generations      int64     `json:"generations"`
+	Swaps            int64     `json:"swaps"`
+	Previews         int64     `json:"previews"`
+	Grades           int64     `json:"grades"`
+	Suggestions      int64     `json:"suggestions"`
+	Searches         int64     `json:"searches"`
+	EstimatedCostUSD float64   `json:"estimatedCostUsd"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+
+	// Day is the UTC date (YYYY-MM-DD) that DayCount refers to.
+	Day      string `json:"day"`
+	DayCount int64  `json:"dayCount"`
+}
+
+// Quota describes a caller's free-tier allowance for the current UTC day.
+type Quota struct {
+	Limit     int64
+	Used      int64
+	Remaining int64
+	ResetAt   time.Time
+}
+
+// Meter tracks usage per client key and persists the counters to a store.
+type Meter struct {
+	logger *slog.Logger
+	store  store.Store
+	// dailyLimit caps generations plus swaps per key per UTC day; 0 disables the cap.
+	dailyLimit int64
+
+	mu       sync.Mutex
+	counters map[string]*Counters
+	// reserved counts operations allowed today but not yet recorded, per key.
+	reserved map[string]int64
+}
+
+// Reservation holds operations of a caller's daily allowance from Allow until
+// they are recorded or released, so concurrent requests can't all pass the
+// check for the last free operation.
+type Reservation struct {
+	m   *Meter
+	key string
+	n   int64
+	// id and records name the operations recorded through the reservation.
+	id      string
+	records int
+}
+
+// NewMeter creates a Meter backed by the given store. dailyLimit is the number of
+// free generations and swaps allowed per key per UTC day; 0 means unlimited.
+func NewMeter(logger *slog.Logger, st store.Store, dailyLimit int64) *Meter {
+	return &Meter{
+		logger:     logger,
+		store:      st,
+		dailyLimit: dailyLimit,
+		counters:   make(map[string]*Counters),
+		reserved:   make(map[string]int64),
+	}
+}
+
+// SetDailyLimit changes the free-tier cap, e.g. on config reload.
+func (m *Meter) SetDailyLimit(limit int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dailyLimit = limit
+}
+
+// today returns the current UTC date and the time the next day starts.
+func today() (string, time.Time) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start.Format(time.DateOnly), start.AddDate(0, 0, 1)
+}
+
+// load returns the in-memory counters for key, reading them from the store on first access.
+// If the store can't be read, nothing is cached: zeroed counters would
+// otherwise overwrite the persisted ones on the next record.
+// The caller must hold m.mu.
+func (m *Meter) load(ctx context.Context, key string) (*Counters, error) {
+	if c, ok := m.counters[key]; ok {
+		return c, nil
+	}
+	c := &Counters{}
+	if err := store.GetJSON(ctx, m.store, namespace, key, c); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load usage counters: %w", err)
+	}
+	m.counters[key] = c
+	return c, nil
+}
+
+// Record adds one operation of the given kind to key's counters and persists them.
+func (m *Meter) Record(ctx context.Context, key string, kind Kind) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.record(ctx, key, kind)
+}
+
+// record is Record with m.mu held. If the counters can't be loaded, the
+// operation is logged and not counted.
+func (m *Meter) record(ctx context.Context, key string, kind Kind) {
+	c, err := m.load(ctx, key)
+	if err != nil {
+		m.logger.Error("Usage not recorded", "key", key, "kind", kind, "error", err)
+		return
+	}
+	day, _ := today()
+	if c.Day != day {
+		c.Day, c.DayCount = day, 0
+	}
+	c.DayCount++
+	switch kind {
+	case KindGeneration:
+		c.Generations++
+	case KindSwap:
+		c.Swaps++
+	case KindPreviews:
+		c.Previews++
+	case KindGrade:
+		c.Grades++
+	case KindSuggestions:
+		c.Suggestions++
+	case KindSearch:
+		c.Searches++
+	}
+	c.EstimatedCostUSD += estimatedCost[kind]
+	c.UpdatedAt = time.Now().UTC()
+
+	if err := store.PutJSON(ctx, m.store, namespace, key, c); err != nil {
+		// Counters stay correct in memory; they will be persisted on the next record.
+		m.logger.Error("Failed to persist usage counters", "key", key, "error", err)
+	}
+}
+
+// Get returns a copy of the counters recorded for key.
+func (m *Meter) Get(ctx context.Context, key string) (Counters, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, err := m.load(ctx, key)
+	if err != nil {
+		return Counters{}, err
+	}
+	return *c, nil
+}
+
+// Quota returns key's free-tier allowance for the current UTC day.
+// A zero Limit means the cap is disabled.
+func (m *Meter) Quota(ctx context.Context, key string) (Quota, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.quota(ctx, key)
+}
+
+// quota is Quota with m.mu held. Reserved operations count as used.
+func (m *Meter) quota(ctx context.Context, key string) (Quota, error) {
+	c, err := m.load(ctx, key)
+	if err != nil {
+		return Quota{}, err
+	}
+	day, resetAt := today()
+	q := Quota{Limit: m.dailyLimit, Used: m.reserved[key], ResetAt: resetAt}
+	if c.Day == day {
+		q.Used += c.DayCount
+	}
+	q.Remaining = max(q.Limit-q.Used, 0)
+	return q, nil
+}
+
+// Allow reports whether key may perform another metered operation today and,
+// if the cap applies, reserves it. The reservation records usage either way;
+// the caller must Release it once done. It fails if the cap applies and key's
+// counters can't be read.
+func (m *Meter) Allow(ctx context.Context, key string) (Quota, *Reservation, bool, error) {
+	res := &Reservation{m: m, key: key, id: uuid.New().String()}
+	q, ok, err := res.Reserve(ctx, 1)
+	return q, res, ok, err
+}
+
+// Reserve reserves n more of the caller's operations for today, if that many
+// are left.
+func (res *Reservation) Reserve(ctx context.Context, n int64) (Quota, bool, error) {
+	m := res.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dailyLimit == 0 {
+		_, resetAt := today()
+		return Quota{ResetAt: resetAt}, true, nil
+	}
+	q, err := m.quota(ctx, res.key)
+	if err != nil {
+		return Quota{}, false, err
+	}
+	if q.Remaining < n {
+		return q, false, nil
+	}
+	m.reserved[res.key] += n
+	res.n += n
+	return q, true, nil
+}
+
+// Record records one operation of the given kind, using up a reserved one
+// if any are left. It returns an ID unique to the operation, e.g. to report it
+// to billing idempotently.
+func (res *Reservation) Record(ctx context.Context, kind Kind) string {
+	m := res.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if res.n > 0 {
+		res.n--
+		m.unreserve(res.key, 1)
+	}
+	m.record(ctx, res.key, kind)
+	res.records++
+	return fmt.Sprintf("%s-%d", res.id, res.records)
+}
+
+// Release gives back the reserved operations that were not recorded, e.g.
+// because the generation failed. It is safe to call more than once.
+func (res *Reservation) Release() {
+	m := res.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.unreserve(res.key, res.n)
+	res.n = 0
+}
+
+// unreserve returns n of key's reserved operations. The caller must hold m.mu.
+func (m *Meter) unreserve(key string, n int64) {
+	if m.reserved[key] -= n; m.reserved[key] <= 0 {
+		delete(m.reserved, key)
+	}
+}
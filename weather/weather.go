@@ -0,0 +1,336 @@
+// weather/weather.go
+//
+// Package weather looks up the conditions expected at an event's venue on its
+// date, so suggested outfits suit them. Venues are geocoded and conditions
+// fetched with the free Open-Meteo APIs: a forecast for dates in its range,
+// and otherwise the weather on the same date a year earlier as the typical
+// conditions. Lookups are cached, since many sessions share a venue and date.
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	geocodingURL = "https://geocoding-api.open-meteo.com/v1/search"
+	forecastURL  = "https://api.open-meteo.com/v1/forecast"
+	archiveURL   = "https://archive-api.open-meteo.com/v1/archive"
+
+	// forecastDays is how many days ahead, today included, the forecast API
+	// covers.
+	forecastDays = 16
+	// placeTTL is how long a geocoded venue is kept; places don't move.
+	placeTTL = 7 * 24 * time.Hour
+	// dailyVariables are the daily values asked of both weather APIs.
+	dailyVariables = "temperature_2m_max,temperature_2m_min,precipitation_sum,relative_humidity_2m_mean,wind_speed_10m_max"
+)
+
+// ErrUnknownPlace is returned by Lookup when the venue cannot be geocoded.
+var ErrUnknownPlace = errors.New("venue not found")
+
+// Conditions are the weather of one day at a place.
+type Conditions struct {
+	// Place is the geocoded place, e.g. "Panaji, Goa, India".
+	Place    string
+	Date     time.Time
+	MinTempC float64
+	MaxTempC float64
+	// PrecipitationMM is the day's total rain and snow.
+	PrecipitationMM float64
+	// HumidityPercent is the mean relative humidity.
+	HumidityPercent float64
+	WindKMH         float64
+	// Typical reports that the date is beyond the forecast range, so these
+	// are the conditions on the same date a year earlier.
+	Typical bool
+}
+
+// Summary describes the conditions for a prompt, e.g. "24-32°C, humid, rain
+// likely".
+func (c Conditions) Summary() string {
+	parts := []string{fmt.Sprintf("%.0f-%.0f°C", c.MinTempC, c.MaxTempC)}
+	switch {
+	case c.HumidityPercent >= 70:
+		parts = append(parts, "humid")
+	case c.HumidityPercent <= 30:
+		parts = append(parts, "dry")
+	}
+	switch {
+	case c.PrecipitationMM >= 2:
+		parts = append(parts, "rain likely")
+	case c.PrecipitationMM >= 0.2:
+		parts = append(parts, "light rain possible")
+	}
+	if c.WindKMH >= 30 {
+		parts = append(parts, "windy")
+	}
+	summary := strings.Join(parts, ", ")
+	if c.Typical {
+		summary = "typically " + summary
+	}
+	return summary
+}
+
+// place is a geocoded venue; a zero place records that it wasn't found.
+type place struct {
+	name      string
+	latitude  float64
+	longitude float64
+}
+
+// Client looks up and caches the conditions at venues.
+type Client struct {
+	client       *http.Client
+	geocodingURL string
+	forecastURL  string
+	archiveURL   string
+	places       *cache[place]
+	conditions   *cache[Conditions]
+	now          func() time.Time
+}
+
+// New creates a Client whose API calls each take at most timeout and whose
+// conditions are cached for ttl.
+func New(timeout, ttl time.Duration) *Client {
+	return &Client{
+		client:       &http.Client{Timeout: timeout},
+		geocodingURL: geocodingURL,
+		forecastURL:  forecastURL,
+		archiveURL:   archiveURL,
+		places:       newCache[place](placeTTL),
+		conditions:   newCache[Conditions](ttl),
+		now:          time.Now,
+	}
+}
+
+// Lookup returns the conditions expected at venue on date. It returns
+// ErrUnknownPlace if the venue cannot be geocoded.
+func (c *Client) Lookup(ctx context.Context, venue string, date time.Time) (Conditions, error) {
+	p, err := c.geocode(ctx, venue)
+	if err != nil {
+		return Conditions{}, err
+	}
+	key := p.name + "|" + date.Format(time.DateOnly)
+	if cond, ok := c.conditions.get(key); ok {
+		return cond, nil
+	}
+	cond, err := c.fetchConditions(ctx, p, date)
+	if err != nil {
+		return Conditions{}, err
+	}
+	c.conditions.put(key, cond)
+	return cond, nil
+}
+
+// geocode resolves a venue such as "Taj Exotica, Goa, India". The geocoding
+// API only matches place names, so the venue is searched part by part, and
+// a match in a later part's region or country is preferred.
+func (c *Client) geocode(ctx context.Context, venue string) (place, error) {
+	key := strings.Join(strings.Fields(strings.ToLower(venue)), " ")
+	if p, ok := c.places.get(key); ok {
+		if p.name == "" {
+			return place{}, ErrUnknownPlace
+		}
+		return p, nil
+	}
+	var parts []string
+	for _, part := range strings.Split(venue, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	for i, name := range parts {
+		results, err := c.search(ctx, name)
+		if err != nil {
+			return place{}, err
+		}
+		if len(results) == 0 {
+			continue
+		}
+		best := results[0]
+		for _, r := range results {
+			if r.within(parts[i+1:]) {
+				best = r
+				break
+			}
+		}
+		p := place{name: best.String(), latitude: best.Latitude, longitude: best.Longitude}
+		c.places.put(key, p)
+		return p, nil
+	}
+	c.places.put(key, place{})
+	return place{}, ErrUnknownPlace
+}
+
+// geocodingResult is the subset of a geocoding API result we use.
+type geocodingResult struct {
+	Name        string  `json:"name"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Admin1      string  `json:"admin1"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"country_code"`
+}
+
+// within reports whether any of regions names the result's region or
+// country.
+func (r geocodingResult) within(regions []string) bool {
+	for _, region := range regions {
+		for _, have := range []string{r.Admin1, r.Country, r.CountryCode} {
+			if have != "" && strings.EqualFold(region, have) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r geocodingResult) String() string {
+	parts := []string{r.Name}
+	for _, s := range []string{r.Admin1, r.Country} {
+		if s != "" && s != parts[len(parts)-1] {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// search asks the geocoding API for places called name.
+func (c *Client) search(ctx context.Context, name string) ([]geocodingResult, error) {
+	query := url.Values{"name": {name}, "count": {"10"}, "language": {"en"}, "format": {"json"}}
+	var out struct {
+		Results []geocodingResult `json:"results"`
+	}
+	if err := c.get(ctx, c.geocodingURL, query, &out); err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
+
+// fetchConditions fetches the forecast for date at p, or the weather a year
+// earlier if date is beyond the forecast range.
+func (c *Client) fetchConditions(ctx context.Context, p place, date time.Time) (Conditions, error) {
+	cond := Conditions{Place: p.name, Date: date}
+	endpoint, day := c.forecastURL, date
+	today := c.now().UTC().Truncate(24 * time.Hour)
+	if !date.Before(today.AddDate(0, 0, forecastDays)) {
+		endpoint, day = c.archiveURL, date.AddDate(-1, 0, 0)
+		cond.Typical = true
+	}
+	query := url.Values{
+		"latitude":   {strconv.FormatFloat(p.latitude, 'f', 4, 64)},
+		"longitude":  {strconv.FormatFloat(p.longitude, 'f', 4, 64)},
+		"daily":      {dailyVariables},
+		"timezone":   {"auto"},
+		"start_date": {day.Format(time.DateOnly)},
+		"end_date":   {day.Format(time.DateOnly)},
+	}
+	var out struct {
+		Daily struct {
+			MaxTemp       []*float64 `json:"temperature_2m_max"`
+			MinTemp       []*float64 `json:"temperature_2m_min"`
+			Precipitation []*float64 `json:"precipitation_sum"`
+			Humidity      []*float64 `json:"relative_humidity_2m_mean"`
+			Wind          []*float64 `json:"wind_speed_10m_max"`
+		} `json:"daily"`
+	}
+	if err := c.get(ctx, endpoint, query, &out); err != nil {
+		return Conditions{}, err
+	}
+	d := out.Daily
+	if first(d.MaxTemp) == nil || first(d.MinTemp) == nil {
+		return Conditions{}, fmt.Errorf("no weather data for %s on %s", p.name, day.Format(time.DateOnly))
+	}
+	cond.MaxTempC, cond.MinTempC = *first(d.MaxTemp), *first(d.MinTemp)
+	for _, v := range []struct {
+		dst *float64
+		src []*float64
+	}{{&cond.PrecipitationMM, d.Precipitation}, {&cond.HumidityPercent, d.Humidity}, {&cond.WindKMH, d.Wind}} {
+		if value := first(v.src); value != nil {
+			*v.dst = *value
+		}
+	}
+	return cond, nil
+}
+
+// first returns the first of a single day's values, which the APIs report
+// as null when unknown.
+func first(values []*float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// get calls an Open-Meteo API and decodes its JSON response into out.
+func (c *Client) get(ctx context.Context, endpoint string, query url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach weather service: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Reason string `json:"reason"`
+		}
+		json.NewDecoder(res.Body).Decode(&apiErr)
+		return fmt.Errorf("weather service returned %s: %s", res.Status, apiErr.Reason)
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode weather response: %w", err)
+	}
+	return nil
+}
+
+// cache keeps values for a fixed time. Expired entries are dropped as new
+// ones are added.
+type cache[V any] struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cached[V]
+}
+
+type cached[V any] struct {
+	value   V
+	expires time.Time
+}
+
+func newCache[V any](ttl time.Duration) *cache[V] {
+	return &cache[V]{ttl: ttl, entries: make(map[string]cached[V])}
+}
+
+func (c *cache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (c *cache[V]) put(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cached[V]{value: value, expires: now.Add(c.ttl)}
+}